@@ -0,0 +1,28 @@
+#!/bin/bash
+
+# Generates the client SDKs (Go module and TypeScript) from the OpenAPI spec
+# of the REST API into sdk/. Needs swag and go.
+
+while getopts v: option
+do
+case "${option}"
+in
+v) VERSION=${OPTARG};;
+esac
+done
+
+if [ -z "$VERSION" ]
+then
+	echo "Please provide the version of the SDKs with the -v flag. e.g: -v 1.0.0"
+	exit 1
+fi
+
+set -e
+
+cd "$(dirname "$0")"
+
+(cd src && swag init)
+
+(cd sdk/generator && go run . -v "${VERSION}" -spec ../../src/docs/swagger.json -out ..)
+
+echo "Generated the SDKs version ${VERSION} in sdk/"
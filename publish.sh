@@ -40,15 +40,17 @@ case "$response" in
 		
 		docker buildx create --name multibuilder
 		docker buildx use multibuilder
+
+		BUILD_ARGS="--build-arg GIT_COMMIT=$(git rev-parse HEAD) --build-arg BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
 		
 		if [[ "$TAG" == "stable" ]]; then
-			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 -t bbernhard/signal-cli-rest-api:$VERSION . --push
-			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 -t bbernhard/signal-cli-rest-api:latest . --push
+			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 $BUILD_ARGS -t bbernhard/signal-cli-rest-api:$VERSION . --push
+			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 $BUILD_ARGS -t bbernhard/signal-cli-rest-api:latest . --push
         fi
 
 		if [[ "$TAG" == "dev" ]]; then
-			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 -t bbernhard/signal-cli-rest-api:${VERSION}-dev . --push
-			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 -t bbernhard/signal-cli-rest-api:latest-dev . --push
+			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 $BUILD_ARGS -t bbernhard/signal-cli-rest-api:${VERSION}-dev . --push
+			docker buildx build --platform linux/amd64,linux/arm64,linux/arm/v7 $BUILD_ARGS -t bbernhard/signal-cli-rest-api:latest-dev . --push
         fi
 
 		;;
@@ -0,0 +1,3 @@
+module github.com/abaskin/signald-rest-api/sdk/generator
+
+go 1.15
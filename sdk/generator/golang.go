@@ -0,0 +1,525 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const goModule = "github.com/abaskin/signald-rest-api/sdk/go"
+
+const goPackage = "signalapi"
+
+const generatedHeader = "Code generated by sdk/generator from src/docs/swagger.json. DO NOT EDIT."
+
+// goReserved are the names the generated methods use themselves, parameters
+// with these names are renamed.
+var goReserved = map[string]bool{
+	"ctx": true, "opts": true, "body": true, "form": true, "r": true, "c": true, "status": true, "data": true,
+	"err": true, "result": true, "break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true, "range": true,
+	"return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+}
+
+// writeGo writes the Go module of the SDK to dir.
+func writeGo(a *api, dir string) error {
+	files := map[string]string{
+		"go.mod":     "module " + goModule + "\n\ngo 1.15\n",
+		"version.go": goVersion(a),
+		"client.go":  goClient(a),
+		"models.go":  goModels(a),
+		"api.go":     goOperations(a),
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, content := range files {
+		data := []byte(content)
+		if strings.HasSuffix(name, ".go") {
+			formatted, err := format.Source(data)
+			if err != nil {
+				return fmt.Errorf("couldn't format %s: %v", name, err)
+			}
+			data = formatted
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func goFile(imports ...string) string {
+	s := "// " + generatedHeader + "\n\npackage " + goPackage + "\n\n"
+	if len(imports) > 0 {
+		s += "import (\n"
+		for _, i := range imports {
+			s += "\t\"" + i + "\"\n"
+		}
+		s += ")\n\n"
+	}
+	return s
+}
+
+func goVersion(a *api) string {
+	return goFile() + "// Version is the version of the SDK.\nconst Version = \"" + a.Version + "\"\n"
+}
+
+// goType returns the Go type of a schema, refs are pointers if ptr is set.
+func (a *api) goType(s *schema, ptr bool) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		if ptr {
+			return "*" + a.refName(s.Ref)
+		}
+		return a.refName(s.Ref)
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + a.goType(s.Items, false)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + a.goType(s.AdditionalProperties, false)
+		}
+	}
+	return "interface{}"
+}
+
+// goFieldType returns the type of a field of a model: scalars and models are
+// pointers, so that unset fields are left out of requests.
+func (a *api) goFieldType(s *schema) string {
+	t := a.goType(s, true)
+	switch t {
+	case "string", "int64", "float64", "bool":
+		return "*" + t
+	}
+	return t
+}
+
+func paramType(p parameter) string {
+	switch p.Type {
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]string"
+	}
+	return "string"
+}
+
+// goParamName returns the name of the argument of a parameter, e.g. deviceID
+// for device_id.
+func goParamName(name string) string {
+	n := lowerFirst(exported(name))
+	if goReserved[n] {
+		n += "Param"
+	}
+	return n
+}
+
+// lowerFirst lowers the leading upper case letters of an exported name, an
+// initialism at the start is lowered as a whole.
+func lowerFirst(name string) string {
+	upper := 0
+	for upper < len(name) && name[upper] >= 'A' && name[upper] <= 'Z' {
+		upper++
+	}
+	switch {
+	case upper == len(name):
+		return strings.ToLower(name)
+	case upper > 1:
+		upper--
+	}
+	return strings.ToLower(name[:upper]) + name[upper:]
+}
+
+// goFormat returns the expression that formats the value v of a parameter
+// as a string.
+func goFormat(p parameter, v string) string {
+	switch p.Type {
+	case "integer":
+		return "strconv.FormatInt(" + v + ", 10)"
+	case "number":
+		return "strconv.FormatFloat(" + v + ", 'f', -1, 64)"
+	case "boolean":
+		return "strconv.FormatBool(" + v + ")"
+	}
+	return v
+}
+
+func goClient(a *api) string {
+	return goFile("bytes", "context", "encoding/json", "fmt", "io", "io/ioutil", "net/http", "net/url", "strings") +
+		`// Client is a client of the ` + a.Title + `.
+type Client struct {
+	// BaseURL is the URL the API is served at, e.g. http://127.0.0.1:8080.
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient if it is nil.
+	HTTPClient *http.Client
+	// Header is sent with every request, e.g. the Authorization header.
+	Header http.Header
+}
+
+// NewClient returns a client of the API served at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, Header: http.Header{}}
+}
+
+// Error is the error of a request the API didn't succeed with.
+type Error struct {
+	StatusCode int
+	// Message is the error the API responded with.
+	Message string
+	// Header is the header of the response, e.g. with Retry-After.
+	Header http.Header
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        io.Reader
+	contentType string
+}
+
+func newRequest(method string, path string) *request {
+	return &request{method: method, path: path, query: url.Values{}, header: http.Header{}}
+}
+
+func (r *request) setJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.body, r.contentType = bytes.NewReader(data), "application/json"
+	return nil
+}
+
+func (r *request) setForm(form url.Values) {
+	r.body, r.contentType = strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"
+}
+
+// send sends a request and returns the status and body of the response, it
+// fails with an *Error if the status isn't 2xx.
+func (c *Client) send(ctx context.Context, r *request) (int, []byte, error) {
+	u := strings.TrimRight(c.BaseURL, "/") + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequest(r.method, u, r.body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data)), Header: resp.Header}
+		var body struct {
+			Error string ` + "`json:\"error\"`" + `
+		}
+		if json.Unmarshal(data, &body) == nil && body.Error != "" {
+			e.Message = body.Error
+		}
+		return resp.StatusCode, nil, e
+	}
+	return resp.StatusCode, data, nil
+}
+
+func decode(data []byte, v interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// PtrString returns a pointer to v, for the optional fields of the models.
+func PtrString(v string) *string { return &v }
+
+// PtrInt64 returns a pointer to v, for the optional fields of the models.
+func PtrInt64(v int64) *int64 { return &v }
+
+// PtrFloat64 returns a pointer to v, for the optional fields of the models.
+func PtrFloat64(v float64) *float64 { return &v }
+
+// PtrBool returns a pointer to v, for the optional fields of the models.
+func PtrBool(v bool) *bool { return &v }
+`
+}
+
+// typeDoc returns the doc comment of a type, the descriptions of the spec
+// start with the name of the Go type of the API, which is replaced.
+func typeDoc(name string, description string, fallback string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return name + " " + fallback
+	}
+	words := strings.SplitN(description, " ", 2)
+	if strings.EqualFold(words[0], name) || exported(words[0]) == name {
+		words[0] = name
+		return strings.Join(words, " ")
+	}
+	return name + ": " + description
+}
+
+func goModels(a *api) string {
+	var b strings.Builder
+	b.WriteString(goFile())
+	for _, m := range a.Models {
+		b.WriteString(comment("//", typeDoc(m.Name, m.Description, "is a model of the API.")))
+		if len(m.Fields) == 0 {
+			b.WriteString("type " + m.Name + " map[string]interface{}\n\n")
+			continue
+		}
+		b.WriteString("type " + m.Name + " struct {\n")
+		for _, f := range m.Fields {
+			b.WriteString(comment("//", f.Description))
+			fmt.Fprintf(&b, "%s %s `json:\"%s,omitempty\"`\n", f.Name, a.goFieldType(f.Schema), f.JSONName)
+		}
+		b.WriteString("}\n\n")
+	}
+	return b.String()
+}
+
+// goResultType returns the type an operation returns besides the error, ""
+// if it only returns an error.
+func (a *api) goResultType(e *endpoint) string {
+	switch {
+	case e.Binary:
+		return "[]byte"
+	case len(e.Results) == 1:
+		return a.goType(e.Results[0].Schema, true)
+	case len(e.Results) > 1:
+		return "*" + e.Name + "Response"
+	}
+	return ""
+}
+
+func goPath(e *endpoint) string {
+	path := fmt.Sprintf("%q", e.Path)
+	for _, p := range e.PathParams {
+		v := goParamName(p.Name)
+		if p.Type == "integer" {
+			v = "strconv.FormatInt(" + v + ", 10)"
+		}
+		path = strings.Replace(path, "{"+p.Name+"}", `" + url.PathEscape(`+v+`) + "`, 1)
+	}
+	return strings.Replace(path, ` + ""`, "", -1)
+}
+
+func goOperations(a *api) string {
+	var b strings.Builder
+	imports := map[string]bool{"context": true, "net/http": false, "net/url": false, "strconv": false}
+
+	for _, e := range a.Operations {
+		result := a.goResultType(e)
+		optional := []parameter{}
+		args := []string{"ctx context.Context"}
+		for _, p := range e.PathParams {
+			args = append(args, goParamName(p.Name)+" "+paramType(p))
+		}
+		for _, params := range [][]parameter{e.QueryParams, e.HeaderParams, e.FormParams} {
+			for _, p := range params {
+				if p.Required {
+					args = append(args, goParamName(p.Name)+" "+paramType(p))
+				} else {
+					optional = append(optional, p)
+				}
+			}
+		}
+		switch {
+		case e.Body != nil:
+			args = append(args, "body "+a.goType(e.Body.Schema, false))
+		case e.RawBody:
+			args = append(args, "body io.Reader")
+			imports["io"] = true
+		case e.FormBody:
+			args = append(args, "form url.Values")
+			imports["net/url"] = true
+		}
+		if len(optional) > 0 {
+			args = append(args, "opts *"+e.Name+"Options")
+		}
+
+		if len(e.Results) > 1 {
+			fmt.Fprintf(&b, "// %sResponse is the response of %s, the field of its status is set.\n", e.Name, e.Name)
+			fmt.Fprintf(&b, "type %sResponse struct {\n", e.Name)
+			for _, r := range e.Results {
+				fmt.Fprintf(&b, "// %s is the response with status %d.\n", r.Name, r.Status)
+				fmt.Fprintf(&b, "%s %s\n", r.Name, a.goType(r.Schema, true))
+			}
+			b.WriteString("}\n\n")
+		}
+		if len(optional) > 0 {
+			fmt.Fprintf(&b, "// %sOptions are the optional parameters of %s.\n", e.Name, e.Name)
+			fmt.Fprintf(&b, "type %sOptions struct {\n", e.Name)
+			for _, p := range optional {
+				b.WriteString(comment("//", p.Description))
+				t := paramType(p)
+				if !strings.HasPrefix(t, "[]") {
+					t = "*" + t
+				}
+				fmt.Fprintf(&b, "%s %s\n", exported(p.Name), t)
+			}
+			b.WriteString("}\n\n")
+		}
+
+		fmt.Fprintf(&b, "// %s sends %s %s.\n", e.Name, e.Method, e.Path)
+		if e.Summary != "" {
+			b.WriteString("//\n" + comment("//", sentence(e.Summary)))
+		}
+		if e.Description != "" && e.Description != e.Summary {
+			b.WriteString("//\n" + comment("//", sentence(e.Description)))
+		}
+		returns := "error"
+		fail := "err"
+		switch {
+		case e.Binary:
+			returns = "(" + result + ", error)"
+			fail = "nil, err"
+		case result != "":
+			returns = "(" + result + ", error)"
+			fail = "result, err"
+		}
+		fmt.Fprintf(&b, "func (c *Client) %s(%s) %s {\n", e.Name, strings.Join(args, ", "), returns)
+		if result != "" && !e.Binary {
+			fmt.Fprintf(&b, "var result %s\n", result)
+		}
+		path := goPath(e)
+		if len(e.PathParams) > 0 {
+			imports["net/url"] = true
+		}
+		fmt.Fprintf(&b, "r := newRequest(%q, %s)\n", e.Method, path)
+
+		form := len(e.FormParams) > 0
+		if form {
+			b.WriteString("form := url.Values{}\n")
+			imports["net/url"] = true
+		}
+		set := func(p parameter, v string) string {
+			switch p.In {
+			case "query":
+				if p.Type == "array" {
+					return "for _, v := range " + v + " {\nr.query.Add(" + fmt.Sprintf("%q", p.Name) + ", v)\n}\n"
+				}
+				return fmt.Sprintf("r.query.Set(%q, %s)\n", p.Name, goFormat(p, v))
+			case "header":
+				return fmt.Sprintf("r.header.Set(%q, %s)\n", p.Name, goFormat(p, v))
+			}
+			return fmt.Sprintf("form.Set(%q, %s)\n", p.Name, goFormat(p, v))
+		}
+		for _, params := range [][]parameter{e.QueryParams, e.HeaderParams, e.FormParams} {
+			for _, p := range params {
+				if p.Required {
+					b.WriteString(set(p, goParamName(p.Name)))
+					if p.Type != "string" {
+						imports["strconv"] = true
+					}
+				}
+			}
+		}
+		if len(optional) > 0 {
+			b.WriteString("if opts != nil {\n")
+			for _, p := range optional {
+				v := "opts." + exported(p.Name)
+				if p.Type == "array" {
+					b.WriteString(set(p, v))
+					continue
+				}
+				fmt.Fprintf(&b, "if %s != nil {\n%s}\n", v, set(p, "*"+v))
+				if p.Type != "string" {
+					imports["strconv"] = true
+				}
+			}
+			b.WriteString("}\n")
+		}
+		for _, p := range e.PathParams {
+			if p.Type == "integer" {
+				imports["strconv"] = true
+			}
+		}
+
+		switch {
+		case form:
+			b.WriteString("r.setForm(form)\n")
+		case e.Body != nil:
+			fmt.Fprintf(&b, "if err := r.setJSON(body); err != nil {\nreturn %s\n}\n", fail)
+		case e.RawBody:
+			b.WriteString("r.body, r.contentType = body, \"application/octet-stream\"\n")
+		case e.FormBody:
+			b.WriteString("r.setForm(form)\n")
+		}
+
+		switch {
+		case result == "":
+			b.WriteString("_, _, err := c.send(ctx, r)\nreturn err\n")
+		case e.Binary:
+			b.WriteString("_, data, err := c.send(ctx, r)\nreturn data, err\n")
+		case len(e.Results) == 1:
+			fmt.Fprintf(&b, "status, data, err := c.send(ctx, r)\nif err != nil || status != %d {\nreturn result, err\n}\n",
+				e.Results[0].Status)
+			b.WriteString("return result, decode(data, &result)\n")
+		default:
+			b.WriteString("status, data, err := c.send(ctx, r)\nif err != nil {\nreturn result, err\n}\n")
+			fmt.Fprintf(&b, "result = &%sResponse{}\nswitch status {\n", e.Name)
+			for _, r := range e.Results {
+				fmt.Fprintf(&b, "case %d:\nreturn result, decode(data, &result.%s)\n", r.Status, r.Name)
+			}
+			b.WriteString("}\nreturn result, nil\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	used := []string{}
+	for i, ok := range imports {
+		if ok {
+			used = append(used, i)
+		}
+	}
+	sort.Strings(used)
+	return goFile(used...) + b.String()
+}
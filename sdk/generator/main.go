@@ -0,0 +1,44 @@
+// Command generator generates the client SDKs of the REST API from its
+// OpenAPI spec: a Go module and a TypeScript package. The SDKs only use the
+// standard libraries, so generating them needs nothing but Go.
+//
+// Usage: go run . -v 1.0.0 [-spec ../../src/docs/swagger.json] [-out ..]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	version := flag.String("v", "", "version of the SDKs, e.g. 1.0.0")
+	specFile := flag.String("spec", "../../src/docs/swagger.json", "OpenAPI spec of the API")
+	out := flag.String("out", "..", "directory the SDKs are generated in, as go/ and typescript/")
+	flag.Parse()
+
+	if *version == "" {
+		fmt.Fprintln(os.Stderr, "Please provide the version of the SDKs with the -v flag. e.g: -v 1.0.0")
+		os.Exit(1)
+	}
+
+	s, err := loadSpec(*specFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	a := newAPI(s, *version)
+
+	for dir, write := range map[string]func(*api, string) error{"go": writeGo, "typescript": writeTypeScript} {
+		dir = filepath.Join(*out, dir)
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := write(a, dir); err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't generate "+dir+": ", err)
+			os.Exit(1)
+		}
+	}
+}
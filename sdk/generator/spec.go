@@ -0,0 +1,404 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// spec is the part of a Swagger 2.0 document the SDKs are generated from.
+type spec struct {
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+	} `json:"info"`
+	Host        string                          `json:"host"`
+	BasePath    string                          `json:"basePath"`
+	Paths       map[string]map[string]operation `json:"paths"`
+	Definitions map[string]*schema              `json:"definitions"`
+}
+
+type operation struct {
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Consumes    []string            `json:"consumes"`
+	Produces    []string            `json:"produces"`
+	Tags        []string            `json:"tags"`
+	Parameters  []parameter         `json:"parameters"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Type        string  `json:"type"`
+	Items       *schema `json:"items"`
+	Schema      *schema `json:"schema"`
+}
+
+type response struct {
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Description          string             `json:"description"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+}
+
+func loadSpec(filename string) (*spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &spec{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// api is the spec prepared for the generators: models and operations with
+// the names they have in the SDKs, in a stable order.
+type api struct {
+	Title      string
+	Version    string
+	Models     []*model
+	Operations []*endpoint
+
+	// modelNames maps the definition names of the spec to model names
+	modelNames map[string]string
+}
+
+type model struct {
+	Name        string
+	Description string
+	Fields      []*field
+}
+
+type field struct {
+	Name        string
+	JSONName    string
+	Description string
+	Schema      *schema
+}
+
+// endpoint is an operation of the API.
+type endpoint struct {
+	Name        string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	PathParams  []parameter
+	QueryParams []parameter
+	// HeaderParams are sent as request headers
+	HeaderParams []parameter
+	FormParams   []parameter
+	Body         *parameter
+	// RawBody is set if the operation takes a stream of bytes as body
+	RawBody bool
+	// FormBody is set if the operation takes a form without documented
+	// fields
+	FormBody bool
+	// Results are the successful responses with a JSON body, the response
+	// of an operation with several of them tells which one it is
+	Results []result
+	// Optional is set if the operation can succeed without a result
+	Optional bool
+	// Binary is set if the successful response isn't JSON
+	Binary bool
+}
+
+type result struct {
+	Status int
+	// Name is the name of the status, e.g. Created
+	Name   string
+	Schema *schema
+}
+
+var methods = []string{"get", "head", "post", "put", "patch", "delete", "options"}
+
+func newAPI(s *spec, version string) *api {
+	a := &api{Title: s.Info.Title, Version: version, modelNames: modelNames(s.Definitions)}
+
+	definitions := []string{}
+	for name := range s.Definitions {
+		definitions = append(definitions, name)
+	}
+	sort.Strings(definitions)
+	for _, name := range definitions {
+		a.Models = append(a.Models, newModel(a.modelNames[name], s.Definitions[name]))
+	}
+	sort.Slice(a.Models, func(i, j int) bool { return a.Models[i].Name < a.Models[j].Name })
+
+	paths := []string{}
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, method := range methods {
+			if op, ok := s.Paths[path][method]; ok {
+				a.Operations = append(a.Operations, newEndpoint(strings.ToUpper(method), path, op))
+			}
+		}
+	}
+	nameEndpoints(a.Operations)
+	return a
+}
+
+// sdkNames are the names the SDKs declare besides the models.
+var sdkNames = map[string]bool{"Client": true, "ClientOptions": true, "Error": true, "NewClient": true,
+	"RequestError": true, "Version": true}
+
+// modelNames names the models after the definitions without their Go package,
+// the package is kept for the names that would collide otherwise.
+func modelNames(definitions map[string]*schema) map[string]string {
+	short := map[string][]string{}
+	for name := range definitions {
+		s := exported(name[strings.LastIndex(name, ".")+1:])
+		short[s] = append(short[s], name)
+	}
+
+	names := map[string]string{}
+	for s, definitions := range short {
+		for _, name := range definitions {
+			if len(definitions) == 1 && !sdkNames[s] {
+				names[name] = s
+			} else {
+				names[name] = exported(strings.Replace(name, ".", "_", -1))
+			}
+		}
+	}
+	return names
+}
+
+func newModel(name string, s *schema) *model {
+	m := &model{Name: name, Description: s.Description}
+	properties := []string{}
+	for property := range s.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		p := s.Properties[property]
+		m.Fields = append(m.Fields, &field{
+			Name:        exported(property),
+			JSONName:    property,
+			Description: p.Description,
+			Schema:      p,
+		})
+	}
+	return m
+}
+
+func newEndpoint(method string, path string, op operation) *endpoint {
+	e := &endpoint{
+		Method:      method,
+		Path:        path,
+		Summary:     op.Summary,
+		Description: op.Description,
+	}
+	for i := range op.Parameters {
+		p := op.Parameters[i]
+		switch p.In {
+		case "path":
+			e.PathParams = append(e.PathParams, p)
+		case "query":
+			e.QueryParams = append(e.QueryParams, p)
+		case "header":
+			e.HeaderParams = append(e.HeaderParams, p)
+		case "formData":
+			e.FormParams = append(e.FormParams, p)
+		case "body":
+			e.Body = &p
+		}
+	}
+	// the path parameters are passed in the order they appear in the path
+	sort.SliceStable(e.PathParams, func(i, j int) bool {
+		return strings.Index(path, "{"+e.PathParams[i].Name+"}") < strings.Index(path, "{"+e.PathParams[j].Name+"}")
+	})
+
+	if e.Body == nil && len(e.FormParams) == 0 {
+		for _, consumes := range op.Consumes {
+			switch consumes {
+			case "application/octet-stream":
+				e.RawBody = true
+			case "application/x-www-form-urlencoded", "multipart/form-data":
+				e.FormBody = true
+			}
+		}
+	}
+
+	isJSON := len(op.Produces) == 0
+	for _, produces := range op.Produces {
+		if produces == "application/json" {
+			isJSON = true
+		}
+	}
+	e.Binary = !isJSON
+
+	codes := []string{}
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		s := op.Responses[code].Schema
+		// a string is a plain status like "OK"
+		if e.Binary || s == nil || s.Ref == "" && s.Type == "string" {
+			continue
+		}
+		status, _ := strconv.Atoi(code)
+		e.Results = append(e.Results, result{Status: status, Name: exported(http.StatusText(status)), Schema: s})
+	}
+	e.Optional = len(e.Results) > 0 && len(e.Results) < len(codes)
+	return e
+}
+
+// nameEndpoints names the operations after their method and the static
+// segments of their path. Operations that would have the same name are
+// named after their path parameters as well.
+func nameEndpoints(endpoints []*endpoint) {
+	count := map[string]int{}
+	for _, e := range endpoints {
+		e.Name = endpointName(e, false)
+		count[e.Name]++
+	}
+	for _, e := range endpoints {
+		if count[e.Name] > 1 {
+			e.Name = endpointName(e, true)
+		}
+	}
+}
+
+func endpointName(e *endpoint, withParams bool) string {
+	name := exported(strings.ToLower(e.Method))
+	params := []string{}
+	for _, segment := range strings.Split(strings.Trim(e.Path, "/"), "/") {
+		if strings.HasPrefix(segment, "{") {
+			params = append(params, exported(strings.Trim(segment, "{}")))
+			continue
+		}
+		name += exported(segment)
+	}
+	if withParams && len(params) > 0 {
+		name += "By" + strings.Join(params, "And")
+	}
+	return name
+}
+
+// initialisms are written in upper case in exported names, like golint
+// wants them.
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true, "SID": true, "SMS": true, "URL": true,
+	"UUID": true,
+}
+
+// exported turns a name like "internal_id", "txnId" or "Messages.json" into
+// an exported Go identifier like "InternalID", "TxnID" or "MessagesJSON".
+func exported(name string) string {
+	words := []string{}
+	word := []rune{}
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	result := ""
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			result += upper
+			continue
+		}
+		result += strings.ToUpper(w[:1]) + w[1:]
+	}
+	if result != "" && unicode.IsDigit([]rune(result)[0]) {
+		result = "V" + result
+	}
+	return result
+}
+
+// refName returns the name of the model a $ref points to.
+func (a *api) refName(ref string) string {
+	return a.modelNames[strings.TrimPrefix(ref, "#/definitions/")]
+}
+
+// commentWidth is the width the comments of the SDKs are wrapped at.
+const commentWidth = 100
+
+// sentence returns text ending with a period, so that a single line isn't
+// taken for a heading of the doc comment.
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text != "" && !strings.ContainsAny(text[len(text)-1:], ".!?:") {
+		text += "."
+	}
+	return text
+}
+
+// wrap breaks the lines of text into lines of at most width characters,
+// words longer than that get a line of their own.
+func wrap(text string, width int) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// comment returns text as the lines of a comment with the given prefix.
+func comment(prefix string, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	lines := []string{}
+	for _, line := range wrap(text, commentWidth-len(prefix)-1) {
+		lines = append(lines, strings.TrimRight(prefix+" "+line, " "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const npmPackage = "signald-rest-api-client"
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// writeTypeScript writes the TypeScript package of the SDK to dir.
+func writeTypeScript(a *api, dir string) error {
+	files := map[string]string{
+		"package.json":   tsPackage(a),
+		"tsconfig.json":  tsConfig,
+		"src/index.ts":   tsIndex(a),
+		"src/models.ts":  tsModels(a),
+		"src/api.ts":     tsOperations(a),
+		".gitignore":     "dist/\nnode_modules/\n",
+		".npmignore":     "src/\ntsconfig.json\n",
+		"src/version.ts": "// " + generatedHeader + "\n\n/** The version of the SDK. */\nexport const VERSION = \"" + a.Version + "\";\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tsPackage(a *api) string {
+	return `{
+  "name": "` + npmPackage + `",
+  "version": "` + a.Version + `",
+  "description": "Client of the ` + a.Title + `",
+  "main": "dist/index.js",
+  "types": "dist/index.d.ts",
+  "files": [
+    "dist"
+  ],
+  "scripts": {
+    "build": "tsc",
+    "prepare": "npm run build"
+  },
+  "devDependencies": {
+    "typescript": "^4.0.0"
+  }
+}
+`
+}
+
+const tsConfig = `{
+  "compilerOptions": {
+    "target": "ES2018",
+    "module": "commonjs",
+    "lib": ["ES2018", "DOM"],
+    "declaration": true,
+    "strict": true,
+    "rootDir": "src",
+    "outDir": "dist"
+  },
+  "include": ["src"]
+}
+`
+
+func tsIndex(a *api) string {
+	return "// " + generatedHeader + "\n\n" +
+		"export * from \"./api\";\nexport * from \"./models\";\nexport * from \"./version\";\n"
+}
+
+// tsDoc returns text as a JSDoc comment with the given indent.
+func tsDoc(indent string, text string) string {
+	text = strings.TrimSpace(strings.Replace(text, "*/", "* /", -1))
+	if text == "" {
+		return ""
+	}
+	lines := wrap(text, commentWidth-len(indent)-3)
+	if len(lines) == 1 {
+		return indent + "/** " + lines[0] + " */\n"
+	}
+	s := indent + "/**\n"
+	for _, line := range lines {
+		s += strings.TrimRight(indent+" * "+strings.TrimSpace(line), " ") + "\n"
+	}
+	return s + indent + " */\n"
+}
+
+// tsType returns the TypeScript type of a schema, models are qualified with
+// prefix.
+func (a *api) tsType(s *schema, prefix string) string {
+	if s == nil {
+		return "unknown"
+	}
+	if s.Ref != "" {
+		return prefix + a.refName(s.Ref)
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := a.tsType(s.Items, prefix)
+		if strings.ContainsAny(item, " |") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "{ [key: string]: " + a.tsType(s.AdditionalProperties, prefix) + " }"
+		}
+	}
+	return "unknown"
+}
+
+func tsParamType(p parameter) string {
+	switch p.Type {
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		return "string[]"
+	}
+	return "string"
+}
+
+func tsProperty(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+func tsModels(a *api) string {
+	var b strings.Builder
+	b.WriteString("// " + generatedHeader + "\n\n")
+	for _, m := range a.Models {
+		b.WriteString(tsDoc("", typeDoc(m.Name, m.Description, "is a model of the API.")))
+		if len(m.Fields) == 0 {
+			fmt.Fprintf(&b, "export type %s = { [key: string]: unknown };\n\n", m.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "export interface %s {\n", m.Name)
+		for _, f := range m.Fields {
+			b.WriteString(tsDoc("  ", f.Description))
+			fmt.Fprintf(&b, "  %s?: %s;\n", tsProperty(f.JSONName), a.tsType(f.Schema, ""))
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// tsParamName returns the name of the argument of a parameter, e.g. deviceID
+// for device_id.
+func tsParamName(name string) string {
+	n := lowerFirst(exported(name))
+	switch n {
+	case "options", "body", "form", "query", "headers", "response", "default", "delete", "new", "function":
+		n += "Param"
+	}
+	return n
+}
+
+func tsClient(a *api) string {
+	return `/** The options of a client. */
+export interface ClientOptions {
+  /** The URL the API is served at, http://127.0.0.1:8080 if it isn't given. */
+  baseURL?: string;
+  /** Headers sent with every request, e.g. the Authorization header. */
+  headers?: { [name: string]: string };
+  /** The fetch function the requests are sent with, the global one if it isn't given. */
+  fetch?: typeof fetch;
+}
+
+/** The error of a request the API didn't succeed with. */
+export class RequestError extends Error {
+  /** The status of the response. */
+  readonly status: number;
+  /** The headers of the response, e.g. with Retry-After. */
+  readonly headers: Headers;
+
+  constructor(status: number, message: string, headers: Headers) {
+    super(message);
+    this.name = "RequestError";
+    this.status = status;
+    this.headers = headers;
+  }
+}
+
+type QueryValue = string | number | boolean | string[] | undefined;
+
+interface Request {
+  method: string;
+  path: string;
+  query?: { [name: string]: QueryValue };
+  headers?: { [name: string]: string };
+  body?: BodyInit;
+  contentType?: string;
+}
+
+/** A client of the ` + a.Title + `. */
+export class Client {
+  readonly baseURL: string;
+  headers: { [name: string]: string };
+  private readonly fetch: typeof fetch;
+
+  constructor(options: ClientOptions = {}) {
+    this.baseURL = (options.baseURL ?? "http://127.0.0.1:8080").replace(/\/+$/, "");
+    this.headers = { ...options.headers };
+    this.fetch = options.fetch ?? ((input, init) => fetch(input, init));
+  }
+
+  /** Sends a request, it fails with a RequestError if the status isn't 2xx. */
+  private async send(request: Request): Promise<Response> {
+    const query = new URLSearchParams();
+    for (const [name, value] of Object.entries(request.query ?? {})) {
+      if (Array.isArray(value)) {
+        value.forEach((v) => query.append(name, v));
+      } else if (value !== undefined) {
+        query.set(name, String(value));
+      }
+    }
+    const search = query.toString();
+    const headers: { [name: string]: string } = { ...this.headers, ...request.headers };
+    if (request.contentType) {
+      headers["Content-Type"] = request.contentType;
+    }
+
+    const response = await this.fetch(this.baseURL + request.path + (search ? "?" + search : ""), {
+      method: request.method,
+      headers,
+      body: request.body,
+    });
+    if (!response.ok) {
+      let message = (await response.text()).trim();
+      try {
+        const body = JSON.parse(message);
+        if (body && typeof body.error === "string") {
+          message = body.error;
+        }
+      } catch {
+        // the error isn't JSON
+      }
+      throw new RequestError(response.status, message || response.statusText, response.headers);
+    }
+    return response;
+  }
+
+  private static async json<T>(response: Response): Promise<T> {
+    const text = await response.text();
+    return (text.trim() === "" ? undefined : JSON.parse(text)) as T;
+  }
+`
+}
+
+// tsResultType returns the type an operation resolves to.
+func (a *api) tsResultType(e *endpoint) string {
+	switch {
+	case e.Binary:
+		return "Blob"
+	case len(e.Results) == 1:
+		t := a.tsType(e.Results[0].Schema, "models.")
+		if e.Optional {
+			t += " | undefined"
+		}
+		return t
+	case len(e.Results) > 1:
+		return e.Name + "Response"
+	}
+	return "void"
+}
+
+func tsPath(e *endpoint) string {
+	path := e.Path
+	for _, p := range e.PathParams {
+		path = strings.Replace(path, "{"+p.Name+"}", "${encodeURIComponent("+tsParamName(p.Name)+")}", 1)
+	}
+	return "`" + path + "`"
+}
+
+func tsOperations(a *api) string {
+	var types, methods strings.Builder
+	for _, e := range a.Operations {
+		name := lowerFirst(e.Name)
+		optional := []parameter{}
+		args := []string{}
+		for _, p := range e.PathParams {
+			args = append(args, tsParamName(p.Name)+": "+tsParamType(p))
+		}
+		for _, params := range [][]parameter{e.QueryParams, e.HeaderParams, e.FormParams} {
+			for _, p := range params {
+				if p.Required {
+					args = append(args, tsParamName(p.Name)+": "+tsParamType(p))
+				} else {
+					optional = append(optional, p)
+				}
+			}
+		}
+		switch {
+		case e.Body != nil:
+			args = append(args, "body: "+a.tsType(e.Body.Schema, "models."))
+		case e.RawBody:
+			args = append(args, "body: BodyInit")
+		case e.FormBody:
+			args = append(args, "form: URLSearchParams | FormData")
+		}
+		if len(optional) > 0 {
+			args = append(args, "options: "+e.Name+"Options = {}")
+		}
+
+		if len(e.Results) > 1 {
+			types.WriteString(tsDoc("", "The response of "+name+", the property of its status is set."))
+			fmt.Fprintf(&types, "export interface %sResponse {\n", e.Name)
+			for _, r := range e.Results {
+				types.WriteString(tsDoc("  ", fmt.Sprintf("The response with status %d.", r.Status)))
+				fmt.Fprintf(&types, "  %s?: %s;\n", lowerFirst(r.Name), a.tsType(r.Schema, "models."))
+			}
+			types.WriteString("}\n\n")
+		}
+		if len(optional) > 0 {
+			types.WriteString(tsDoc("", "The optional parameters of "+name+"."))
+			fmt.Fprintf(&types, "export interface %sOptions {\n", e.Name)
+			for _, p := range optional {
+				types.WriteString(tsDoc("  ", p.Description))
+				fmt.Fprintf(&types, "  %s?: %s;\n", tsProperty(p.Name), tsParamType(p))
+			}
+			types.WriteString("}\n\n")
+		}
+
+		doc := e.Method + " " + e.Path
+		if e.Summary != "" {
+			doc += "\n\n" + sentence(e.Summary)
+		}
+		if e.Description != "" && e.Description != e.Summary {
+			doc += "\n\n" + sentence(e.Description)
+		}
+		methods.WriteString("\n" + tsDoc("  ", doc))
+		result := a.tsResultType(e)
+		fmt.Fprintf(&methods, "  async %s(%s): Promise<%s> {\n", name, strings.Join(args, ", "), result)
+
+		request := []string{fmt.Sprintf("method: %q", e.Method), "path: " + tsPath(e)}
+		query, headers, form := []string{}, []string{}, []string{}
+		for _, params := range [][]parameter{e.QueryParams, e.HeaderParams, e.FormParams} {
+			for _, p := range params {
+				v := tsParamName(p.Name)
+				if !p.Required {
+					v = "options." + p.Name
+					if !tsIdentifier.MatchString(p.Name) {
+						v = fmt.Sprintf("options[%q]", p.Name)
+					}
+				}
+				switch p.In {
+				case "query":
+					query = append(query, tsProperty(p.Name)+": "+v)
+				case "header":
+					if p.Required {
+						headers = append(headers, fmt.Sprintf("%q: String(%s)", p.Name, v))
+					} else {
+						headers = append(headers, fmt.Sprintf("...(%s === undefined ? {} : { %q: String(%s) })", v, p.Name, v))
+					}
+				case "formData":
+					form = append(form, fmt.Sprintf("[%q, %s]", p.Name, v))
+				}
+			}
+		}
+		if len(query) > 0 {
+			request = append(request, "query: { "+strings.Join(query, ", ")+" }")
+		}
+		if len(headers) > 0 {
+			request = append(request, "headers: { "+strings.Join(headers, ", ")+" }")
+		}
+		switch {
+		case len(form) > 0:
+			methods.WriteString("    const form = new URLSearchParams();\n")
+			fmt.Fprintf(&methods, "    for (const [name, value] of [%s] as [string, string | undefined][]) {\n",
+				strings.Join(form, ", "))
+			methods.WriteString("      if (value !== undefined) {\n        form.set(name, String(value));\n      }\n    }\n")
+			request = append(request, "body: form", `contentType: "application/x-www-form-urlencoded"`)
+		case e.Body != nil:
+			request = append(request, "body: JSON.stringify(body)", `contentType: "application/json"`)
+		case e.RawBody:
+			request = append(request, "body", `contentType: "application/octet-stream"`)
+		case e.FormBody:
+			request = append(request, "body: form")
+		}
+
+		send := "this.send({ " + strings.Join(request, ", ") + " })"
+		switch {
+		case result == "void":
+			fmt.Fprintf(&methods, "    await %s;\n", send)
+		case e.Binary:
+			fmt.Fprintf(&methods, "    const response = await %s;\n    return response.blob();\n", send)
+		case len(e.Results) == 1:
+			fmt.Fprintf(&methods, "    const response = await %s;\n", send)
+			if e.Optional {
+				fmt.Fprintf(&methods, "    if (response.status !== %d) {\n      return undefined;\n    }\n", e.Results[0].Status)
+			}
+			fmt.Fprintf(&methods, "    return Client.json<%s>(response);\n", a.tsType(e.Results[0].Schema, "models."))
+		default:
+			fmt.Fprintf(&methods, "    const response = await %s;\n    switch (response.status) {\n", send)
+			for _, r := range e.Results {
+				fmt.Fprintf(&methods, "      case %d:\n        return { %s: await Client.json<%s>(response) };\n",
+					r.Status, lowerFirst(r.Name), a.tsType(r.Schema, "models."))
+			}
+			methods.WriteString("    }\n    return {};\n")
+		}
+		methods.WriteString("  }\n")
+	}
+
+	return "// " + generatedHeader + "\n\nimport * as models from \"./models\";\n\n" + types.String() +
+		tsClient(a) + methods.String() + "}\n"
+}
@@ -0,0 +1,3 @@
+module github.com/abaskin/signald-rest-api/sdk/go
+
+go 1.15
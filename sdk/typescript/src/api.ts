@@ -0,0 +1,1635 @@
+// Code generated by sdk/generator from src/docs/swagger.json. DO NOT EDIT.
+
+import * as models from "./models";
+
+/** The optional parameters of postV20100401AccountsMessagesJSON. */
+export interface PostV20100401AccountsMessagesJSONOptions {
+  /** Message */
+  Body?: string;
+  /** Media URL */
+  MediaUrl?: string;
+}
+
+/** The optional parameters of getAdminAudit. */
+export interface GetAdminAuditOptions {
+  /** Only list the changes of this actor */
+  actor?: string;
+  /** Only list changes of this action (e.g. account_settings.update) */
+  action?: string;
+  /** Only list changes of this target (e.g. a number) */
+  target?: string;
+  /** Only list changes after this time, timestamp in milliseconds or RFC 3339 time */
+  since?: string;
+  /** Number of entries to skip */
+  offset?: number;
+  /** Maximum number of entries to return */
+  limit?: number;
+}
+
+/** The optional parameters of postAdminImport. */
+export interface PostAdminImportOptions {
+  /** merge (default) or replace */
+  mode?: string;
+}
+
+/** The optional parameters of getAdminWebhooksDeadLetters. */
+export interface GetAdminWebhooksDeadLettersOptions {
+  /** Number of events to skip */
+  offset?: number;
+  /** Maximum number of events to return */
+  limit?: number;
+}
+
+/** The optional parameters of getAdminWebhooksDeliveries. */
+export interface GetAdminWebhooksDeliveriesOptions {
+  /** Number of attempts to skip */
+  offset?: number;
+  /** Maximum number of attempts to return */
+  limit?: number;
+}
+
+/** The optional parameters of getV1AccountsStats. */
+export interface GetV1AccountsStatsOptions {
+  /** Comma separated list of windows, e.g. 5m,1h */
+  windows?: string;
+}
+
+/** The optional parameters of getV1AttachmentsThumbnail. */
+export interface GetV1AttachmentsThumbnailOptions {
+  /** Length of the longer edge in pixels (default 256, maximum 1024) */
+  size?: number;
+}
+
+/** The optional parameters of getV1Challenges. */
+export interface GetV1ChallengesOptions {
+  /** Only the challenges of this number */
+  number?: string;
+}
+
+/** The optional parameters of getV1Contacts. */
+export interface GetV1ContactsOptions {
+  /** Only list contacts whose name contains this text */
+  name?: string;
+  /** Number of contacts to skip */
+  offset?: number;
+  /** Maximum number of contacts to return */
+  limit?: number;
+}
+
+/** The optional parameters of getV1Conversations. */
+export interface GetV1ConversationsOptions {
+  /** Number of conversations to skip */
+  offset?: number;
+  /** Maximum number of conversations to return */
+  limit?: number;
+}
+
+/** The optional parameters of deleteV1Data. */
+export interface DeleteV1DataOptions {
+  /** Return a confirmation token instead of deleting */
+  preflight?: boolean;
+  /** Confirmation token of a preflight request */
+  confirmation?: string;
+  /** Confirm the deletion without a token */
+  confirm?: boolean;
+}
+
+/** The optional parameters of getV1Groups. */
+export interface GetV1GroupsOptions {
+  /** Only list groups whose name contains this text */
+  name?: string;
+  /** Number of groups to skip */
+  offset?: number;
+  /** Maximum number of groups to return */
+  limit?: number;
+}
+
+/** The optional parameters of postV1GroupsSync. */
+export interface PostV1GroupsSyncOptions {
+  /** Only report the changes */
+  dry_run?: boolean;
+}
+
+/** The optional parameters of deleteV1Groups. */
+export interface DeleteV1GroupsOptions {
+  /** Member to make admin before leaving */
+  new_admin?: string;
+  /** Leave the group without admin */
+  force?: boolean;
+}
+
+/** The optional parameters of getV1Link. */
+export interface GetV1LinkOptions {
+  /** Name of the device (default -default-device-name) */
+  device_name?: string;
+  /** png (default), svg, ascii or ansi */
+  format?: string;
+  /** Error correction level: L, M (default), Q or H */
+  level?: string;
+  /** Size of PNG and SVG images in pixels (64 to 2048, default 256) */
+  size?: number;
+}
+
+/** The optional parameters of getV1MessageRequests. */
+export interface GetV1MessageRequestsOptions {
+  /** pending (default), accepted, blocked or all */
+  state?: string;
+}
+
+/** The optional parameters of getV1Messages. */
+export interface GetV1MessagesOptions {
+  /** include lists the deleted messages as well */
+  deleted?: string;
+  /** Cursor of the message to continue after */
+  after?: string;
+  /** Number of messages to skip */
+  offset?: number;
+  /** Maximum number of messages to return */
+  limit?: number;
+}
+
+/** The optional parameters of getV1Receive. */
+export interface GetV1ReceiveOptions {
+  /** inline embeds attachments up to the inline size limit as base64 */
+  attachments?: string;
+  /** Seconds to wait for messages (default -receive-timeout) */
+  timeout?: number;
+  /**
+   * Cursor of the last processed message of a subscribed number, the messages up to it are
+   * acknowledged
+   */
+  after?: string;
+  /** Maximum number of messages to return with a cursor */
+  limit?: number;
+}
+
+/** The optional parameters of postV1Register. */
+export interface PostV1RegisterOptions {
+  /** Register the number again if it is already active */
+  force?: boolean;
+}
+
+/** The optional parameters of getV1SearchMessages. */
+export interface GetV1SearchMessagesOptions {
+  /** Phone Number of the Sender */
+  sender?: string;
+  /** Group ID */
+  group?: string;
+  /** Oldest message, timestamp in milliseconds or RFC 3339 time */
+  from?: string;
+  /** Newest message, timestamp in milliseconds or RFC 3339 time */
+  to?: string;
+  /** Number of results to skip */
+  offset?: number;
+  /** Maximum number of results to return */
+  limit?: number;
+}
+
+/** The optional parameters of getV1SimpleReceive. */
+export interface GetV1SimpleReceiveOptions {
+  /** Receive timeout in seconds */
+  timeout?: number;
+}
+
+/** The response of getV1SimpleSend, the property of its status is set. */
+export interface GetV1SimpleSendResponse {
+  /** The response with status 201. */
+  created?: models.SentMessageResponse;
+  /** The response with status 202. */
+  accepted?: models.QueuedMessages;
+}
+
+/** The optional parameters of getV1SimpleSend. */
+export interface GetV1SimpleSendOptions {
+  /** Message */
+  message?: string;
+}
+
+/** The response of postV1SimpleSend, the property of its status is set. */
+export interface PostV1SimpleSendResponse {
+  /** The response with status 201. */
+  created?: models.SentMessageResponse;
+  /** The response with status 202. */
+  accepted?: models.QueuedMessages;
+}
+
+/** The optional parameters of postV1SimpleSend. */
+export interface PostV1SimpleSendOptions {
+  /** Message */
+  message?: string;
+}
+
+/** The response of postV2Send, the property of its status is set. */
+export interface PostV2SendResponse {
+  /** The response with status 201. */
+  created?: models.SentMessageResponse;
+  /** The response with status 202. */
+  accepted?: models.QueuedMessages;
+}
+
+/** The optional parameters of getV3AccountsGroupsByNumber. */
+export interface GetV3AccountsGroupsByNumberOptions {
+  /** Only list groups whose name contains this text */
+  name?: string;
+  /** Number of groups to skip */
+  offset?: number;
+  /** Maximum number of groups to return */
+  limit?: number;
+}
+
+/** The optional parameters of deleteV3AccountsGroups. */
+export interface DeleteV3AccountsGroupsOptions {
+  /** Member to make admin before leaving */
+  new_admin?: string;
+  /** Leave the group without admin */
+  force?: boolean;
+}
+
+/** The response of postV3AccountsMessages, the property of its status is set. */
+export interface PostV3AccountsMessagesResponse {
+  /** The response with status 201. */
+  created?: models.SentMessageResponse;
+  /** The response with status 202. */
+  accepted?: models.QueuedMessages;
+}
+
+/** The options of a client. */
+export interface ClientOptions {
+  /** The URL the API is served at, http://127.0.0.1:8080 if it isn't given. */
+  baseURL?: string;
+  /** Headers sent with every request, e.g. the Authorization header. */
+  headers?: { [name: string]: string };
+  /** The fetch function the requests are sent with, the global one if it isn't given. */
+  fetch?: typeof fetch;
+}
+
+/** The error of a request the API didn't succeed with. */
+export class RequestError extends Error {
+  /** The status of the response. */
+  readonly status: number;
+  /** The headers of the response, e.g. with Retry-After. */
+  readonly headers: Headers;
+
+  constructor(status: number, message: string, headers: Headers) {
+    super(message);
+    this.name = "RequestError";
+    this.status = status;
+    this.headers = headers;
+  }
+}
+
+type QueryValue = string | number | boolean | string[] | undefined;
+
+interface Request {
+  method: string;
+  path: string;
+  query?: { [name: string]: QueryValue };
+  headers?: { [name: string]: string };
+  body?: BodyInit;
+  contentType?: string;
+}
+
+/** A client of the Signal Cli REST API. */
+export class Client {
+  readonly baseURL: string;
+  headers: { [name: string]: string };
+  private readonly fetch: typeof fetch;
+
+  constructor(options: ClientOptions = {}) {
+    this.baseURL = (options.baseURL ?? "http://127.0.0.1:8080").replace(/\/+$/, "");
+    this.headers = { ...options.headers };
+    this.fetch = options.fetch ?? ((input, init) => fetch(input, init));
+  }
+
+  /** Sends a request, it fails with a RequestError if the status isn't 2xx. */
+  private async send(request: Request): Promise<Response> {
+    const query = new URLSearchParams();
+    for (const [name, value] of Object.entries(request.query ?? {})) {
+      if (Array.isArray(value)) {
+        value.forEach((v) => query.append(name, v));
+      } else if (value !== undefined) {
+        query.set(name, String(value));
+      }
+    }
+    const search = query.toString();
+    const headers: { [name: string]: string } = { ...this.headers, ...request.headers };
+    if (request.contentType) {
+      headers["Content-Type"] = request.contentType;
+    }
+
+    const response = await this.fetch(this.baseURL + request.path + (search ? "?" + search : ""), {
+      method: request.method,
+      headers,
+      body: request.body,
+    });
+    if (!response.ok) {
+      let message = (await response.text()).trim();
+      try {
+        const body = JSON.parse(message);
+        if (body && typeof body.error === "string") {
+          message = body.error;
+        }
+      } catch {
+        // the error isn't JSON
+      }
+      throw new RequestError(response.status, message || response.statusText, response.headers);
+    }
+    return response;
+  }
+
+  private static async json<T>(response: Response): Promise<T> {
+    const text = await response.text();
+    return (text.trim() === "" ? undefined : JSON.parse(text)) as T;
+  }
+
+  /**
+   * POST /2010-04-01/Accounts/{sid}/Messages.json
+   *
+   * Send a message with the Twilio Messages API.
+   *
+   * Accepts the requests of the Messages API of Twilio, so that code sending SMS with Twilio can
+   * send Signal messages by changing the base URL. From is the number of the account, To the
+   * recipient (a number or a group id), Body the message and MediaUrl (can be repeated) the URLs of
+   * attachments, which are fetched by the API. The account SID and auth token aren't checked, the
+   * SID is the client of the quotas when it is sent as user of the basic authentication like the
+   * SDKs of Twilio do.
+   */
+  async postV20100401AccountsMessagesJSON(sid: string, from: string, to: string, options: PostV20100401AccountsMessagesJSONOptions = {}): Promise<models.TwilioMessage> {
+    const form = new URLSearchParams();
+    for (const [name, value] of [["From", from], ["To", to], ["Body", options.Body], ["MediaUrl", options.MediaUrl]] as [string, string | undefined][]) {
+      if (value !== undefined) {
+        form.set(name, String(value));
+      }
+    }
+    const response = await this.send({ method: "POST", path: `/2010-04-01/Accounts/${encodeURIComponent(sid)}/Messages.json`, body: form, contentType: "application/x-www-form-urlencoded" });
+    return Client.json<models.TwilioMessage>(response);
+  }
+
+  /**
+   * PUT /_matrix/app/v1/transactions/{txnId}
+   *
+   * Receive a transaction of the Matrix homeserver.
+   *
+   * The application service API the homeserver pushes the events of the bridged rooms to. The
+   * messages posted in the rooms are sent to the mapped Signal conversations.
+   */
+  async putMatrixAppV1Transactions(txnID: string): Promise<void> {
+    await this.send({ method: "PUT", path: `/_matrix/app/v1/transactions/${encodeURIComponent(txnID)}` });
+  }
+
+  /**
+   * GET /_matrix/app/v1/users/{id}
+   *
+   * Query a user or room alias of the Matrix bridge.
+   *
+   * The bridge maps rooms statically and provides no users or aliases.
+   */
+  async getMatrixAppV1Users(id: string): Promise<void> {
+    await this.send({ method: "GET", path: `/_matrix/app/v1/users/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * GET /admin/accounts/{number}/settings
+   *
+   * Get the settings of a number.
+   *
+   * Get the settings that apply to a registered number.
+   */
+  async getAdminAccountsSettings(number: string): Promise<models.AccountSettings> {
+    const response = await this.send({ method: "GET", path: `/admin/accounts/${encodeURIComponent(number)}/settings` });
+    return Client.json<models.AccountSettings>(response);
+  }
+
+  /**
+   * PUT /admin/accounts/{number}/settings
+   *
+   * Change the settings of a number.
+   *
+   * Replace the settings of a registered number. The settings take precedence over the ones in the
+   * config file.
+   */
+  async putAdminAccountsSettings(number: string, body: models.AccountSettings): Promise<models.AccountSettings> {
+    const response = await this.send({ method: "PUT", path: `/admin/accounts/${encodeURIComponent(number)}/settings`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.AccountSettings>(response);
+  }
+
+  /**
+   * DELETE /admin/accounts/{number}/settings
+   *
+   * Reset the settings of a number.
+   *
+   * Drop the settings changed via the API, so that the ones in the config file apply again.
+   */
+  async deleteAdminAccountsSettings(number: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/admin/accounts/${encodeURIComponent(number)}/settings` });
+  }
+
+  /**
+   * GET /admin/audit
+   *
+   * List the audit log.
+   *
+   * List the changes made via /admin and the other configuration endpoints (chat commands), the
+   * newest first, with the actor and the values before and after the change. The listing is
+   * paginated, the total number of entries is returned in the X-Total-Count header.
+   */
+  async getAdminAudit(options: GetAdminAuditOptions = {}): Promise<models.AuditEntry[]> {
+    const response = await this.send({ method: "GET", path: `/admin/audit`, query: { actor: options.actor, action: options.action, target: options.target, since: options.since, offset: options.offset, limit: options.limit } });
+    return Client.json<models.AuditEntry[]>(response);
+  }
+
+  /**
+   * GET /admin/backends
+   *
+   * List the signald backends.
+   *
+   * List the signald backends with the numbers routed to them, whether they are draining and the
+   * number of requests in flight.
+   */
+  async getAdminBackends(): Promise<models.BackendStatus[]> {
+    const response = await this.send({ method: "GET", path: `/admin/backends` });
+    return Client.json<models.BackendStatus[]>(response);
+  }
+
+  /**
+   * POST /admin/backends/{name}/drain
+   *
+   * Drain a signald backend.
+   *
+   * Reject new requests for the numbers of a backend with 503, so that it can be replaced once the
+   * requests in flight finished.
+   */
+  async postAdminBackendsDrain(name: string): Promise<models.BackendStatus> {
+    const response = await this.send({ method: "POST", path: `/admin/backends/${encodeURIComponent(name)}/drain` });
+    return Client.json<models.BackendStatus>(response);
+  }
+
+  /**
+   * POST /admin/backends/{name}/resume
+   *
+   * Resume a signald backend.
+   *
+   * Accept requests for the numbers of a drained backend again.
+   */
+  async postAdminBackendsResume(name: string): Promise<models.BackendStatus> {
+    const response = await this.send({ method: "POST", path: `/admin/backends/${encodeURIComponent(name)}/resume` });
+    return Client.json<models.BackendStatus>(response);
+  }
+
+  /**
+   * GET /admin/export
+   *
+   * Export the configuration.
+   *
+   * Export the chat commands and the account settings changed via the API as JSON.
+   */
+  async getAdminExport(): Promise<models.ConfigExport> {
+    const response = await this.send({ method: "GET", path: `/admin/export` });
+    return Client.json<models.ConfigExport>(response);
+  }
+
+  /**
+   * GET /admin/faults
+   *
+   * Show the injected faults.
+   *
+   * Show the faults that are injected into requests and received messages. Fault injection needs to
+   * be enabled with -fault-injection.
+   */
+  async getAdminFaults(): Promise<models.FaultSettings | undefined> {
+    const response = await this.send({ method: "GET", path: `/admin/faults` });
+    if (response.status !== 200) {
+      return undefined;
+    }
+    return Client.json<models.FaultSettings>(response);
+  }
+
+  /**
+   * PUT /admin/faults
+   *
+   * Inject faults.
+   *
+   * Delay requests, fail a fraction of them with the given status and drop a fraction of the
+   * received messages, so that the retry and backoff logic of clients can be tested. The admin API
+   * and the health checks aren't affected unless they are listed in paths. Fault injection needs to
+   * be enabled with -fault-injection.
+   */
+  async putAdminFaults(body: models.FaultSettings): Promise<models.FaultSettings> {
+    const response = await this.send({ method: "PUT", path: `/admin/faults`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.FaultSettings>(response);
+  }
+
+  /**
+   * DELETE /admin/faults
+   *
+   * Stop injecting faults.
+   *
+   * Stop injecting faults into requests and received messages.
+   */
+  async deleteAdminFaults(): Promise<void> {
+    await this.send({ method: "DELETE", path: `/admin/faults` });
+  }
+
+  /**
+   * POST /admin/import
+   *
+   * Import the configuration.
+   *
+   * Import an export of another instance. By default the imported entities are merged into the
+   * existing ones (entities with the same name are replaced), with mode=replace all existing
+   * entities are replaced.
+   */
+  async postAdminImport(body: models.ConfigExport, options: PostAdminImportOptions = {}): Promise<void> {
+    await this.send({ method: "POST", path: `/admin/import`, query: { mode: options.mode }, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * GET /admin/maintenance
+   *
+   * Show the maintenance mode.
+   *
+   * Show whether the maintenance mode is enabled and the messages held until it ends, as well as
+   * the ones that failed when they were sent after it.
+   */
+  async getAdminMaintenance(): Promise<models.MaintenanceStatus> {
+    const response = await this.send({ method: "GET", path: `/admin/maintenance` });
+    return Client.json<models.MaintenanceStatus>(response);
+  }
+
+  /**
+   * PUT /admin/maintenance
+   *
+   * Enable or disable the maintenance mode.
+   *
+   * While the maintenance mode is enabled messages are accepted with 202 and the id they are held
+   * with, but they are only sent once the maintenance mode is disabled again. They are then sent in
+   * the order they were accepted. The messages are only held in memory by the replica that accepted
+   * them.
+   */
+  async putAdminMaintenance(body: models.MaintenanceRequest): Promise<models.MaintenanceStatus> {
+    const response = await this.send({ method: "PUT", path: `/admin/maintenance`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.MaintenanceStatus>(response);
+  }
+
+  /**
+   * DELETE /admin/maintenance/held/{id}
+   *
+   * Discard a held message.
+   *
+   * Remove a message held during maintenance, or one that failed when it was sent after it, without
+   * sending it.
+   */
+  async deleteAdminMaintenanceHeld(id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/admin/maintenance/held/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * GET /admin/quotas
+   *
+   * List the quota usage.
+   *
+   * List the messages and attachment bytes the clients sent in the current hour and day (the
+   * windows start at the full hour and at midnight UTC) with their quotas.
+   */
+  async getAdminQuotas(): Promise<models.QuotaUsage[]> {
+    const response = await this.send({ method: "GET", path: `/admin/quotas` });
+    return Client.json<models.QuotaUsage[]>(response);
+  }
+
+  /**
+   * GET /admin/quotas/{client}
+   *
+   * Show the quota usage of a client.
+   *
+   * Show the messages and attachment bytes a client sent in the current hour and day with its
+   * quotas.
+   */
+  async getAdminQuotasByClient(client: string): Promise<models.QuotaUsage> {
+    const response = await this.send({ method: "GET", path: `/admin/quotas/${encodeURIComponent(client)}` });
+    return Client.json<models.QuotaUsage>(response);
+  }
+
+  /**
+   * DELETE /admin/quotas/{client}
+   *
+   * Reset the quota usage of a client.
+   *
+   * Reset the messages and attachment bytes a client sent in the current hour and day, e.g. after a
+   * runaway job was stopped.
+   */
+  async deleteAdminQuotas(client: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/admin/quotas/${encodeURIComponent(client)}` });
+  }
+
+  /**
+   * GET /admin/webhooks
+   *
+   * List the webhooks.
+   *
+   * List the webhooks events were delivered to with the number of delivered events, failed delivery
+   * attempts and dead-lettered events, and the last error.
+   */
+  async getAdminWebhooks(): Promise<models.WebhookStatus[]> {
+    const response = await this.send({ method: "GET", path: `/admin/webhooks` });
+    return Client.json<models.WebhookStatus[]>(response);
+  }
+
+  /**
+   * GET /admin/webhooks/dead-letters
+   *
+   * List the dead-lettered webhook events.
+   *
+   * List the events that couldn't be delivered to a webhook after all retries, the oldest first.
+   * The listing is paginated, the total number of events is returned in the X-Total-Count header.
+   */
+  async getAdminWebhooksDeadLetters(options: GetAdminWebhooksDeadLettersOptions = {}): Promise<models.DeadLetter[]> {
+    const response = await this.send({ method: "GET", path: `/admin/webhooks/dead-letters`, query: { offset: options.offset, limit: options.limit } });
+    return Client.json<models.DeadLetter[]>(response);
+  }
+
+  /**
+   * DELETE /admin/webhooks/dead-letters/{id}
+   *
+   * Discard a dead-lettered webhook event.
+   *
+   * Remove an event from the dead-letter queue without delivering it.
+   */
+  async deleteAdminWebhooksDeadLetters(id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/admin/webhooks/dead-letters/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * POST /admin/webhooks/dead-letters/{id}/replay
+   *
+   * Replay a dead-lettered webhook event.
+   *
+   * Deliver a dead-lettered event to its webhook again. It is removed from the dead-letter queue if
+   * the webhook accepts it.
+   */
+  async postAdminWebhooksDeadLettersReplay(id: string): Promise<models.WebhookDelivery> {
+    const response = await this.send({ method: "POST", path: `/admin/webhooks/dead-letters/${encodeURIComponent(id)}/replay` });
+    return Client.json<models.WebhookDelivery>(response);
+  }
+
+  /**
+   * GET /admin/webhooks/deliveries
+   *
+   * List the deliveries of a webhook.
+   *
+   * List the latest 100 delivery attempts of a webhook, the most recent first. The listing is
+   * paginated, the total number of attempts is returned in the X-Total-Count header.
+   */
+  async getAdminWebhooksDeliveries(url: string, options: GetAdminWebhooksDeliveriesOptions = {}): Promise<models.WebhookDelivery[]> {
+    const response = await this.send({ method: "GET", path: `/admin/webhooks/deliveries`, query: { url: url, offset: options.offset, limit: options.limit } });
+    return Client.json<models.WebhookDelivery[]>(response);
+  }
+
+  /**
+   * GET /metrics
+   *
+   * Get the metrics of the API.
+   *
+   * Metrics in the Prometheus text format, or in the OpenMetrics text format if the Accept header
+   * asks for it: the sent and received messages, the errors of signald and the Signal servers by
+   * class (e.g. rate_limited when Signal throttles) and the duration of send requests. The metrics
+   * are labeled by number and recipient if the labels are enabled with -metrics-labels.
+   */
+  async getMetrics(): Promise<Blob> {
+    const response = await this.send({ method: "GET", path: `/metrics` });
+    return response.blob();
+  }
+
+  /**
+   * GET /v1/about
+   *
+   * Lists general information about the API.
+   *
+   * Returns the supported API versions, the build (git commit and date), the mode (standalone or
+   * replicated with Redis), the storage, the enabled subsystems and the version of every signald
+   * backend with the capabilities it supports. Endpoints that need a capability the backend of a
+   * number doesn't support respond with 501.
+   */
+  async getV1About(): Promise<models.About> {
+    const response = await this.send({ method: "GET", path: `/v1/about` });
+    return Client.json<models.About>(response);
+  }
+
+  /**
+   * GET /v1/accounts
+   *
+   * List the accounts.
+   *
+   * List the numbers signald has accounts for. Supports If-None-Match with the returned ETag.
+   */
+  async getV1Accounts(): Promise<models.AccountEntry[]> {
+    const response = await this.send({ method: "GET", path: `/v1/accounts` });
+    return Client.json<models.AccountEntry[]>(response);
+  }
+
+  /**
+   * GET /v1/accounts/{number}/settings
+   *
+   * Get the privacy settings of a number.
+   *
+   * Get the phone number privacy settings last set via the API: who sees the phone number
+   * (phone_number_sharing) and who can find the account by it (discoverable_by_number). Numbers
+   * whose settings weren't changed via the API show the defaults of Signal.
+   */
+  async getV1AccountsSettings(number: string): Promise<models.PrivacySettings> {
+    const response = await this.send({ method: "GET", path: `/v1/accounts/${encodeURIComponent(number)}/settings` });
+    return Client.json<models.PrivacySettings>(response);
+  }
+
+  /**
+   * PUT /v1/accounts/{number}/settings
+   *
+   * Change the privacy settings of a number.
+   *
+   * Change who sees the phone number (phone_number_sharing: everybody or nobody) and who can find
+   * the account by it (discoverable_by_number: everybody or nobody). Settings that aren't given are
+   * left unchanged. An account can only be hidden from discovery if its number isn't shared.
+   * Backends that don't support the settings respond with 501.
+   */
+  async putV1AccountsSettings(number: string, body: models.PrivacyUpdate): Promise<models.PrivacySettings> {
+    const response = await this.send({ method: "PUT", path: `/v1/accounts/${encodeURIComponent(number)}/settings`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.PrivacySettings>(response);
+  }
+
+  /**
+   * GET /v1/accounts/{number}/stats
+   *
+   * Show statistics of a number.
+   *
+   * Show the number of sent, received and failed messages and the average send latency of a
+   * registered number over time windows (by default the ones given with -stats-windows), the time
+   * of the last activity and the number of queued messages.
+   */
+  async getV1AccountsStats(number: string, options: GetV1AccountsStatsOptions = {}): Promise<models.StatsReport> {
+    const response = await this.send({ method: "GET", path: `/v1/accounts/${encodeURIComponent(number)}/stats`, query: { windows: options.windows } });
+    return Client.json<models.StatsReport>(response);
+  }
+
+  /**
+   * POST /v1/attachments/uploads
+   *
+   * Start a resumable attachment upload.
+   *
+   * Creates a new upload session. The size is optional, if provided the upload can only be
+   * finalized once all bytes are received. Uploads can't exceed the maximum upload size.
+   */
+  async postV1AttachmentsUploads(body: models.CreateUploadRequest): Promise<models.UploadStatus> {
+    const response = await this.send({ method: "POST", path: `/v1/attachments/uploads`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.UploadStatus>(response);
+  }
+
+  /**
+   * GET /v1/attachments/uploads/{id}
+   *
+   * Get the state of a resumable attachment upload.
+   *
+   * Returns the number of bytes received so far, which is the offset the next chunk needs to start
+   * at.
+   */
+  async getV1AttachmentsUploads(id: string): Promise<models.UploadStatus> {
+    const response = await this.send({ method: "GET", path: `/v1/attachments/uploads/${encodeURIComponent(id)}` });
+    return Client.json<models.UploadStatus>(response);
+  }
+
+  /**
+   * PUT /v1/attachments/uploads/{id}
+   *
+   * Upload a chunk of an attachment.
+   *
+   * Appends the request body to the upload. The Upload-Offset header needs to contain the offset of
+   * the chunk, i.e. the number of bytes already received. A chunk beyond the size of the upload, or
+   * the maximum upload size if it has none, is rejected.
+   */
+  async putV1AttachmentsUploads(id: string, uploadOffset: number, body: BodyInit): Promise<models.UploadStatus> {
+    const response = await this.send({ method: "PUT", path: `/v1/attachments/uploads/${encodeURIComponent(id)}`, headers: { "Upload-Offset": String(uploadOffset) }, body, contentType: "application/octet-stream" });
+    return Client.json<models.UploadStatus>(response);
+  }
+
+  /**
+   * DELETE /v1/attachments/uploads/{id}
+   *
+   * Abort a resumable attachment upload.
+   *
+   * Aborts the upload and removes the data received so far.
+   */
+  async deleteV1AttachmentsUploads(id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/attachments/uploads/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * POST /v1/attachments/uploads/{id}/finalize
+   *
+   * Finalize a resumable attachment upload.
+   *
+   * Completes the upload and returns a token that can be used in the attachment_tokens field when
+   * sending a message.
+   */
+  async postV1AttachmentsUploadsFinalize(id: string): Promise<models.UploadToken> {
+    const response = await this.send({ method: "POST", path: `/v1/attachments/uploads/${encodeURIComponent(id)}/finalize` });
+    return Client.json<models.UploadToken>(response);
+  }
+
+  /**
+   * GET /v1/attachments/{number}/{id}/thumbnail
+   *
+   * Get the thumbnail of a received attachment.
+   *
+   * Get a JPEG thumbnail of a received image or video attachment. Thumbnails are generated on the
+   * first request and cached.
+   */
+  async getV1AttachmentsThumbnail(number: string, id: string, options: GetV1AttachmentsThumbnailOptions = {}): Promise<Blob> {
+    const response = await this.send({ method: "GET", path: `/v1/attachments/${encodeURIComponent(number)}/${encodeURIComponent(id)}/thumbnail`, query: { size: options.size } });
+    return response.blob();
+  }
+
+  /**
+   * GET /v1/challenges
+   *
+   * List the challenges.
+   *
+   * List the challenges Signal requires numbers to solve before it delivers further messages (proof
+   * required), with the messages held until they are solved. A challenge_required event is emitted
+   * for every new challenge.
+   */
+  async getV1Challenges(options: GetV1ChallengesOptions = {}): Promise<models.RateLimitChallenge[]> {
+    const response = await this.send({ method: "GET", path: `/v1/challenges`, query: { number: options.number } });
+    return Client.json<models.RateLimitChallenge[]>(response);
+  }
+
+  /**
+   * GET /v1/challenges/{id}
+   *
+   * Show a challenge.
+   *
+   * Show a challenge Signal requires a number to solve, with the messages held until it is solved.
+   */
+  async getV1ChallengesByID(id: string): Promise<models.RateLimitChallenge> {
+    const response = await this.send({ method: "GET", path: `/v1/challenges/${encodeURIComponent(id)}` });
+    return Client.json<models.RateLimitChallenge>(response);
+  }
+
+  /**
+   * POST /v1/challenges/{id}
+   *
+   * Submit a solved challenge.
+   *
+   * Submit the captcha solved at https://signalcaptchas.org/challenge/generate.html for a
+   * challenge, or an empty captcha if signald received the push challenge. The messages held for
+   * the challenge are then sent in the background, in the order they were sent.
+   */
+  async postV1Challenges(id: string, body: models.ChallengeSolution): Promise<void> {
+    await this.send({ method: "POST", path: `/v1/challenges/${encodeURIComponent(id)}`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * DELETE /v1/challenges/{id}
+   *
+   * Discard a challenge.
+   *
+   * Remove a challenge and drop the messages held for it without sending them.
+   */
+  async deleteV1Challenges(id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/challenges/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * GET /v1/commands
+   *
+   * List all chat commands.
+   *
+   * List all registered chat commands.
+   */
+  async getV1Commands(): Promise<models.Command[]> {
+    const response = await this.send({ method: "GET", path: `/v1/commands` });
+    return Client.json<models.Command[]>(response);
+  }
+
+  /**
+   * POST /v1/commands
+   *
+   * Register a chat command.
+   *
+   * Registers (or replaces) a chat command. Incoming messages starting with the command prefix and
+   * the command name are posted to the url, the reply is sent back to the chat.
+   */
+  async postV1Commands(body: models.Command): Promise<models.Command> {
+    const response = await this.send({ method: "POST", path: `/v1/commands`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.Command>(response);
+  }
+
+  /**
+   * DELETE /v1/commands/{name}
+   *
+   * Delete a chat command.
+   *
+   * Delete a registered chat command.
+   */
+  async deleteV1Commands(name: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/commands/${encodeURIComponent(name)}` });
+  }
+
+  /**
+   * GET /v1/contacts/{number}
+   *
+   * List the contacts of a number.
+   *
+   * List the contacts of a registered number. The listing can be filtered by name and paginated,
+   * the total number of matching contacts is returned in the X-Total-Count header. Supports
+   * If-None-Match with the returned ETag.
+   */
+  async getV1Contacts(number: string, options: GetV1ContactsOptions = {}): Promise<models.ContactEntry[]> {
+    const response = await this.send({ method: "GET", path: `/v1/contacts/${encodeURIComponent(number)}`, query: { name: options.name, offset: options.offset, limit: options.limit } });
+    return Client.json<models.ContactEntry[]>(response);
+  }
+
+  /**
+   * GET /v1/conversations/{number}
+   *
+   * List the conversations of a number.
+   *
+   * List the contacts and groups a registered number exchanged messages with, the most recently
+   * active first, with a preview of the last message and the number of unread messages. The listing
+   * is paginated, the total number of conversations is returned in the X-Total-Count header.
+   * Supports If-None-Match with the returned ETag.
+   */
+  async getV1Conversations(number: string, options: GetV1ConversationsOptions = {}): Promise<models.ConversationEntry[]> {
+    const response = await this.send({ method: "GET", path: `/v1/conversations/${encodeURIComponent(number)}`, query: { offset: options.offset, limit: options.limit } });
+    return Client.json<models.ConversationEntry[]>(response);
+  }
+
+  /**
+   * GET /v1/conversations/{number}/unread
+   *
+   * Get the number of unread messages of a number.
+   *
+   * Get the number of unread messages in total and of every conversation with unread messages.
+   */
+  async getV1ConversationsUnread(number: string): Promise<models.UnreadCounts> {
+    const response = await this.send({ method: "GET", path: `/v1/conversations/${encodeURIComponent(number)}/unread` });
+    return Client.json<models.UnreadCounts>(response);
+  }
+
+  /**
+   * POST /v1/conversations/{number}/{peer}/read
+   *
+   * Mark a conversation as read.
+   *
+   * Mark the messages of a conversation up to the given timestamp (or all of them) as read and send
+   * read receipts to their senders.
+   */
+  async postV1ConversationsRead(number: string, peer: string, body: models.MarkReadRequest): Promise<models.MarkReadResult> {
+    const response = await this.send({ method: "POST", path: `/v1/conversations/${encodeURIComponent(number)}/${encodeURIComponent(peer)}/read`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.MarkReadResult>(response);
+  }
+
+  /**
+   * DELETE /v1/data/{number}/{contact}
+   *
+   * Delete all data stored about a contact.
+   *
+   * Purges all data the API keeps about the given contact of the registered number and returns a
+   * deletion report. The request needs to be confirmed with confirm=true or a token from a
+   * preflight request (preflight=true).
+   */
+  async deleteV1Data(number: string, contact: string, options: DeleteV1DataOptions = {}): Promise<models.DeletionReport> {
+    const response = await this.send({ method: "DELETE", path: `/v1/data/${encodeURIComponent(number)}/${encodeURIComponent(contact)}`, query: { preflight: options.preflight, confirmation: options.confirmation, confirm: options.confirm } });
+    return Client.json<models.DeletionReport>(response);
+  }
+
+  /**
+   * POST /v1/devices/{number}
+   *
+   * Link a device to a registered number.
+   *
+   * Link another device (e.g. a phone) to a number the API is registered with as primary device.
+   * The URI is the content of the QR code the new device shows.
+   */
+  async postV1Devices(number: string, body: models.AddDeviceRequest): Promise<void> {
+    await this.send({ method: "POST", path: `/v1/devices/${encodeURIComponent(number)}`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * PUT /v1/devices/{number}/{device_id}/name
+   *
+   * Change the name of a linked device.
+   *
+   * Change the name the device the API runs as was given when it was linked. signald can only
+   * rename the device it runs as, so device_id needs to be its device id.
+   */
+  async putV1DevicesName(number: string, deviceID: number, body: models.DeviceNameRequest): Promise<void> {
+    await this.send({ method: "PUT", path: `/v1/devices/${encodeURIComponent(number)}/${encodeURIComponent(deviceID)}/name`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * GET /v1/groups/{number}
+   *
+   * List all Signal Groups.
+   *
+   * List all Signal Groups with their members (also with their UUIDs and roles in member_details),
+   * the members who were invited but haven't joined yet and the ones who requested to join. The
+   * listing can be filtered by name and paginated, the total number of matching groups is returned
+   * in the X-Total-Count header. Supports If-None-Match with the returned ETag.
+   */
+  async getV1Groups(number: string, options: GetV1GroupsOptions = {}): Promise<models.GroupEntry[]> {
+    const response = await this.send({ method: "GET", path: `/v1/groups/${encodeURIComponent(number)}`, query: { name: options.name, offset: options.offset, limit: options.limit } });
+    return Client.json<models.GroupEntry[]>(response);
+  }
+
+  /**
+   * POST /v1/groups/{number}
+   *
+   * Create a new Signal Group.
+   *
+   * Create a new Signal Group with the specified members.
+   */
+  async postV1Groups(number: string): Promise<models.CreateGroupResponse> {
+    const response = await this.send({ method: "POST", path: `/v1/groups/${encodeURIComponent(number)}` });
+    return Client.json<models.CreateGroupResponse>(response);
+  }
+
+  /**
+   * POST /v1/groups/{number}/sync
+   *
+   * Sync groups with a desired state.
+   *
+   * Create groups and update names and members of groups to match the given list. Groups are
+   * matched by id if given and by name otherwise, groups that aren't listed are left alone. Returns
+   * the changes made (or with dry_run the changes that would be made).
+   */
+  async postV1GroupsSync(number: string, body: models.DesiredGroup[], options: PostV1GroupsSyncOptions = {}): Promise<models.GroupSyncReport> {
+    const response = await this.send({ method: "POST", path: `/v1/groups/${encodeURIComponent(number)}/sync`, query: { dry_run: options.dry_run }, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.GroupSyncReport>(response);
+  }
+
+  /**
+   * PATCH /v1/groups/{number}/{groupid}
+   *
+   * Update a Signal Group.
+   *
+   * Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it)
+   * of a group. Settings that aren't given are left unchanged.
+   */
+  async patchV1Groups(number: string, groupid: string, body: models.GroupUpdate): Promise<void> {
+    await this.send({ method: "PATCH", path: `/v1/groups/${encodeURIComponent(number)}/${encodeURIComponent(groupid)}`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * DELETE /v1/groups/{number}/{groupid}
+   *
+   * Delete a Signal Group.
+   *
+   * Delete a Signal Group. If the account is the last admin of the group, new_admin (the number or
+   * UUID of a member) is made admin before the group is left, so that it isn't left without admin.
+   * Without new_admin the last admin can only leave with force=true.
+   */
+  async deleteV1Groups(number: string, groupid: string, options: DeleteV1GroupsOptions = {}): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/groups/${encodeURIComponent(number)}/${encodeURIComponent(groupid)}`, query: { new_admin: options.new_admin, force: options.force } });
+  }
+
+  /**
+   * GET /v1/health/ready
+   *
+   * Check whether the API is ready.
+   *
+   * Reports ready once all numbers given with -subscribe-number are subscribed to incoming messages
+   * and, if signald is run by the API, signald is ready. The connection state of every number is
+   * listed, as well as the outcome of the startup check of the signald sockets, which failed
+   * sockets are checked again for.
+   */
+  async getV1HealthReady(): Promise<models.Readiness> {
+    const response = await this.send({ method: "GET", path: `/v1/health/ready` });
+    return Client.json<models.Readiness>(response);
+  }
+
+  /**
+   * GET /v1/link
+   *
+   * Link device and generate QR code.
+   *
+   * Start linking signald as device to an account and return the QR code the primary device scans.
+   * The QR code is a PNG image, an SVG image or text (ascii, or ansi with terminal colors),
+   * selected with format or the Accept header (image/png, image/svg+xml, text/plain). Scanners that
+   * struggle with long URIs may do better with a larger size or a higher error correction level.
+   */
+  async getV1Link(options: GetV1LinkOptions = {}): Promise<Blob> {
+    const response = await this.send({ method: "GET", path: `/v1/link`, query: { device_name: options.device_name, format: options.format, level: options.level, size: options.size } });
+    return response.blob();
+  }
+
+  /**
+   * GET /v1/link/sessions
+   *
+   * List the link sessions.
+   *
+   * List the devices that are being linked and the outcome of the recently finished link attempts.
+   */
+  async getV1LinkSessions(): Promise<models.LinkSession[]> {
+    const response = await this.send({ method: "GET", path: `/v1/link/sessions` });
+    return Client.json<models.LinkSession[]>(response);
+  }
+
+  /**
+   * GET /v1/message-requests/{number}
+   *
+   * List the message requests of a number.
+   *
+   * List the conversations with senders that aren't contacts, by default the pending ones. Messages
+   * of pending senders are received with message_request set.
+   */
+  async getV1MessageRequests(number: string, options: GetV1MessageRequestsOptions = {}): Promise<models.MessageRequest[]> {
+    const response = await this.send({ method: "GET", path: `/v1/message-requests/${encodeURIComponent(number)}`, query: { state: options.state } });
+    return Client.json<models.MessageRequest[]>(response);
+  }
+
+  /**
+   * POST /v1/message-requests/{number}/{sender}/accept
+   *
+   * Accept a message request.
+   *
+   * Accept the conversation with a sender that isn't a contact, its messages are no longer flagged
+   * as message request. Accepting a blocked sender unblocks it. Sending a message to a sender
+   * accepts it as well.
+   */
+  async postV1MessageRequestsAccept(number: string, sender: string): Promise<models.MessageRequest> {
+    const response = await this.send({ method: "POST", path: `/v1/message-requests/${encodeURIComponent(number)}/${encodeURIComponent(sender)}/accept` });
+    return Client.json<models.MessageRequest>(response);
+  }
+
+  /**
+   * POST /v1/message-requests/{number}/{sender}/block
+   *
+   * Block a sender.
+   *
+   * Block a sender, its messages are dropped when they are received.
+   */
+  async postV1MessageRequestsBlock(number: string, sender: string): Promise<models.MessageRequest> {
+    const response = await this.send({ method: "POST", path: `/v1/message-requests/${encodeURIComponent(number)}/${encodeURIComponent(sender)}/block` });
+    return Client.json<models.MessageRequest>(response);
+  }
+
+  /**
+   * POST /v1/message-requests/{number}/{sender}/report-spam
+   *
+   * Block a sender and report it as spam.
+   *
+   * Block a sender and report it as spam. signald can't pass the report on to Signal, so it is
+   * logged and emitted as spam_reported event to the webhooks.
+   */
+  async postV1MessageRequestsReportSpam(number: string, sender: string): Promise<models.MessageRequest> {
+    const response = await this.send({ method: "POST", path: `/v1/message-requests/${encodeURIComponent(number)}/${encodeURIComponent(sender)}/report-spam` });
+    return Client.json<models.MessageRequest>(response);
+  }
+
+  /**
+   * DELETE /v1/messages/{number}/{message_id}
+   *
+   * Delete a message from the message store.
+   *
+   * Delete a message from the history of the API only, e.g. to moderate the archive. The recipients
+   * keep the message. The message is hidden right away and purged later.
+   */
+  async deleteV1Messages(number: string, messageID: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/messages/${encodeURIComponent(number)}/${encodeURIComponent(messageID)}` });
+  }
+
+  /**
+   * GET /v1/messages/{number}/{peer}
+   *
+   * List the messages of a conversation.
+   *
+   * List the messages of a conversation in the message store, oldest first. Deleted messages are
+   * only listed with deleted=include until they are purged. The listing is paginated, the total
+   * number of messages is returned in the X-Total-Count header. Every message has a cursor, to
+   * continue after it pass it as after instead of an offset. The cursor of the last message is
+   * returned in the X-Next-Cursor header.
+   */
+  async getV1Messages(number: string, peer: string, options: GetV1MessagesOptions = {}): Promise<models.HistoryMessage[]> {
+    const response = await this.send({ method: "GET", path: `/v1/messages/${encodeURIComponent(number)}/${encodeURIComponent(peer)}`, query: { deleted: options.deleted, after: options.after, offset: options.offset, limit: options.limit } });
+    return Client.json<models.HistoryMessage[]>(response);
+  }
+
+  /**
+   * POST /v1/polls
+   *
+   * Create a poll.
+   *
+   * Send a question with numbered options to a group. Replies of the members with the number of an
+   * option (also as keycap emoji) or its text are counted as votes, the last vote of every member
+   * counts.
+   */
+  async postV1Polls(body: models.CreatePollRequest): Promise<models.PollResults> {
+    const response = await this.send({ method: "POST", path: `/v1/polls`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.PollResults>(response);
+  }
+
+  /**
+   * GET /v1/polls/{id}
+   *
+   * Get the results of a poll.
+   *
+   * Get the number of votes of every option.
+   */
+  async getV1Polls(id: string): Promise<models.PollResults> {
+    const response = await this.send({ method: "GET", path: `/v1/polls/${encodeURIComponent(id)}` });
+    return Client.json<models.PollResults>(response);
+  }
+
+  /**
+   * DELETE /v1/polls/{id}
+   *
+   * Delete a poll.
+   *
+   * Delete a poll and its votes.
+   */
+  async deleteV1Polls(id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/polls/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * GET /v1/profiles/{number}/{recipient}/avatar
+   *
+   * Get the profile avatar of a contact.
+   *
+   * Get the profile avatar of a contact as image. signald's avatar directory needs to be accessible
+   * by the REST API. Supports If-None-Match with the returned ETag.
+   */
+  async getV1ProfilesAvatar(number: string, recipient: string): Promise<Blob> {
+    const response = await this.send({ method: "GET", path: `/v1/profiles/${encodeURIComponent(number)}/${encodeURIComponent(recipient)}/avatar` });
+    return response.blob();
+  }
+
+  /**
+   * GET /v1/queue/{number}
+   *
+   * List the queued messages.
+   *
+   * List the messages of a number that are waiting for the identity of their recipient to be
+   * trusted, or that failed when they were sent again.
+   */
+  async getV1Queue(number: string): Promise<models.QueuedMessage[]> {
+    const response = await this.send({ method: "GET", path: `/v1/queue/${encodeURIComponent(number)}` });
+    return Client.json<models.QueuedMessage[]>(response);
+  }
+
+  /**
+   * DELETE /v1/queue/{number}/{id}
+   *
+   * Remove a queued message.
+   *
+   * Remove a message from the queue without sending it.
+   */
+  async deleteV1Queue(number: string, id: string): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v1/queue/${encodeURIComponent(number)}/${encodeURIComponent(id)}` });
+  }
+
+  /**
+   * POST /v1/receipts/{number}/bulk
+   *
+   * Mark many messages as read.
+   *
+   * Send read receipts for many messages, given as pairs of the sender and the timestamp of the
+   * message. The receipts for the messages of a sender are sent with a single signald request.
+   * Senders whose receipts couldn't be sent are listed with the error.
+   */
+  async postV1ReceiptsBulk(number: string, body: models.BulkReceiptsRequest): Promise<models.BulkReceiptsResult> {
+    const response = await this.send({ method: "POST", path: `/v1/receipts/${encodeURIComponent(number)}/bulk`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.BulkReceiptsResult>(response);
+  }
+
+  /**
+   * GET /v1/receive/{number}
+   *
+   * Receive Signal Messages.
+   *
+   * Receives Signal Messages from the Signal Network.
+   */
+  async getV1Receive(number: string, options: GetV1ReceiveOptions = {}): Promise<models.ReceiveResults> {
+    const response = await this.send({ method: "GET", path: `/v1/receive/${encodeURIComponent(number)}`, query: { attachments: options.attachments, timeout: options.timeout, after: options.after, limit: options.limit } });
+    return Client.json<models.ReceiveResults>(response);
+  }
+
+  /**
+   * POST /v1/register/{number}
+   *
+   * Register a phone number.
+   *
+   * Register a phone number with the signal network. If Signal requires a captcha it is passed to
+   * the captcha solver (-captcha-solver-url), or it can be solved manually and given as captcha.
+   * Numbers that are already registered and verified are rejected with 409 and their registration
+   * state, unless force is set.
+   */
+  async postV1Register(number: string, options: PostV1RegisterOptions = {}): Promise<void> {
+    await this.send({ method: "POST", path: `/v1/register/${encodeURIComponent(number)}`, query: { force: options.force } });
+  }
+
+  /**
+   * GET /v1/register/{number}/captcha
+   *
+   * Show the captcha challenge of a number.
+   *
+   * Show whether Signal requires a captcha to register the number, which couldn't be solved by the
+   * captcha solver. The captcha is solved at https://signalcaptchas.org/registration/generate.html,
+   * the number is then registered again with the captcha.
+   */
+  async getV1RegisterCaptcha(number: string): Promise<models.CaptchaChallenge> {
+    const response = await this.send({ method: "GET", path: `/v1/register/${encodeURIComponent(number)}/captcha` });
+    return Client.json<models.CaptchaChallenge>(response);
+  }
+
+  /**
+   * POST /v1/register/{number}/resend
+   *
+   * Request another verification code.
+   *
+   * Request another verification code for a number that waits for its code, by SMS or by voice call
+   * (use_voice). Another code can be requested a minute after the last one, and at most 5 codes
+   * until the number is verified. The state of the verification is shown by
+   * /v1/register/{number}/status.
+   */
+  async postV1RegisterResend(number: string, body: models.ResendRequest): Promise<models.RegistrationStatus> {
+    const response = await this.send({ method: "POST", path: `/v1/register/${encodeURIComponent(number)}/resend`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.RegistrationStatus>(response);
+  }
+
+  /**
+   * GET /v1/register/{number}/status
+   *
+   * Show the registration state of a number.
+   *
+   * Show whether a number is unregistered, needs a captcha, waits for its verification code or is
+   * active, with a hint what to do next. For a number waiting for its code the codes requested, how
+   * the last one was delivered (SMS or voice call), the number of codes that can still be requested
+   * and when the next one can be requested are shown.
+   */
+  async getV1RegisterStatus(number: string): Promise<models.RegistrationStatus> {
+    const response = await this.send({ method: "GET", path: `/v1/register/${encodeURIComponent(number)}/status` });
+    return Client.json<models.RegistrationStatus>(response);
+  }
+
+  /**
+   * POST /v1/register/{number}/verify/{token}
+   *
+   * Verify a registered phone number.
+   *
+   * Verify a registered phone number with the signal network.
+   */
+  async postV1RegisterVerify(number: string, token: string, body: models.VerifyNumberSettings): Promise<void> {
+    await this.send({ method: "POST", path: `/v1/register/${encodeURIComponent(number)}/verify/${encodeURIComponent(token)}`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * GET /v1/search/messages/{number}
+   *
+   * Search the messages of a number.
+   *
+   * Full-text search in the messages of the message store, the newest first. All words of the query
+   * need to be contained in a message. The results can be filtered by sender, group and time. The
+   * listing is paginated, the total number of results is returned in the X-Total-Count header.
+   */
+  async getV1SearchMessages(number: string, q: string, options: GetV1SearchMessagesOptions = {}): Promise<models.SearchHit[]> {
+    const response = await this.send({ method: "GET", path: `/v1/search/messages/${encodeURIComponent(number)}`, query: { q: q, sender: options.sender, group: options.group, from: options.from, to: options.to, offset: options.offset, limit: options.limit } });
+    return Client.json<models.SearchHit[]>(response);
+  }
+
+  /**
+   * POST /v1/selftest/{number}
+   *
+   * Run a self-test of a number.
+   *
+   * Sends a note to self from the number through signald and reports the duration and outcome of
+   * every step, as smoke test after a deploy. The message takes the same path as other messages
+   * (maintenance, rate limits, quotas of the client selftest, send hooks), the test fails if it is
+   * queued instead of sent.
+   */
+  async postV1Selftest(number: string): Promise<models.SelfTestReport> {
+    const response = await this.send({ method: "POST", path: `/v1/selftest/${encodeURIComponent(number)}` });
+    return Client.json<models.SelfTestReport>(response);
+  }
+
+  /**
+   * POST /v1/send
+   *
+   * Send a signal message.
+   *
+   * Send a signal message.
+   */
+  async postV1Send(body: models.SendMessageV1): Promise<models.SentMessageResponse> {
+    const response = await this.send({ method: "POST", path: `/v1/send`, body: JSON.stringify(body), contentType: "application/json" });
+    return Client.json<models.SentMessageResponse>(response);
+  }
+
+  /**
+   * POST /v1/sessions/{number}/{recipient}/reset
+   *
+   * Reset the session with a recipient.
+   *
+   * Send an end session message to the recipient and drop the session state, e.g. when messages of
+   * the recipient can't be decrypted anymore. A new session is started with the next message.
+   */
+  async postV1SessionsReset(number: string, recipient: string): Promise<void> {
+    await this.send({ method: "POST", path: `/v1/sessions/${encodeURIComponent(number)}/${encodeURIComponent(recipient)}/reset` });
+  }
+
+  /**
+   * GET /v1/simple/groups
+   *
+   * List the groups as a flat list.
+   *
+   * List the ids and names of the groups of a number, e.g. to pick the group to send to in a
+   * low-code tool.
+   */
+  async getV1SimpleGroups(number: string): Promise<models.SimpleGroup[]> {
+    const response = await this.send({ method: "GET", path: `/v1/simple/groups`, query: { number: number } });
+    return Client.json<models.SimpleGroup[]>(response);
+  }
+
+  /**
+   * GET /v1/simple/receive
+   *
+   * Receive messages as a flat list.
+   *
+   * Receive the messages of a number as a flat list of the sender, the group, the text and the
+   * number of attachments. Other messages (receipts, typing, group updates) are left out.
+   */
+  async getV1SimpleReceive(number: string, options: GetV1SimpleReceiveOptions = {}): Promise<models.SimpleMessage[]> {
+    const response = await this.send({ method: "GET", path: `/v1/simple/receive`, query: { number: number, timeout: options.timeout } });
+    return Client.json<models.SimpleMessage[]>(response);
+  }
+
+  /**
+   * GET /v1/simple/send
+   *
+   * Send a message with query parameters.
+   *
+   * Send a message without JSON body for low-code tools that can only make GET requests. to can be
+   * repeated or contain comma separated numbers, or a single group id.
+   */
+  async getV1SimpleSend(number: string, to: string, options: GetV1SimpleSendOptions = {}): Promise<GetV1SimpleSendResponse> {
+    const response = await this.send({ method: "GET", path: `/v1/simple/send`, query: { number: number, to: to, message: options.message } });
+    switch (response.status) {
+      case 201:
+        return { created: await Client.json<models.SentMessageResponse>(response) };
+      case 202:
+        return { accepted: await Client.json<models.QueuedMessages>(response) };
+    }
+    return {};
+  }
+
+  /**
+   * POST /v1/simple/send
+   *
+   * Send a message with form parameters.
+   *
+   * Send a message without JSON body for low-code tools: the parameters are given as form fields
+   * (URL encoded or multipart) or in the query. to can be repeated or contain comma separated
+   * numbers, or a single group id. Attachments are uploaded as files in multipart form fields named
+   * attachment.
+   */
+  async postV1SimpleSend(number: string, to: string, options: PostV1SimpleSendOptions = {}): Promise<PostV1SimpleSendResponse> {
+    const form = new URLSearchParams();
+    for (const [name, value] of [["number", number], ["to", to], ["message", options.message]] as [string, string | undefined][]) {
+      if (value !== undefined) {
+        form.set(name, String(value));
+      }
+    }
+    const response = await this.send({ method: "POST", path: `/v1/simple/send`, body: form, contentType: "application/x-www-form-urlencoded" });
+    switch (response.status) {
+      case 201:
+        return { created: await Client.json<models.SentMessageResponse>(response) };
+      case 202:
+        return { accepted: await Client.json<models.QueuedMessages>(response) };
+    }
+    return {};
+  }
+
+  /**
+   * POST /v2/send
+   *
+   * Send a signal message.
+   *
+   * Send a signal message. Groups can be given as group id ("group.<base64>"), as internal id (the
+   * base64 encoded id signald uses) or as group invite link. Shared contacts are sent as vCard
+   * attachments. Locations are rejected with 501, Signal has no location messages. The recipient
+   * "self" (or note_to_self without recipients) sends a note to self. The timestamp of the message
+   * can be given (e.g. to replay history), the one used is returned.
+   */
+  async postV2Send(body: models.SendMessageV2): Promise<PostV2SendResponse> {
+    const response = await this.send({ method: "POST", path: `/v2/send`, body: JSON.stringify(body), contentType: "application/json" });
+    switch (response.status) {
+      case 201:
+        return { created: await Client.json<models.SentMessageResponse>(response) };
+      case 202:
+        return { accepted: await Client.json<models.QueuedMessages>(response) };
+    }
+    return {};
+  }
+
+  /**
+   * GET /v3/accounts/{number}/groups
+   *
+   * List the groups of an account.
+   *
+   * List the groups of an account, optionally filtered by name. The listing is paginated, the total
+   * number of groups is returned in the X-Total-Count header.
+   */
+  async getV3AccountsGroupsByNumber(number: string, options: GetV3AccountsGroupsByNumberOptions = {}): Promise<models.V3Group[]> {
+    const response = await this.send({ method: "GET", path: `/v3/accounts/${encodeURIComponent(number)}/groups`, query: { name: options.name, offset: options.offset, limit: options.limit } });
+    return Client.json<models.V3Group[]>(response);
+  }
+
+  /**
+   * GET /v3/accounts/{number}/groups/{group_id}
+   *
+   * Show a group.
+   */
+  async getV3AccountsGroupsByNumberAndGroupID(number: string, groupID: string): Promise<models.V3Group> {
+    const response = await this.send({ method: "GET", path: `/v3/accounts/${encodeURIComponent(number)}/groups/${encodeURIComponent(groupID)}` });
+    return Client.json<models.V3Group>(response);
+  }
+
+  /**
+   * PATCH /v3/accounts/{number}/groups/{group_id}
+   *
+   * Update a group.
+   *
+   * Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it)
+   * of a group. Settings that aren't given are left unchanged.
+   */
+  async patchV3AccountsGroups(number: string, groupID: string, body: models.GroupUpdate): Promise<void> {
+    await this.send({ method: "PATCH", path: `/v3/accounts/${encodeURIComponent(number)}/groups/${encodeURIComponent(groupID)}`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * DELETE /v3/accounts/{number}/groups/{group_id}
+   *
+   * Leave a group.
+   *
+   * Leave a group. If the account is the last admin of the group, new_admin (the number or UUID of
+   * a member) is made admin before, so that the group isn't left without admin. Without new_admin
+   * the last admin can only leave with force=true.
+   */
+  async deleteV3AccountsGroups(number: string, groupID: string, options: DeleteV3AccountsGroupsOptions = {}): Promise<void> {
+    await this.send({ method: "DELETE", path: `/v3/accounts/${encodeURIComponent(number)}/groups/${encodeURIComponent(groupID)}`, query: { new_admin: options.new_admin, force: options.force } });
+  }
+
+  /**
+   * POST /v3/accounts/{number}/messages
+   *
+   * Send a message.
+   *
+   * Send a message to recipients or to a group. Responds with 201 and the timestamp of the message,
+   * or with 202 and the ids of the messages queued until the identity of a recipient is trusted.
+   */
+  async postV3AccountsMessages(number: string, body: models.V3SendRequest): Promise<PostV3AccountsMessagesResponse> {
+    const response = await this.send({ method: "POST", path: `/v3/accounts/${encodeURIComponent(number)}/messages`, body: JSON.stringify(body), contentType: "application/json" });
+    switch (response.status) {
+      case 201:
+        return { created: await Client.json<models.SentMessageResponse>(response) };
+      case 202:
+        return { accepted: await Client.json<models.QueuedMessages>(response) };
+    }
+    return {};
+  }
+
+  /**
+   * GET /v3/accounts/{number}/registration
+   *
+   * Show the registration state of a phone number.
+   *
+   * Show whether a number is unregistered, needs a captcha, waits for its verification code or is
+   * active, with a hint what to do next.
+   */
+  async getV3AccountsRegistration(number: string): Promise<models.RegistrationStatus> {
+    const response = await this.send({ method: "GET", path: `/v3/accounts/${encodeURIComponent(number)}/registration` });
+    return Client.json<models.RegistrationStatus>(response);
+  }
+
+  /**
+   * POST /v3/accounts/{number}/registration
+   *
+   * Register a phone number.
+   *
+   * Request a verification code for a phone number, which is sent by SMS or voice call. The number
+   * is then verified with the code. Numbers that are already active are rejected with 409 unless
+   * force is set.
+   */
+  async postV3AccountsRegistration(number: string, body: models.V3Registration): Promise<void> {
+    await this.send({ method: "POST", path: `/v3/accounts/${encodeURIComponent(number)}/registration`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+
+  /**
+   * POST /v3/accounts/{number}/registration/verification
+   *
+   * Verify a phone number.
+   *
+   * Verify a registered phone number with the code it received, with the registration lock pin if
+   * it has one.
+   */
+  async postV3AccountsRegistrationVerification(number: string, body: models.V3Verification): Promise<void> {
+    await this.send({ method: "POST", path: `/v3/accounts/${encodeURIComponent(number)}/registration/verification`, body: JSON.stringify(body), contentType: "application/json" });
+  }
+}
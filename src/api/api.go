@@ -3,153 +3,221 @@ package api
 import (
 	"bytes"
 	"encoding/base64"
-	"io/ioutil"
-	"os"
+	"errors"
+	"net/http"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
 	"github.com/gin-gonic/gin"
-	"github.com/h2non/filetype"
 	jsoniter "github.com/json-iterator/go"
 	log "github.com/sirupsen/logrus"
-	qrcode "github.com/skip2/go-qrcode"
 )
 
 const groupPrefix = "group."
 
 type groupEntry struct {
-	name       string   `json:"name"`
-	id         string   `json:"id"`
-	internalID string   `json:"internal_id"`
-	members    []string `json:"members"`
-	active     bool     `json:"active"`
-	blocked    bool     `json:"blocked"`
+	Name       string   `json:"name"`
+	ID         string   `json:"id"`
+	InternalID string   `json:"internal_id"`
+	Members    []string `json:"members"`
+	Active     bool     `json:"active"`
+	Blocked    bool     `json:"blocked"`
+	// MemberDetails are the members with their UUIDs and roles.
+	MemberDetails []groupMemberEntry `json:"member_details"`
+	// PendingMembers were invited but haven't joined yet.
+	PendingMembers []groupMemberEntry `json:"pending_members"`
+	// RequestingMembers requested to join via the invite link.
+	RequestingMembers []groupMemberEntry `json:"requesting_members"`
+	// ExpirationTimer is the time in seconds after which messages disappear,
+	// 0 if it is disabled. Only v2 groups report it.
+	ExpirationTimer int `json:"expiration_timer"`
 }
 
-type request struct {
-	// Register Number
-	useVoice bool `json:"use_voice"`
+// Error is the body of the responses to failed requests.
+type Error struct {
+	Msg string `json:"error"`
+}
+
+// createGroupResponse is returned when a group was created.
+type createGroupResponse struct {
+	ID string `json:"id"`
+}
 
-	// Verify Number
-	pin string `json:"pin"`
+// verifyNumberSettings are the optional settings of a verification.
+type verifyNumberSettings struct {
+	Pin string `json:"pin"`
+}
 
-	// Send Message
-	number            string   `json:"number"`
-	recipients        []string `json:"recipients"`
-	message           string   `json:"message"`
-	base64Attachment  string   `json:"base64_attachment"`
-	base64Attachments []string `json:"base64_attachments"` //V2
-	isGroup           bool     `json:"is_group"`
+type request struct {
+	// Register Number
+	UseVoice bool   `json:"use_voice"`
+	Captcha  string `json:"captcha"`
 
 	// Create Group
-	name    string   `json:"name"`
-	members []string `json:"members"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
 }
 
-type about struct {
-	supportedAPIVersions []string `json:"versions"`
-	buildNr              int      `json:"build"`
+// sendMessageV1 is the body of /v1/send.
+type sendMessageV1 struct {
+	Number           string   `json:"number"`
+	Recipients       []string `json:"recipients"`
+	Message          string   `json:"message"`
+	Base64Attachment string   `json:"base64_attachment"`
+	IsGroup          bool     `json:"is_group"`
 }
 
-func convertInternalGroupIDToGroupID(internalID string) string {
-	return groupPrefix + base64.StdEncoding.EncodeToString([]byte(internalID))
+// sendMessageV2 is the body of /v2/send.
+type sendMessageV2 struct {
+	Number            string          `json:"number"`
+	Recipients        []string        `json:"recipients"`
+	Message           string          `json:"message"`
+	Base64Attachments []string        `json:"base64_attachments"`
+	AttachmentTokens  []string        `json:"attachment_tokens"`
+	Contacts          []sharedContact `json:"contacts"`
+	Location          *location       `json:"location"`
+	NoteToSelf        bool            `json:"note_to_self"`
+	Timestamp         int64           `json:"timestamp"`
 }
 
-func (a *Api) send(c *gin.Context, number string, message string, recipients []string,
-	base64Attachments []string, isGroup bool) {
+// noteToSelfRecipient is the recipient that sends a message to the number it
+// is sent from, which ends up in the note to self conversation.
+const noteToSelfRecipient = "self"
 
-	if len(recipients) == 0 {
-		c.JSON(400, gin.H{"error": "Please specify at least one recipient"})
-		return
+// resolveNoteToSelf replaces the recipient "self" with number. With
+// noteToSelf empty recipients are replaced too, and no recipients at all
+// send to number.
+func resolveNoteToSelf(number string, recipients []string, noteToSelf bool) []string {
+	if noteToSelf && len(recipients) == 0 {
+		return []string{number}
 	}
 
-	groupID := ""
-	if isGroup {
-		if len(recipients) > 1 {
-			c.JSON(400, gin.H{"error": "More than one group is currently not allowed"})
-			return
-		}
-
-		if _, err := base64.StdEncoding.DecodeString(recipients[0]); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid group id"})
-			return
+	resolved := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		if strings.EqualFold(recipient, noteToSelfRecipient) || (noteToSelf && recipient == "") {
+			recipient = number
 		}
-
-		groupID = recipients[0]
-		recipients[0] = ""
+		resolved[i] = recipient
 	}
+	return resolved
+}
 
-	attachments := []signald.RequestAttachment{}
-	for _, base64Attachment := range base64Attachments {
-		dec, err := base64.StdEncoding.DecodeString(base64Attachment)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
-
-		fType, err := filetype.Get(dec)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
+// sentMessageResponse is returned by the send endpoints, the timestamp
+// identifies the message in receipts and reactions.
+type sentMessageResponse struct {
+	Timestamp int64 `json:"timestamp"`
+}
 
-		f, err := ioutil.TempFile(a.attachmentTmpDir, "signald-rest-api-*."+fType.Extension)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
-		defer os.Remove(f.Name())
-		defer f.Close()
-		attachments = append(attachments, signald.RequestAttachment{
-			Filename: f.Name(),
-		})
+// BuildInfo identifies the build of the API, it is set with -ldflags when
+// building.
+type BuildInfo struct {
+	GitCommit string
+	BuildDate string
+}
 
-		if _, err := f.Write(dec); err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
-		if err := f.Sync(); err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
+type about struct {
+	SupportedAPIVersions []string              `json:"versions"`
+	BuildNr              int                   `json:"build"`
+	GitCommit            string                `json:"git_commit"`
+	BuildDate            string                `json:"build_date"`
+	GoVersion            string                `json:"go_version"`
+	Mode                 string                `json:"mode"`
+	Storage              string                `json:"storage"`
+	Backend              aboutBackend          `json:"backend"`
+	Subsystems           map[string]bool       `json:"subsystems"`
+	Backends             []backendCapabilities `json:"backends"`
+}
 
-		f.Close()
-	}
+// aboutBackend describes the messaging backend the API uses.
+type aboutBackend struct {
+	Type string `json:"type"`
+	// Supervised is set if the API runs signald itself.
+	Supervised bool `json:"supervised"`
+	// Count is the number of configured signald backends.
+	Count int `json:"count"`
+}
 
-	for _, to := range recipients {
-		_, err := a.s.Send(number, signald.RequestAddress{Number: to},
-			groupID, message, attachments, signald.RequestQuote{})
+// convertInternalGroupIDToGroupID returns the group id the API uses for a
+// group. It is URL-safe base64 encoded, so that it can be used in paths.
+func convertInternalGroupIDToGroupID(internalID string) string {
+	return groupPrefix + base64.RawURLEncoding.EncodeToString([]byte(internalID))
+}
 
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
+// send sends a message and responds with the timestamp, or with the ids of the
+// messages queued until the identity of a recipient is trusted.
+func (a *Api) send(c *gin.Context, number string, message string, recipients []string,
+	base64Attachments []string, attachmentTokens []string, isGroup bool, timestamp int64) {
+
+	result, err := a.service.Submit(service.Send{
+		Number:            number,
+		Message:           message,
+		Recipients:        recipients,
+		Base64Attachments: base64Attachments,
+		AttachmentTokens:  attachmentTokens,
+		IsGroup:           isGroup,
+		Timestamp:         timestamp,
+		Client:            a.audit.actor(c),
+		Context:           c.Request.Context(),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	for name, value := range result.Quota {
+		c.Header(name, value)
 	}
 
-	c.JSON(201, nil)
+	if len(result.Queued) > 0 {
+		c.JSON(202, queuedMessages{Queued: result.Queued})
+		return
+	}
+	c.JSON(201, sentMessageResponse{Timestamp: result.Timestamp})
 }
 
 func (a *Api) getGroups(number string) ([]groupEntry, error) {
 	groupEntries := []groupEntry{}
 
-	message, err := a.s.ListGroups(number)
+	message, err := a.listings.get(groupsListingKey(number), func() (signald.Response, error) {
+		return a.client(number).ListGroups(number)
+	})
 	if err != nil {
 		return groupEntries, err
 	}
+	details := a.groupDetails(number)
 
 	for _, group := range message.Data.Groups {
 		g := groupEntry{
-			internalID: group.GroupID,
-			id:         convertInternalGroupIDToGroupID(group.GroupID),
-			name:       group.Name,
-			blocked:    false,
-			active:     false,
+			InternalID:        group.GroupID,
+			ID:                convertInternalGroupIDToGroupID(group.GroupID),
+			Name:              group.Name,
+			Blocked:           false,
+			Active:            false,
+			MemberDetails:     []groupMemberEntry{},
+			PendingMembers:    []groupMemberEntry{},
+			RequestingMembers: []groupMemberEntry{},
+		}
+		if internalID, ok := normalizeInternalGroupID(group.GroupID); ok {
+			if d, ok := details[internalID]; ok {
+				g.ExpirationTimer = d.timer
+				g.MemberDetails = d.members
+				g.PendingMembers = d.pending
+				g.RequestingMembers = d.requesting
+			}
 		}
 
 		for _, m := range group.Members {
-			g.members = append(g.members, m.Number)
+			g.Members = append(g.Members, m.Number)
 			if number == m.Number {
-				g.active = true
+				g.Active = true
+			}
+		}
+		if len(g.MemberDetails) == 0 {
+			for _, m := range group.Members {
+				g.MemberDetails = append(g.MemberDetails, groupMemberEntry{UUID: m.UUID, Number: m.Number})
 			}
 		}
 
@@ -159,40 +227,521 @@ func (a *Api) getGroups(number string) ([]groupEntry, error) {
 	return groupEntries, nil
 }
 
+// Config contains the settings of the REST API.
+type Config struct {
+	SignaldSocketPath       string
+	AttachmentTmpDir        string
+	AttachmentCacheTTL      time.Duration
+	UploadTTL               time.Duration
+	SendHookURLs            []string
+	SendHookTimeout         time.Duration
+	ReceiveProcessors       []ReceiveProcessor
+	DataDir                 string
+	CommandPrefix           string
+	CommandTimeout          time.Duration
+	WebhookURLs             []string
+	Webhooks                []Webhook
+	WebhookRetries          int
+	DrainInterval           time.Duration
+	ReceiveTimeout          time.Duration
+	DefaultDeviceName       string
+	UserAgent               string
+	CaptchaSolverURL        string
+	CaptchaSolverTimeout    time.Duration
+	WebhookTimeout          time.Duration
+	TrustPolicy             string
+	ResendAfterTrust        bool
+	AccountSettings         map[string]AccountSettings
+	StatsWindows            []time.Duration
+	SubscribeNumbers        []string
+	ListingCacheTTL         time.Duration
+	InlineAttachmentMaxSize int64
+	SignaldAttachmentDir    string
+	FFmpegPath              string
+	VideoLimits             *VideoLimits
+	MessageStoreSize        int
+	MessagePurgeAfter       time.Duration
+	ProxyURL                string
+	EgressAllowlist         []string
+	// MessageStoreKey encrypts the persisted message store if it is set.
+	MessageStoreKey []byte
+	// MetricsBuckets are the buckets of the send duration histogram.
+	MetricsBuckets []float64
+	// MetricsLabels are the optional labels of the metrics (number,
+	// recipient).
+	MetricsLabels []string
+	// SignaldCommand is the command line of signald if the API runs it.
+	SignaldCommand []string
+	// SignaldBackendsConfig is the JSON file with the signald backends the
+	// numbers are routed to.
+	SignaldBackendsConfig string
+	// RedisURL is the Redis the replicas of the API share their state in,
+	// if empty the state is kept in memory.
+	RedisURL string
+	// StorageDSN is the database the server side state is kept in.
+	StorageDSN string
+	// AttachmentFsync is the fsync policy of attachment files (always,
+	// never, batch).
+	AttachmentFsync string
+	// AttachmentTimeout is how long the attachments of a message may take
+	// to process before sending (0 means no limit).
+	AttachmentTimeout time.Duration
+	// AttachmentTmpReserve is the number of bytes that are kept free in the
+	// attachment tmp dir, attachments that don't fit are rejected.
+	AttachmentTmpReserve int64
+	// UploadMaxSize is the maximum size in bytes of a resumable upload.
+	UploadMaxSize int64
+	// AuditActorHeader is the header the authenticating reverse proxy puts
+	// the user in, who is recorded as actor of changes in the audit log.
+	AuditActorHeader string
+	// SignaldCheck is how the API reacts if signald can't be used on startup
+	// (strict, degraded, off).
+	SignaldCheck string
+	// Build identifies the build in /v1/about.
+	Build BuildInfo
+	// FaultInjection enables injecting faults via /admin/faults.
+	FaultInjection bool
+	// SignaldDataDir is the data directory of signald.
+	SignaldDataDir string
+	// AccountArchive is a tar.gz archive of a signald data dir that is
+	// extracted into SignaldDataDir on startup.
+	AccountArchive string
+	// AutoLinkURL is the provisioner the linking URI is posted to on startup
+	// if signald has no account, e.g. /v1/devices/{number} of the API running
+	// the primary device.
+	AutoLinkURL string
+	// DecryptionFailurePolicy is how the API reacts to messages that can't
+	// be decrypted (ignore, reset).
+	DecryptionFailurePolicy string
+	// Quotas limit the messages and attachment bytes the clients send per
+	// hour and day, * applies to the clients without quotas of their own.
+	Quotas map[string]QuotaLimits
+	// Matrix bridges conversations to a Matrix homeserver, if it is set.
+	Matrix *MatrixBridge
+	// XMPP bridges contacts and groups to an XMPP server, if it is set.
+	XMPP *XMPPBridge
+	// SMTP receives emails and sends them as Signal messages, if it is set.
+	SMTP *SMTPServer
+	// Syslog forwards syslog messages as Signal messages, if it is set.
+	Syslog *SyslogListener
+}
+
 type Api struct {
-	attachmentTmpDir string
-	s                *signald.Signald
+	attachmentTmpDir  string
+	backends          *backendRouter
+	purgers           purgerRegistry
+	attachments       *attachmentStager
+	uploads           *uploadManager
+	sendHooks         *sendHooks
+	pipeline          []receiveStage
+	commands          *commandRegistry
+	webhooks          *webhooks
+	digests           *webhookDigests
+	groupStates       *groupStates
+	trustPolicy       string
+	queue             *sendQueue
+	accounts          *accountRegistry
+	stats             *statsRecorder
+	subscriptions     *subscriptions
+	bus               *eventBus
+	drains            *drainer
+	receiveTimeout    int
+	defaultDeviceName string
+	links             *linkSessions
+	listings          *listingCache
+	directory         *accountDirectory
+	confirmations     *confirmations
+	metrics           *metrics
+	captchas          *captchaSolver
+	inlineMaxSize     int64
+	thumbnails        *thumbnails
+	videos            *videoGuard
+	polls             *pollRegistry
+	messageRequests   *messageRequests
+	store             *messageStore
+	supervisor        *supervisor
+	idempotency       *idempotencyKeys
+	leader            *leaderElection
+	muxes             *signaldMuxes
+	audit             *auditLog
+	signaldChecks     *signaldChecks
+	service           *service.Service
+	versions          *backendVersions
+	about             about
+	verifications     *verificationAttempts
+	privacy           *privacyRegistry
+	maintenance       *maintenance
+	faults            *faultInjector
+	recent            *recentMessages
+	decryptionResets  *decryptionResets
+	challenges        *rateLimitChallenges
+	quotas            *quotas
+	matrix            *matrixBridge
+	xmpp              *xmppBridge
+	mediaClient       *http.Client
+	smtp              *smtpServer
+	syslog            *syslogListener
 }
 
-func NewApi(signaldSocketPath string, attachmentTmpDir string) *Api {
-	return &Api{
-		attachmentTmpDir: attachmentTmpDir,
-		s: &signald.Signald{
-			SocketPath: signaldSocketPath,
-			Verbose:    false,
-			StatusJSON: true,
+func NewApi(config Config) (*Api, error) {
+	if err := validateTrustPolicy(config.TrustPolicy); err != nil {
+		return nil, err
+	}
+	if err := validateDecryptionFailurePolicy(config.DecryptionFailurePolicy); err != nil {
+		return nil, err
+	}
+	if err := validateFsyncPolicy(config.AttachmentFsync); err != nil {
+		return nil, err
+	}
+	if err := validateSignaldCheck(config.SignaldCheck); err != nil {
+		return nil, err
+	}
+	syncer := newFileSyncer(config.AttachmentFsync)
+
+	a := &Api{
+		attachmentTmpDir: config.AttachmentTmpDir,
+		attachments:      newAttachmentStager(config.AttachmentTmpDir, config.AttachmentCacheTTL, syncer, config.AttachmentTmpReserve),
+		groupStates:      newGroupStates(),
+		stats:            newStatsRecorder(config.StatsWindows),
+		links:            newLinkSessions(config.SignaldSocketPath),
+		listings:         newListingCache(config.ListingCacheTTL),
+		trustPolicy:      config.TrustPolicy,
+		inlineMaxSize:    config.InlineAttachmentMaxSize,
+		thumbnails:       newThumbnails(config.SignaldAttachmentDir, config.FFmpegPath),
+		bus:              newEventBus(),
+		muxes:            newSignaldMuxes(),
+		versions:         newBackendVersions(),
+	}
+	a.directory = newAccountDirectory(a.listAccounts)
+	a.service = service.New(serviceBackend{a}, config.AttachmentTimeout)
+	a.confirmations = newConfirmations()
+	a.decryptionResets = newDecryptionResets(config.DecryptionFailurePolicy)
+	var err error
+	a.metrics, err = newMetrics(config.MetricsBuckets, config.MetricsLabels)
+	if err != nil {
+		return nil, err
+	}
+	if config.VideoLimits != nil {
+		a.videos = newVideoGuard(*config.VideoLimits, config.AttachmentTmpDir)
+	}
+	if config.UploadMaxSize <= 0 {
+		return nil, errors.New("Invalid maximum upload size")
+	}
+	a.uploads = newUploadManager(config.AttachmentTmpDir, config.UploadTTL, config.UploadMaxSize, a.attachments)
+
+	var shared *sharedState
+	if config.RedisURL != "" {
+		if shared, err = newSharedState(config.RedisURL); err != nil {
+			return nil, err
+		}
+	}
+	a.idempotency = newIdempotencyKeys(shared)
+	if config.DrainInterval != 0 && config.DrainInterval < minDrainInterval {
+		return nil, errors.New("Invalid drain interval (minimum 10s)")
+	}
+
+	// signald takes the timeout in full seconds
+	a.receiveTimeout = int((config.ReceiveTimeout + time.Second - 1) / time.Second)
+	if a.receiveTimeout < 1 || a.receiveTimeout > maxReceiveTimeout {
+		return nil, errors.New("Invalid receive timeout (1s to " + strconv.Itoa(maxReceiveTimeout) + "s)")
+	}
+
+	a.leader, err = newLeaderElection(shared)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openStateDB(config.StorageDSN, config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	a.drains = newDrainer(config.DrainInterval, shared, db)
+	a.bus.subscribe("drain", a.drains.consume, eventMessageSent)
+
+	a.backends, err = newBackendRouter(config.SignaldSocketPath, config.SignaldBackendsConfig)
+	if err != nil {
+		return nil, err
+	}
+	a.accounts, err = newAccountRegistry(config.AccountSettings, newStateStore(db, config.DataDir, "accounts"))
+	if err != nil {
+		return nil, err
+	}
+	a.accounts.shared = shared
+	userAgent, err := expandIdentification("user agent", config.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	a.defaultDeviceName, err = expandIdentification("device name", config.DefaultDeviceName)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEgress(config.ProxyURL, config.EgressAllowlist, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	a.sendHooks = newSendHooks(config.SendHookURLs, e.client(config.SendHookTimeout))
+	a.captchas = newCaptchaSolver(config.CaptchaSolverURL, e.client(config.CaptchaSolverTimeout))
+	a.verifications = newVerificationAttempts()
+	a.maintenance = newMaintenance(a.attachments)
+	a.challenges = newRateLimitChallenges(a.attachments)
+	a.quotas = newQuotas(config.Quotas)
+	a.mediaClient = e.publicClient(twilioMediaTimeout)
+
+	hooks := append([]Webhook{}, config.Webhooks...)
+	for _, url := range config.WebhookURLs {
+		hooks = append(hooks, Webhook{URL: url})
+	}
+	deliveries, err := newWebhookDeliveries(newStateStore(db, config.DataDir, "dead_letters"))
+	if err != nil {
+		return nil, err
+	}
+	a.webhooks, err = newWebhooks(hooks, e.client(config.WebhookTimeout), config.WebhookRetries, deliveries, func(number string) []string {
+		return a.accounts.get(number).WebhookURLs
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.bus.subscribe("webhooks", func(e busEvent) {
+		a.webhooks.emit(e.Type, e.Number, e.Data, e.Source)
+	}, eventGroupMemberJoined, eventGroupMemberLeft, eventGroupNameChanged, eventGroupUpdated, eventIdentityChanged,
+		eventMessageRequest, eventSpamReported, eventReceipt, eventDecryptionFailed, eventChallengeRequired)
+	if config.Matrix != nil {
+		a.matrix = newMatrixBridge(config.Matrix, e.client(matrixTimeout))
+		a.bus.subscribe("matrix bridge", a.bridgeReceived, eventReceived)
+		a.purgers.register(a.matrix)
+	}
+	if config.XMPP != nil {
+		a.xmpp = newXMPPBridge(config.XMPP)
+		a.bus.subscribe("xmpp bridge", a.bridgeReceivedToXMPP, eventReceived)
+		go a.xmpp.run(a.bridgeFromXMPP)
+	}
+	if config.SMTP != nil {
+		if a.smtp, err = newSMTPServer(config.SMTP); err != nil {
+			return nil, err
+		}
+		log.Info("Receiving emails on ", config.SMTP.Listen)
+		go a.smtp.serve(a.mailToSignal)
+	}
+	if config.Syslog != nil {
+		if a.syslog, err = newSyslogListener(config.Syslog); err != nil {
+			return nil, err
+		}
+		if config.Syslog.UDP != "" {
+			log.Info("Receiving syslog messages on ", config.Syslog.UDP, " (udp)")
+		}
+		if config.Syslog.TCP != "" {
+			log.Info("Receiving syslog messages on ", config.Syslog.TCP, " (tcp)")
+		}
+		a.syslog.serve(a.syslogToSignal)
+	}
+	if a.digests = newWebhookDigests(a.webhooks); a.digests != nil {
+		a.bus.subscribe("webhook digests", a.digests.consume, eventReceived)
+		a.purgers.register(a.digests)
+		a.digests.start()
+	}
+
+	// injected faults drop received messages before any stage sees them
+	a.faults = newFaultInjector(config.FaultInjection)
+	a.pipeline = append(a.pipeline, a.faultStage())
+
+	// typing messages are dropped unless the number wants them
+	a.pipeline = append(a.pipeline, a.typingStage())
+
+	// blocked senders are dropped before their messages reach the processors
+	a.messageRequests, err = newMessageRequests(newStateStore(db, config.DataDir, "message_requests"))
+	if err != nil {
+		return nil, err
+	}
+	a.bus.subscribe("message requests", a.messageRequests.consume, eventMessageSent)
+	a.purgers.register(a.messageRequests)
+	a.pipeline = append(a.pipeline, a.messageRequestStage())
+
+	for i := range config.ReceiveProcessors {
+		p := &config.ReceiveProcessors[i]
+		if err := p.init(e); err != nil {
+			return nil, err
+		}
+		a.pipeline = append(a.pipeline, p.stage())
+	}
+
+	a.commands, err = newCommandRegistry(config.CommandPrefix, e.client(config.CommandTimeout),
+		newStateStore(db, config.DataDir, "commands"))
+	if err != nil {
+		return nil, err
+	}
+	a.polls, err = newPollRegistry(newStateStore(db, config.DataDir, "polls"))
+	if err != nil {
+		return nil, err
+	}
+	a.audit, err = newAuditLog(config.AuditActorHeader, newStateStore(db, config.DataDir, "audit"),
+		newEntityStore(db, "audit"))
+	if err != nil {
+		return nil, err
+	}
+	a.privacy, err = newPrivacyRegistry(newStateStore(db, config.DataDir, "privacy"))
+	if err != nil {
+		return nil, err
+	}
+
+	a.pipeline = append(a.pipeline, a.commandStage(), a.groupEventStage(), a.receiptStage(),
+		a.readReceiptStage(), a.thumbnailStage(), a.pollStage())
+	a.purgers.register(a.thumbnails)
+	a.purgers.register(a.polls)
+	a.purgers.register(a.webhooks)
+	a.purgers.register(a.audit)
+	a.purgers.register(a.listings)
+	a.purgers.register(a.maintenance)
+	a.purgers.register(a.challenges)
+	a.purgers.register(a.metrics)
+	a.purgers.register(a.decryptionResets)
+	a.purgers.register(a.drains)
+
+	if config.MessageStoreSize > 0 {
+		state := newStateStore(db, config.DataDir, "messages")
+		if config.MessageStoreKey != nil {
+			if state, err = newEncryptedState(state, "messages", config.MessageStoreKey); err != nil {
+				return nil, err
+			}
+		} else {
+			log.Warn("The message store is persisted in plain text, set a key to encrypt it")
+		}
+		a.store, err = newMessageStore(config.MessageStoreSize, config.MessagePurgeAfter, state)
+		if err != nil {
+			return nil, err
+		}
+		if config.MessageStoreKey != nil {
+			// the index would have the bodies in plain text
+			if err := dropSearchIndex(db); err != nil {
+				return nil, errors.New("Couldn't remove the full-text index: " + err.Error())
+			}
+		} else {
+			index, err := newSearchIndex(db)
+			if err == nil && index != nil {
+				err = a.store.setIndex(index)
+			}
+			if err != nil {
+				log.Warn(err.Error(), ", searching the message store without full-text index")
+			}
+		}
+		a.bus.subscribe("message store", a.storeEvent, eventReceived, eventMessageSent)
+		a.recent = newRecentMessages()
+		a.purgers.register(a.store)
+		a.purgers.register(a.recent)
+		go a.store.run()
+	}
+
+	if config.ResendAfterTrust {
+		a.queue, err = newSendQueue(a.attachments, shared, newEntityStore(db, "queue"))
+		if err != nil {
+			return nil, err
+		}
+		a.purgers.register(a.queue)
+		go a.runQueue()
+	}
+
+	go a.attachments.run()
+	go syncer.run()
+	go a.uploads.run()
+	go a.runAccountSync()
+	go a.idempotency.run()
+	go a.leader.run()
+
+	if config.AccountArchive != "" {
+		if err := importAccountArchive(config.AccountArchive, config.SignaldDataDir); err != nil {
+			return nil, err
+		}
+	}
+	if config.AutoLinkURL != "" && a.defaultDeviceName == "" {
+		return nil, errors.New("Linking on startup needs a device name (-default-device-name)")
+	}
+
+	// signald run by the API is checked by the supervisor once it started
+	a.signaldChecks = newSignaldChecks()
+	if config.SignaldCheck != SignaldCheckOff && len(config.SignaldCommand) == 0 {
+		if err := a.signaldChecks.run(a.backends.activeSocketPaths()); err != nil {
+			if config.SignaldCheck == SignaldCheckStrict {
+				return nil, err
+			}
+			log.Warn("Starting degraded, requests will fail until signald is fixed: ", err.Error())
+		}
+	}
+
+	if len(config.SignaldCommand) > 0 {
+		a.supervisor = newSupervisor(config.SignaldCommand, config.SignaldSocketPath)
+		a.supervisor.start()
+	}
+
+	go a.backends.reloadOnSignal()
+
+	a.subscriptions = newSubscriptions(a.backends.socketPath, a.processReceived, a.bus, shared, db, config.SubscribeNumbers)
+	a.purgers.register(a.subscriptions)
+	a.subscriptions.start()
+	go a.runDrain()
+	if config.AutoLinkURL != "" {
+		go a.autoLink(config.AutoLinkURL, a.defaultDeviceName, e.client(autoLinkTimeout))
+	}
+
+	if err := a.checkPurgers(); err != nil {
+		return nil, err
+	}
+
+	a.about = a.describe(config, db)
+	return a, nil
+}
+
+// describe returns the part of /v1/about that doesn't change while the API
+// runs.
+func (a *Api) describe(config Config, db *stateDB) about {
+	info := about{
+		SupportedAPIVersions: []string{"v1", "v2", "v3"},
+		BuildNr:              2,
+		GitCommit:            config.Build.GitCommit,
+		BuildDate:            config.Build.BuildDate,
+		GoVersion:            runtime.Version(),
+		Mode:                 "standalone",
+		Storage:              "memory",
+		Backend:              aboutBackend{Type: "signald", Supervised: a.supervisor != nil, Count: len(a.backends.list())},
+		Subsystems: map[string]bool{
+			"webhooks":           len(a.webhooks.hooks) > 0,
+			"message_store":      a.store != nil,
+			"send_queue":         a.queue != nil,
+			"video_transcoding":  a.videos != nil,
+			"send_hooks":         len(config.SendHookURLs) > 0,
+			"receive_processors": len(config.ReceiveProcessors) > 0,
 		},
 	}
+	if a.leader.shared != nil {
+		info.Mode = "replicated"
+	}
+	if db != nil {
+		info.Storage = db.driver
+	}
+	return info
 }
 
 // @Summary Lists general information about the API
 // @Tags General
-// @Description Returns the supported API versions and the internal build nr
+// @Description Returns the supported API versions, the build (git commit and date), the mode (standalone or replicated with Redis), the storage, the enabled subsystems and the version of every signald backend with the capabilities it supports. Endpoints that need a capability the backend of a number doesn't support respond with 501.
 // @Produce  json
-// @Success 200 {object} About
+// @Success 200 {object} about
 // @Router /v1/about [get]
 func (a *Api) About(c *gin.Context) {
-	c.JSON(200, about{supportedAPIVersions: []string{"v1", "v2"}, buildNr: 2})
+	info := a.about
+	info.Backends = a.backendsCapabilities()
+	c.JSON(200, info)
 }
 
 // @Summary Register a phone number.
 // @Tags Devices
-// @Description Register a phone number with the signal network.
+// @Description Register a phone number with the signal network. If Signal requires a captcha it is passed to the captcha solver (-captcha-solver-url), or it can be solved manually and given as captcha. Numbers that are already registered and verified are rejected with 409 and their registration state, unless force is set.
 // @Accept  json
 // @Produce  json
 // @Success 201
 // @Failure 400 {object} Error
+// @Failure 409 {object} registrationStatus
+// @Failure 429 {object} Error
 // @Param number path string true "Registered Phone Number"
+// @Param force query bool false "Register the number again if it is already active"
 // @Router /v1/register/{number} [post]
 func (a *Api) RegisterNumber(c *gin.Context) {
 	number := c.Param("number")
@@ -213,8 +762,13 @@ func (a *Api) RegisterNumber(c *gin.Context) {
 		}
 	}
 
-	if _, err := a.s.Register(number, "", req.useVoice); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	err := a.registerNumber(number, normalizeCaptcha(req.Captcha), req.UseVoice, c.Query("force") == "true")
+	if conflict, ok := err.(*registrationConflictError); ok {
+		c.JSON(409, gin.H{"error": err.Error(), "account": conflict.status})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(201, nil)
@@ -228,7 +782,7 @@ func (a *Api) RegisterNumber(c *gin.Context) {
 // @Success 201 {string} string "OK"
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
-// @Param data body VerifyNumberSettings true "Additional Settings"
+// @Param data body verifyNumberSettings true "Additional Settings"
 // @Param token path string true "Verification Code"
 // @Router /v1/register/{number}/verify/{token} [post]
 func (a *Api) VerifyRegisteredNumber(c *gin.Context) {
@@ -244,7 +798,7 @@ func (a *Api) VerifyRegisteredNumber(c *gin.Context) {
 		return
 	}
 
-	req := request{}
+	req := verifyNumberSettings{}
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(c.Request.Body)
 	if buf.String() != "" {
@@ -256,8 +810,8 @@ func (a *Api) VerifyRegisteredNumber(c *gin.Context) {
 		}
 	}
 
-	if _, err := a.s.Verify(number, token, req.pin); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if err := a.service.Verify(number, token, req.Pin); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(201, nil)
@@ -268,44 +822,57 @@ func (a *Api) VerifyRegisteredNumber(c *gin.Context) {
 // @Description Send a signal message
 // @Accept  json
 // @Produce  json
-// @Success 201 {string} string "OK"
+// @Success 201 {object} sentMessageResponse
 // @Failure 400 {object} Error
-// @Param data body SendMessageV1 true "Input Data"
+// @Param data body sendMessageV1 true "Input Data"
 // @Router /v1/send [post]
 // @Deprecated
 func (a *Api) Send(c *gin.Context) {
-	req := request{}
+	req := sendMessageV1{}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
 		return
 	}
 
 	base64Attachments := []string{}
-	if req.base64Attachment != "" {
-		base64Attachments = append(base64Attachments, req.base64Attachment)
+	if req.Base64Attachment != "" {
+		base64Attachments = append(base64Attachments, req.Base64Attachment)
 	}
 
-	a.send(c, req.number, req.message, req.recipients, base64Attachments, req.isGroup)
+	recipients := req.Recipients
+	if !req.IsGroup {
+		recipients = resolveNoteToSelf(req.Number, recipients, false)
+	}
+	a.send(c, req.Number, req.Message, recipients, base64Attachments, []string{}, req.IsGroup, 0)
 }
 
 // @Summary Send a signal message.
 // @Tags Messages
-// @Description Send a signal message
+// @Description Send a signal message. Groups can be given as group id ("group.<base64>"), as internal id (the base64 encoded id signald uses) or as group invite link. Shared contacts are sent as vCard attachments. Locations are rejected with 501, Signal has no location messages. The recipient "self" (or note_to_self without recipients) sends a note to self. The timestamp of the message can be given (e.g. to replay history), the one used is returned.
 // @Accept  json
 // @Produce  json
-// @Success 201 {string} string "OK"
+// @Success 201 {object} sentMessageResponse
+// @Success 202 {object} queuedMessages
 // @Failure 400 {object} Error
-// @Param data body SendMessageV2 true "Input Data"
+// @Failure 404 {object} Error
+// @Failure 501 {object} Error
+// @Param data body sendMessageV2 true "Input Data"
 // @Router /v2/send [post]
 func (a *Api) SendV2(c *gin.Context) {
-	req := request{}
+	req := sendMessageV2{}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
 		log.Error(err.Error())
 		return
 	}
 
-	if len(req.recipients) == 0 {
+	if req.Timestamp < 0 {
+		c.JSON(400, gin.H{"error": "Invalid timestamp"})
+		return
+	}
+
+	req.Recipients = resolveNoteToSelf(req.Number, req.Recipients, req.NoteToSelf)
+	if len(req.Recipients) == 0 {
 		c.JSON(400, gin.H{"error": "Couldn't process request - please provide at least one recipient"})
 		return
 	}
@@ -313,9 +880,9 @@ func (a *Api) SendV2(c *gin.Context) {
 	groups := []string{}
 	recipients := []string{}
 
-	for _, recipient := range req.recipients {
-		if strings.HasPrefix(recipient, groupPrefix) {
-			groups = append(groups, strings.TrimPrefix(recipient, groupPrefix))
+	for _, recipient := range req.Recipients {
+		if isGroupRecipient(recipient) {
+			groups = append(groups, recipient)
 		} else {
 			recipients = append(recipients, recipient)
 		}
@@ -331,13 +898,31 @@ func (a *Api) SendV2(c *gin.Context) {
 		return
 	}
 
+	if req.Location != nil {
+		respondError(c, errLocationUnsupported)
+		return
+	}
+
+	attachmentTokens := req.AttachmentTokens
+	if len(req.Contacts) > 0 {
+		tokens, err := a.stageSharedContacts(req.Contacts)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		for _, token := range tokens {
+			defer a.attachments.release(token)
+		}
+		attachmentTokens = append(append([]string{}, attachmentTokens...), tokens...)
+	}
+
 	if len(recipients) > 0 {
-		a.send(c, req.number, req.message, recipients, req.base64Attachments, false)
+		a.send(c, req.Number, req.Message, recipients, req.Base64Attachments, attachmentTokens, false, req.Timestamp)
 		return
 	}
 
 	for _, group := range groups {
-		a.send(c, req.number, req.message, []string{group}, req.base64Attachments, true)
+		a.send(c, req.Number, req.Message, []string{group}, req.Base64Attachments, attachmentTokens, true, req.Timestamp)
 	}
 }
 
@@ -346,9 +931,14 @@ func (a *Api) SendV2(c *gin.Context) {
 // @Description Receives Signal Messages from the Signal Network.
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} []string
+// @Success 200 {object} receiveResults
 // @Failure 400 {object} Error
+// @Failure 404 {object} Error
 // @Param number path string true "Registered Phone Number"
+// @Param attachments query string false "inline embeds attachments up to the inline size limit as base64"
+// @Param timeout query int false "Seconds to wait for messages (default -receive-timeout)"
+// @Param after query string false "Cursor of the last processed message of a subscribed number, the messages up to it are acknowledged"
+// @Param limit query int false "Maximum number of messages to return with a cursor"
 // @Router /v1/receive/{number} [get]
 func (a *Api) Receive(c *gin.Context) {
 	number := c.Param("number")
@@ -357,17 +947,90 @@ func (a *Api) Receive(c *gin.Context) {
 		return
 	}
 
-	rc := make(chan signald.RawResponse)
-	sc := make(chan struct{})
-	a.s.Receive(rc, sc, number, 1, true)
+	attachments := c.Query("attachments")
+	if attachments != "" && attachments != receiveAttachmentsInline {
+		c.JSON(400, gin.H{"error": "Invalid attachments mode " + attachments + " (supported: inline)"})
+		return
+	}
+
+	timeout := a.receiveTimeout
+	if value := c.Query("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 || seconds > maxReceiveTimeout {
+			c.JSON(400, gin.H{"error": "Invalid timeout " + value + " (1 to " + strconv.Itoa(maxReceiveTimeout) + " seconds)"})
+			return
+		}
+		timeout = seconds
+	}
+
+	if a.webhooks.enabled(number) {
+		a.refreshGroupStates(number)
+	}
+
+	_, withCursor := c.GetQuery("after")
+	if sub, ok := a.subscriptions.get(number); ok && withCursor {
+		p, err := parsePage(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if p.offset > 0 {
+			c.JSON(400, gin.H{"error": "Please provide either an offset or a cursor"})
+			return
+		}
+		after, err := parseAfter(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
-	message := signald.RawResponse{}
-	for {
-		message = <-rc
+		messages := sub.read(after, p.limit, time.Duration(timeout)*time.Second)
+		if attachments == receiveAttachmentsInline {
+			inlineAttachments(messages, a.inlineMaxSize)
+		}
+		if len(messages) > 0 {
+			setNextCursor(c, after, messages[len(messages)-1].Cursor)
+		} else {
+			setNextCursor(c, after, "")
+		}
+		c.JSON(200, receiveResults{
+			Type: "receive_results",
+			Done: true,
+			Data: messages,
+		})
+		return
+	} else if ok {
+		messages := sub.fetch(time.Duration(timeout) * time.Second)
+		if attachments == receiveAttachmentsInline {
+			inlineAttachments(messages, a.inlineMaxSize)
+		}
+		c.JSON(200, receiveResults{
+			Type: "receive_results",
+			Done: true,
+			Data: messages,
+		})
+		return
+	}
+
+	if withCursor {
+		c.JSON(400, gin.H{"error": "Cursors are only supported for numbers subscribed with -subscribe-number"})
+		return
+	}
 
-		if message.Done {
-			break
+	unlock := a.drains.lock(number)
+	defer unlock()
+	message := a.receiveOnce(number, timeout)
+
+	if messages, ok := message.Data.([]signald.RawResponse); ok {
+		received := a.processReceived(number, messages)
+		if a.drainInterval(number) > 0 {
+			// messages drained in the background come first
+			received = append(a.drains.buffer(number).fetch(0), received...)
+		}
+		if attachments == receiveAttachmentsInline {
+			inlineAttachments(received, a.inlineMaxSize)
 		}
+		message.Data = received
 	}
 
 	c.JSON(200, message)
@@ -378,7 +1041,7 @@ func (a *Api) Receive(c *gin.Context) {
 // @Description Create a new Signal Group with the specified members.
 // @Accept  json
 // @Produce  json
-// @Success 201 {object} CreateGroup
+// @Success 201 {object} createGroupResponse
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Router /v1/groups/{number} [post]
@@ -396,36 +1059,27 @@ func (a *Api) CreateGroup(c *gin.Context) {
 		return
 	}
 
-	if _, err := a.s.CreateGroup(number, "", req.name, req.members, ""); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	message, err := a.s.ListGroups(number)
+	groupID, err := a.service.CreateGroup(number, req.Name, req.Members)
 	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	internalGroupID := ""
-	for _, group := range message.Data.Groups {
-		if group.Name == req.name {
-			internalGroupID = group.GroupID
-			break
-		}
-	}
-
-	c.JSON(201, gin.H{"id": convertInternalGroupIDToGroupID(internalGroupID)})
+	c.JSON(201, createGroupResponse{ID: groupID})
 }
 
 // @Summary List all Signal Groups.
 // @Tags Groups
-// @Description List all Signal Groups.
+// @Description List all Signal Groups with their members (also with their UUIDs and roles in member_details), the members who were invited but haven't joined yet and the ones who requested to join. The listing can be filtered by name and paginated, the total number of matching groups is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} []GroupEntry
+// @Success 200 {object} []groupEntry
+// @Success 304 {string} string "Not Modified"
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
+// @Param name query string false "Only list groups whose name contains this text"
+// @Param offset query int false "Number of groups to skip"
+// @Param limit query int false "Maximum number of groups to return"
 // @Router /v1/groups/{number} [get]
 func (a *Api) GetGroups(c *gin.Context) {
 	number := c.Param("number")
@@ -434,24 +1088,44 @@ func (a *Api) GetGroups(c *gin.Context) {
 		return
 	}
 
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	groups, err := a.getGroups(number)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, groups)
+	filtered := []groupEntry{}
+	for _, group := range groups {
+		if matchesName(group.Name, c.Query("name")) {
+			filtered = append(filtered, group)
+		}
+	}
+
+	start, end := p.bounds(c, len(filtered))
+	respondWithETag(c, filtered[start:end])
 }
 
 // @Summary Delete a Signal Group.
 // @Tags Groups
-// @Description Delete a Signal Group.
+// @Description Delete a Signal Group. If the account is the last admin of the group, new_admin (the number or UUID of a member) is made admin before the group is left, so that it isn't left without admin. Without new_admin the last admin can only leave with force=true.
 // @Accept  json
 // @Produce  json
 // @Success 200 {string} string "OK"
 // @Failure 400 {object} Error
+// @Failure 403 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 501 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param groupid path string true "Group Id"
+// @Param new_admin query string false "Member to make admin before leaving"
+// @Param force query bool false "Leave the group without admin"
 // @Router /v1/groups/{number}/{groupid} [delete]
 func (a *Api) DeleteGroup(c *gin.Context) {
 	number := c.Param("number")
@@ -466,73 +1140,57 @@ func (a *Api) DeleteGroup(c *gin.Context) {
 		return
 	}
 
-	groupID, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(base64EncodedGroupID, groupPrefix))
+	err := a.service.LeaveGroup(number, base64EncodedGroupID, c.Query("new_admin"), c.Query("force") == "true")
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid group id"})
+		respondError(c, err)
 		return
 	}
-
-	if _, err := a.s.LeaveGroup(number, base64.StdEncoding.EncodeToString(groupID)); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
 	c.JSON(200, nil)
 }
 
 // @Summary Link device and generate QR code.
 // @Tags Devices
-// @Description test
-// @Produce  json
+// @Description Start linking signald as device to an account and return the QR code the primary device scans. The QR code is a PNG image, an SVG image or text (ascii, or ansi with terminal colors), selected with format or the Accept header (image/png, image/svg+xml, text/plain). Scanners that struggle with long URIs may do better with a larger size or a higher error correction level.
+// @Produce  png
+// @Produce  image/svg+xml
+// @Produce  plain
 // @Success 200 {string} string	"Image"
+// @Failure 400 {object} Error
+// @Param device_name query string false "Name of the device (default -default-device-name)"
+// @Param format query string false "png (default), svg, ascii or ansi"
+// @Param level query string false "Error correction level: L, M (default), Q or H"
+// @Param size query int false "Size of PNG and SVG images in pixels (64 to 2048, default 256)"
 // @Router /v1/link [get]
 func (a *Api) Link(c *gin.Context) {
 	deviceName := c.Query("device_name")
+	if deviceName == "" {
+		deviceName = a.defaultDeviceName
+	}
 	if deviceName == "" {
 		c.JSON(400, gin.H{"error": "Please provide a name for the device"})
 		return
 	}
 
-	// We need to handle the socket connection so it stays up between function
-	// calls.
-	if !a.s.IsConnected() {
-		if err := a.s.Connect(); err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
-		}
-	}
-
-	// First we call Link which returns the URI.
-	message, err := a.s.Link(deviceName, "")
+	options, err := parseQROptions(c)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
-		a.s.Disconnect()
 		return
 	}
 
-	q, err := qrcode.New(message.Data.URI, qrcode.Medium)
+	session, uri, err := a.links.start(deviceName)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
-		a.s.Disconnect()
 		return
 	}
 
-	q.DisableBorder = true
-	var png []byte
-	png, err = q.PNG(256)
+	data, contentType, err := renderQRCode(uri, options)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
-		a.s.Disconnect()
 		return
 	}
 
-	// display the QRcode
-	c.Data(200, "image/png", png)
-
-	// call Link a second time with the returned request ID to get the status
-	// of the link attempt.
-	go func() {
-		a.s.Link(deviceName, message.ID)
-		a.s.Disconnect()
-	}()
+	// display the QRcode, the outcome of the link attempt can be checked
+	// with the link sessions
+	c.Header("X-Link-Session-Id", session.ID)
+	c.Data(200, contentType, data)
 }
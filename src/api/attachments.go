@@ -0,0 +1,258 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/h2non/filetype"
+	log "github.com/sirupsen/logrus"
+)
+
+type stagedAttachment struct {
+	filename string
+	refs     int
+	released time.Time
+}
+
+// attachmentStager writes attachments to the tmp dir content addressed by
+// their SHA-256 hash. The same blob is only written once, no matter how many
+// recipients it is sent to, and it lingers for a while after the last user
+// released it, so that a retried request can reuse it.
+type attachmentStager struct {
+	mutex  sync.Mutex
+	dir    string
+	linger time.Duration
+	files  map[string]*stagedAttachment
+	syncer *fileSyncer
+	// reserve is the number of bytes that are kept free in dir.
+	reserve int64
+}
+
+func newAttachmentStager(dir string, linger time.Duration, syncer *fileSyncer, reserve int64) *attachmentStager {
+	return &attachmentStager{
+		dir:     dir,
+		linger:  linger,
+		files:   make(map[string]*stagedAttachment),
+		syncer:  syncer,
+		reserve: reserve,
+	}
+}
+
+// acquire takes a reference on an already staged attachment. The caller
+// needs to hold the mutex.
+func (s *attachmentStager) acquire(hash string) (string, bool) {
+	staged, ok := s.files[hash]
+	if !ok {
+		return "", false
+	}
+	if _, err := os.Stat(staged.filename); err != nil {
+		delete(s.files, hash)
+		return "", false
+	}
+	staged.refs++
+	return staged.filename, true
+}
+
+func (s *attachmentStager) filename(hash string, extension string) string {
+	filename := filepath.Join(s.dir, "signald-rest-api-"+hash)
+	if extension != "" {
+		filename += "." + extension
+	}
+	return filename
+}
+
+// stageWriteChunk is the size of the buffered writes of attachments, the
+// write is canceled between chunks.
+const stageWriteChunk = 64 << 10
+
+// stage makes sure data is available as a file and returns its hash and
+// filename. Every call to stage needs to be paired with a call to release.
+func (s *attachmentStager) stage(data []byte, extension string) (string, string, error) {
+	return s.stageContext(context.Background(), data, extension)
+}
+
+// stageContext stages data like stage, the write stops when ctx is done. The
+// mutex is only held to look up and register the file, not while it is
+// written.
+func (s *attachmentStager) stageContext(ctx context.Context, data []byte, extension string) (string, string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if filename, ok := s.lookup(hash); ok {
+		return hash, filename, nil
+	}
+	if err := s.checkSpace(int64(len(data))); err != nil {
+		return "", "", err
+	}
+
+	f, err := ioutil.TempFile(s.dir, "signald-rest-api-*.partial")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriterSize(f, stageWriteChunk)
+	for offset := 0; offset < len(data); offset += stageWriteChunk {
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
+		end := offset + stageWriteChunk
+		if end > len(data) {
+			end = len(data)
+		}
+		if _, err := w.Write(data[offset:end]); err != nil {
+			return "", "", err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", "", err
+	}
+	if err := s.syncer.sync(f); err != nil {
+		return "", "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// staged by another request in the meantime
+	if filename, ok := s.acquire(hash); ok {
+		return hash, filename, nil
+	}
+	filename := s.filename(hash, extension)
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return "", "", err
+	}
+	s.syncer.written(filename)
+
+	s.files[hash] = &stagedAttachment{filename: filename, refs: 1}
+	return hash, filename, nil
+}
+
+// stageBase64 stages a base64 encoded attachment with the extension of its
+// file type.
+func (s *attachmentStager) stageBase64(ctx context.Context, encoded string) (string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", err
+	}
+	kind, err := filetype.Get(data)
+	if err != nil {
+		return "", "", err
+	}
+	return s.stageContext(ctx, data, kind.Extension)
+}
+
+// stageFile moves the file at path into the staging area. If the same content
+// is already staged the file is removed instead.
+func (s *attachmentStager) stageFile(path string, extension string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return "", "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if filename, ok := s.acquire(hash); ok {
+		os.Remove(path)
+		return hash, filename, nil
+	}
+
+	filename := s.filename(hash, extension)
+	if err := os.Rename(path, filename); err != nil {
+		return "", "", err
+	}
+	s.syncer.written(filename)
+
+	s.files[hash] = &stagedAttachment{filename: filename, refs: 1}
+	return hash, filename, nil
+}
+
+// lookup takes a reference on the staged attachment with the given hash.
+func (s *attachmentStager) lookup(hash string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.acquire(hash)
+}
+
+// adopt takes a reference on an attachment that was staged before the API
+// restarted. It returns false if the file doesn't exist anymore.
+func (s *attachmentStager) adopt(hash string, filename string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.acquire(hash); ok {
+		return true
+	}
+	if _, err := os.Stat(filename); err != nil {
+		return false
+	}
+	s.files[hash] = &stagedAttachment{filename: filename, refs: 1}
+	return true
+}
+
+func (s *attachmentStager) release(hash string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	staged, ok := s.files[hash]
+	if !ok {
+		return
+	}
+	staged.refs--
+	if staged.refs > 0 {
+		return
+	}
+	staged.released = time.Now()
+	if s.linger <= 0 {
+		s.remove(hash)
+	}
+}
+
+func (s *attachmentStager) remove(hash string) {
+	if err := os.Remove(s.files[hash].filename); err != nil && !os.IsNotExist(err) {
+		log.Error("Couldn't remove staged attachment: ", err.Error())
+	}
+	delete(s.files, hash)
+}
+
+func (s *attachmentStager) cleanup() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for hash, staged := range s.files {
+		if staged.refs <= 0 && time.Since(staged.released) >= s.linger {
+			s.remove(hash)
+		}
+	}
+}
+
+func (s *attachmentStager) run() {
+	if s.linger <= 0 {
+		return
+	}
+	for range time.Tick(s.linger / 2) {
+		s.cleanup()
+	}
+}
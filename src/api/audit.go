@@ -0,0 +1,279 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxAuditEntries is the number of entries the audit log keeps, older ones
+// are dropped.
+const maxAuditEntries = 10000
+
+// anonymousActor is the actor of changes made without an authenticated user.
+const anonymousActor = "anonymous"
+
+// auditEntry records a change of the configuration with the values before and
+// after it.
+type auditEntry struct {
+	ID       int64       `json:"id"`
+	Time     time.Time   `json:"time"`
+	Actor    string      `json:"actor"`
+	ClientIP string      `json:"client_ip"`
+	Action   string      `json:"action"`
+	Target   string      `json:"target,omitempty"`
+	Previous interface{} `json:"previous"`
+	New      interface{} `json:"new"`
+}
+
+// auditState is the document the audit log was kept in before its entries
+// were stored one row each, only the next ID is still kept in it.
+type auditState struct {
+	NextID  int64        `json:"next_id"`
+	Entries []auditEntry `json:"entries,omitempty"`
+}
+
+// auditLog records the changes made via /admin and the other configuration
+// endpoints. The API doesn't authenticate users itself, the actor is taken
+// from the header an authenticating reverse proxy sets, or from basic auth.
+// Every entry is stored on its own, recording a change only writes its entry
+// and the counter of the IDs. If the replicas share the store, the entries
+// are loaded from it when the log is read.
+type auditLog struct {
+	mutex       sync.Mutex
+	state       stateStore
+	store       entityStore
+	actorHeader string
+	log         auditState
+}
+
+func newAuditLog(actorHeader string, state stateStore, store entityStore) (*auditLog, error) {
+	l := &auditLog{state: state, store: store, actorHeader: actorHeader, log: auditState{NextID: 1}}
+	if err := state.load(&l.log); err != nil {
+		return nil, errors.New("Couldn't load audit log: " + err.Error())
+	}
+
+	entries, err := l.loadEntries()
+	if err != nil {
+		return nil, errors.New("Couldn't load audit log: " + err.Error())
+	}
+
+	if len(entries) == 0 && len(l.log.Entries) > 0 {
+		// move the entries out of the document of a previous version
+		for _, e := range l.log.Entries {
+			if err := store.put(auditEntryID(e.ID), e); err != nil {
+				return nil, errors.New("Couldn't migrate audit log: " + err.Error())
+			}
+		}
+		if err := state.save(auditState{NextID: l.log.NextID}); err != nil {
+			return nil, errors.New("Couldn't migrate audit log: " + err.Error())
+		}
+		return l, nil
+	}
+
+	l.log.Entries = entries
+	if len(entries) > 0 && entries[len(entries)-1].ID >= l.log.NextID {
+		l.log.NextID = entries[len(entries)-1].ID + 1
+	}
+	return l, nil
+}
+
+// loadEntries reads the stored entries, the oldest first.
+func (l *auditLog) loadEntries() ([]auditEntry, error) {
+	entries := []auditEntry{}
+	err := l.store.loadAll(func(data []byte) error {
+		e := auditEntry{}
+		if err := jsoniter.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// refresh loads the entries the other replicas recorded, the caller needs to
+// hold the mutex.
+func (l *auditLog) refresh() error {
+	if !l.store.shared() {
+		return nil
+	}
+	entries, err := l.loadEntries()
+	if err != nil {
+		return err
+	}
+	l.log.Entries = entries
+	return nil
+}
+
+// nextID takes the ID of a new entry from the stored counter, which the
+// replicas share. The caller needs to hold the mutex.
+func (l *auditLog) nextID() (int64, error) {
+	state := auditState{NextID: l.log.NextID}
+	id := int64(0)
+	err := l.state.update(&state, func(bool) error {
+		if state.NextID < l.log.NextID {
+			state.NextID = l.log.NextID
+		}
+		id = state.NextID
+		state.NextID++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	l.log.NextID = state.NextID
+	return id, nil
+}
+
+// auditEntryID returns the ID of the stored entry, padded so that the entries
+// are loaded in order.
+func auditEntryID(id int64) string {
+	padded := strconv.FormatInt(id, 10)
+	for len(padded) < 20 {
+		padded = "0" + padded
+	}
+	return padded
+}
+
+// actor returns the authenticated user of a request.
+func (l *auditLog) actor(c *gin.Context) string {
+	if l.actorHeader != "" {
+		if actor := c.GetHeader(l.actorHeader); actor != "" {
+			return actor
+		}
+	}
+	if user, _, ok := c.Request.BasicAuth(); ok && user != "" {
+		return user
+	}
+	return anonymousActor
+}
+
+// record adds a change made by the request to the audit log.
+func (l *auditLog) record(c *gin.Context, action string, target string, previous interface{}, current interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	id, err := l.nextID()
+	if err != nil {
+		log.Error("Couldn't save audit log: ", err.Error())
+		return
+	}
+	l.log.Entries = append(l.log.Entries, auditEntry{
+		ID:       id,
+		Time:     time.Now(),
+		Actor:    l.actor(c),
+		ClientIP: c.ClientIP(),
+		Action:   action,
+		Target:   target,
+		Previous: previous,
+		New:      current,
+	})
+	entry := l.log.Entries[len(l.log.Entries)-1]
+	if err := l.store.put(auditEntryID(entry.ID), entry); err != nil {
+		log.Error("Couldn't save audit log: ", err.Error())
+	}
+
+	if len(l.log.Entries) > maxAuditEntries {
+		dropped := []string{}
+		for _, e := range l.log.Entries[:len(l.log.Entries)-maxAuditEntries] {
+			dropped = append(dropped, auditEntryID(e.ID))
+		}
+		l.log.Entries = l.log.Entries[len(l.log.Entries)-maxAuditEntries:]
+		if err := l.store.remove(dropped...); err != nil {
+			log.Error("Couldn't save audit log: ", err.Error())
+		}
+	}
+}
+
+func (l *auditLog) purgerName() string {
+	return "audit log"
+}
+
+// purgeContact deletes the entries that mention both number and contact, e.g.
+// the changes of dead-lettered events to contact.
+func (l *auditLog) purgeContact(number string, contact string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return 0, err
+	}
+	kept := []auditEntry{}
+	deleted := []string{}
+	for _, e := range l.log.Entries {
+		data, err := jsoniter.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		if mentions(string(data), number) && mentions(string(data), contact) {
+			deleted = append(deleted, auditEntryID(e.ID))
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	l.log.Entries = kept
+	// the IDs of the deleted entries aren't used again, the counter is
+	// saved with every entry
+	return len(deleted), l.store.remove(deleted...)
+}
+
+// list returns the entries that match the filters, the newest first.
+func (l *auditLog) list(actor string, action string, target string, since time.Time) []auditEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.refresh(); err != nil {
+		log.Error("Couldn't load audit log: ", err.Error())
+	}
+	entries := []auditEntry{}
+	for i := len(l.log.Entries) - 1; i >= 0; i-- {
+		e := l.log.Entries[i]
+		if (actor != "" && e.Actor != actor) || (action != "" && e.Action != action) ||
+			(target != "" && e.Target != target) || e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// @Summary List the audit log.
+// @Tags Admin
+// @Description List the changes made via /admin and the other configuration endpoints (chat commands), the newest first, with the actor and the values before and after the change. The listing is paginated, the total number of entries is returned in the X-Total-Count header.
+// @Produce  json
+// @Success 200 {object} []auditEntry
+// @Failure 400 {object} Error
+// @Param actor query string false "Only list the changes of this actor"
+// @Param action query string false "Only list changes of this action (e.g. account_settings.update)"
+// @Param target query string false "Only list changes of this target (e.g. a number)"
+// @Param since query string false "Only list changes after this time, timestamp in milliseconds or RFC 3339 time"
+// @Param offset query int false "Number of entries to skip"
+// @Param limit query int false "Maximum number of entries to return"
+// @Router /admin/audit [get]
+func (a *Api) GetAuditLog(c *gin.Context) {
+	since, err := parseSearchTime(c.Query("since"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries := a.audit.list(c.Query("actor"), c.Query("action"), c.Query("target"),
+		time.Unix(0, since*int64(time.Millisecond)))
+	start, end := p.bounds(c, len(entries))
+	c.JSON(200, entries[start:end])
+}
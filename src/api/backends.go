@@ -0,0 +1,349 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBackend is the name of the signald given with -signald-socket-path,
+// it serves the numbers that aren't routed to another backend.
+const defaultBackend = "default"
+
+const backendRetryAfter = 5
+
+// SignaldBackend is a signald daemon and the numbers it has the accounts of.
+type SignaldBackend struct {
+	SocketPath string   `json:"socket_path"`
+	Numbers    []string `json:"numbers,omitempty"`
+}
+
+type backendStatus struct {
+	Name       string   `json:"name"`
+	SocketPath string   `json:"socket_path"`
+	Numbers    []string `json:"numbers"`
+	Draining   bool     `json:"draining"`
+	InFlight   int      `json:"in_flight"`
+}
+
+type backend struct {
+	socketPath string
+	numbers    []string
+	draining   bool
+	inFlight   int
+}
+
+// backendRouter routes the requests of every number to the signald it has its
+// account in. The backends are read from a JSON file that maps their names to
+// their socket and numbers, it is read again on SIGHUP. Backends can be
+// drained, i.e. requests for their numbers are rejected while the ones in
+// flight finish, so that the daemon can be replaced.
+type backendRouter struct {
+	mutex    sync.Mutex
+	filename string
+	backends map[string]*backend
+	routes   map[string]string
+}
+
+// LoadSignaldBackends reads the signald backends from a JSON file.
+func LoadSignaldBackends(filename string) (map[string]SignaldBackend, error) {
+	backends := make(map[string]SignaldBackend)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return backends, err
+	}
+
+	if err := jsoniter.Unmarshal(data, &backends); err != nil {
+		return backends, errors.New("Couldn't parse signald backends config: " + err.Error())
+	}
+
+	return backends, nil
+}
+
+func newBackendRouter(socketPath string, filename string) (*backendRouter, error) {
+	r := &backendRouter{
+		filename: filename,
+		backends: map[string]*backend{defaultBackend: {socketPath: socketPath}},
+		routes:   make(map[string]string),
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// configure replaces the backends other than the default one. Backends that
+// are kept keep their drain state and requests in flight.
+func (r *backendRouter) configure(config map[string]SignaldBackend) error {
+	routes := make(map[string]string)
+	for name, b := range config {
+		if name == defaultBackend {
+			return errors.New("The backend name " + defaultBackend + " is reserved")
+		}
+		if b.SocketPath == "" {
+			return errors.New("Please provide the socket path of backend " + name)
+		}
+		for _, number := range b.Numbers {
+			if other, ok := routes[number]; ok {
+				return errors.New(number + " is routed to backends " + other + " and " + name)
+			}
+			routes[number] = name
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	backends := map[string]*backend{defaultBackend: r.backends[defaultBackend]}
+	for name, b := range config {
+		if current, ok := r.backends[name]; ok {
+			current.socketPath = b.SocketPath
+			current.numbers = b.Numbers
+			backends[name] = current
+			continue
+		}
+		backends[name] = &backend{socketPath: b.SocketPath, numbers: b.Numbers}
+	}
+	r.backends = backends
+	r.routes = routes
+	return nil
+}
+
+func (r *backendRouter) reload() error {
+	if r.filename == "" {
+		return nil
+	}
+	config, err := LoadSignaldBackends(r.filename)
+	if err != nil {
+		return err
+	}
+	return r.configure(config)
+}
+
+func (r *backendRouter) reloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if err := r.reload(); err != nil {
+			log.Error("Couldn't reload signald backends: ", err.Error())
+			continue
+		}
+		log.Info("Reloaded signald backends")
+	}
+}
+
+// lookup returns the backend of number, the caller needs to hold the mutex.
+func (r *backendRouter) lookup(number string) (string, *backend) {
+	name, ok := r.routes[number]
+	if !ok {
+		name = defaultBackend
+	}
+	return name, r.backends[name]
+}
+
+func (r *backendRouter) socketPath(number string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, b := r.lookup(number)
+	return b.socketPath
+}
+
+// activeSocketPaths returns the socket paths of the backends that aren't
+// draining.
+func (r *backendRouter) activeSocketPaths() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	paths := []string{}
+	for _, b := range r.backends {
+		if !b.draining {
+			paths = append(paths, b.socketPath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// acquire counts a request for number as in flight on its backend. The
+// returned function has to be called when the request finished.
+func (r *backendRouter) acquire(number string) (func(), error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	name, b := r.lookup(number)
+	if b.draining {
+		return nil, errors.New("The signald backend " + name + " of " + number + " is draining")
+	}
+	b.inFlight++
+	return func() {
+		r.mutex.Lock()
+		b.inFlight--
+		r.mutex.Unlock()
+	}, nil
+}
+
+func (r *backendRouter) setDraining(name string, draining bool) (backendStatus, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b, ok := r.backends[name]
+	if !ok {
+		return backendStatus{}, false
+	}
+	b.draining = draining
+	return b.status(name), true
+}
+
+// get returns the status of the backend with the given name, nil if there is
+// none.
+func (r *backendRouter) get(name string) *backendStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b, ok := r.backends[name]
+	if !ok {
+		return nil
+	}
+	status := b.status(name)
+	return &status
+}
+
+func (b *backend) status(name string) backendStatus {
+	numbers := append([]string{}, b.numbers...)
+	sort.Strings(numbers)
+	return backendStatus{
+		Name:       name,
+		SocketPath: b.socketPath,
+		Numbers:    numbers,
+		Draining:   b.draining,
+		InFlight:   b.inFlight,
+	}
+}
+
+func (r *backendRouter) list() []backendStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	backends := []backendStatus{}
+	for name, b := range r.backends {
+		backends = append(backends, b.status(name))
+	}
+	sort.Slice(backends, func(i, j int) bool { return backends[i].Name < backends[j].Name })
+	return backends
+}
+
+// client returns a signald client for the backend of number.
+func (a *Api) client(number string) *signald.Signald {
+	return &signald.Signald{
+		SocketPath: a.backends.socketPath(number),
+		Verbose:    false,
+		StatusJSON: true,
+	}
+}
+
+// listAccounts lists the accounts of the backends that aren't draining.
+func (a *Api) listAccounts() (signald.Response, error) {
+	accounts := signald.Response{}
+	for _, socketPath := range a.backends.activeSocketPaths() {
+		s := &signald.Signald{SocketPath: socketPath, Verbose: false, StatusJSON: true}
+		message, err := s.ListAccounts()
+		if err != nil {
+			return signald.Response{}, err
+		}
+		accounts.Type = message.Type
+		accounts.Data.Accounts = append(accounts.Data.Accounts, message.Data.Accounts...)
+	}
+	return accounts, nil
+}
+
+// acquireBackend counts a request for number as in flight, or responds with
+// 503 if its backend is draining.
+func (a *Api) acquireBackend(c *gin.Context, number string) (func(), bool) {
+	release, err := a.backends.acquire(number)
+	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(backendRetryAfter))
+		c.JSON(503, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return release, true
+}
+
+// BackendGate tracks the requests in flight on the backend of the number in
+// the path and rejects them while the backend is draining.
+func (a *Api) BackendGate(c *gin.Context) {
+	number := c.Param("number")
+	if number == "" {
+		c.Next()
+		return
+	}
+
+	release, ok := a.acquireBackend(c, number)
+	if !ok {
+		c.Abort()
+		return
+	}
+	defer release()
+	c.Next()
+}
+
+// @Summary List the signald backends.
+// @Tags Admin
+// @Description List the signald backends with the numbers routed to them, whether they are draining and the number of requests in flight.
+// @Produce  json
+// @Success 200 {object} []backendStatus
+// @Router /admin/backends [get]
+func (a *Api) GetBackends(c *gin.Context) {
+	c.JSON(200, a.backends.list())
+}
+
+// @Summary Drain a signald backend.
+// @Tags Admin
+// @Description Reject new requests for the numbers of a backend with 503, so that it can be replaced once the requests in flight finished.
+// @Produce  json
+// @Success 200 {object} backendStatus
+// @Failure 404 {object} Error
+// @Param name path string true "Backend Name"
+// @Router /admin/backends/{name}/drain [post]
+func (a *Api) DrainBackend(c *gin.Context) {
+	previous := a.backends.get(c.Param("name"))
+	status, ok := a.backends.setDraining(c.Param("name"), true)
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such backend"})
+		return
+	}
+	log.Info("Draining signald backend ", status.Name)
+	a.audit.record(c, "backend.drain", status.Name, previous, status)
+	c.JSON(200, status)
+}
+
+// @Summary Resume a signald backend.
+// @Tags Admin
+// @Description Accept requests for the numbers of a drained backend again.
+// @Produce  json
+// @Success 200 {object} backendStatus
+// @Failure 404 {object} Error
+// @Param name path string true "Backend Name"
+// @Router /admin/backends/{name}/resume [post]
+func (a *Api) ResumeBackend(c *gin.Context) {
+	previous := a.backends.get(c.Param("name"))
+	status, ok := a.backends.setDraining(c.Param("name"), false)
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such backend"})
+		return
+	}
+	log.Info("Resumed signald backend ", status.Name)
+	a.audit.record(c, "backend.resume", status.Name, previous, status)
+	c.JSON(200, status)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// eventReceived is published for every incoming message that passed the
+	// receive pipeline, the data is the incomingMessage.
+	eventReceived = "received"
+	// eventMessageSent is published for every message sent to a recipient,
+	// the data is the sentMessage.
+	eventMessageSent = "message_sent"
+)
+
+const eventBusBufferSize = 1000
+
+// busEvent is an event published on the event bus. Source is the signald
+// message the event was caused by, if any.
+type busEvent struct {
+	Type   string
+	Number string
+	Data   interface{}
+	Source interface{}
+}
+
+// sentMessage is the data of a message_sent event.
+type sentMessage struct {
+	Timestamp   int64
+	Recipient   string
+	GroupID     string
+	Message     string
+	Attachments int
+}
+
+// eventConsumer receives the events of some types from the bus.
+type eventConsumer struct {
+	name   string
+	types  map[string]bool
+	events chan busEvent
+}
+
+// eventBus decouples the producers of events (the subscriptions of numbers,
+// the receive pipeline, sending) from their consumers (the receive buffers,
+// the webhooks, the message store). Every consumer has a buffer of its own
+// and handles the events in the order they were published, a slow consumer
+// only drops its own events once its buffer is full.
+type eventBus struct {
+	mutex     sync.Mutex
+	consumers []*eventConsumer
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// subscribe calls handle for the events of the given types (all events if
+// none are given) in a goroutine of its own.
+func (b *eventBus) subscribe(name string, handle func(e busEvent), types ...string) *eventConsumer {
+	c := &eventConsumer{
+		name:   name,
+		types:  make(map[string]bool),
+		events: make(chan busEvent, eventBusBufferSize),
+	}
+	for _, t := range types {
+		c.types[t] = true
+	}
+
+	b.mutex.Lock()
+	b.consumers = append(b.consumers, c)
+	b.mutex.Unlock()
+
+	go func() {
+		for e := range c.events {
+			handle(e)
+		}
+	}()
+	return c
+}
+
+// unsubscribe removes a consumer, the events it already got are still
+// handled.
+func (b *eventBus) unsubscribe(c *eventConsumer) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, consumer := range b.consumers {
+		if consumer == c {
+			b.consumers = append(b.consumers[:i], b.consumers[i+1:]...)
+			close(c.events)
+			return
+		}
+	}
+}
+
+// publish passes an event to the consumers of its type without waiting for
+// them.
+func (b *eventBus) publish(e busEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, c := range b.consumers {
+		if len(c.types) > 0 && !c.types[e.Type] {
+			continue
+		}
+		select {
+		case c.events <- e:
+		default:
+			log.Warn("Event buffer of ", c.name, " is full, dropping ", e.Type, " event of ", e.Number)
+		}
+	}
+}
+
+// publishSent publishes a message_sent event.
+func (a *Api) publishSent(number string, recipient string, groupID string, message string, attachments int, timestamp int64) {
+	a.bus.publish(busEvent{Type: eventMessageSent, Number: number, Data: sentMessage{
+		Timestamp:   timestamp,
+		Recipient:   recipient,
+		GroupID:     groupID,
+		Message:     message,
+		Attachments: attachments,
+	}})
+}
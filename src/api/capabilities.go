@@ -0,0 +1,157 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// The features that depend on the version of signald.
+const (
+	capabilityProfiles            = "profiles"
+	capabilityGroupInviteLinks    = "group_invite_links"
+	capabilityGroupMemberRemoval  = "group_member_removal"
+	capabilityDeviceNames         = "device_names"
+	capabilityVersionedProtocolV1 = "versioned_protocol_v1"
+	capabilityPrivacySettings     = "privacy_settings"
+	capabilityGroupRoles          = "group_roles"
+	capabilitySessionReset        = "session_reset"
+	capabilityChallenges          = "challenges"
+)
+
+// capability is a feature of the API that needs a minimum version of signald.
+// MinVersion is empty for features no version of signald supports yet.
+type capability struct {
+	Name        string
+	MinVersion  string
+	Description string
+}
+
+// capabilities lists the features the backend version decides on, the other
+// features work with every supported signald.
+var capabilities = []capability{
+	{capabilityVersionedProtocolV1, minSignaldVersion, "The versioned protocol"},
+	{capabilityProfiles, "0.11.0", "Fetching profiles and avatars"},
+	{capabilityGroupInviteLinks, "0.11.0", "Resolving group invite links"},
+	{capabilityGroupMemberRemoval, "0.11.0", "Removing group members (v2 groups)"},
+	{capabilityDeviceNames, "0.12.0", "Renaming the device the API runs as"},
+	{capabilityGroupRoles, "0.12.0", "Changing the roles of group members"},
+	{capabilitySessionReset, "0.13.0", "Resetting sessions"},
+	{capabilityChallenges, "0.14.0", "Submitting solved rate limit challenges"},
+	{capabilityPrivacySettings, "0.24.0", "Changing the phone number privacy settings"},
+}
+
+// backendVersionTTL is how long the version of a backend is cached, a changed
+// version is noticed after a restart of signald at the latest then.
+const backendVersionTTL = 5 * time.Minute
+
+type backendVersion struct {
+	version string
+	fetched time.Time
+}
+
+// backendVersions caches the versions the signald backends greet with.
+type backendVersions struct {
+	mutex    sync.Mutex
+	versions map[string]backendVersion
+}
+
+func newBackendVersions() *backendVersions {
+	return &backendVersions{versions: make(map[string]backendVersion)}
+}
+
+// get returns the version of the signald at socketPath, or "" if it can't be
+// reached.
+func (v *backendVersions) get(socketPath string) string {
+	v.mutex.Lock()
+	cached, ok := v.versions[socketPath]
+	v.mutex.Unlock()
+	if ok && time.Since(cached.fetched) < backendVersionTTL {
+		return cached.version
+	}
+
+	version, err := probeSignald(socketPath)
+	if version == "" && err != nil {
+		return ""
+	}
+	v.mutex.Lock()
+	v.versions[socketPath] = backendVersion{version: version, fetched: time.Now()}
+	v.mutex.Unlock()
+	return version
+}
+
+// availableCapabilities returns the names of the capabilities a signald of
+// the given version supports.
+func availableCapabilities(version string) []string {
+	names := []string{}
+	for _, c := range capabilities {
+		if c.MinVersion != "" && versionAtLeast(version, c.MinVersion) {
+			names = append(names, c.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backendCapabilities are the version and the capabilities of a signald
+// backend.
+type backendCapabilities struct {
+	SocketPath   string   `json:"socket_path"`
+	Version      string   `json:"version,omitempty"`
+	Capabilities []string `json:"capabilities"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// backendsCapabilities returns the capabilities of the active backends.
+func (a *Api) backendsCapabilities() []backendCapabilities {
+	backends := []backendCapabilities{}
+	for _, socketPath := range a.backends.activeSocketPaths() {
+		b := backendCapabilities{SocketPath: socketPath, Capabilities: []string{}}
+		if b.Version = a.versions.get(socketPath); b.Version == "" {
+			b.Error = "Couldn't query the version of signald"
+		} else {
+			b.Capabilities = availableCapabilities(b.Version)
+		}
+		backends = append(backends, b)
+	}
+	return backends
+}
+
+// supports fails with 501 if the backend of number is too old for the
+// capability. If the version can't be queried the request is left to fail
+// in signald.
+func (a *Api) supports(number string, name string) error {
+	for _, c := range capabilities {
+		if c.Name == name && c.MinVersion == "" {
+			return service.NewError(service.Unsupported, c.Description+" is not supported by signald")
+		}
+	}
+
+	version := a.versions.get(a.backends.socketPath(number))
+	if version == "" {
+		return nil
+	}
+	for _, c := range capabilities {
+		if c.Name == name && !versionAtLeast(version, c.MinVersion) {
+			return service.NewError(service.Unsupported, c.Description+" requires signald "+c.MinVersion+
+				" or newer, the backend of "+number+" runs "+version)
+		}
+	}
+	return nil
+}
+
+// RequireCapability rejects requests for a number in the path whose backend
+// doesn't support the capability with 501.
+func (a *Api) RequireCapability(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := a.supports(c.Param("number"), name); err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
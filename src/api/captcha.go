@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// captchaPrefix is the scheme of the link the captcha page of Signal opens
+// once it is solved, the token follows it.
+const captchaPrefix = "signalcaptcha://"
+
+const captchaPageURL = "https://signalcaptchas.org/registration/generate.html"
+
+// captchaChallenge is a captcha Signal requires before a number can be
+// registered.
+type captchaChallenge struct {
+	Number    string    `json:"number"`
+	Operation string    `json:"operation"`
+	Since     time.Time `json:"since"`
+	// SolverError is the error of the captcha solver, if it couldn't solve
+	// the captcha.
+	SolverError string `json:"solver_error,omitempty"`
+}
+
+type captchaSolverResponse struct {
+	Captcha string `json:"captcha"`
+}
+
+// captchaSolver passes the captchas Signal requires to an external solver,
+// which returns the token of the solved captcha.
+type captchaSolver struct {
+	url    string
+	client *http.Client
+
+	mutex      sync.Mutex
+	challenges map[string]captchaChallenge
+}
+
+func newCaptchaSolver(url string, client *http.Client) *captchaSolver {
+	return &captchaSolver{
+		url:        url,
+		client:     client,
+		challenges: make(map[string]captchaChallenge),
+	}
+}
+
+// normalizeCaptcha strips the scheme of the link from a captcha token.
+func normalizeCaptcha(captcha string) string {
+	return strings.TrimPrefix(strings.TrimSpace(captcha), captchaPrefix)
+}
+
+// solve posts the challenge to the solver and returns the captcha token.
+func (s *captchaSolver) solve(challenge captchaChallenge) (string, error) {
+	if s.url == "" {
+		return "", errors.New("No captcha solver configured")
+	}
+
+	body, err := jsoniter.Marshal(challenge)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", errors.New("Couldn't call captcha solver: " + err.Error())
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", errors.New("Couldn't read response of captcha solver: " + err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", errors.New("Captcha solver failed with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	solved := captchaSolverResponse{}
+	if err := jsoniter.Unmarshal(data, &solved); err != nil {
+		return "", errors.New("Invalid response of captcha solver: " + err.Error())
+	}
+	if captcha := normalizeCaptcha(solved.Captcha); captcha != "" {
+		return captcha, nil
+	}
+	return "", errors.New("The captcha solver returned no captcha")
+}
+
+// challenged records that number needs to solve a captcha for operation.
+func (s *captchaSolver) challenged(number string, operation string) captchaChallenge {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	challenge, ok := s.challenges[number]
+	if !ok || challenge.Operation != operation {
+		challenge = captchaChallenge{Number: number, Operation: operation, Since: time.Now()}
+	}
+	s.challenges[number] = challenge
+	return challenge
+}
+
+func (s *captchaSolver) failed(number string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if challenge, ok := s.challenges[number]; ok {
+		challenge.SolverError = err.Error()
+		s.challenges[number] = challenge
+	}
+}
+
+func (s *captchaSolver) solved(number string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.challenges, number)
+}
+
+func (s *captchaSolver) challenge(number string) (captchaChallenge, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	challenge, ok := s.challenges[number]
+	return challenge, ok
+}
+
+// register registers number, passing the captcha to the solver if Signal
+// requires one and none was given.
+func (a *Api) register(number string, captcha string, voice bool) error {
+	resp, err := a.client(number).Register(number, captcha, voice)
+	if err == nil {
+		a.captchas.solved(number)
+		return nil
+	}
+	a.metrics.observeError(number, "register", resp, err)
+	if signalErrorClass(resp, err) != errorCaptchaRequired {
+		return err
+	}
+
+	challenge := a.captchas.challenged(number, "register")
+	if captcha == "" && a.captchas.url != "" {
+		solved, serr := a.captchas.solve(challenge)
+		if serr == nil {
+			log.Info("Captcha for registering ", number, " solved, registering again")
+			return a.register(number, solved, voice)
+		}
+		log.Error("Couldn't solve captcha for registering ", number, ": ", serr.Error())
+		a.captchas.failed(number, serr)
+	}
+	return errors.New("Signal requires a captcha to register " + number + ", solve it at " + captchaPageURL +
+		" and register again with the captcha")
+}
+
+// @Summary Show the captcha challenge of a number.
+// @Tags Devices
+// @Description Show whether Signal requires a captcha to register the number, which couldn't be solved by the captcha solver. The captcha is solved at https://signalcaptchas.org/registration/generate.html, the number is then registered again with the captcha.
+// @Produce  json
+// @Success 200 {object} captchaChallenge
+// @Failure 404 {object} Error
+// @Param number path string true "Phone Number"
+// @Router /v1/register/{number}/captcha [get]
+func (a *Api) GetCaptchaChallenge(c *gin.Context) {
+	challenge, ok := a.captchas.challenge(c.Param("number"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "No captcha required"})
+		return
+	}
+	c.JSON(200, challenge)
+}
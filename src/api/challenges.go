@@ -0,0 +1,453 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// eventChallengeRequired is emitted when Signal requires a number to solve a
+// challenge before it delivers further messages.
+const eventChallengeRequired = "challenge_required"
+
+// challengeOptionPush is the option of a challenge Signal sends as push
+// message, which signald can solve without a captcha.
+const challengeOptionPush = "push_challenge"
+
+// proofRequiredError is the error signald responds with when Signal rate
+// limits a number and requires it to prove that it isn't a spammer.
+type proofRequiredError struct {
+	message    string
+	token      string
+	options    []string
+	retryAfter int
+}
+
+func (e *proofRequiredError) Error() string {
+	return e.message
+}
+
+// proofRequiredFrom returns the proof required error of a response of signald,
+// if it is one. signald passes the token of the challenge along, which is
+// needed to submit the solved challenge.
+func proofRequiredFrom(raw signald.RawResponse) (*proofRequiredError, bool) {
+	data, _ := raw.Data.(map[string]interface{})
+	text := raw.Type
+	if m, ok := data["message"].(string); ok {
+		text += " " + m
+	}
+	if !strings.Contains(strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(text)), "proofrequired") {
+		return nil, false
+	}
+
+	if nested, ok := data["error"].(map[string]interface{}); ok {
+		data = nested
+	}
+	e := &proofRequiredError{message: signaldError(raw).Error()}
+	e.token, _ = data["token"].(string)
+	if options, ok := data["options"].([]interface{}); ok {
+		for _, option := range options {
+			if s, ok := option.(string); ok {
+				e.options = append(e.options, strings.ToLower(s))
+			}
+		}
+	}
+	if retryAfter, ok := data["retry_after"].(float64); ok {
+		e.retryAfter = int(retryAfter)
+	}
+	return e, true
+}
+
+// parkedSend is a message to a single recipient or group that is held until
+// the challenge of its number is solved.
+type parkedSend struct {
+	ID          string    `json:"id"`
+	Recipient   string    `json:"recipient,omitempty"`
+	GroupID     string    `json:"group_id,omitempty"`
+	Attachments int       `json:"attachments"`
+	Created     time.Time `json:"created"`
+
+	internalGroupID string
+	message         string
+	hashes          []string
+	filenames       []string
+}
+
+// rateLimitChallenge is a challenge Signal requires a number to solve, with
+// the messages held until it is solved.
+type rateLimitChallenge struct {
+	ID     string `json:"id"`
+	Number string `json:"number"`
+	// Options are the challenges Signal accepts, recaptcha and/or
+	// push_challenge.
+	Options []string `json:"options"`
+	// RetryAfter is the number of seconds after which Signal accepts messages
+	// again without the challenge, if it tells.
+	RetryAfter int          `json:"retry_after,omitempty"`
+	Since      time.Time    `json:"since"`
+	Messages   []parkedSend `json:"messages"`
+
+	token string
+}
+
+// challengeSolution is the solved challenge. The captcha is the token of the
+// captcha solved at https://signalcaptchas.org/challenge/generate.html, it
+// can be omitted if the push challenge was received.
+type challengeSolution struct {
+	Captcha string `json:"captcha"`
+}
+
+// rateLimitChallenges holds the messages of the numbers Signal requires to
+// solve a challenge. A number has at most one challenge, the messages of
+// further proof required errors are added to it. The messages are only kept
+// in memory, their staged attachments are kept until they are sent.
+type rateLimitChallenges struct {
+	mutex      sync.Mutex
+	stager     *attachmentStager
+	challenges map[string]*rateLimitChallenge
+}
+
+func newRateLimitChallenges(stager *attachmentStager) *rateLimitChallenges {
+	return &rateLimitChallenges{stager: stager, challenges: make(map[string]*rateLimitChallenge)}
+}
+
+// park holds a message until the challenge of number is solved. It takes its
+// own reference on the staged attachments and returns the challenge, which is
+// new if the number had none.
+func (r *rateLimitChallenges) park(number string, proof *proofRequiredError, send parkedSend) (rateLimitChallenge, bool, error) {
+	send.filenames = nil
+	for i, hash := range send.hashes {
+		filename, ok := r.stager.lookup(hash)
+		if !ok {
+			for _, acquired := range send.hashes[:i] {
+				r.stager.release(acquired)
+			}
+			return rateLimitChallenge{}, false, errors.New("Attachment " + hash + " is no longer staged")
+		}
+		send.filenames = append(send.filenames, filename)
+	}
+	send.Attachments = len(send.filenames)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, created := r.find(number), false
+	if c == nil {
+		id, err := newUploadID()
+		if err != nil {
+			r.release(send)
+			return rateLimitChallenge{}, false, err
+		}
+		c, created = &rateLimitChallenge{ID: id, Number: number, Since: time.Now()}, true
+		r.challenges[id] = c
+	}
+	if proof.token != "" {
+		c.token = proof.token
+	}
+	if len(proof.options) > 0 {
+		c.Options = proof.options
+	}
+	c.RetryAfter = proof.retryAfter
+	c.Messages = append(c.Messages, send)
+	return c.copy(), created, nil
+}
+
+// find returns the challenge of number, the caller needs to hold the mutex.
+func (r *rateLimitChallenges) find(number string) *rateLimitChallenge {
+	for _, c := range r.challenges {
+		if c.Number == number {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *rateLimitChallenge) copy() rateLimitChallenge {
+	copied := *c
+	copied.Options = append([]string{}, c.Options...)
+	copied.Messages = append([]parkedSend{}, c.Messages...)
+	return copied
+}
+
+func (r *rateLimitChallenges) get(id string) (rateLimitChallenge, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.challenges[id]
+	if !ok {
+		return rateLimitChallenge{}, false
+	}
+	return c.copy(), true
+}
+
+// list returns the challenges of number, or of all numbers if it is empty.
+func (r *rateLimitChallenges) list(number string) []rateLimitChallenge {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	challenges := []rateLimitChallenge{}
+	for _, c := range r.challenges {
+		if number == "" || c.Number == number {
+			challenges = append(challenges, c.copy())
+		}
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		return challenges[i].Since.Before(challenges[j].Since)
+	})
+	return challenges
+}
+
+// take removes a challenge and returns it with its messages, which the caller
+// needs to release.
+func (r *rateLimitChallenges) take(id string) (*rateLimitChallenge, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.challenges[id]
+	delete(r.challenges, id)
+	return c, ok
+}
+
+// remove drops a challenge without sending its messages.
+func (r *rateLimitChallenges) remove(id string) bool {
+	c, ok := r.take(id)
+	if !ok {
+		return false
+	}
+	for _, send := range c.Messages {
+		r.release(send)
+	}
+	return true
+}
+
+func (r *rateLimitChallenges) release(send parkedSend) {
+	for _, hash := range send.hashes {
+		r.stager.release(hash)
+	}
+}
+
+func (r *rateLimitChallenges) purgerName() string {
+	return "challenged messages"
+}
+
+// purgeContact drops the messages of number to contact that are held for a
+// challenge.
+func (r *rateLimitChallenges) purgeContact(number string, contact string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := 0
+	for _, c := range r.challenges {
+		if c.Number != number {
+			continue
+		}
+		kept := []parkedSend{}
+		for _, send := range c.Messages {
+			if send.Recipient == contact {
+				r.release(send)
+				deleted++
+				continue
+			}
+			kept = append(kept, send)
+		}
+		c.Messages = kept
+	}
+	return deleted, nil
+}
+
+// parkChallenged holds a message Signal required proof for and emits a
+// challenge_required event if the number had no challenge yet.
+func (a *Api) parkChallenged(number string, proof *proofRequiredError, send parkedSend) (string, error) {
+	// messages held for a challenge again keep their id
+	if send.ID == "" {
+		id, err := newUploadID()
+		if err != nil {
+			return "", err
+		}
+		send.ID = id
+		send.Created = time.Now()
+	}
+	if send.internalGroupID != "" {
+		send.GroupID = convertInternalGroupIDToGroupID(send.internalGroupID)
+	}
+
+	challenge, created, err := a.challenges.park(number, proof, send)
+	if err != nil {
+		return "", err
+	}
+	if created {
+		log.Warn("Signal requires ", number, " to solve a challenge, holding its messages until it is solved (",
+			proof.Error(), ")")
+		a.bus.publish(busEvent{Type: eventChallengeRequired, Number: number, Data: challenge})
+	}
+	return send.ID, nil
+}
+
+// offersPush returns whether signald can solve the challenge with the push
+// challenge it receives.
+func (c *rateLimitChallenge) offersPush() bool {
+	for _, option := range c.Options {
+		if option == challengeOptionPush {
+			return true
+		}
+	}
+	return false
+}
+
+// solveChallenge submits a solved challenge to Signal and sends the messages
+// held for it in the background.
+func (a *Api) solveChallenge(id string, solution challengeSolution) error {
+	challenge, ok := a.challenges.get(id)
+	if !ok {
+		return service.NewError(service.NotFound, "No such challenge")
+	}
+	if err := a.supports(challenge.Number, capabilityChallenges); err != nil {
+		return err
+	}
+
+	captcha := normalizeCaptcha(solution.Captcha)
+	if captcha == "" && len(challenge.Options) > 0 && !challenge.offersPush() {
+		return service.NewError(service.Invalid, "Please specify the captcha, Signal offers no push challenge")
+	}
+
+	request := map[string]interface{}{
+		"type":      "submit_challenge",
+		"account":   challenge.Number,
+		"challenge": challenge.token,
+	}
+	if captcha != "" {
+		request["captcha_token"] = captcha
+	}
+	resp, err := a.requestSignald(challenge.Number, request)
+	if err != nil {
+		return service.NewError(service.Failed, err.Error())
+	}
+	if resp.Type != "submit_challenge" {
+		return service.NewError(service.Invalid, "Couldn't submit the challenge: "+signaldError(resp).Error())
+	}
+
+	log.Info("Challenge of ", challenge.Number, " solved, sending the held messages")
+	go a.resumeChallenged(id)
+	return nil
+}
+
+// resumeChallenged sends the messages held for a solved challenge in the
+// order they were sent. If Signal requires proof again, the remaining
+// messages are held for the new challenge.
+func (a *Api) resumeChallenged(id string) {
+	challenge, ok := a.challenges.take(id)
+	if !ok {
+		return
+	}
+	number := challenge.Number
+
+	var proof *proofRequiredError
+	for _, send := range challenge.Messages {
+		if proof != nil {
+			if _, err := a.parkChallenged(number, proof, send); err != nil {
+				log.Error("Couldn't hold message ", send.ID, " for the new challenge: ", err.Error())
+			}
+			a.challenges.release(send)
+			continue
+		}
+
+		attachments := []signald.RequestAttachment{}
+		for _, filename := range send.filenames {
+			attachments = append(attachments, signald.RequestAttachment{Filename: filename})
+		}
+		start := time.Now()
+		timestamp := milliseconds(start)
+		_, err := a.sendMessage(number, signald.RequestAddress{Number: send.Recipient}, send.internalGroupID,
+			send.message, attachments, timestamp)
+		a.stats.sent(number, time.Since(start), err)
+
+		if e, ok := err.(*proofRequiredError); ok {
+			proof = e
+			if _, err := a.parkChallenged(number, proof, send); err != nil {
+				log.Error("Couldn't hold message ", send.ID, " for the new challenge: ", err.Error())
+			}
+		} else if err != nil {
+			log.Error("Couldn't send message ", send.ID, " held for a challenge: ", err.Error())
+		} else {
+			log.Info("Sent message ", send.ID, " held for a challenge")
+		}
+		a.challenges.release(send)
+	}
+}
+
+// @Summary List the challenges.
+// @Tags Messages
+// @Description List the challenges Signal requires numbers to solve before it delivers further messages (proof required), with the messages held until they are solved. A challenge_required event is emitted for every new challenge.
+// @Produce  json
+// @Success 200 {object} []rateLimitChallenge
+// @Param number query string false "Only the challenges of this number"
+// @Router /v1/challenges [get]
+func (a *Api) GetChallenges(c *gin.Context) {
+	c.JSON(200, a.challenges.list(c.Query("number")))
+}
+
+// @Summary Show a challenge.
+// @Tags Messages
+// @Description Show a challenge Signal requires a number to solve, with the messages held until it is solved.
+// @Produce  json
+// @Success 200 {object} rateLimitChallenge
+// @Failure 404 {object} Error
+// @Param id path string true "Challenge ID"
+// @Router /v1/challenges/{id} [get]
+func (a *Api) GetChallenge(c *gin.Context) {
+	challenge, ok := a.challenges.get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such challenge"})
+		return
+	}
+	c.JSON(200, challenge)
+}
+
+// @Summary Submit a solved challenge.
+// @Tags Messages
+// @Description Submit the captcha solved at https://signalcaptchas.org/challenge/generate.html for a challenge, or an empty captcha if signald received the push challenge. The messages held for the challenge are then sent in the background, in the order they were sent.
+// @Accept  json
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Failure 501 {object} Error
+// @Failure 502 {object} Error
+// @Param id path string true "Challenge ID"
+// @Param data body challengeSolution true "Solved Challenge"
+// @Router /v1/challenges/{id} [post]
+func (a *Api) SolveChallenge(c *gin.Context) {
+	solution := challengeSolution{}
+	if err := c.BindJSON(&solution); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+	if err := a.solveChallenge(c.Param("id"), solution); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
+
+// @Summary Discard a challenge.
+// @Tags Messages
+// @Description Remove a challenge and drop the messages held for it without sending them.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 404 {object} Error
+// @Param id path string true "Challenge ID"
+// @Router /v1/challenges/{id} [delete]
+func (a *Api) DeleteChallenge(c *gin.Context) {
+	if !a.challenges.remove(c.Param("id")) {
+		c.JSON(404, gin.H{"error": "No such challenge"})
+		return
+	}
+	c.Status(204)
+}
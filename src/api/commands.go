@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// commandsClient is the client the replies of chat commands are sent as,
+// their quotas apply to it.
+const commandsClient = "commands"
+
+// command maps a chat command ("!name args") to a webhook. The reply of the
+// webhook is sent back to the chat the command was received in.
+type command struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Numbers     []string `json:"numbers,omitempty"`
+}
+
+type commandRequest struct {
+	Number   string      `json:"number"`
+	Sender   string      `json:"sender"`
+	GroupID  string      `json:"group_id,omitempty"`
+	Command  string      `json:"command"`
+	Args     string      `json:"args"`
+	Envelope interface{} `json:"envelope"`
+}
+
+type commandResponse struct {
+	Message string `json:"message"`
+}
+
+type commandRegistry struct {
+	mutex    sync.Mutex
+	prefix   string
+	state    stateStore
+	client   *http.Client
+	commands map[string]command
+}
+
+func newCommandRegistry(prefix string, client *http.Client, state stateStore) (*commandRegistry, error) {
+	r := &commandRegistry{
+		prefix:   prefix,
+		state:    state,
+		client:   client,
+		commands: make(map[string]command),
+	}
+
+	commands := []command{}
+	if err := state.load(&commands); err != nil {
+		return nil, errors.New("Couldn't load commands: " + err.Error())
+	}
+	r.setCommands(commands)
+
+	return r, nil
+}
+
+// setCommands replaces the commands, the caller needs to hold the mutex.
+func (r *commandRegistry) setCommands(commands []command) {
+	r.commands = make(map[string]command)
+	for _, cmd := range commands {
+		r.commands[cmd.Name] = cmd
+	}
+}
+
+// document returns the commands as they are persisted, the caller needs to
+// hold the mutex.
+func (r *commandRegistry) document() []command {
+	commands := []command{}
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// refresh loads the commands other replicas changed, the caller needs to hold
+// the mutex.
+func (r *commandRegistry) refresh() {
+	commands := []command{}
+	reloaded, err := r.state.refresh(&commands)
+	if err != nil {
+		log.Error("Couldn't load commands: ", err.Error())
+		return
+	}
+	if reloaded {
+		r.setCommands(commands)
+	}
+}
+
+// update changes the commands and saves them, the caller needs to hold the
+// mutex.
+func (r *commandRegistry) update(change func() error) error {
+	commands := r.document()
+	return r.state.update(&commands, func(reloaded bool) error {
+		if reloaded {
+			r.setCommands(commands)
+		}
+		if err := change(); err != nil {
+			return err
+		}
+		commands = r.document()
+		return nil
+	})
+}
+
+// list returns the registered commands sorted by name.
+func (r *commandRegistry) list() []command {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	return r.document()
+}
+
+func (r *commandRegistry) get(name string) (command, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+func (r *commandRegistry) put(cmd command) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() error {
+		r.commands[cmd.Name] = cmd
+		return nil
+	})
+}
+
+func (r *commandRegistry) remove(name string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err := r.update(func() error {
+		if _, ok := r.commands[name]; !ok {
+			return errStateUnchanged
+		}
+		delete(r.commands, name)
+		return nil
+	})
+	if err == errStateUnchanged {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// replace replaces all commands.
+func (r *commandRegistry) replace(commands []command) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() error {
+		r.setCommands(commands)
+		return nil
+	})
+}
+
+// parse splits a message text into command name and arguments.
+func (r *commandRegistry) parse(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, r.prefix) {
+		return "", "", false
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(text, r.prefix)), " ", 2)
+	name := strings.ToLower(fields[0])
+	if !commandNamePattern.MatchString(name) {
+		return "", "", false
+	}
+
+	args := ""
+	if len(fields) > 1 {
+		args = strings.TrimSpace(fields[1])
+	}
+	return name, args, true
+}
+
+// validate checks the command and normalizes its name.
+func (cmd *command) validate() error {
+	cmd.Name = strings.ToLower(cmd.Name)
+	if !commandNamePattern.MatchString(cmd.Name) {
+		return errors.New("Please provide a valid command name (letters, digits, - and _)")
+	}
+
+	if cmd.URL == "" {
+		return errors.New("Please provide a url")
+	}
+	return nil
+}
+
+func (cmd *command) enabledFor(number string) bool {
+	if len(cmd.Numbers) == 0 {
+		return true
+	}
+	for _, n := range cmd.Numbers {
+		if n == number {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *commandRegistry) call(cmd command, req commandRequest) (string, error) {
+	body, err := jsoniter.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Post(cmd.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", errors.New("Failed with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	cmdResp := commandResponse{}
+	if len(bytes.TrimSpace(data)) != 0 {
+		if err := jsoniter.Unmarshal(data, &cmdResp); err != nil {
+			return "", err
+		}
+	}
+	return cmdResp.Message, nil
+}
+
+// commandStage returns the receive stage that dispatches chat commands. The
+// webhook is called in the background, the message itself is passed on
+// tagged with the command name.
+func (a *Api) commandStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		e, err := msg.envelope()
+		if err != nil || e.DataMessage == nil {
+			return true
+		}
+
+		name, args, ok := a.commands.parse(e.DataMessage.Message)
+		if !ok {
+			return true
+		}
+
+		cmd, ok := a.commands.get(name)
+		if !ok || !cmd.enabledFor(number) {
+			return true
+		}
+
+		msg.Tags = append(msg.Tags, "command:"+cmd.Name)
+
+		req := commandRequest{
+			Number:   number,
+			Sender:   e.Source.Number,
+			Command:  cmd.Name,
+			Args:     args,
+			Envelope: msg.Data,
+		}
+		if e.DataMessage.GroupInfo != nil {
+			req.GroupID = e.DataMessage.GroupInfo.GroupID
+		}
+
+		go func() {
+			reply, err := a.commands.call(cmd, req)
+			if err != nil {
+				log.Error("Command ", cmd.Name, " failed: ", err.Error())
+				return
+			}
+			if reply == "" {
+				return
+			}
+
+			out := service.Send{Number: number, Message: reply, Recipients: []string{req.Sender}, Client: commandsClient}
+			if req.GroupID != "" {
+				out.Recipients, out.IsGroup = []string{req.GroupID}, true
+			}
+			if _, err := a.service.Submit(out); err != nil {
+				log.Error("Couldn't send reply of command ", cmd.Name, ": ", err.Error())
+			}
+		}()
+
+		return true
+	}
+}
+
+// @Summary List all chat commands.
+// @Tags Commands
+// @Description List all registered chat commands.
+// @Produce  json
+// @Success 200 {object} []command
+// @Router /v1/commands [get]
+func (a *Api) GetCommands(c *gin.Context) {
+	c.JSON(200, a.commands.list())
+}
+
+// @Summary Register a chat command.
+// @Tags Commands
+// @Description Registers (or replaces) a chat command. Incoming messages starting with the command prefix and the command name are posted to the url, the reply is sent back to the chat.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} command
+// @Failure 400 {object} Error
+// @Param data body command true "Command"
+// @Router /v1/commands [post]
+func (a *Api) CreateCommand(c *gin.Context) {
+	cmd := command{}
+	if err := c.BindJSON(&cmd); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	if err := cmd.validate(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var previous interface{}
+	if existing, ok := a.commands.get(cmd.Name); ok {
+		previous = existing
+	}
+	if err := a.commands.put(cmd); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	a.audit.record(c, "command.create", cmd.Name, previous, cmd)
+
+	c.JSON(201, cmd)
+}
+
+// @Summary Delete a chat command.
+// @Tags Commands
+// @Description Delete a registered chat command.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 404 {object} Error
+// @Param name path string true "Command Name"
+// @Router /v1/commands/{name} [delete]
+func (a *Api) DeleteCommand(c *gin.Context) {
+	name := strings.ToLower(c.Param("name"))
+	previous, _ := a.commands.get(name)
+	ok, err := a.commands.remove(name)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(404, gin.H{"error": "Command not found"})
+		return
+	}
+	a.audit.record(c, "command.delete", name, previous, nil)
+	c.Status(204)
+}
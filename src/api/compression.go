@@ -0,0 +1,78 @@
+package api
+
+import (
+	"compress/gzip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipWriter compresses the body of a response. The compression starts with
+// the first write of the body, so that responses without one (e.g. 204 and
+// 304) are sent as they are.
+type gzipWriter struct {
+	gin.ResponseWriter
+	writer *gzip.Writer
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.writer == nil {
+		if !bodyAllowed(w.Status()) {
+			return w.ResponseWriter.Write(data)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		// the length of the compressed body isn't known
+		w.Header().Del("Content-Length")
+		w.writer = gzip.NewWriter(w.ResponseWriter)
+	}
+	return w.writer.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// close ends the compressed body, if the response has one.
+func (w *gzipWriter) close() error {
+	if w.writer == nil {
+		return nil
+	}
+	return w.writer.Close()
+}
+
+// bodyAllowed returns whether a response with the status can have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != 204 && status != 304
+}
+
+// acceptsGzip returns whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		parts := strings.Split(coding, ";")
+		if strings.TrimSpace(parts[0]) != "gzip" {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if q := strings.Replace(p, " ", "", -1); q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// CompressResponse compresses the response with gzip if the client accepts
+// it, for endpoints that return many messages at once.
+func (a *Api) CompressResponse(c *gin.Context) {
+	c.Header("Vary", "Accept-Encoding")
+	if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		c.Next()
+		return
+	}
+
+	w := &gzipWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+	w.close()
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const confirmationTTL = 5 * time.Minute
+
+type confirmationToken struct {
+	Token   string    `json:"confirmation_token"`
+	Expires time.Time `json:"expires"`
+}
+
+type pendingConfirmation struct {
+	action  string
+	expires time.Time
+}
+
+// confirmations holds the tokens handed out by preflight calls of destructive
+// requests. A token confirms a single request with the same method and path.
+type confirmations struct {
+	mutex   sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{pending: make(map[string]pendingConfirmation)}
+}
+
+func (c *confirmations) issue(action string) (confirmationToken, error) {
+	token, err := newUploadID()
+	if err != nil {
+		return confirmationToken{}, err
+	}
+	expires := time.Now().Add(confirmationTTL)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for t, p := range c.pending {
+		if time.Now().After(p.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = pendingConfirmation{action: action, expires: expires}
+	return confirmationToken{Token: token, Expires: expires}, nil
+}
+
+// consume returns whether token confirms action and invalidates it.
+func (c *confirmations) consume(token string, action string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	p, ok := c.pending[token]
+	if !ok || p.action != action || time.Now().After(p.expires) {
+		return false
+	}
+	delete(c.pending, token)
+	return true
+}
+
+// confirmedRequest is recorded in the audit log for a confirmed destructive
+// request.
+type confirmedRequest struct {
+	Number      string `json:"number,omitempty"`
+	ConfirmedBy string `json:"confirmed_by"`
+}
+
+// ConfirmDestructive guards requests that delete data. With ?preflight=true
+// a confirmation token is returned instead of running the request, the
+// request then needs ?confirmation=<token> or ?confirm=true. Other requests
+// are rejected with 428. Confirmed requests are recorded in the audit log.
+func (a *Api) ConfirmDestructive(c *gin.Context) {
+	action := c.Request.Method + " " + c.Request.URL.Path
+
+	if c.Query("preflight") == "true" {
+		token, err := a.confirmations.issue(action)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.JSON(200, token)
+		c.Abort()
+		return
+	}
+
+	confirmedBy := ""
+	if token := c.Query("confirmation"); token != "" {
+		if !a.confirmations.consume(token, action) {
+			c.JSON(428, gin.H{"error": "Invalid or expired confirmation token"})
+			c.Abort()
+			return
+		}
+		confirmedBy = "token"
+	} else if c.Query("confirm") == "true" {
+		confirmedBy = "confirm=true"
+	} else {
+		c.JSON(428, gin.H{"error": "This request deletes data, please confirm it with confirm=true or a token from a preflight request (preflight=true)"})
+		c.Abort()
+		return
+	}
+
+	log.Warn("Destructive request ", action, " from ", c.ClientIP(), " confirmed with ", confirmedBy)
+	// the route instead of the path, the entry would name the contact of a
+	// data deletion otherwise and be purged by it
+	a.audit.record(c, "destructive.confirm", c.Request.Method+" "+c.FullPath(), nil, confirmedRequest{
+		Number:      c.Param("number"),
+		ConfirmedBy: confirmedBy,
+	})
+	c.Next()
+}
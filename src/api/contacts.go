@@ -0,0 +1,61 @@
+package api
+
+import (
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+type contactEntry struct {
+	Number                string `json:"number"`
+	UUID                  string `json:"uuid,omitempty"`
+	Name                  string `json:"name"`
+	Color                 string `json:"color,omitempty"`
+	MessageExpirationTime int    `json:"message_expiration_time"`
+}
+
+// @Summary List the contacts of a number.
+// @Tags Contacts
+// @Description List the contacts of a registered number. The listing can be filtered by name and paginated, the total number of matching contacts is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.
+// @Produce  json
+// @Success 200 {object} []contactEntry
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param name query string false "Only list contacts whose name contains this text"
+// @Param offset query int false "Number of contacts to skip"
+// @Param limit query int false "Maximum number of contacts to return"
+// @Router /v1/contacts/{number} [get]
+func (a *Api) GetContacts(c *gin.Context) {
+	number := c.Param("number")
+
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := a.listings.get(contactsListingKey(number), func() (signald.Response, error) {
+		return a.client(number).ListContacts(number)
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	contacts := []contactEntry{}
+	for _, contact := range message.Data.Contacts {
+		if !matchesName(contact.Name, c.Query("name")) {
+			continue
+		}
+		contacts = append(contacts, contactEntry{
+			Number:                contact.Address.Number,
+			UUID:                  contact.Address.UUID,
+			Name:                  contact.Name,
+			Color:                 contact.Color,
+			MessageExpirationTime: contact.MessageExpirationTime,
+		})
+	}
+
+	start, end := p.bounds(c, len(contacts))
+	respondWithETag(c, contacts[start:end])
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+const messagePreviewLength = 100
+
+type messagePreview struct {
+	Sender      string `json:"sender"`
+	Outgoing    bool   `json:"outgoing"`
+	Preview     string `json:"preview"`
+	Attachments int    `json:"attachments,omitempty"`
+}
+
+type conversationEntry struct {
+	Peer        string          `json:"peer"`
+	IsGroup     bool            `json:"is_group"`
+	Name        string          `json:"name,omitempty"`
+	Timestamp   int64           `json:"timestamp"`
+	Unread      int             `json:"unread"`
+	LastMessage *messagePreview `json:"last_message"`
+}
+
+type unreadCount struct {
+	Peer   string `json:"peer"`
+	Unread int    `json:"unread"`
+}
+
+type unreadCounts struct {
+	Total         int           `json:"total"`
+	Conversations []unreadCount `json:"conversations"`
+}
+
+// historyMessage is a stored message with its position in the conversation.
+type historyMessage struct {
+	storedMessage
+	Cursor string `json:"cursor"`
+}
+
+type markReadRequest struct {
+	// Timestamp of the newest message that was read, if it is 0 the whole
+	// conversation is read.
+	Timestamp int64 `json:"timestamp"`
+}
+
+type markReadResult struct {
+	Receipts int    `json:"receipts"`
+	Error    string `json:"error,omitempty"`
+}
+
+func preview(body string) string {
+	if utf8.RuneCountInString(body) <= messagePreviewLength {
+		return body
+	}
+	runes := []rune(body)
+	return string(runes[:messagePreviewLength]) + "…"
+}
+
+func newConversationEntry(c storedConversation) conversationEntry {
+	entry := conversationEntry{
+		Peer:    c.Peer,
+		IsGroup: c.IsGroup,
+		Name:    c.Name,
+		Unread:  c.unread(),
+	}
+	if m, ok := c.last(); ok {
+		entry.Timestamp = m.Timestamp
+		entry.LastMessage = &messagePreview{
+			Sender:      m.Sender,
+			Outgoing:    m.Outgoing,
+			Preview:     preview(m.Body),
+			Attachments: m.Attachments,
+		}
+	}
+	return entry
+}
+
+// @Summary List the conversations of a number.
+// @Tags Messages
+// @Description List the contacts and groups a registered number exchanged messages with, the most recently active first, with a preview of the last message and the number of unread messages. The listing is paginated, the total number of conversations is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.
+// @Produce  json
+// @Success 200 {object} []conversationEntry
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param offset query int false "Number of conversations to skip"
+// @Param limit query int false "Maximum number of conversations to return"
+// @Router /v1/conversations/{number} [get]
+func (a *Api) GetConversations(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversations := []conversationEntry{}
+	for _, conversation := range a.store.list(c.Param("number")) {
+		conversations = append(conversations, newConversationEntry(conversation))
+	}
+
+	start, end := p.bounds(c, len(conversations))
+	respondWithETag(c, conversations[start:end])
+}
+
+// @Summary Get the number of unread messages of a number.
+// @Tags Messages
+// @Description Get the number of unread messages in total and of every conversation with unread messages.
+// @Produce  json
+// @Success 200 {object} unreadCounts
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Router /v1/conversations/{number}/unread [get]
+func (a *Api) GetUnreadCounts(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	counts := unreadCounts{Conversations: []unreadCount{}}
+	for _, conversation := range a.store.list(c.Param("number")) {
+		if unread := conversation.unread(); unread > 0 {
+			counts.Total += unread
+			counts.Conversations = append(counts.Conversations, unreadCount{Peer: conversation.Peer, Unread: unread})
+		}
+	}
+	c.JSON(200, counts)
+}
+
+// @Summary Mark a conversation as read.
+// @Tags Messages
+// @Description Mark the messages of a conversation up to the given timestamp (or all of them) as read and send read receipts to their senders.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} markReadResult
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param peer path string true "Phone Number or Group ID of the Conversation"
+// @Param data body markReadRequest false "Newest Read Message"
+// @Router /v1/conversations/{number}/{peer}/read [post]
+func (a *Api) MarkConversationRead(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	number := c.Param("number")
+	peer := c.Param("peer")
+	if isGroupRecipient(peer) {
+		internalID, ok := parseGroupID(peer)
+		if !ok {
+			c.JSON(400, gin.H{"error": "Invalid group id"})
+			return
+		}
+		peer = convertInternalGroupIDToGroupID(internalID)
+	}
+
+	req := markReadRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+			return
+		}
+	}
+
+	read, ok := a.store.markRead(number, peer, req.Timestamp)
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such conversation"})
+		return
+	}
+
+	senders := []string{}
+	for sender := range read {
+		senders = append(senders, sender)
+	}
+	sort.Strings(senders)
+
+	result := markReadResult{}
+	for _, sender := range senders {
+		err := a.markRead(a.client(number), number, signald.RequestAddress{Number: sender}, read[sender])
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Receipts += len(read[sender])
+	}
+	c.JSON(200, result)
+}
+
+// @Summary List the messages of a conversation.
+// @Tags Messages
+// @Description List the messages of a conversation in the message store, oldest first. Deleted messages are only listed with deleted=include until they are purged. The listing is paginated, the total number of messages is returned in the X-Total-Count header. Every message has a cursor, to continue after it pass it as after instead of an offset. The cursor of the last message is returned in the X-Next-Cursor header.
+// @Produce  json
+// @Success 200 {object} []historyMessage
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param peer path string true "Phone Number or Group ID of the Conversation"
+// @Param deleted query string false "include lists the deleted messages as well"
+// @Param after query string false "Cursor of the message to continue after"
+// @Param offset query int false "Number of messages to skip"
+// @Param limit query int false "Maximum number of messages to return"
+// @Router /v1/messages/{number}/{peer} [get]
+func (a *Api) GetMessages(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	after, err := parseAfter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if after != nil && p.offset > 0 {
+		c.JSON(400, gin.H{"error": "Please provide either an offset or a cursor"})
+		return
+	}
+	deleted := c.Query("deleted")
+	if deleted != "" && deleted != "include" {
+		c.JSON(400, gin.H{"error": "Invalid deleted mode " + deleted + " (supported: include)"})
+		return
+	}
+
+	peer := c.Param("peer")
+	if isGroupRecipient(peer) {
+		internalID, ok := parseGroupID(peer)
+		if !ok {
+			c.JSON(400, gin.H{"error": "Invalid group id"})
+			return
+		}
+		peer = convertInternalGroupIDToGroupID(internalID)
+	}
+
+	messages, ok := a.store.messages(c.Param("number"), peer, deleted == "include")
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such conversation"})
+		return
+	}
+	if after != nil {
+		i := sort.Search(len(messages), func(i int) bool { return after.before(messages[i].cursor()) })
+		messages = messages[i:]
+	}
+
+	start, end := p.bounds(c, len(messages))
+	history := []historyMessage{}
+	for _, m := range messages[start:end] {
+		history = append(history, historyMessage{storedMessage: m, Cursor: m.cursor().String()})
+	}
+	if len(history) > 0 {
+		setNextCursor(c, after, history[len(history)-1].Cursor)
+	} else {
+		setNextCursor(c, after, "")
+	}
+	c.JSON(200, history)
+}
+
+// @Summary Delete a message from the message store.
+// @Tags Messages
+// @Description Delete a message from the history of the API only, e.g. to moderate the archive. The recipients keep the message. The message is hidden right away and purged later.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param message_id path string true "Message ID"
+// @Router /v1/messages/{number}/{message_id} [delete]
+func (a *Api) DeleteStoredMessage(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	if !a.store.remove(c.Param("number"), c.Param("message_id")) {
+		c.JSON(404, gin.H{"error": "No such message"})
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cursor is a position in a listing that is ordered by timestamp and, for
+// entries with the same timestamp, by ID. Clients get it as an opaque string
+// and pass it back to continue after the entry it points to.
+type cursor struct {
+	timestamp int64
+	id        string
+}
+
+func (c cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.timestamp, 10) + "." + c.id))
+}
+
+func parseCursor(value string) (cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, errors.New("Invalid cursor " + value)
+	}
+	parts := strings.SplitN(string(data), ".", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return cursor{}, errors.New("Invalid cursor " + value)
+	}
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return cursor{}, errors.New("Invalid cursor " + value)
+	}
+	return cursor{timestamp: timestamp, id: parts[1]}, nil
+}
+
+// before reports whether c comes before o. IDs of the same timestamp are
+// compared as strings, so numeric IDs need to be padded to the same length.
+func (c cursor) before(o cursor) bool {
+	return c.timestamp < o.timestamp || c.timestamp == o.timestamp && c.id < o.id
+}
+
+// parseAfter returns the cursor given with the after query parameter, or nil
+// if it is empty.
+func parseAfter(c *gin.Context) (*cursor, error) {
+	value := c.Query("after")
+	if value == "" {
+		return nil, nil
+	}
+	after, err := parseCursor(value)
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// setNextCursor sets the X-Next-Cursor header to the cursor of the last entry
+// of a page, or to the cursor the page was requested with if it is empty.
+func setNextCursor(c *gin.Context, after *cursor, last string) {
+	if last != "" {
+		c.Header("X-Next-Cursor", last)
+	} else if after != nil {
+		c.Header("X-Next-Cursor", after.String())
+	}
+}
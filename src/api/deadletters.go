@@ -0,0 +1,366 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookDeliveryLogSize = 100
+	deadLetterQueueSize    = 1000
+)
+
+// webhookDelivery is an attempt to deliver an event to a webhook.
+type webhookDelivery struct {
+	EventType string    `json:"event_type"`
+	Number    string    `json:"number"`
+	Time      time.Time `json:"time"`
+	Attempt   int       `json:"attempt"`
+	Status    int       `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Duration  int64     `json:"duration_ms"`
+}
+
+// webhookStatus counts the deliveries of a webhook. Failed counts the failed
+// attempts, DeadLettered the events that failed after all retries.
+type webhookStatus struct {
+	URL           string     `json:"url"`
+	Delivered     int        `json:"delivered"`
+	Failed        int        `json:"failed"`
+	DeadLettered  int        `json:"dead_lettered"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastDelivered *time.Time `json:"last_delivered,omitempty"`
+
+	deliveries []webhookDelivery
+}
+
+// deadLetter is an event that couldn't be delivered to a webhook.
+type deadLetter struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	EventType   string    `json:"event_type"`
+	Number      string    `json:"number"`
+	ContentType string    `json:"content_type"`
+	Payload     string    `json:"payload"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	Time        time.Time `json:"time"`
+}
+
+// webhookDeliveries keeps the latest deliveries of every webhook in memory and
+// the events that couldn't be delivered in a persisted dead-letter queue of at
+// most 1000 events.
+type webhookDeliveries struct {
+	mutex       sync.Mutex
+	state       stateStore
+	webhooks    map[string]*webhookStatus
+	deadLetters []deadLetter
+}
+
+func newWebhookDeliveries(state stateStore) (*webhookDeliveries, error) {
+	d := &webhookDeliveries{
+		state:       state,
+		webhooks:    make(map[string]*webhookStatus),
+		deadLetters: []deadLetter{},
+	}
+	if err := state.load(&d.deadLetters); err != nil {
+		return nil, errors.New("Couldn't load dead-lettered webhook events: " + err.Error())
+	}
+	return d, nil
+}
+
+// status returns the status of a webhook, creating it if needed. The caller
+// needs to hold the mutex.
+func (d *webhookDeliveries) status(url string) *webhookStatus {
+	s, ok := d.webhooks[url]
+	if !ok {
+		s = &webhookStatus{URL: url}
+		d.webhooks[url] = s
+	}
+	return s
+}
+
+func (d *webhookDeliveries) record(url string, delivery webhookDelivery) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s := d.status(url)
+	if delivery.Error == "" {
+		s.Delivered++
+		s.LastDelivered = &delivery.Time
+	} else {
+		s.Failed++
+		s.LastError = delivery.Error
+	}
+	s.deliveries = append(s.deliveries, delivery)
+	if len(s.deliveries) > webhookDeliveryLogSize {
+		s.deliveries = s.deliveries[len(s.deliveries)-webhookDeliveryLogSize:]
+	}
+}
+
+// list returns the status of every webhook that was delivered to, sorted by
+// URL.
+func (d *webhookDeliveries) list() []webhookStatus {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	statuses := []webhookStatus{}
+	for _, s := range d.webhooks {
+		statuses = append(statuses, *s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].URL < statuses[j].URL })
+	return statuses
+}
+
+// deliveries returns the latest deliveries of a webhook, the most recent
+// first.
+func (d *webhookDeliveries) deliveries(url string) ([]webhookDelivery, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s, ok := d.webhooks[url]
+	if !ok {
+		return nil, false
+	}
+	deliveries := []webhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, s.deliveries[i])
+	}
+	return deliveries, true
+}
+
+// refresh loads the dead-lettered events other replicas changed, the caller
+// needs to hold the mutex.
+func (d *webhookDeliveries) refresh() {
+	if _, err := d.state.refresh(&d.deadLetters); err != nil {
+		log.Error("Couldn't load dead-lettered webhook events: ", err.Error())
+	}
+}
+
+// update changes the dead-lettered events and saves them, the caller needs to
+// hold the mutex. It returns false if change returned errStateUnchanged.
+func (d *webhookDeliveries) update(change func() error) (bool, error) {
+	err := d.state.update(&d.deadLetters, func(bool) error { return change() })
+	if err == errStateUnchanged {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *webhookDeliveries) addDeadLetter(l deadLetter) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.status(l.URL).DeadLettered++
+	_, err := d.update(func() error {
+		if len(d.deadLetters) >= deadLetterQueueSize {
+			log.Warn("Dead-letter queue of webhooks is full, dropping the oldest event")
+			d.deadLetters = d.deadLetters[1:]
+		}
+		d.deadLetters = append(d.deadLetters, l)
+		return nil
+	})
+	return err
+}
+
+// listDeadLetters returns the dead-lettered events, the oldest first.
+func (d *webhookDeliveries) listDeadLetters() []deadLetter {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.refresh()
+	return append([]deadLetter{}, d.deadLetters...)
+}
+
+func (d *webhookDeliveries) getDeadLetter(id string) (deadLetter, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.refresh()
+	for _, l := range d.deadLetters {
+		if l.ID == id {
+			return l, true
+		}
+	}
+	return deadLetter{}, false
+}
+
+// updateDeadLetter replaces a dead-lettered event, e.g. after a failed replay.
+func (d *webhookDeliveries) updateDeadLetter(l deadLetter) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	_, err := d.update(func() error {
+		for i := range d.deadLetters {
+			if d.deadLetters[i].ID == l.ID {
+				d.deadLetters[i] = l
+				return nil
+			}
+		}
+		return errStateUnchanged
+	})
+	return err
+}
+
+func (d *webhookDeliveries) removeDeadLetter(id string) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.update(func() error {
+		for i, l := range d.deadLetters {
+			if l.ID == id {
+				d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+				return nil
+			}
+		}
+		return errStateUnchanged
+	})
+}
+
+func (d *webhookDeliveries) purgerName() string {
+	return "dead letters"
+}
+
+// purgeContact deletes the dead-lettered events of number whose payload
+// mentions contact.
+func (d *webhookDeliveries) purgeContact(number string, contact string) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	deleted := 0
+	_, err := d.update(func() error {
+		kept := []deadLetter{}
+		for _, l := range d.deadLetters {
+			if l.Number != number || !mentions(l.Payload, contact) {
+				kept = append(kept, l)
+			}
+		}
+		if deleted = len(d.deadLetters) - len(kept); deleted == 0 {
+			return errStateUnchanged
+		}
+		d.deadLetters = kept
+		return nil
+	})
+	return deleted, err
+}
+
+// @Summary List the webhooks.
+// @Tags Admin
+// @Description List the webhooks events were delivered to with the number of delivered events, failed delivery attempts and dead-lettered events, and the last error.
+// @Produce  json
+// @Success 200 {object} []webhookStatus
+// @Router /admin/webhooks [get]
+func (a *Api) GetWebhooks(c *gin.Context) {
+	c.JSON(200, a.webhooks.deliveries.list())
+}
+
+// @Summary List the deliveries of a webhook.
+// @Tags Admin
+// @Description List the latest 100 delivery attempts of a webhook, the most recent first. The listing is paginated, the total number of attempts is returned in the X-Total-Count header.
+// @Produce  json
+// @Success 200 {object} []webhookDelivery
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param url query string true "Webhook URL"
+// @Param offset query int false "Number of attempts to skip"
+// @Param limit query int false "Maximum number of attempts to return"
+// @Router /admin/webhooks/deliveries [get]
+func (a *Api) GetWebhookDeliveries(c *gin.Context) {
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, ok := a.webhooks.deliveries.deliveries(c.Query("url"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "No deliveries to this webhook"})
+		return
+	}
+	start, end := p.bounds(c, len(deliveries))
+	c.JSON(200, deliveries[start:end])
+}
+
+// @Summary List the dead-lettered webhook events.
+// @Tags Admin
+// @Description List the events that couldn't be delivered to a webhook after all retries, the oldest first. The listing is paginated, the total number of events is returned in the X-Total-Count header.
+// @Produce  json
+// @Success 200 {object} []deadLetter
+// @Failure 400 {object} Error
+// @Param offset query int false "Number of events to skip"
+// @Param limit query int false "Maximum number of events to return"
+// @Router /admin/webhooks/dead-letters [get]
+func (a *Api) GetDeadLetters(c *gin.Context) {
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	deadLetters := a.webhooks.deliveries.listDeadLetters()
+	start, end := p.bounds(c, len(deadLetters))
+	c.JSON(200, deadLetters[start:end])
+}
+
+// @Summary Replay a dead-lettered webhook event.
+// @Tags Admin
+// @Description Deliver a dead-lettered event to its webhook again. It is removed from the dead-letter queue if the webhook accepts it.
+// @Produce  json
+// @Success 200 {object} webhookDelivery
+// @Failure 404 {object} Error
+// @Failure 502 {object} Error
+// @Param id path string true "Event ID"
+// @Router /admin/webhooks/dead-letters/{id}/replay [post]
+func (a *Api) ReplayDeadLetter(c *gin.Context) {
+	l, ok := a.webhooks.deliveries.getDeadLetter(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such event"})
+		return
+	}
+
+	l.Attempts++
+	delivery := a.webhooks.attempt(l.URL, l.ContentType, l.EventType, l.Number, []byte(l.Payload), l.Attempts)
+	if delivery.Error != "" {
+		l.Error = delivery.Error
+		if err := a.webhooks.deliveries.updateDeadLetter(l); err != nil {
+			log.Error("Couldn't update dead-lettered webhook event: ", err.Error())
+		}
+		c.JSON(502, gin.H{"error": "Couldn't deliver event: " + delivery.Error})
+		return
+	}
+
+	if _, err := a.webhooks.deliveries.removeDeadLetter(l.ID); err != nil {
+		log.Error("Couldn't remove dead-lettered webhook event: ", err.Error())
+	}
+	a.audit.record(c, "dead_letter.replay", l.ID, l, nil)
+	c.JSON(200, delivery)
+}
+
+// @Summary Discard a dead-lettered webhook event.
+// @Tags Admin
+// @Description Remove an event from the dead-letter queue without delivering it.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Event ID"
+// @Router /admin/webhooks/dead-letters/{id} [delete]
+func (a *Api) DeleteDeadLetter(c *gin.Context) {
+	previous, _ := a.webhooks.deliveries.getDeadLetter(c.Param("id"))
+	removed, err := a.webhooks.deliveries.removeDeadLetter(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't remove event: " + err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(404, gin.H{"error": "No such event"})
+		return
+	}
+	a.audit.record(c, "dead_letter.delete", c.Param("id"), previous, nil)
+	c.Status(204)
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	log "github.com/sirupsen/logrus"
+)
+
+// The policies for messages that can't be decrypted.
+const (
+	DecryptionFailureIgnore = "ignore"
+	DecryptionFailureReset  = "reset"
+)
+
+// eventDecryptionFailed is published for every incoming message signald
+// couldn't decrypt, the data is the decryptionFailure.
+const eventDecryptionFailed = "decryption_failed"
+
+// decryptionResetInterval is how often the session with a sender is reset at
+// most, so that senders whose messages keep failing don't cause a reset loop.
+const decryptionResetInterval = time.Hour
+
+// signald reports decryption failures with different types and exceptions
+// depending on its version, so they are recognized by their text without
+// spaces and underscores.
+var decryptionFailureErrors = []string{"decryptionerror", "invalidmessage", "nosession", "invalidkeyid",
+	"invalidkeyexception", "invalidversion", "legacymessage"}
+
+// decryptionFailure is the data of a decryption_failed event.
+type decryptionFailure struct {
+	Sender    string `json:"sender,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Error     string `json:"error"`
+	// Reset is set if the session with the sender is reset because of the
+	// failure.
+	Reset bool `json:"reset"`
+}
+
+func validateDecryptionFailurePolicy(policy string) error {
+	switch policy {
+	case DecryptionFailureIgnore, DecryptionFailureReset:
+		return nil
+	}
+	return errors.New("Invalid decryption failure policy " + policy + " (supported: ignore, reset)")
+}
+
+// decryptionFailureFrom returns the decryption failure an incoming message
+// reports, if it reports one.
+func decryptionFailureFrom(m signald.RawResponse) (decryptionFailure, bool) {
+	data, _ := m.Data.(map[string]interface{})
+
+	details := []string{}
+	if m.Type != "message" {
+		details = append(details, m.Type)
+	}
+	if m.Error != nil {
+		details = append(details, m.Error.Error())
+	}
+	for _, key := range []string{"message", "exception"} {
+		switch v := data[key].(type) {
+		case string:
+			details = append(details, v)
+		case map[string]interface{}:
+			for _, field := range []string{"type", "message"} {
+				if s, ok := v[field].(string); ok {
+					details = append(details, s)
+				}
+			}
+		}
+	}
+	text := strings.Join(details, ": ")
+
+	normalized := strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(text))
+	failed := false
+	for _, e := range decryptionFailureErrors {
+		failed = failed || strings.Contains(normalized, e)
+	}
+	if !failed {
+		return decryptionFailure{}, false
+	}
+
+	failure := decryptionFailure{Error: text}
+	switch source := data["source"].(type) {
+	case string:
+		failure.Sender = source
+	case map[string]interface{}:
+		failure.Sender, _ = source["number"].(string)
+		if failure.Sender == "" {
+			failure.Sender, _ = source["uuid"].(string)
+		}
+	}
+	if failure.Sender == "" {
+		failure.Sender, _ = data["sender"].(string)
+	}
+	if timestamp, ok := data["timestamp"].(float64); ok {
+		failure.Timestamp = int64(timestamp)
+	}
+	return failure, true
+}
+
+// decryptionResets remembers when the sessions with senders were reset last.
+type decryptionResets struct {
+	mutex  sync.Mutex
+	policy string
+	resets map[string]time.Time
+}
+
+func newDecryptionResets(policy string) *decryptionResets {
+	return &decryptionResets{policy: policy, resets: make(map[string]time.Time)}
+}
+
+func (r *decryptionResets) purgerName() string {
+	return "decryption resets"
+}
+
+// purgeContact forgets when the session of number with contact was reset.
+func (r *decryptionResets) purgeContact(number string, contact string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := number + ":" + contact
+	if _, ok := r.resets[key]; !ok {
+		return 0, nil
+	}
+	delete(r.resets, key)
+	return 1, nil
+}
+
+// due returns whether the session of number with sender is reset, and marks
+// it as reset if so.
+func (r *decryptionResets) due(number string, sender string) bool {
+	if r.policy != DecryptionFailureReset || sender == "" {
+		return false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := number + ":" + sender
+	if last, ok := r.resets[key]; ok && time.Since(last) < decryptionResetInterval {
+		return false
+	}
+	r.resets[key] = time.Now()
+	for k, last := range r.resets {
+		if time.Since(last) >= decryptionResetInterval {
+			delete(r.resets, k)
+		}
+	}
+	return true
+}
+
+// handleDecryptionFailure emits a decryption_failed event and resets the
+// session with the sender if the policy asks for it.
+func (a *Api) handleDecryptionFailure(number string, failure decryptionFailure, source interface{}) {
+	log.Warn("Couldn't decrypt a message to ", number, " from ", failure.Sender, ": ", failure.Error)
+
+	if a.decryptionResets.due(number, failure.Sender) {
+		failure.Reset = true
+		go func() {
+			if err := a.resetSession(number, failure.Sender); err != nil {
+				log.Error("Couldn't reset the session with ", failure.Sender, " after a decryption failure: ", err.Error())
+			}
+		}()
+	}
+
+	a.bus.publish(busEvent{Type: eventDecryptionFailed, Number: number, Data: failure, Source: source})
+}
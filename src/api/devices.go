@@ -0,0 +1,134 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+const primaryDeviceID = 1
+
+type addDeviceRequest struct {
+	URI string `json:"uri"`
+}
+
+type deviceNameRequest struct {
+	Name string `json:"name"`
+}
+
+// getAccount returns the signald account of number.
+func (a *Api) getAccount(number string) (signald.Account, bool, error) {
+	message, err := a.listAccounts()
+	if err != nil {
+		return signald.Account{}, false, err
+	}
+	for _, account := range message.Data.Accounts {
+		if account.Username == number {
+			return account, true, nil
+		}
+	}
+	return signald.Account{}, false, nil
+}
+
+// @Summary Link a device to a registered number.
+// @Tags Devices
+// @Description Link another device (e.g. a phone) to a number the API is registered with as primary device. The URI is the content of the QR code the new device shows.
+// @Accept  json
+// @Produce  json
+// @Success 201 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body addDeviceRequest true "Device URI"
+// @Router /v1/devices/{number} [post]
+func (a *Api) AddDevice(c *gin.Context) {
+	number := c.Param("number")
+
+	req := addDeviceRequest{}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+	if !strings.HasPrefix(req.URI, "tsdevice:") {
+		c.JSON(400, gin.H{"error": "Please provide the tsdevice: URI of the device"})
+		return
+	}
+
+	account, found, err := a.getAccount(number)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(404, gin.H{"error": "Unknown number " + number})
+		return
+	}
+	if account.DeviceID != primaryDeviceID {
+		c.JSON(400, gin.H{"error": "Devices can only be linked if the API is the primary device of the number"})
+		return
+	}
+
+	if _, err := a.client(number).AddDevice(number, req.URI); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(201, nil)
+}
+
+// @Summary Change the name of a linked device.
+// @Tags Devices
+// @Description Change the name the device the API runs as was given when it was linked. signald can only rename the device it runs as, so device_id needs to be its device id.
+// @Accept  json
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param device_id path int true "Device ID"
+// @Param data body deviceNameRequest true "New Name"
+// @Router /v1/devices/{number}/{device_id}/name [put]
+func (a *Api) SetDeviceName(c *gin.Context) {
+	number := c.Param("number")
+	deviceID, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid device id"})
+		return
+	}
+
+	req := deviceNameRequest{}
+	if err := c.BindJSON(&req); err != nil || req.Name == "" {
+		c.JSON(400, gin.H{"error": "Please provide a name for the device"})
+		return
+	}
+
+	account, found, err := a.getAccount(number)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(404, gin.H{"error": "Unknown number " + number})
+		return
+	}
+	if account.DeviceID != deviceID {
+		c.JSON(400, gin.H{"error": "Only the device the API runs as (device " + strconv.Itoa(account.DeviceID) + ") can be renamed"})
+		return
+	}
+
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":        "set_device_name",
+		"account":     number,
+		"device_name": req.Name,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if resp.Type != "set_device_name" {
+		c.JSON(400, gin.H{"error": signaldError(resp).Error()})
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// eventGroupDigest is posted to the webhooks with a digest for the messages a
+// group received during the digest interval.
+const eventGroupDigest = "group_digest"
+
+const (
+	minDigestInterval     = time.Minute
+	defaultDigestMessages = 5
+)
+
+// WebhookDigest batches the messages of groups for a webhook, which gets one
+// group_digest event per group and interval. If Groups is empty the messages
+// of all groups are batched.
+type WebhookDigest struct {
+	Interval string   `json:"interval"`
+	Groups   []string `json:"groups"`
+	// Latest is the number of the latest messages the digest contains.
+	Latest int `json:"latest"`
+
+	interval time.Duration
+}
+
+func (d *WebhookDigest) init(url string) error {
+	var err error
+	if d.interval, err = time.ParseDuration(d.Interval); err != nil || d.interval < minDigestInterval {
+		return errors.New("Invalid digest interval of webhook " + url + " (minimum 1m)")
+	}
+	if d.Latest < 0 {
+		return errors.New("Invalid number of latest messages in the digest of webhook " + url)
+	}
+	if d.Latest == 0 {
+		d.Latest = defaultDigestMessages
+	}
+	return nil
+}
+
+func (d *WebhookDigest) includes(groupID string) bool {
+	if len(d.Groups) == 0 {
+		return true
+	}
+	for _, g := range d.Groups {
+		if g == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+type digestMessage struct {
+	Sender    string `json:"sender"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// groupDigest is the data of a group_digest event: the number of messages a
+// group received, by sender, and the latest messages.
+type groupDigest struct {
+	GroupID  string          `json:"group_id"`
+	Name     string          `json:"name,omitempty"`
+	Messages int             `json:"messages"`
+	Senders  map[string]int  `json:"senders"`
+	From     int64           `json:"from"`
+	To       int64           `json:"to"`
+	Latest   []digestMessage `json:"latest"`
+}
+
+type digestKey struct {
+	number  string
+	groupID string
+}
+
+// webhookDigests collects the group messages for the webhooks with a digest
+// and posts the digests every interval. The batches are only kept in memory.
+type webhookDigests struct {
+	mutex    sync.Mutex
+	webhooks *webhooks
+	batches  map[*Webhook]map[digestKey]*groupDigest
+}
+
+// newWebhookDigests returns nil if no webhook has a digest.
+func newWebhookDigests(w *webhooks) *webhookDigests {
+	d := &webhookDigests{webhooks: w, batches: make(map[*Webhook]map[digestKey]*groupDigest)}
+	for i := range w.hooks {
+		if w.hooks[i].Digest != nil {
+			d.batches[&w.hooks[i]] = make(map[digestKey]*groupDigest)
+		}
+	}
+	if len(d.batches) == 0 {
+		return nil
+	}
+	return d
+}
+
+// consume adds received group messages to the batches of the webhooks.
+func (d *webhookDigests) consume(e busEvent) {
+	msg, ok := e.Data.(incomingMessage)
+	if !ok {
+		return
+	}
+	env, err := msg.envelope()
+	if err != nil || env.DataMessage == nil || env.DataMessage.GroupInfo == nil {
+		return
+	}
+	// group updates aren't messages
+	info := env.DataMessage.GroupInfo
+	if info.Type != "" && info.Type != "DELIVER" {
+		return
+	}
+	key := digestKey{number: e.Number, groupID: convertInternalGroupIDToGroupID(info.GroupID)}
+	timestamp := env.DataMessage.Timestamp
+	if timestamp == 0 {
+		timestamp = env.Timestamp
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for hook, batches := range d.batches {
+		if !hook.matches(e.Number) || !hook.Digest.includes(key.groupID) {
+			continue
+		}
+		digest, ok := batches[key]
+		if !ok {
+			digest = &groupDigest{GroupID: key.groupID, Senders: make(map[string]int), From: timestamp, Latest: []digestMessage{}}
+			batches[key] = digest
+		}
+		if info.Name != "" {
+			digest.Name = info.Name
+		}
+		digest.Messages++
+		digest.Senders[env.Source.Number]++
+		digest.To = timestamp
+		digest.Latest = append(digest.Latest, digestMessage{Sender: env.Source.Number, Timestamp: timestamp, Message: env.DataMessage.Message})
+		if len(digest.Latest) > hook.Digest.Latest {
+			digest.Latest = digest.Latest[len(digest.Latest)-hook.Digest.Latest:]
+		}
+	}
+}
+
+// flush posts the digests collected for hook and starts new batches.
+func (d *webhookDigests) flush(hook *Webhook) {
+	d.mutex.Lock()
+	batches := d.batches[hook]
+	d.batches[hook] = make(map[digestKey]*groupDigest)
+	d.mutex.Unlock()
+
+	for key, digest := range batches {
+		d.webhooks.deliver(hook, event{
+			Type:      eventGroupDigest,
+			Number:    key.number,
+			Timestamp: milliseconds(time.Now()),
+			Data:      digest,
+		}, nil)
+	}
+}
+
+func (d *webhookDigests) purgerName() string {
+	return "webhook digests"
+}
+
+// purgeContact removes the messages contact sent from the digests of number
+// that weren't posted yet.
+func (d *webhookDigests) purgeContact(number string, contact string) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	deleted := 0
+	for _, batches := range d.batches {
+		for key, digest := range batches {
+			sent, ok := digest.Senders[contact]
+			if key.number != number || !ok {
+				continue
+			}
+			delete(digest.Senders, contact)
+			digest.Messages -= sent
+			latest := []digestMessage{}
+			for _, m := range digest.Latest {
+				if m.Sender != contact {
+					latest = append(latest, m)
+				}
+			}
+			digest.Latest = latest
+			if digest.Messages == 0 {
+				delete(batches, key)
+			}
+			deleted += sent
+		}
+	}
+	return deleted, nil
+}
+
+// start posts the digests of every webhook in its interval.
+func (d *webhookDigests) start() {
+	for hook := range d.batches {
+		go func(hook *Webhook) {
+			for range time.Tick(hook.Digest.interval) {
+				d.flush(hook)
+			}
+		}(hook)
+	}
+}
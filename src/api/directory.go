@@ -0,0 +1,100 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// accountDirectoryTTL is how long the list of accounts is used before it
+	// is fetched from signald again.
+	accountDirectoryTTL = 30 * time.Second
+	// accountDirectoryMinRefresh limits how often an unknown number causes the
+	// list to be fetched again, so that new accounts are found quickly but
+	// requests for foreign numbers don't hit signald every time.
+	accountDirectoryMinRefresh = 5 * time.Second
+)
+
+// accountDirectory knows the numbers signald has accounts for, so that
+// requests for numbers that aren't managed by this instance can be rejected
+// before they reach signald.
+type accountDirectory struct {
+	mutex     sync.Mutex
+	list      func() (signald.Response, error)
+	numbers   map[string]bool
+	refreshed time.Time
+}
+
+func newAccountDirectory(list func() (signald.Response, error)) *accountDirectory {
+	return &accountDirectory{list: list}
+}
+
+// known returns whether signald has an account for number.
+func (d *accountDirectory) known(number string) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	age := time.Since(d.refreshed)
+	if d.numbers != nil && age < accountDirectoryTTL && (d.numbers[number] || age < accountDirectoryMinRefresh) {
+		return d.numbers[number], nil
+	}
+
+	message, err := d.list()
+	if err != nil {
+		return false, err
+	}
+	d.numbers = make(map[string]bool)
+	for _, account := range message.Data.Accounts {
+		d.numbers[account.Username] = true
+	}
+	d.refreshed = time.Now()
+	return d.numbers[number], nil
+}
+
+// invalidate makes the next lookup fetch the accounts, after an account was
+// added.
+func (d *accountDirectory) invalidate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.refreshed = time.Time{}
+}
+
+// knownAccount fails with 404 if signald has no account for number. If the
+// accounts can't be listed it doesn't fail, the operation fails with the
+// error of signald then.
+func (a *Api) knownAccount(number string) error {
+	known, err := a.directory.known(number)
+	if err != nil {
+		log.Warn("Couldn't list the accounts to check ", number, ": ", err.Error())
+		return nil
+	}
+	if !known {
+		return service.NewError(service.NotFound, "Account "+number+" not found on this instance")
+	}
+	return nil
+}
+
+// checkAccount responds with 404 if signald has no account for number.
+func (a *Api) checkAccount(c *gin.Context, number string) bool {
+	if err := a.knownAccount(number); err != nil {
+		respondError(c, err)
+		return false
+	}
+	return true
+}
+
+// AccountGate rejects requests for a number in the path that isn't managed by
+// this instance with 404.
+func (a *Api) AccountGate(c *gin.Context) {
+	if !a.checkAccount(c, c.Param("number")) {
+		c.Abort()
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/abaskin/signald-rest-api/service"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkSpace fails with 507 if writing size bytes to the tmp dir would leave
+// less than the reserve free. If the free space can't be determined the
+// write is let through.
+func (s *attachmentStager) checkSpace(size int64) error {
+	if s.reserve <= 0 {
+		return nil
+	}
+	free, err := freeSpace(s.dir)
+	if err != nil {
+		log.Warn("Couldn't check the free space in ", s.dir, ": ", err.Error())
+		return nil
+	}
+	if free-size < s.reserve {
+		return service.NewError(service.StorageFull, "Not enough space for the attachment ("+strconv.FormatInt(size, 10)+
+			" bytes, "+strconv.FormatInt(free, 10)+" bytes free, "+strconv.FormatInt(s.reserve, 10)+" bytes reserved)")
+	}
+	return nil
+}
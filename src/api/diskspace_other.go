@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package api
+
+import "errors"
+
+// freeSpace isn't supported on this platform, the disk space isn't checked.
+func freeSpace(dir string) (int64, error) {
+	return 0, errors.New("Checking the free disk space isn't supported")
+}
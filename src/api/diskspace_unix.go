@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package api
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to the API in the file
+// system of dir.
+func freeSpace(dir string) (int64, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	drainCheckInterval = 10 * time.Second
+	minDrainInterval   = 10 * time.Second
+)
+
+// drainer keeps track of the numbers that send messages through the API, so
+// that their messages can be received in the background if no client does.
+// signald only processes receipts and refreshes the keys of an account while
+// it receives, accounts that only send break eventually. The drained messages
+// are buffered until a client receives.
+type drainer struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	shared   *sharedState
+	db       *stateDB
+	senders  map[string]bool
+	received map[string]time.Time
+	locks    map[string]*sync.Mutex
+	buffers  map[string]*receiveBuffer
+}
+
+func newDrainer(interval time.Duration, shared *sharedState, db *stateDB) *drainer {
+	return &drainer{
+		interval: interval,
+		shared:   shared,
+		db:       db,
+		senders:  make(map[string]bool),
+		received: make(map[string]time.Time),
+		locks:    make(map[string]*sync.Mutex),
+		buffers:  make(map[string]*receiveBuffer),
+	}
+}
+
+// consume learns the numbers that send from the message_sent events.
+func (d *drainer) consume(e busEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.senders[e.Number] = true
+}
+
+func (d *drainer) numbers() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	numbers := []string{}
+	for number := range d.senders {
+		numbers = append(numbers, number)
+	}
+	sort.Strings(numbers)
+	return numbers
+}
+
+// lock makes sure that the messages of number are only received by one
+// request or the drain loop at a time. It returns the function that unlocks.
+func (d *drainer) lock(number string) func() {
+	d.mutex.Lock()
+	l, ok := d.locks[number]
+	if !ok {
+		l = &sync.Mutex{}
+		d.locks[number] = l
+	}
+	d.mutex.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// touch records that the messages of number were received.
+func (d *drainer) touch(number string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.received[number] = time.Now()
+}
+
+// due returns whether the messages of number weren't received for interval.
+func (d *drainer) due(number string, interval time.Duration) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return time.Since(d.received[number]) >= interval
+}
+
+// buffer returns the buffer of the drained messages of number.
+func (d *drainer) buffer(number string) *receiveBuffer {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	b, ok := d.buffers[number]
+	if !ok {
+		b = newReceiveBuffer(d.shared, d.db, "drain_buffer", number)
+		d.buffers[number] = b
+	}
+	return b
+}
+
+func (d *drainer) purgerName() string {
+	return "drained messages"
+}
+
+// purgeContact removes the drained messages of number that mention contact.
+func (d *drainer) purgeContact(number string, contact string) (int, error) {
+	d.mutex.Lock()
+	b, ok := d.buffers[number]
+	d.mutex.Unlock()
+
+	if !ok {
+		return 0, nil
+	}
+	return b.purgeContact(contact)
+}
+
+// drainInterval returns how often the messages of number are received in the
+// background if no client receives them, 0 if they aren't.
+func (a *Api) drainInterval(number string) time.Duration {
+	if interval := a.accounts.get(number).drainInterval; interval > 0 {
+		return interval
+	}
+	return a.drains.interval
+}
+
+// receiveOnce receives the pending messages of number from signald, waiting up
+// to timeout seconds for them. The caller needs to hold the lock of the
+// number.
+func (a *Api) receiveOnce(number string, timeout int) signald.RawResponse {
+	rc := make(chan signald.RawResponse)
+	sc := make(chan struct{})
+	go a.client(number).Receive(rc, sc, number, timeout, true)
+
+	message := signald.RawResponse{}
+	for {
+		message = <-rc
+
+		if message.Done || message.Error != nil {
+			break
+		}
+	}
+	a.drains.touch(number)
+	return message
+}
+
+// runDrain receives the messages of the numbers that sent messages, aren't
+// subscribed and weren't received from for their drain interval. With several
+// replicas only the leader drains.
+func (a *Api) runDrain() {
+	for range time.Tick(drainCheckInterval) {
+		if !a.leader.isLeader() {
+			continue
+		}
+		for _, number := range a.drains.numbers() {
+			if _, ok := a.subscriptions.get(number); ok {
+				continue
+			}
+			if interval := a.drainInterval(number); interval <= 0 || !a.drains.due(number, interval) {
+				continue
+			}
+			a.drain(number)
+		}
+	}
+}
+
+func (a *Api) drain(number string) {
+	unlock := a.drains.lock(number)
+	defer unlock()
+
+	message := a.receiveOnce(number, a.receiveTimeout)
+	if message.Error != nil {
+		log.Error("Couldn't drain messages of ", number, ": ", message.Error.Error())
+		return
+	}
+	messages, _ := message.Data.([]signald.RawResponse)
+	received := a.processReceived(number, messages)
+	for _, m := range received {
+		a.drains.buffer(number).push(m)
+	}
+	if len(received) > 0 {
+		log.Info("Drained ", len(received), " messages of ", number)
+	}
+}
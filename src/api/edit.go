@@ -0,0 +1,29 @@
+package api
+
+// Only incoming edits are handled. Sending edits was declined: no signald
+// version can send them, an endpoint for it would only ever answer 501.
+
+// messageEdit links an incoming edit to the message it replaces. OriginalID
+// is the ID of the message in the message store.
+type messageEdit struct {
+	TargetTimestamp int64  `json:"target_timestamp"`
+	OriginalID      string `json:"original_id"`
+}
+
+type envelopeEditMessage struct {
+	TargetSentTimestamp int64                `json:"targetSentTimestamp"`
+	DataMessage         *envelopeDataMessage `json:"dataMessage"`
+}
+
+// edit returns the message an incoming edit replaces, if the message is an
+// edit.
+func (m *incomingMessage) edit() *messageEdit {
+	e, err := m.envelope()
+	if err != nil || e.EditMessage == nil || e.EditMessage.TargetSentTimestamp == 0 {
+		return nil
+	}
+	return &messageEdit{
+		TargetTimestamp: e.EditMessage.TargetSentTimestamp,
+		OriginalID:      messageID(e.Source.Number, e.EditMessage.TargetSentTimestamp),
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// egress is the transport of all outgoing HTTP requests of the API (hooks,
+// processors, chat commands, webhooks). It sends them through the configured
+// proxy and only to the hosts on the allowlist.
+type egress struct {
+	transport *http.Transport
+	allowlist []string
+	userAgent string
+	// publicOnly rejects requests to internal addresses, see publicClient.
+	publicOnly bool
+}
+
+// newEgress returns the transport for the given proxy (http, https or
+// socks5 URL). Without proxy the proxy environment variables apply. An empty
+// allowlist allows all hosts. The user agent is set on requests that don't
+// have one, if it is given.
+func newEgress(proxyURL string, allowlist []string, userAgent string) (*egress, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Host == "" {
+			return nil, errors.New("Invalid proxy url " + proxyURL)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			return nil, errors.New("Unsupported proxy scheme " + u.Scheme + " (supported: http, https, socks5)")
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	e := &egress{transport: transport, userAgent: userAgent}
+	for _, host := range allowlist {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			e.allowlist = append(e.allowlist, host)
+		}
+	}
+	return e, nil
+}
+
+// allowed returns whether requests to host are allowed. Allowlist entries
+// starting with "*." match all subdomains.
+func (e *egress) allowed(host string) bool {
+	if len(e.allowlist) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, allowed := range e.allowlist {
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *egress) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !e.allowed(req.URL.Hostname()) {
+		return nil, errors.New("Host " + req.URL.Hostname() + " is not on the egress allowlist")
+	}
+	if e.publicOnly {
+		var err error
+		if req, err = e.checkProxied(req); err != nil {
+			return nil, err
+		}
+	}
+	if e.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		// a round tripper mustn't modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", e.userAgent)
+	}
+	return e.transport.RoundTrip(req)
+}
+
+func (e *egress) client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: e}
+}
+
+// internalNetworks are the loopback, private, link-local and other networks
+// that aren't reachable on the internet.
+var internalNetworks = func() []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range []string{"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+func internalAddress(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func internalAddressError(address string) error {
+	return errors.New("Requests to the internal address " + address + " are not allowed")
+}
+
+type viaProxyKey struct{}
+
+// checkProxied checks the addresses of the host of a request that goes
+// through a proxy, which connects to the host itself. It marks the request, so
+// that the connection to the proxy isn't checked.
+func (e *egress) checkProxied(req *http.Request) (*http.Request, error) {
+	if e.transport.Proxy == nil {
+		return req, nil
+	}
+	proxyURL, err := e.transport.Proxy(req)
+	if err != nil || proxyURL == nil {
+		return req, err
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if internalAddress(addr.IP) {
+			return nil, internalAddressError(addr.IP.String())
+		}
+	}
+	return req.WithContext(context.WithValue(req.Context(), viaProxyKey{}, true)), nil
+}
+
+// publicClient returns a client for URLs given by the callers of the API,
+// which only connects to public addresses: the address is checked when
+// connecting, after the DNS resolution, so that host names resolving to
+// internal addresses are rejected as well. Requests through a proxy are
+// checked before, with the addresses the host resolves to.
+func (e *egress) publicClient(timeout time.Duration) *http.Client {
+	transport := e.transport.Clone()
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if ctx.Value(viaProxyKey{}) == nil {
+			dialer.Control = func(network string, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if ip := net.ParseIP(host); err != nil || ip == nil || internalAddress(ip) {
+					return internalAddressError(host)
+				}
+				return nil
+			}
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	public := &egress{transport: transport, allowlist: e.allowlist, userAgent: e.userAgent, publicOnly: true}
+	return &http.Client{Timeout: timeout, Transport: public}
+}
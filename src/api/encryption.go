@@ -0,0 +1,185 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// stateKeySize is the size of the AES-256 keys the state is encrypted with.
+const stateKeySize = 32
+
+// encryptedDocument is a state document encrypted with AES-GCM. Every document
+// is encrypted with a data key of its own, which is stored wrapped with the
+// key the API is configured with (envelope encryption).
+type encryptedDocument struct {
+	WrappedKey string `json:"wrapped_key"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// LoadStateKey reads a base64 encoded 256 bit key from a file.
+func LoadStateKey(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("Couldn't read key: " + err.Error())
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != stateKeySize {
+		return nil, errors.New("The key in " + filename + " needs to be 32 base64 encoded bytes")
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and returns the nonce followed by the ciphertext,
+// unseal reverses it.
+func seal(aead cipher.AEAD, plaintext []byte, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func unseal(aead cipher.AEAD, sealed []byte, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("The encrypted data is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
+
+// encryptedState encrypts the state of a subsystem before it is stored. The
+// name of the state is authenticated along with it, so that a document can't
+// be swapped for the one of another subsystem.
+type encryptedState struct {
+	state stateStore
+	name  string
+	key   cipher.AEAD
+}
+
+func newEncryptedState(state stateStore, name string, key []byte) (*encryptedState, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedState{state: state, name: name, key: aead}, nil
+}
+
+// load decrypts the state into v. State that was stored before the encryption
+// was enabled is read as it is and stored encrypted right away.
+func (s *encryptedState) load(v interface{}) error {
+	doc := encryptedDocument{}
+	if err := s.state.load(&doc); err != nil {
+		return err
+	}
+	if doc.Ciphertext == "" {
+		if err := s.state.load(v); err != nil {
+			return err
+		}
+		return s.save(v)
+	}
+	return s.decrypt(doc, v)
+}
+
+// decrypt decrypts the state in doc into v.
+func (s *encryptedState) decrypt(doc encryptedDocument, v interface{}) error {
+	wrapped, err := base64.StdEncoding.DecodeString(doc.WrappedKey)
+	if err != nil {
+		return err
+	}
+	dataKey, err := unseal(s.key, wrapped, []byte(s.name))
+	if err != nil {
+		return errors.New("Couldn't decrypt the data key of the " + s.name + " state, is the key right?")
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(doc.Ciphertext)
+	if err != nil {
+		return err
+	}
+	data, err := unseal(aead, sealed, []byte(s.name))
+	if err != nil {
+		return errors.New("Couldn't decrypt the " + s.name + " state: " + err.Error())
+	}
+	return reloadState(string(data), v)
+}
+
+func (s *encryptedState) save(v interface{}) error {
+	doc, err := s.encrypt(v)
+	if err != nil {
+		return err
+	}
+	return s.state.save(doc)
+}
+
+// encrypt encrypts v with a new data key.
+func (s *encryptedState) encrypt(v interface{}) (encryptedDocument, error) {
+	data, err := jsoniter.Marshal(v)
+	if err != nil {
+		return encryptedDocument{}, err
+	}
+
+	dataKey := make([]byte, stateKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return encryptedDocument{}, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return encryptedDocument{}, err
+	}
+	sealed, err := seal(aead, data, []byte(s.name))
+	if err != nil {
+		return encryptedDocument{}, err
+	}
+	wrapped, err := seal(s.key, dataKey, []byte(s.name))
+	if err != nil {
+		return encryptedDocument{}, err
+	}
+	return encryptedDocument{
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Ciphertext: base64.StdEncoding.EncodeToString(sealed),
+	}, nil
+}
+
+// update changes the state in the encrypted document of the store, the
+// document another instance saved is decrypted into v first.
+func (s *encryptedState) update(v interface{}, change func(reloaded bool) error) error {
+	doc := encryptedDocument{}
+	return s.state.update(&doc, func(reloaded bool) error {
+		if reloaded {
+			if err := s.decrypt(doc, v); err != nil {
+				return err
+			}
+		}
+		if err := change(reloaded); err != nil {
+			return err
+		}
+		var err error
+		doc, err = s.encrypt(v)
+		return err
+	})
+}
+
+func (s *encryptedState) refresh(v interface{}) (bool, error) {
+	doc := encryptedDocument{}
+	reloaded, err := s.state.refresh(&doc)
+	if err != nil || !reloaded {
+		return false, err
+	}
+	return true, s.decrypt(doc, v)
+}
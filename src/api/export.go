@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const exportVersion = 1
+
+// configExport contains the server side entities that are managed through the
+// API, so that they can be moved to another instance.
+type configExport struct {
+	Version         int                        `json:"version"`
+	Commands        []command                  `json:"commands"`
+	AccountSettings map[string]AccountSettings `json:"account_settings"`
+}
+
+func (e *configExport) validate() error {
+	if e.Version != exportVersion {
+		return errors.New("Unsupported export version")
+	}
+	for i := range e.Commands {
+		if err := e.Commands[i].validate(); err != nil {
+			return errors.New("Invalid command " + e.Commands[i].Name + ": " + err.Error())
+		}
+	}
+	for number, settings := range e.AccountSettings {
+		if err := settings.init(); err != nil {
+			return errors.New("Invalid settings of " + number + ": " + err.Error())
+		}
+		e.AccountSettings[number] = settings
+	}
+	return nil
+}
+
+// @Summary Export the configuration.
+// @Tags Admin
+// @Description Export the chat commands and the account settings changed via the API as JSON.
+// @Produce  json
+// @Success 200 {object} configExport
+// @Router /admin/export [get]
+func (a *Api) ExportConfig(c *gin.Context) {
+	c.JSON(200, a.exportConfig())
+}
+
+func (a *Api) exportConfig() configExport {
+	return configExport{
+		Version:         exportVersion,
+		Commands:        a.commands.list(),
+		AccountSettings: a.accounts.overridden(),
+	}
+}
+
+// @Summary Import the configuration.
+// @Tags Admin
+// @Description Import an export of another instance. By default the imported entities are merged into the existing ones (entities with the same name are replaced), with mode=replace all existing entities are replaced.
+// @Accept  json
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Param mode query string false "merge (default) or replace"
+// @Param data body configExport true "Export"
+// @Router /admin/import [post]
+func (a *Api) ImportConfig(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "merge")
+	if mode != "merge" && mode != "replace" {
+		c.JSON(400, gin.H{"error": "Invalid mode " + mode + " (supported: merge, replace)"})
+		return
+	}
+
+	e := configExport{}
+	if err := c.BindJSON(&e); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+	if err := e.validate(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := a.exportConfig()
+	commands := e.Commands
+	settings := e.AccountSettings
+	if settings == nil {
+		settings = make(map[string]AccountSettings)
+	}
+	if mode == "merge" {
+		imported := make(map[string]bool)
+		for _, cmd := range commands {
+			imported[cmd.Name] = true
+		}
+		for _, cmd := range a.commands.list() {
+			if !imported[cmd.Name] {
+				commands = append(commands, cmd)
+			}
+		}
+		for number, s := range a.accounts.overridden() {
+			if _, ok := settings[number]; !ok {
+				settings[number] = s
+			}
+		}
+	}
+
+	if err := a.commands.replace(commands); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save commands: " + err.Error()})
+		return
+	}
+	if err := a.accounts.replace(settings); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save account settings: " + err.Error()})
+		return
+	}
+	a.audit.record(c, "config.import", mode, previous, a.exportConfig())
+	c.Status(204)
+}
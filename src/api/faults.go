@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// faultSettings are the faults injected into requests and received messages,
+// so that integrators can test how they cope with a slow or failing API.
+type faultSettings struct {
+	// Delay is added to every affected request, e.g. 2s.
+	Delay string `json:"delay,omitempty"`
+	// DelayJitter adds a random delay of up to this duration.
+	DelayJitter string `json:"delay_jitter,omitempty"`
+	// ErrorRate is the fraction of affected requests (0 to 1) that fail with
+	// ErrorStatus.
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// ErrorStatus is the status failed requests respond with, 503 if it isn't
+	// set.
+	ErrorStatus int `json:"error_status,omitempty"`
+	// DropReceiveRate is the fraction of received messages (0 to 1) that are
+	// dropped.
+	DropReceiveRate float64 `json:"drop_receive_rate,omitempty"`
+	// Paths are the path prefixes of the affected requests, all requests
+	// except the admin API and the health checks if it is empty.
+	Paths []string `json:"paths,omitempty"`
+
+	delay       time.Duration
+	delayJitter time.Duration
+}
+
+func (s *faultSettings) init() error {
+	var err error
+	if s.Delay != "" {
+		if s.delay, err = time.ParseDuration(s.Delay); err != nil || s.delay < 0 {
+			return errors.New("Invalid delay " + s.Delay)
+		}
+	}
+	if s.DelayJitter != "" {
+		if s.delayJitter, err = time.ParseDuration(s.DelayJitter); err != nil || s.delayJitter < 0 {
+			return errors.New("Invalid delay jitter " + s.DelayJitter)
+		}
+	}
+	if s.ErrorRate < 0 || s.ErrorRate > 1 {
+		return errors.New("Invalid error rate, it needs to be between 0 and 1")
+	}
+	if s.ErrorStatus == 0 {
+		s.ErrorStatus = 503
+	}
+	if s.ErrorStatus < 400 || s.ErrorStatus > 599 {
+		return errors.New("Invalid error status " + strconv.Itoa(s.ErrorStatus))
+	}
+	if s.DropReceiveRate < 0 || s.DropReceiveRate > 1 {
+		return errors.New("Invalid drop receive rate, it needs to be between 0 and 1")
+	}
+	return nil
+}
+
+// affects returns whether requests to path are affected by the faults.
+func (s *faultSettings) affects(path string) bool {
+	if len(s.Paths) == 0 {
+		return !strings.HasPrefix(path, "/admin") && !strings.HasPrefix(path, "/v1/health")
+	}
+	for _, prefix := range s.Paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// faultInjector injects the configured faults. It is only enabled with
+// -fault-injection, no faults are injected until they are configured.
+type faultInjector struct {
+	mutex    sync.Mutex
+	enabled  bool
+	settings *faultSettings
+	random   *rand.Rand
+}
+
+func newFaultInjector(enabled bool) *faultInjector {
+	return &faultInjector{enabled: enabled, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (f *faultInjector) get() *faultSettings {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.settings
+}
+
+func (f *faultInjector) set(settings *faultSettings) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.settings = settings
+}
+
+// chance returns true with the probability rate.
+func (f *faultInjector) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.random.Float64() < rate
+}
+
+func (f *faultInjector) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return time.Duration(f.random.Int63n(int64(max)))
+}
+
+// InjectFaults delays requests and fails them with the configured rate.
+func (a *Api) InjectFaults(c *gin.Context) {
+	s := a.faults.get()
+	if s == nil || !s.affects(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+
+	if delay := s.delay + a.faults.jitter(s.delayJitter); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.Request.Context().Done():
+			timer.Stop()
+		}
+	}
+
+	if a.faults.chance(s.ErrorRate) {
+		c.Header("X-Injected-Fault", "true")
+		c.AbortWithStatusJSON(s.ErrorStatus, gin.H{"error": "Injected fault"})
+		return
+	}
+	c.Next()
+}
+
+// faultStage returns the receive stage that drops received messages with the
+// configured rate.
+func (a *Api) faultStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		s := a.faults.get()
+		if s == nil || !a.faults.chance(s.DropReceiveRate) {
+			return true
+		}
+		log.Info("Dropped a received message of ", number, " (injected fault)")
+		return false
+	}
+}
+
+func (a *Api) faultsEnabled(c *gin.Context) bool {
+	if !a.faults.enabled {
+		c.JSON(404, gin.H{"error": "Fault injection is disabled, start the API with -fault-injection to enable it"})
+		return false
+	}
+	return true
+}
+
+// @Summary Show the injected faults.
+// @Tags Admin
+// @Description Show the faults that are injected into requests and received messages. Fault injection needs to be enabled with -fault-injection.
+// @Produce  json
+// @Success 200 {object} faultSettings
+// @Success 204 {string} string "No faults are injected"
+// @Failure 404 {object} Error
+// @Router /admin/faults [get]
+func (a *Api) GetFaults(c *gin.Context) {
+	if !a.faultsEnabled(c) {
+		return
+	}
+	s := a.faults.get()
+	if s == nil {
+		c.Status(204)
+		return
+	}
+	c.JSON(200, s)
+}
+
+// @Summary Inject faults.
+// @Tags Admin
+// @Description Delay requests, fail a fraction of them with the given status and drop a fraction of the received messages, so that the retry and backoff logic of clients can be tested. The admin API and the health checks aren't affected unless they are listed in paths. Fault injection needs to be enabled with -fault-injection.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} faultSettings
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param data body faultSettings true "Faults"
+// @Router /admin/faults [put]
+func (a *Api) SetFaults(c *gin.Context) {
+	if !a.faultsEnabled(c) {
+		return
+	}
+
+	settings := &faultSettings{}
+	if err := c.BindJSON(settings); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+	if err := settings.init(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := a.faults.get()
+	a.faults.set(settings)
+	log.Warn("Injecting faults: delay ", settings.delay, ", error rate ", settings.ErrorRate,
+		", drop receive rate ", settings.DropReceiveRate)
+	a.audit.record(c, "faults.update", "", previous, settings)
+	c.JSON(200, settings)
+}
+
+// @Summary Stop injecting faults.
+// @Tags Admin
+// @Description Stop injecting faults into requests and received messages.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 404 {object} Error
+// @Router /admin/faults [delete]
+func (a *Api) DeleteFaults(c *gin.Context) {
+	if !a.faultsEnabled(c) {
+		return
+	}
+
+	previous := a.faults.get()
+	a.faults.set(nil)
+	log.Info("Stopped injecting faults")
+	a.audit.record(c, "faults.delete", "", previous, nil)
+	c.Status(204)
+}
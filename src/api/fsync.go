@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The fsync policies of attachment files: always syncs every file before it
+// is used, never leaves it to the operating system and batch syncs the files
+// written in the last second together.
+const (
+	FsyncAlways = "always"
+	FsyncNever  = "never"
+	FsyncBatch  = "batch"
+)
+
+const fsyncBatchInterval = time.Second
+
+func validateFsyncPolicy(policy string) error {
+	switch policy {
+	case "", FsyncAlways, FsyncNever, FsyncBatch:
+		return nil
+	}
+	return errors.New("Invalid fsync policy " + policy + " (supported: always, never, batch)")
+}
+
+// fileSyncer flushes attachment files to disk according to the fsync policy.
+// With the policy batch, flushed is closed once the pending files are synced
+// and flushing once the batch that is being synced is.
+type fileSyncer struct {
+	mutex    sync.Mutex
+	policy   string
+	pending  map[string]bool
+	flushed  chan struct{}
+	flushing chan struct{}
+}
+
+func newFileSyncer(policy string) *fileSyncer {
+	if policy == "" {
+		policy = FsyncAlways
+	}
+	return &fileSyncer{policy: policy, pending: make(map[string]bool), flushed: make(chan struct{})}
+}
+
+// sync is called when f has been written, it is synced right away with the
+// policy always.
+func (s *fileSyncer) sync(f *os.File) error {
+	if s.policy != FsyncAlways {
+		return nil
+	}
+	return f.Sync()
+}
+
+// written is called with the final name of a written file, it is synced with
+// the next batch with the policy batch.
+func (s *fileSyncer) written(filename string) {
+	if s.policy != FsyncBatch {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pending[filename] = true
+}
+
+// await waits until the files written so far are synced with the policy
+// batch, so that they are on disk before they are handed to signald.
+func (s *fileSyncer) await(ctx context.Context) error {
+	if s.policy != FsyncBatch {
+		return nil
+	}
+	s.mutex.Lock()
+	done := s.flushing
+	if len(s.pending) > 0 {
+		done = s.flushed
+	}
+	s.mutex.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *fileSyncer) flush() {
+	s.mutex.Lock()
+	pending, done := s.pending, s.flushed
+	s.pending, s.flushed, s.flushing = make(map[string]bool), make(chan struct{}), done
+	s.mutex.Unlock()
+
+	defer func() {
+		close(done)
+		s.mutex.Lock()
+		if s.flushing == done {
+			s.flushing = nil
+		}
+		s.mutex.Unlock()
+	}()
+
+	for filename := range pending {
+		f, err := os.Open(filename)
+		if err != nil {
+			// sent and removed in the meantime
+			continue
+		}
+		if err := f.Sync(); err != nil {
+			log.Error("Couldn't sync attachment ", filename, ": ", err.Error())
+		}
+		f.Close()
+	}
+}
+
+func (s *fileSyncer) run() {
+	if s.policy != FsyncBatch {
+		return
+	}
+	for range time.Tick(fsyncBatchInterval) {
+		s.flush()
+	}
+}
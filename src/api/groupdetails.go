@@ -0,0 +1,208 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// The roles of members of v2 groups.
+const (
+	groupRoleAdmin   = "admin"
+	groupRoleDefault = "default"
+)
+
+// groupMemberEntry is a member of a group, or one that was invited but hasn't
+// joined yet or that requested to join via the invite link.
+type groupMemberEntry struct {
+	UUID   string `json:"uuid"`
+	Number string `json:"number,omitempty"`
+	// Role is admin or default, v1 groups have no roles.
+	Role string `json:"role,omitempty"`
+	// InvitedBy is the UUID of the member who invited a pending member.
+	InvitedBy string `json:"invited_by,omitempty"`
+	// Timestamp is when the member was invited or requested to join, in
+	// milliseconds. Older signald versions don't report it.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// signaldGroupMember is the detail signald keeps of the members of v2 groups.
+type signaldGroupMember struct {
+	UUID      string `json:"uuid"`
+	Role      string `json:"role"`
+	AddedBy   string `json:"addedBy"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// signaldGroupV2 is a v2 group as returned by list_groups of the versioned
+// protocol.
+type signaldGroupV2 struct {
+	ID                     string                   `json:"id"`
+	Timer                  int                      `json:"timer"`
+	Members                []signald.RequestAddress `json:"members"`
+	MemberDetail           []signaldGroupMember     `json:"memberDetail"`
+	PendingMembers         []signald.RequestAddress `json:"pendingMembers"`
+	PendingMemberDetail    []signaldGroupMember     `json:"pendingMemberDetail"`
+	RequestingMembers      []signald.RequestAddress `json:"requestingMembers"`
+	RequestingMemberDetail []signaldGroupMember     `json:"requestingMemberDetail"`
+}
+
+// groupDetails are the members of a group with their roles, the pending and
+// requesting members and the disappearing message timer.
+type groupDetails struct {
+	timer      int
+	members    []groupMemberEntry
+	pending    []groupMemberEntry
+	requesting []groupMemberEntry
+}
+
+func newGroupMemberEntries(addresses []signald.RequestAddress, details []signaldGroupMember) []groupMemberEntry {
+	byUUID := make(map[string]signaldGroupMember)
+	for _, d := range details {
+		byUUID[d.UUID] = d
+	}
+
+	entries := []groupMemberEntry{}
+	for _, address := range addresses {
+		detail := byUUID[address.UUID]
+		entries = append(entries, groupMemberEntry{
+			UUID:      address.UUID,
+			Number:    address.Number,
+			Role:      groupRole(detail.Role),
+			InvitedBy: detail.AddedBy,
+			Timestamp: detail.Timestamp,
+		})
+	}
+	return entries
+}
+
+// groupRole returns the role of a member as signald reports it (ADMINISTRATOR
+// or DEFAULT).
+func groupRole(role string) string {
+	switch role {
+	case "ADMINISTRATOR":
+		return groupRoleAdmin
+	case "DEFAULT":
+		return groupRoleDefault
+	}
+	return ""
+}
+
+// groupDetails returns the members with their roles, the pending and
+// requesting members and the timers of the v2 groups of number by internal
+// id. The legacy group listing doesn't have them, so
+// they are listed with the versioned protocol. If that fails the groups are
+// listed without them.
+func (a *Api) groupDetails(number string) map[string]groupDetails {
+	details := make(map[string]groupDetails)
+	// signald lists v2 groups since it supports invite links
+	if a.supports(number, capabilityGroupInviteLinks) != nil {
+		return details
+	}
+
+	resp, err := a.listings.getRaw(groupDetailsListingKey(number), func() (signald.RawResponse, error) {
+		return a.requestSignald(number, map[string]interface{}{
+			"type":    "list_groups",
+			"account": number,
+		})
+	})
+	if err == nil && resp.Type != "list_groups" {
+		a.listings.invalidate(groupDetailsListingKey(number))
+		err = signaldError(resp)
+	}
+	if err != nil {
+		log.Warn("Couldn't list the member details of the groups of ", number, ": ", err.Error())
+		return details
+	}
+
+	data := struct {
+		Groups []signaldGroupV2 `json:"groups"`
+	}{}
+	encoded, err := jsoniter.Marshal(resp.Data)
+	if err == nil {
+		err = jsoniter.Unmarshal(encoded, &data)
+	}
+	if err != nil {
+		log.Warn("Couldn't list the member details of the groups of ", number, ": ", err.Error())
+		return details
+	}
+
+	for _, group := range data.Groups {
+		if internalID, ok := normalizeInternalGroupID(group.ID); ok {
+			details[internalID] = groupDetails{
+				timer:      group.Timer,
+				members:    newGroupMemberEntries(group.Members, group.MemberDetail),
+				pending:    newGroupMemberEntries(group.PendingMembers, group.PendingMemberDetail),
+				requesting: newGroupMemberEntries(group.RequestingMembers, group.RequestingMemberDetail),
+			}
+		}
+	}
+	return details
+}
+
+// findGroupMember returns the member of a group with the given number or
+// UUID.
+func findGroupMember(members []groupMemberEntry, member string) (groupMemberEntry, bool) {
+	for _, m := range members {
+		if (m.Number != "" && m.Number == member) || (m.UUID != "" && strings.EqualFold(m.UUID, member)) {
+			return m, true
+		}
+	}
+	return groupMemberEntry{}, false
+}
+
+// lastGroupAdmin returns whether number is the only admin of a v2 group with
+// other members.
+func (a *Api) lastGroupAdmin(number string, groupID string) bool {
+	details, ok := a.groupDetails(number)[groupID]
+	if !ok || len(details.members) < 2 {
+		return false
+	}
+	admins := 0
+	self := false
+	for _, m := range details.members {
+		if m.Role == groupRoleAdmin {
+			admins++
+			self = self || m.Number == number
+		}
+	}
+	return self && admins == 1
+}
+
+// promoteGroupMember makes a member of a v2 group admin.
+func (a *Api) promoteGroupMember(number string, groupID string, member string) error {
+	if err := a.supports(number, capabilityGroupRoles); err != nil {
+		return err
+	}
+
+	a.listings.invalidate(groupsListingKey(number))
+	details, ok := a.groupDetails(number)[groupID]
+	if !ok {
+		return service.NewError(service.Invalid, "Only v2 groups have admins")
+	}
+	m, ok := findGroupMember(details.members, member)
+	if !ok || m.Number == number {
+		return service.NewError(service.Invalid, member+" isn't another member of the group")
+	}
+	if m.Role == groupRoleAdmin {
+		return nil
+	}
+
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":       "update_group",
+		"account":    number,
+		"groupID":    groupID,
+		"updateRole": map[string]string{"uuid": m.UUID, "role": "ADMINISTRATOR"},
+	})
+	if err == nil && resp.Type != "update_group" {
+		err = signaldError(resp)
+	}
+	if err != nil {
+		return service.NewError(groupErrorKind(err), "Couldn't make "+member+" admin: "+err.Error())
+	}
+	log.Info("Made ", member, " admin of group ", convertInternalGroupIDToGroupID(groupID))
+	return nil
+}
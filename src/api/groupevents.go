@@ -0,0 +1,163 @@
+package api
+
+import (
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventGroupMemberJoined = "group_member_joined"
+	eventGroupMemberLeft   = "group_member_left"
+	eventGroupNameChanged  = "group_name_changed"
+	eventGroupUpdated      = "group_updated"
+)
+
+type groupEvent struct {
+	GroupID string   `json:"group_id"`
+	Name    string   `json:"name,omitempty"`
+	OldName string   `json:"old_name,omitempty"`
+	Members []string `json:"members,omitempty"`
+	Actor   string   `json:"actor"`
+}
+
+type groupState struct {
+	name    string
+	members map[string]bool
+}
+
+// groupStates remembers the last known name and members of the groups of
+// every number, so that group updates can be turned into join/leave events.
+type groupStates struct {
+	mutex  sync.Mutex
+	groups map[string]map[string]groupState
+}
+
+func newGroupStates() *groupStates {
+	return &groupStates{groups: make(map[string]map[string]groupState)}
+}
+
+func (s *groupStates) set(number string, groupID string, state groupState) (groupState, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.groups[number]; !ok {
+		s.groups[number] = make(map[string]groupState)
+	}
+	old, ok := s.groups[number][groupID]
+	s.groups[number][groupID] = state
+	return old, ok
+}
+
+func (s *groupStates) removeMember(number string, groupID string, member string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if state, ok := s.groups[number][groupID]; ok {
+		delete(state.members, member)
+	}
+}
+
+// refreshGroupStates snapshots the groups of number before messages are
+// received.
+func (a *Api) refreshGroupStates(number string) {
+	message, err := a.client(number).ListGroups(number)
+	if err != nil {
+		log.Error("Couldn't list groups: ", err.Error())
+		return
+	}
+
+	for _, group := range message.Data.Groups {
+		state := groupState{name: group.Name, members: make(map[string]bool)}
+		for _, m := range group.Members {
+			state.members[m.Number] = true
+		}
+		a.groupStates.set(number, group.GroupID, state)
+	}
+}
+
+func memberDiff(a map[string]bool, b map[string]bool) []string {
+	diff := []string{}
+	for m := range a {
+		if !b[m] {
+			diff = append(diff, m)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// groupEventStage returns the receive stage that emits webhook events for
+// group updates.
+func (a *Api) groupEventStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		if !a.webhooks.enabled(number) {
+			return true
+		}
+
+		e, err := msg.envelope()
+		if err != nil || e.DataMessage == nil || e.DataMessage.GroupInfo == nil {
+			return true
+		}
+
+		info := e.DataMessage.GroupInfo
+		groupID := convertInternalGroupIDToGroupID(info.GroupID)
+		actor := e.Source.Number
+
+		switch info.Type {
+		case "QUIT":
+			a.groupStates.removeMember(number, info.GroupID, actor)
+			a.bus.publish(busEvent{Type: eventGroupMemberLeft, Number: number, Data: groupEvent{
+				GroupID: groupID,
+				Members: []string{actor},
+				Actor:   actor,
+			}, Source: msg.Data})
+
+		case "UPDATE":
+			state := groupState{name: info.Name, members: make(map[string]bool)}
+			for _, m := range info.Members {
+				state.members[m.Number] = true
+			}
+
+			old, known := a.groupStates.set(number, info.GroupID, state)
+			if !known {
+				members := memberDiff(state.members, nil)
+				a.bus.publish(busEvent{Type: eventGroupUpdated, Number: number, Data: groupEvent{
+					GroupID: groupID,
+					Name:    state.name,
+					Members: members,
+					Actor:   actor,
+				}, Source: msg.Data})
+				break
+			}
+
+			if joined := memberDiff(state.members, old.members); len(joined) > 0 {
+				a.bus.publish(busEvent{Type: eventGroupMemberJoined, Number: number, Data: groupEvent{
+					GroupID: groupID,
+					Name:    state.name,
+					Members: joined,
+					Actor:   actor,
+				}, Source: msg.Data})
+			}
+			if left := memberDiff(old.members, state.members); len(left) > 0 {
+				a.bus.publish(busEvent{Type: eventGroupMemberLeft, Number: number, Data: groupEvent{
+					GroupID: groupID,
+					Name:    state.name,
+					Members: left,
+					Actor:   actor,
+				}, Source: msg.Data})
+			}
+			if state.name != old.name {
+				a.bus.publish(busEvent{Type: eventGroupNameChanged, Number: number, Data: groupEvent{
+					GroupID: groupID,
+					Name:    state.name,
+					OldName: old.name,
+					Actor:   actor,
+				}, Source: msg.Data})
+			}
+		}
+
+		return true
+	}
+}
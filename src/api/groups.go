@@ -0,0 +1,380 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+const groupInviteLinkPrefix = "https://signal.group/"
+
+// decodeBase64 decodes standard as well as URL-safe base64, with or without
+// padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// normalizeInternalGroupID returns a group id as signald uses it, the
+// standard base64 encoding of the 16 (v1 groups) or 32 (v2 groups) byte
+// identifier. The id may be given in any base64 encoding.
+func normalizeInternalGroupID(id string) (string, bool) {
+	raw, err := decodeBase64(id)
+	if err != nil || (len(raw) != 16 && len(raw) != 32) {
+		return "", false
+	}
+	return base64.StdEncoding.EncodeToString(raw), true
+}
+
+func isInternalGroupID(id string) bool {
+	_, ok := normalizeInternalGroupID(id)
+	return ok
+}
+
+func hasGroupPrefix(id string) bool {
+	return len(id) >= len(groupPrefix) && strings.EqualFold(id[:len(groupPrefix)], groupPrefix)
+}
+
+// isGroupRecipient returns whether a recipient of a message refers to a group
+// rather than a phone number.
+func isGroupRecipient(recipient string) bool {
+	return hasGroupPrefix(recipient) ||
+		strings.HasPrefix(recipient, groupInviteLinkPrefix) ||
+		isInternalGroupID(recipient)
+}
+
+// resolveGroupID returns the internal id of a group given as group id
+// ("group.<base64>", also without prefix), internal id or group invite link.
+// Both standard and URL-safe base64 are accepted.
+func (a *Api) resolveGroupID(number string, group string) (string, error) {
+	if strings.HasPrefix(group, groupInviteLinkPrefix) {
+		return a.resolveGroupInviteLink(number, group)
+	}
+
+	internalID, ok := parseGroupID(group)
+	if !ok {
+		return "", errors.New("Invalid group id")
+	}
+	return internalID, nil
+}
+
+// parseGroupID returns the internal id of a group given as group id or
+// internal id.
+func parseGroupID(group string) (string, bool) {
+	if internalID, ok := normalizeInternalGroupID(group); ok {
+		return internalID, true
+	}
+
+	if hasGroupPrefix(group) {
+		group = group[len(groupPrefix):]
+		if internalID, ok := normalizeInternalGroupID(group); ok {
+			return internalID, true
+		}
+	}
+	decoded, err := decodeBase64(group)
+	if err != nil {
+		return "", false
+	}
+	return normalizeInternalGroupID(string(decoded))
+}
+
+// resolveGroupInviteLink looks up the group with the given invite link among
+// the groups of number.
+func (a *Api) resolveGroupInviteLink(number string, link string) (string, error) {
+	if err := a.supports(number, capabilityGroupInviteLinks); err != nil {
+		return "", err
+	}
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":    "list_groups",
+		"account": number,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Type != "list_groups" {
+		return "", errors.New("Couldn't resolve group invite link: " + signaldError(resp).Error())
+	}
+
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		groups, _ := data["groups"].([]interface{})
+		for _, g := range groups {
+			group, _ := g.(map[string]interface{})
+			id, _ := group["id"].(string)
+			inviteLink, _ := group["inviteLink"].(string)
+			if id != "" && sameInviteLink(inviteLink, link) {
+				return id, nil
+			}
+		}
+	}
+	return "", &groupNotFoundError{"No group with invite link " + link}
+}
+
+// sameInviteLink compares invite links, ignoring the padding of the encoded
+// group data.
+func sameInviteLink(a string, b string) bool {
+	return a != "" && strings.TrimRight(a, "=") == strings.TrimRight(b, "=")
+}
+
+// groupNotFoundError is returned if a number has no group with the given
+// id or invite link.
+type groupNotFoundError struct {
+	text string
+}
+
+func (e *groupNotFoundError) Error() string {
+	return e.text
+}
+
+// signald reports missing groups and missing rights with different error
+// types depending on its version, so they are recognized by their text
+// without spaces and underscores.
+var (
+	groupNotFoundErrors  = []string{"unknowngroup", "groupnotfound", "groupnotactive", "notamember"}
+	groupForbiddenErrors = []string{"nopermission", "notanadmin", "notadmin", "insufficientrights",
+		"permissiondenied", "grouppatchnotaccepted", "authorizationfailed"}
+)
+
+// groupErrorKind returns the kind of error to report if an operation on a
+// group failed: NotFound if the group doesn't exist (for the number),
+// Forbidden if the number lacks the rights and Invalid otherwise.
+func groupErrorKind(err error) service.Kind {
+	if _, ok := err.(*groupNotFoundError); ok {
+		return service.NotFound
+	}
+	if e, ok := err.(*service.Error); ok {
+		return e.Kind
+	}
+
+	text := strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(err.Error()))
+	for _, e := range groupNotFoundErrors {
+		if strings.Contains(text, e) {
+			return service.NotFound
+		}
+	}
+	for _, e := range groupForbiddenErrors {
+		if strings.Contains(text, e) {
+			return service.Forbidden
+		}
+	}
+	return service.Invalid
+}
+
+// groupError returns the error of an operation on a group with its kind.
+func groupError(err error) error {
+	return service.NewError(groupErrorKind(err), err.Error())
+}
+
+const (
+	groupChangeCreated        = "created"
+	groupChangeRenamed        = "renamed"
+	groupChangeMembersAdded   = "members_added"
+	groupChangeMembersRemoved = "members_removed"
+)
+
+// @Summary Update a Signal Group.
+// @Tags Groups
+// @Description Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it) of a group. Settings that aren't given are left unchanged.
+// @Accept  json
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 403 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group Id"
+// @Param data body service.GroupUpdate true "Group Settings"
+// @Router /v1/groups/{number}/{groupid} [patch]
+func (a *Api) UpdateGroup(c *gin.Context) {
+	update := service.GroupUpdate{}
+	if err := c.BindJSON(&update); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	if err := a.service.UpdateGroup(c.Param("number"), c.Param("groupid"), update); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
+
+// desiredGroup is the state a group should be in after a sync. Groups are
+// matched by id if it is given and by name otherwise.
+type desiredGroup struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type groupChange struct {
+	GroupID string   `json:"group_id,omitempty"`
+	Name    string   `json:"name"`
+	Action  string   `json:"action"`
+	Members []string `json:"members,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type groupSyncReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Changes   []groupChange `json:"changes"`
+	Unchanged []string      `json:"unchanged"`
+}
+
+func membersSet(number string, members []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, m := range members {
+		if m != number {
+			set[m] = true
+		}
+	}
+	return set
+}
+
+// planGroupSync compares the desired state with the current groups of number
+// and returns the changes needed.
+func planGroupSync(number string, current []groupEntry, desired []desiredGroup) ([]groupChange, []string, error) {
+	byID := make(map[string]groupEntry)
+	byName := make(map[string]groupEntry)
+	for _, group := range current {
+		byID[group.InternalID] = group
+		byName[group.Name] = group
+	}
+
+	changes := []groupChange{}
+	unchanged := []string{}
+	for _, d := range desired {
+		if d.Name == "" {
+			return nil, nil, errors.New("Every group needs a name")
+		}
+
+		var group groupEntry
+		var ok bool
+		if d.ID != "" {
+			internalID, valid := parseGroupID(d.ID)
+			if !valid {
+				return nil, nil, errors.New("Invalid group id " + d.ID)
+			}
+			if group, ok = byID[internalID]; !ok {
+				return nil, nil, errors.New("Unknown group " + d.ID)
+			}
+		} else {
+			group, ok = byName[d.Name]
+		}
+
+		want := membersSet(number, d.Members)
+		if !ok {
+			changes = append(changes, groupChange{Name: d.Name, Action: groupChangeCreated, Members: memberDiff(want, nil)})
+			continue
+		}
+
+		have := membersSet(number, group.Members)
+		changed := false
+		if group.Name != d.Name {
+			changes = append(changes, groupChange{GroupID: group.InternalID, Name: d.Name, Action: groupChangeRenamed})
+			changed = true
+		}
+		if added := memberDiff(want, have); len(added) > 0 {
+			changes = append(changes, groupChange{GroupID: group.InternalID, Name: d.Name, Action: groupChangeMembersAdded, Members: added})
+			changed = true
+		}
+		if removed := memberDiff(have, want); len(removed) > 0 {
+			changes = append(changes, groupChange{GroupID: group.InternalID, Name: d.Name, Action: groupChangeMembersRemoved, Members: removed})
+			changed = true
+		}
+		if !changed {
+			unchanged = append(unchanged, convertInternalGroupIDToGroupID(group.InternalID))
+		}
+	}
+	return changes, unchanged, nil
+}
+
+// applyGroupChange makes a planned change via signald.
+func (a *Api) applyGroupChange(number string, change groupChange) error {
+	switch change.Action {
+	case groupChangeCreated:
+		_, err := a.client(number).CreateGroup(number, "", change.Name, change.Members, "")
+		return err
+	case groupChangeRenamed:
+		_, err := a.client(number).CreateGroup(number, change.GroupID, change.Name, nil, "")
+		return err
+	case groupChangeMembersAdded:
+		_, err := a.client(number).CreateGroup(number, change.GroupID, "", change.Members, "")
+		return err
+	case groupChangeMembersRemoved:
+		if err := a.supports(number, capabilityGroupMemberRemoval); err != nil {
+			return err
+		}
+		members := []map[string]string{}
+		for _, m := range change.Members {
+			members = append(members, map[string]string{"number": m})
+		}
+		resp, err := a.requestSignald(number, map[string]interface{}{
+			"type":          "update_group",
+			"account":       number,
+			"groupID":       change.GroupID,
+			"removeMembers": members,
+		})
+		if err == nil && resp.Type != "update_group" {
+			err = signaldError(resp)
+		}
+		return err
+	}
+	return errors.New("Unknown change " + change.Action)
+}
+
+// @Summary Sync groups with a desired state.
+// @Tags Groups
+// @Description Create groups and update names and members of groups to match the given list. Groups are matched by id if given and by name otherwise, groups that aren't listed are left alone. Returns the changes made (or with dry_run the changes that would be made).
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} groupSyncReport
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param dry_run query bool false "Only report the changes"
+// @Param data body []desiredGroup true "Desired Groups"
+// @Router /v1/groups/{number}/sync [post]
+func (a *Api) SyncGroups(c *gin.Context) {
+	number := c.Param("number")
+
+	desired := []desiredGroup{}
+	if err := c.BindJSON(&desired); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	a.listings.invalidate(groupsListingKey(number))
+	current, err := a.getGroups(number)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, unchanged, err := planGroupSync(number, current, desired)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := groupSyncReport{DryRun: c.Query("dry_run") == "true", Changes: changes, Unchanged: unchanged}
+	if !report.DryRun {
+		for i := range report.Changes {
+			if err := a.applyGroupChange(number, report.Changes[i]); err != nil {
+				report.Changes[i].Error = err.Error()
+			}
+		}
+		a.listings.invalidate(groupsListingKey(number))
+	}
+	for i := range report.Changes {
+		if report.Changes[i].GroupID != "" {
+			report.Changes[i].GroupID = convertInternalGroupIDToGroupID(report.Changes[i].GroupID)
+		}
+	}
+
+	c.JSON(200, report)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// outgoingMessage is posted to every configured send hook before a message is
+// handed to signald.
+type outgoingMessage struct {
+	Number      string   `json:"number"`
+	Recipients  []string `json:"recipients"`
+	IsGroup     bool     `json:"is_group"`
+	Message     string   `json:"message"`
+	Attachments int      `json:"attachments"`
+}
+
+// hookResponse is the (optional) answer of a send hook. A hook can veto the
+// message or replace its text, an empty response leaves the message as is.
+type hookResponse struct {
+	Veto    bool    `json:"veto"`
+	Reason  string  `json:"reason"`
+	Message *string `json:"message"`
+}
+
+type vetoError struct {
+	hook   string
+	reason string
+}
+
+func (e *vetoError) Error() string {
+	if e.reason == "" {
+		return "Message rejected by send hook " + e.hook
+	}
+	return "Message rejected by send hook " + e.hook + ": " + e.reason
+}
+
+type sendHooks struct {
+	urls   []string
+	client *http.Client
+}
+
+func newSendHooks(urls []string, client *http.Client) *sendHooks {
+	return &sendHooks{
+		urls:   urls,
+		client: client,
+	}
+}
+
+// apply runs the message through all hooks in the configured order. Every
+// hook sees the message as modified by the hooks before it.
+func (h *sendHooks) apply(msg outgoingMessage) (outgoingMessage, error) {
+	for _, url := range h.urls {
+		body, err := jsoniter.Marshal(msg)
+		if err != nil {
+			return msg, err
+		}
+
+		resp, err := h.client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return msg, errors.New("Couldn't call send hook " + url + ": " + err.Error())
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return msg, errors.New("Couldn't read response of send hook " + url + ": " + err.Error())
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return msg, errors.New("Send hook " + url + " failed with status " + strconv.Itoa(resp.StatusCode))
+		}
+
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		hookResp := hookResponse{}
+		if err := jsoniter.Unmarshal(data, &hookResp); err != nil {
+			return msg, errors.New("Invalid response of send hook " + url + ": " + err.Error())
+		}
+
+		if hookResp.Veto {
+			return msg, &vetoError{hook: url, reason: hookResp.Reason}
+		}
+
+		if hookResp.Message != nil {
+			msg.Message = *hookResp.Message
+		}
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyKeyTTL       = 24 * time.Hour
+	idempotencyKeyMaxLength = 255
+)
+
+// idempotentResponse is the response stored for an idempotency key.
+type idempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+type idempotencyEntry struct {
+	response *idempotentResponse
+	expires  time.Time
+}
+
+// idempotencyKeys remembers the responses of the requests with an
+// Idempotency-Key header, so that a retried request isn't processed twice.
+// The keys are kept in memory, or in Redis if replicas share their state.
+type idempotencyKeys struct {
+	mutex   sync.Mutex
+	shared  *sharedState
+	entries map[string]*idempotencyEntry
+}
+
+func newIdempotencyKeys(shared *sharedState) *idempotencyKeys {
+	return &idempotencyKeys{shared: shared, entries: make(map[string]*idempotencyEntry)}
+}
+
+// reserve marks a key as in progress. It returns the stored response if the
+// key was used before, or false if another request with the key is still in
+// progress.
+func (k *idempotencyKeys) reserve(key string) (*idempotentResponse, bool, error) {
+	if k.shared != nil {
+		return k.shared.reserve(key)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if e, ok := k.entries[key]; ok && time.Now().Before(e.expires) {
+		return e.response, false, nil
+	}
+	k.entries[key] = &idempotencyEntry{expires: time.Now().Add(idempotencyKeyTTL)}
+	return nil, true, nil
+}
+
+// complete stores the response of a key, or frees the key if response is nil.
+func (k *idempotencyKeys) complete(key string, response *idempotentResponse) error {
+	if k.shared != nil {
+		return k.shared.complete(key, response)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if response == nil {
+		delete(k.entries, key)
+		return nil
+	}
+	k.entries[key] = &idempotencyEntry{response: response, expires: time.Now().Add(idempotencyKeyTTL)}
+	return nil
+}
+
+func (k *idempotencyKeys) run() {
+	for range time.Tick(time.Minute) {
+		k.mutex.Lock()
+		for key, e := range k.entries {
+			if time.Now().After(e.expires) {
+				delete(k.entries, key)
+			}
+		}
+		k.mutex.Unlock()
+	}
+}
+
+// capturingWriter keeps a copy of the response body.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent makes requests with an Idempotency-Key header idempotent: the
+// response is stored for 24 hours and returned again for requests with the
+// same key, without processing them. Responses that are worth retrying (rate
+// limited or server errors) aren't stored.
+func (a *Api) Idempotent(c *gin.Context) {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > idempotencyKeyMaxLength {
+		c.AbortWithStatusJSON(400, gin.H{"error": "Idempotency key is too long"})
+		return
+	}
+	key = c.FullPath() + " " + key
+
+	response, ok, err := a.idempotency.reserve(key)
+	if err != nil {
+		c.AbortWithStatusJSON(400, gin.H{"error": "Couldn't check idempotency key: " + err.Error()})
+		return
+	}
+	if response != nil {
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(response.Status, response.ContentType, response.Body)
+		c.Abort()
+		return
+	}
+	if !ok {
+		c.AbortWithStatusJSON(409, gin.H{"error": "A request with this idempotency key is in progress"})
+		return
+	}
+
+	w := &capturingWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+
+	response = &idempotentResponse{
+		Status:      w.Status(),
+		ContentType: w.Header().Get("Content-Type"),
+		Body:        w.body.Bytes(),
+	}
+	if response.Status == 429 || response.Status >= 500 {
+		response = nil
+	}
+	if err := a.idempotency.complete(key, response); err != nil {
+		log.Error("Couldn't store response of idempotency key: ", err.Error())
+	}
+}
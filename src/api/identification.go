@@ -0,0 +1,36 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// identificationData is passed to the templates of the default device name
+// and the user agent, so that every instance of a fleet can identify itself.
+type identificationData struct {
+	Hostname string
+}
+
+// expandIdentification expands a device name or user agent template. Besides
+// {{.Hostname}} the templates can use environment variables with
+// {{env "NAME"}}.
+func expandIdentification(name string, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	t, err := template.New(name).Funcs(template.FuncMap{"env": os.Getenv}).Parse(text)
+	if err != nil {
+		return "", errors.New("Invalid " + name + " template: " + err.Error())
+	}
+
+	hostname, _ := os.Hostname()
+	var b bytes.Buffer
+	if err := t.Execute(&b, identificationData{Hostname: hostname}); err != nil {
+		return "", errors.New("Invalid " + name + " template: " + err.Error())
+	}
+	return strings.TrimSpace(b.String()), nil
+}
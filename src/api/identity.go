@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/abaskin/signald-go/signald"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TrustNever      = "never"
+	TrustOnFirstUse = "tofu"
+	TrustAlways     = "always"
+)
+
+const eventIdentityChanged = "identity_changed"
+
+type identityEvent struct {
+	Address      signald.RequestAddress `json:"address"`
+	Fingerprint  string                 `json:"fingerprint"`
+	SafetyNumber string                 `json:"safety_number"`
+	Trusted      bool                   `json:"trusted"`
+}
+
+func validateTrustPolicy(policy string) error {
+	switch policy {
+	case TrustNever, TrustOnFirstUse, TrustAlways:
+		return nil
+	}
+	return errors.New("Invalid trust policy " + policy + " (supported: never, tofu, always)")
+}
+
+// shouldTrust decides according to the trust policy whether a new identity is
+// trusted automatically. With trust on first use that's only the case if no
+// other identity of the address is known.
+func (a *Api) shouldTrust(number string, identity signald.UntrustedIdentityException) bool {
+	switch a.trustPolicy {
+	case TrustAlways:
+		return true
+	case TrustOnFirstUse:
+		message, err := a.client(number).ListIdentities(number, identity.RemoteAddress)
+		if err != nil {
+			log.Error("Couldn't list identities: ", err.Error())
+			return false
+		}
+		for _, known := range message.Data.Identities {
+			if known.Fingerprint != identity.Fingerprint {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// handleUntrustedIdentity emits an identity_changed event and trusts the
+// identity if the policy allows it. It returns whether the identity is
+// trusted now.
+func (a *Api) handleUntrustedIdentity(number string, identity signald.UntrustedIdentityException) bool {
+	trusted := false
+	if identity.Fingerprint != "" && a.shouldTrust(number, identity) {
+		if _, err := a.client(number).Trust(number, identity.RemoteAddress, identity.Fingerprint); err != nil {
+			log.Error("Couldn't trust identity: ", err.Error())
+		} else {
+			trusted = true
+			go a.resendQueued(number, identity.RemoteAddress.Number)
+		}
+	}
+
+	a.bus.publish(busEvent{Type: eventIdentityChanged, Number: number, Data: identityEvent{
+		Address:      identity.RemoteAddress,
+		Fingerprint:  identity.Fingerprint,
+		SafetyNumber: identity.SafetyNumber,
+		Trusted:      trusted,
+	}, Source: identity})
+
+	return trusted
+}
+
+// sendMessage sends a message via signald. If the send fails because the
+// identity of the recipient changed, the identity is handled according to the
+// trust policy and the send is retried once if it is trusted now. The message
+// is sent with the given timestamp.
+func (a *Api) sendMessage(number string, to signald.RequestAddress, groupID string, message string,
+	attachments []signald.RequestAttachment, timestamp int64) (signald.Response, error) {
+	resp, err := a.signaldSend(number, to, groupID, message, attachments, timestamp)
+	if err == nil {
+		a.publishSent(number, to.Number, groupID, message, len(attachments), timestamp)
+	}
+	if err == nil || resp.Type != "untrusted_identity" {
+		return resp, err
+	}
+
+	identity := resp.Data.UntrustedIdentityException
+	if identity.Fingerprint == "" && !to.Empty() {
+		// signald-go doesn't decode the details of the identity, so look
+		// them up
+		identity = a.findUntrustedIdentity(number, to)
+	}
+
+	if !a.handleUntrustedIdentity(number, identity) {
+		return resp, err
+	}
+
+	resp, err = a.signaldSend(number, to, groupID, message, attachments, timestamp)
+	if err == nil {
+		a.publishSent(number, to.Number, groupID, message, len(attachments), timestamp)
+	}
+	return resp, err
+}
+
+// findUntrustedIdentity returns the most recently added untrusted identity of
+// address.
+func (a *Api) findUntrustedIdentity(number string, address signald.RequestAddress) signald.UntrustedIdentityException {
+	identity := signald.UntrustedIdentityException{RemoteAddress: address}
+
+	message, err := a.client(number).ListIdentities(number, address)
+	if err != nil {
+		log.Error("Couldn't list identities: ", err.Error())
+		return identity
+	}
+
+	if known, ok := newestUntrustedIdentity(message.Data.Identities); ok {
+		identity.Fingerprint = known.Fingerprint
+		identity.SafetyNumber = known.SafetyNumber
+	}
+	return identity
+}
+
+func newestUntrustedIdentity(identities []signald.Identity) (signald.Identity, bool) {
+	newest := signald.Identity{}
+	found := false
+	for _, known := range identities {
+		if known.TrustLevel == "UNTRUSTED" && (!found || known.Added > newest.Added) {
+			newest = known
+			found = true
+		}
+	}
+	return newest, found
+}
+
+// untrustedIdentityFromData decodes the untrusted identity reported in a
+// received message.
+func untrustedIdentityFromData(data interface{}) (signald.UntrustedIdentityException, error) {
+	identity := signald.UntrustedIdentityException{}
+	b, err := jsoniter.Marshal(data)
+	if err != nil {
+		return identity, err
+	}
+	err = jsoniter.Unmarshal(b, &identity)
+	return identity, err
+}
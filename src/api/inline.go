@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+const receiveAttachmentsInline = "inline"
+
+// inlineAttachments embeds the attachments of the received data messages that
+// are at most maxSize bytes as base64 in the field "data" of the attachment.
+// Attachments that are larger or can't be read get the reason in the field
+// "inlineError" instead.
+func inlineAttachments(messages []incomingMessage, maxSize int64) {
+	for i := range messages {
+		data, _ := messages[i].Data.(map[string]interface{})
+		dataMessage, _ := data["dataMessage"].(map[string]interface{})
+		attachments, _ := dataMessage["attachments"].([]interface{})
+		if len(attachments) == 0 {
+			continue
+		}
+
+		// the message is shared with the consumers of the event bus, so the
+		// attachments are embedded in a copy
+		inlined := make([]interface{}, len(attachments))
+		for j, a := range attachments {
+			attachment, ok := a.(map[string]interface{})
+			if !ok {
+				inlined[j] = a
+				continue
+			}
+			attachment = copyMap(attachment)
+			if err := inlineAttachment(attachment, maxSize); err != "" {
+				attachment["inlineError"] = err
+			}
+			inlined[j] = attachment
+		}
+		dataMessage = copyMap(dataMessage)
+		dataMessage["attachments"] = inlined
+		data = copyMap(data)
+		data["dataMessage"] = dataMessage
+		messages[i].Data = data
+	}
+}
+
+// copyMap returns a shallow copy of m.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func inlineAttachment(attachment map[string]interface{}, maxSize int64) string {
+	filename, _ := attachment["storedFilename"].(string)
+	if filename == "" {
+		return "The attachment wasn't stored"
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "Couldn't read attachment"
+	}
+	if info.Size() > maxSize {
+		return "The attachment exceeds the inline size limit of " + strconv.FormatInt(maxSize, 10) + " bytes"
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "Couldn't read attachment"
+	}
+	attachment["data"] = base64.StdEncoding.EncodeToString(content)
+	return ""
+}
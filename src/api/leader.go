@@ -0,0 +1,80 @@
+package api
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	leaderLockName      = "leader"
+	leaderLockTTL       = 15 * time.Second
+	leaderRenewInterval = 5 * time.Second
+)
+
+// leaderElection elects one of the replicas sharing their state in Redis as
+// leader, the background tasks that must only run once (e.g. syncing the
+// accounts) run on the leader only. The leader holds a lock in Redis that it
+// renews regularly, if it stops another replica takes over once the lock
+// expired. Without Redis the instance is always the leader.
+type leaderElection struct {
+	mutex  sync.Mutex
+	shared *sharedState
+	id     string
+	leader bool
+}
+
+func newLeaderElection(shared *sharedState) (*leaderElection, error) {
+	l := &leaderElection{shared: shared, leader: shared == nil}
+	if shared == nil {
+		return l, nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	l.id = hostname + "-" + id
+	l.campaign()
+	return l, nil
+}
+
+func (l *leaderElection) isLeader() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.leader
+}
+
+// campaign takes or renews the leader lock.
+func (l *leaderElection) campaign() {
+	leader, err := l.shared.acquireLock(leaderLockName, l.id, leaderLockTTL)
+	if err != nil {
+		log.Error("Couldn't take the leader lock: ", err.Error())
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if leader && !l.leader {
+		log.Info("This instance (", l.id, ") is the leader now")
+	} else if !leader && l.leader {
+		log.Warn("This instance (", l.id, ") is no longer the leader")
+	}
+	l.leader = leader
+}
+
+func (l *leaderElection) run() {
+	if l.shared == nil {
+		return
+	}
+	for range time.Tick(leaderRenewInterval) {
+		l.campaign()
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	linkWaitingForScan = "waiting_for_scan"
+	linkLinked         = "linked"
+	linkFailed         = "failed"
+)
+
+const (
+	linkTimeout = 10 * time.Minute
+	linkLinger  = 10 * time.Minute
+)
+
+type linkSession struct {
+	ID         string     `json:"id"`
+	DeviceName string     `json:"device_name"`
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Started    time.Time  `json:"started"`
+	Finished   *time.Time `json:"finished,omitempty"`
+}
+
+// linkSessions keeps track of the devices being linked. Every session has a
+// connection to signald of its own, so that devices can be linked
+// concurrently. Finished sessions are kept for a while so that their outcome
+// can be checked.
+type linkSessions struct {
+	mutex      sync.Mutex
+	socketPath string
+	sessions   map[string]*linkSession
+}
+
+func newLinkSessions(socketPath string) *linkSessions {
+	return &linkSessions{
+		socketPath: socketPath,
+		sessions:   make(map[string]*linkSession),
+	}
+}
+
+// start requests a linking URI from signald and waits for the device to be
+// linked in the background.
+func (l *linkSessions) start(deviceName string) (linkSession, string, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return linkSession{}, "", err
+	}
+	conn, err := dialSignald(l.socketPath, signald.Request{Type: "link", ID: id, DeviceName: deviceName})
+	if err != nil {
+		return linkSession{}, "", err
+	}
+	conn.conn.SetReadDeadline(time.Now().Add(linkTimeout))
+
+	message, err := conn.await(id)
+	if err != nil {
+		conn.close()
+		return linkSession{}, "", err
+	}
+	if message.Type != "linking_uri" {
+		conn.close()
+		return linkSession{}, "", signaldError(message)
+	}
+	uri := ""
+	if data, ok := message.Data.(map[string]interface{}); ok {
+		uri, _ = data["uri"].(string)
+	}
+	if uri == "" {
+		conn.close()
+		return linkSession{}, "", errors.New("signald didn't return a linking URI")
+	}
+
+	session := &linkSession{
+		ID:         id,
+		DeviceName: deviceName,
+		State:      linkWaitingForScan,
+		Started:    time.Now(),
+	}
+
+	l.mutex.Lock()
+	l.sessions[session.ID] = session
+	l.mutex.Unlock()
+
+	go l.wait(conn, session)
+	return *session, uri, nil
+}
+
+func (l *linkSessions) wait(conn *signaldConn, session *linkSession) {
+	defer conn.close()
+
+	message, err := conn.await(session.ID)
+	if err == nil && message.Type != "linking_successful" {
+		err = signaldError(message)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	session.Finished = &now
+	if err != nil {
+		log.Error("Couldn't link device ", session.DeviceName, ": ", err.Error())
+		session.State = linkFailed
+		session.Error = err.Error()
+		return
+	}
+	log.Info("Linked device ", session.DeviceName)
+	session.State = linkLinked
+}
+
+// list returns the sessions sorted by start time and forgets the ones that
+// finished a while ago.
+func (l *linkSessions) list() []linkSession {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	sessions := []linkSession{}
+	for id, session := range l.sessions {
+		if session.Finished != nil && time.Since(*session.Finished) > linkLinger {
+			delete(l.sessions, id)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.Before(sessions[j].Started) })
+	return sessions
+}
+
+// @Summary List the link sessions.
+// @Tags Devices
+// @Description List the devices that are being linked and the outcome of the recently finished link attempts.
+// @Produce  json
+// @Success 200 {object} []linkSession
+// @Router /v1/link/sessions [get]
+func (a *Api) GetLinkSessions(c *gin.Context) {
+	c.JSON(200, a.links.list())
+}
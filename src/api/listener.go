@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+)
+
+// systemdListenFDsStart is the first file descriptor systemd passes with
+// socket activation.
+const systemdListenFDsStart = 3
+
+// Listener returns the listener the REST API is served on: the socket passed
+// by systemd socket activation, or a Unix domain socket if unixSocket is set.
+// If neither applies nil is returned and the API listens on TCP.
+func Listener(unixSocket string, mode os.FileMode) (net.Listener, error) {
+	if l, err := systemdListener(); l != nil || err != nil {
+		return l, err
+	}
+	if unixSocket == "" {
+		return nil, nil
+	}
+
+	// a socket left over from a previous run would make listening fail
+	if info, err := os.Stat(unixSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(unixSocket)
+	}
+
+	l, err := net.Listen("unix", unixSocket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(unixSocket, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+	if fds > 1 {
+		return nil, errors.New("Socket activation with more than one socket isn't supported")
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(systemdListenFDsStart, "systemd-socket")
+	defer f.Close()
+	return net.FileListener(f)
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+type cachedListing struct {
+	response signald.Response
+	raw      signald.RawResponse
+	fetched  time.Time
+}
+
+// listingCache keeps the listings (groups, contacts, accounts) signald
+// returned for a short time, so that dashboards polling the API don't hit
+// signald with every request. Changes made through the API invalidate the
+// affected listings.
+type listingCache struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	listings map[string]cachedListing
+}
+
+func newListingCache(ttl time.Duration) *listingCache {
+	return &listingCache{
+		ttl:      ttl,
+		listings: make(map[string]cachedListing),
+	}
+}
+
+func (l *listingCache) get(key string, fetch func() (signald.Response, error)) (signald.Response, error) {
+	if l.ttl <= 0 {
+		return fetch()
+	}
+	if cached, ok := l.cached(key); ok {
+		return cached.response, nil
+	}
+
+	response, err := fetch()
+	if err != nil {
+		return response, err
+	}
+	l.store(key, cachedListing{response: response})
+	return response, nil
+}
+
+// getRaw is get for listings requested with the versioned protocol.
+func (l *listingCache) getRaw(key string, fetch func() (signald.RawResponse, error)) (signald.RawResponse, error) {
+	if l.ttl <= 0 {
+		return fetch()
+	}
+	if cached, ok := l.cached(key); ok {
+		return cached.raw, nil
+	}
+
+	response, err := fetch()
+	if err != nil {
+		return response, err
+	}
+	l.store(key, cachedListing{raw: response})
+	return response, nil
+}
+
+func (l *listingCache) cached(key string) (cachedListing, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	cached, ok := l.listings[key]
+	return cached, ok && time.Since(cached.fetched) < l.ttl
+}
+
+func (l *listingCache) store(key string, listing cachedListing) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	listing.fetched = time.Now()
+	l.listings[key] = listing
+	for k, cached := range l.listings {
+		if time.Since(cached.fetched) >= l.ttl {
+			delete(l.listings, k)
+		}
+	}
+}
+
+// invalidate drops a listing together with the listings derived from it,
+// e.g. the group details of the groups listing.
+func (l *listingCache) invalidate(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for k := range l.listings {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(l.listings, k)
+		}
+	}
+}
+
+func (l *listingCache) purgerName() string {
+	return "listing cache"
+}
+
+// purgeContact drops the cached contacts and groups of number, they list
+// contact.
+func (l *listingCache) purgeContact(number string, contact string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	deleted := 0
+	for k := range l.listings {
+		for _, key := range []string{contactsListingKey(number), groupsListingKey(number)} {
+			if k == key || strings.HasPrefix(k, key+"/") {
+				delete(l.listings, k)
+				deleted++
+				break
+			}
+		}
+	}
+	return deleted, nil
+}
+
+func groupsListingKey(number string) string {
+	return "groups:" + number
+}
+
+// groupDetailsListingKey is the key of the v2 group details of number, which
+// signald only returns with the versioned protocol.
+func groupDetailsListingKey(number string) string {
+	return groupsListingKey(number) + "/details"
+}
+
+func contactsListingKey(number string) string {
+	return "contacts:" + number
+}
+
+const accountsListingKey = "accounts"
+
+// respondWithETag sends v as JSON with an ETag of its content. If the client
+// already has the content (If-None-Match) 304 is returned instead.
+func respondWithETag(c *gin.Context, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondDataWithETag(c, "application/json; charset=utf-8", body)
+}
+
+// respondDataWithETag sends body with an ETag of its content. If the client
+// already has the content (If-None-Match) 304 is returned instead.
+func respondDataWithETag(c *gin.Context, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+
+	for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag || match == "*" {
+			c.Status(304)
+			return
+		}
+	}
+
+	c.Data(200, contentType, body)
+}
+
+type accountEntry struct {
+	Number     string `json:"number"`
+	DeviceID   int    `json:"device_id"`
+	Registered bool   `json:"registered"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+// @Summary List the accounts.
+// @Tags Accounts
+// @Description List the numbers signald has accounts for. Supports If-None-Match with the returned ETag.
+// @Produce  json
+// @Success 200 {object} []accountEntry
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} Error
+// @Router /v1/accounts [get]
+func (a *Api) GetAccounts(c *gin.Context) {
+	message, err := a.listings.get(accountsListingKey, a.listAccounts)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	accounts := []accountEntry{}
+	for _, account := range message.Data.Accounts {
+		accounts = append(accounts, accountEntry{
+			Number:     account.Username,
+			DeviceID:   account.DeviceID,
+			Registered: account.Registered,
+			Subscribed: account.Subscribed,
+		})
+	}
+	respondWithETag(c, accounts)
+}
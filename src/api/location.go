@@ -0,0 +1,17 @@
+package api
+
+import "github.com/abaskin/signald-rest-api/service"
+
+// location is a place sent along with a message. Signal has no location or
+// venue message and signald can't send one, so requests with a location are
+// rejected instead of sending the place as text.
+type location struct {
+	Latitude  *float64 `json:"lat"`
+	Longitude *float64 `json:"lon"`
+	Label     string   `json:"label,omitempty"`
+}
+
+// errLocationUnsupported rejects requests with a location.
+var errLocationUnsupported = service.NewError(service.Unsupported,
+	"Signal has no location messages and signald can't send them, "+
+		"please send the location as text")
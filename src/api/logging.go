@@ -0,0 +1,149 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RedactionOff  = "off"
+	RedactionMask = "mask"
+	RedactionHash = "hash"
+)
+
+var (
+	// tokens include a leading #, so that hashed numbers aren't redacted
+	// again
+	tokenPattern = regexp.MustCompile(`[#+]?[0-9A-Za-z]+`)
+	// only numbers in E.164 format are redacted, other runs of digits are
+	// timestamps, ids or sizes
+	phoneNumberPattern = regexp.MustCompile(`^\+[0-9]{7,15}$`)
+)
+
+// Redactor removes phone numbers from everything that ends up in the logs.
+// Depending on the mode a number is either masked (only the last two digits
+// stay visible) or replaced by a salted hash, so that requests for the same
+// number can still be correlated without revealing it.
+type Redactor struct {
+	mode string
+	salt []byte
+}
+
+func NewRedactor(mode string, salt string) (*Redactor, error) {
+	switch mode {
+	case RedactionOff, RedactionMask, RedactionHash:
+	default:
+		return nil, errors.New("Invalid log redaction mode " + mode + " (supported: off, mask, hash)")
+	}
+	// without salt the hashes of the few possible phone numbers are easily
+	// reversed
+	if mode == RedactionHash && salt == "" {
+		return nil, errors.New("The log redaction mode hash needs a salt")
+	}
+	return &Redactor{mode: mode, salt: []byte(salt)}, nil
+}
+
+func (r *Redactor) redactNumber(number string) string {
+	switch r.mode {
+	case RedactionHash:
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(number))
+		return "#" + hex.EncodeToString(mac.Sum(nil))[:12]
+	case RedactionMask:
+		return strings.Repeat("*", len(number)-2) + number[len(number)-2:]
+	}
+	return number
+}
+
+// Redact replaces all phone numbers found in value. Only standalone numbers
+// with a leading + are redacted, digits that are part of an identifier are
+// left alone.
+func (r *Redactor) Redact(value string) string {
+	if r.mode == RedactionOff {
+		return value
+	}
+	return tokenPattern.ReplaceAllStringFunc(value, func(token string) string {
+		if !phoneNumberPattern.MatchString(token) {
+			return token
+		}
+		return r.redactNumber(token)
+	})
+}
+
+// redactingFormatter redacts the phone numbers in the message and the fields of
+// log entries before they are formatted.
+type redactingFormatter struct {
+	log.Formatter
+	redactor *Redactor
+}
+
+func (f *redactingFormatter) Format(entry *log.Entry) ([]byte, error) {
+	redacted := *entry
+	redacted.Message = f.redactor.Redact(entry.Message)
+	redacted.Data = make(log.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			value = f.redactor.Redact(v)
+		case error:
+			value = f.redactor.Redact(v.Error())
+		}
+		redacted.Data[key] = value
+	}
+	return f.Formatter.Format(&redacted)
+}
+
+// RedactLogs redacts the phone numbers in everything logged with the standard
+// logger.
+func RedactLogs(r *Redactor) {
+	if r.mode == RedactionOff {
+		return
+	}
+	log.SetFormatter(&redactingFormatter{Formatter: log.StandardLogger().Formatter, redactor: r})
+}
+
+// RequestLogger logs the metadata of every request. Request and response
+// bodies (messages, attachments) are never logged, query parameters are
+// logged by name only and phone numbers in the path are redacted.
+func RequestLogger(r *Redactor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		queryKeys := []string{}
+		for key := range c.Request.URL.Query() {
+			queryKeys = append(queryKeys, key)
+		}
+		sort.Strings(queryKeys)
+
+		entry := log.WithFields(log.Fields{
+			"method":        c.Request.Method,
+			"path":          r.Redact(c.Request.URL.Path),
+			"query":         strings.Join(queryKeys, ","),
+			"status":        c.Writer.Status(),
+			"latency":       time.Since(start).String(),
+			"client_ip":     c.ClientIP(),
+			"request_size":  c.Request.ContentLength,
+			"response_size": c.Writer.Size(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", r.Redact(c.Errors.String()))
+		}
+
+		if c.Writer.Status() >= 500 {
+			entry.Error("Request failed")
+		} else {
+			entry.Info("Request handled")
+		}
+	}
+}
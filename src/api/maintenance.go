@@ -0,0 +1,320 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	heldStateHeld    = "held"
+	heldStateSending = "sending"
+	heldStateFailed  = "failed"
+)
+
+// maxHeldMessages is the number of messages held during maintenance, further
+// messages are rejected.
+const maxHeldMessages = 10000
+
+// heldMessage is a message accepted during maintenance that is sent when the
+// maintenance ends.
+type heldMessage struct {
+	ID          string    `json:"id"`
+	Number      string    `json:"number"`
+	Recipients  []string  `json:"recipients"`
+	IsGroup     bool      `json:"is_group"`
+	Attachments int       `json:"attachments"`
+	State       string    `json:"state"`
+	Error       string    `json:"error,omitempty"`
+	Created     time.Time `json:"created"`
+
+	send service.Send
+}
+
+// maintenanceStatus is the state of the maintenance mode with the messages
+// held.
+type maintenanceStatus struct {
+	Enabled bool          `json:"enabled"`
+	Since   *time.Time    `json:"since,omitempty"`
+	Reason  string        `json:"reason,omitempty"`
+	Held    []heldMessage `json:"held"`
+}
+
+// maintenanceRequest enables or disables the maintenance mode.
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// maintenance holds the messages sent while the API is in maintenance mode
+// and sends them in order once it ends, so that senders don't see errors
+// while e.g. signald is upgraded. The messages are only kept in memory, their
+// attachments are staged on disk and kept until they are sent.
+type maintenance struct {
+	mutex   sync.Mutex
+	stager  *attachmentStager
+	enabled bool
+	since   time.Time
+	reason  string
+	held    []*heldMessage
+}
+
+func newMaintenance(stager *attachmentStager) *maintenance {
+	return &maintenance{stager: stager}
+}
+
+func (m *maintenance) active() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.enabled
+}
+
+// set enables or disables the maintenance mode and returns whether it was
+// enabled before.
+func (m *maintenance) set(enabled bool, reason string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	was := m.enabled
+	m.enabled = enabled
+	m.reason = reason
+	if enabled && !was {
+		m.since = time.Now()
+	}
+	return was
+}
+
+func (m *maintenance) status() maintenanceStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := maintenanceStatus{Enabled: m.enabled, Reason: m.reason, Held: []heldMessage{}}
+	if m.enabled {
+		since := m.since
+		s.Since = &since
+	}
+	for _, h := range m.held {
+		s.Held = append(s.Held, *h)
+	}
+	return s
+}
+
+// hold keeps a message until the maintenance ends. The base64 encoded
+// attachments are staged, so that only references to the staged files are
+// kept in memory. It takes its own reference on the staged attachments of the
+// message.
+func (m *maintenance) hold(out service.Send) (string, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return "", err
+	}
+
+	tokens := []string{}
+	release := func() {
+		for _, token := range tokens {
+			m.stager.release(token)
+		}
+	}
+	for _, attachment := range out.Base64Attachments {
+		hash, _, err := m.stager.stageBase64(out.RequestContext(), attachment)
+		if err != nil {
+			release()
+			return "", service.NewError(service.Invalid, "Couldn't stage attachment: "+err.Error())
+		}
+		tokens = append(tokens, hash)
+	}
+	for _, token := range out.AttachmentTokens {
+		if _, ok := m.stager.lookup(token); !ok {
+			release()
+			return "", service.NewError(service.Invalid, "Unknown or expired attachment token "+token)
+		}
+		tokens = append(tokens, token)
+	}
+	out.Base64Attachments, out.AttachmentTokens = nil, tokens
+	// the request is done when the message is sent
+	out.Context = nil
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.held) >= maxHeldMessages {
+		release()
+		return "", service.NewError(service.Unavailable, "Too many messages held during maintenance")
+	}
+	m.held = append(m.held, &heldMessage{
+		ID:          id,
+		Number:      out.Number,
+		Recipients:  out.Recipients,
+		IsGroup:     out.IsGroup,
+		Attachments: len(tokens),
+		State:       heldStateHeld,
+		Created:     time.Now(),
+		send:        out,
+	})
+	return id, nil
+}
+
+// next returns the oldest message that is still held and marks it as being
+// sent, unless the maintenance mode is enabled again.
+func (m *maintenance) next() *heldMessage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.enabled {
+		return nil
+	}
+	for _, h := range m.held {
+		if h.State == heldStateHeld {
+			h.State = heldStateSending
+			return h
+		}
+	}
+	return nil
+}
+
+// done removes a sent message, or keeps it as failed.
+func (m *maintenance) done(h *heldMessage, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		h.State = heldStateFailed
+		h.Error = err.Error()
+		return
+	}
+	m.remove(h.ID)
+}
+
+// remove drops a message and its attachments, the caller needs to hold the
+// mutex.
+func (m *maintenance) remove(id string) bool {
+	for i, h := range m.held {
+		if h.ID != id {
+			continue
+		}
+		for _, token := range h.send.AttachmentTokens {
+			m.stager.release(token)
+		}
+		m.held = append(m.held[:i], m.held[i+1:]...)
+		return true
+	}
+	return false
+}
+
+func (m *maintenance) discard(id string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.remove(id)
+}
+
+func (m *maintenance) purgerName() string {
+	return "held messages"
+}
+
+// purgeContact removes contact from the recipients of the messages number
+// holds, messages without recipients left are dropped. Messages that are
+// being sent already can't be purged.
+func (m *maintenance) purgeContact(number string, contact string) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	for _, h := range append([]*heldMessage{}, m.held...) {
+		if h.Number != number || h.IsGroup || h.State == heldStateSending {
+			continue
+		}
+		recipients := []string{}
+		for _, recipient := range h.Recipients {
+			if recipient != contact {
+				recipients = append(recipients, recipient)
+			}
+		}
+		if len(recipients) == len(h.Recipients) {
+			continue
+		}
+		deleted++
+		if len(recipients) == 0 {
+			m.remove(h.ID)
+			continue
+		}
+		h.Recipients, h.send.Recipients = recipients, recipients
+	}
+	return deleted, nil
+}
+
+// dispatchHeld sends the messages held during the maintenance in the order
+// they were accepted.
+func (a *Api) dispatchHeld() {
+	for h := a.maintenance.next(); h != nil; h = a.maintenance.next() {
+		_, err := a.service.Submit(h.send)
+		if err != nil {
+			log.Error("Couldn't send message ", h.ID, " held during maintenance: ", err.Error())
+		} else {
+			log.Info("Sent message ", h.ID, " held during maintenance")
+		}
+		a.maintenance.done(h, err)
+	}
+}
+
+// @Summary Show the maintenance mode.
+// @Tags Admin
+// @Description Show whether the maintenance mode is enabled and the messages held until it ends, as well as the ones that failed when they were sent after it.
+// @Produce  json
+// @Success 200 {object} maintenanceStatus
+// @Router /admin/maintenance [get]
+func (a *Api) GetMaintenance(c *gin.Context) {
+	c.JSON(200, a.maintenance.status())
+}
+
+// @Summary Enable or disable the maintenance mode.
+// @Tags Admin
+// @Description While the maintenance mode is enabled messages are accepted with 202 and the id they are held with, but they are only sent once the maintenance mode is disabled again. They are then sent in the order they were accepted. The messages are only held in memory by the replica that accepted them.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} maintenanceStatus
+// @Failure 400 {object} Error
+// @Param data body maintenanceRequest true "Maintenance Mode"
+// @Router /admin/maintenance [put]
+func (a *Api) SetMaintenance(c *gin.Context) {
+	req := maintenanceRequest{}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	previous := a.maintenance.status()
+	if was := a.maintenance.set(req.Enabled, req.Reason); was != req.Enabled {
+		if req.Enabled {
+			log.Warn("Maintenance mode enabled, messages are held until it ends")
+		} else {
+			log.Info("Maintenance mode disabled, sending the held messages")
+			go a.dispatchHeld()
+		}
+	}
+
+	status := a.maintenance.status()
+	a.audit.record(c, "maintenance.update", "", maintenanceRequest{Enabled: previous.Enabled, Reason: previous.Reason}, req)
+	c.JSON(200, status)
+}
+
+// @Summary Discard a held message.
+// @Tags Admin
+// @Description Remove a message held during maintenance, or one that failed when it was sent after it, without sending it.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 404 {object} Error
+// @Param id path string true "Held Message ID"
+// @Router /admin/maintenance/held/{id} [delete]
+func (a *Api) DiscardHeldMessage(c *gin.Context) {
+	if !a.maintenance.discard(c.Param("id")) {
+		c.JSON(404, gin.H{"error": "No such held message"})
+		return
+	}
+	a.audit.record(c, "maintenance.discard", c.Param("id"), nil, nil)
+	c.Status(204)
+}
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// matrixClient is the client messages bridged from Matrix are sent as, their
+// quotas apply to it.
+const matrixClient = "matrix"
+
+// matrixTimeout is the timeout of the requests to the homeserver.
+const matrixTimeout = 10 * time.Second
+
+// matrixTransactionsKept is the number of transaction ids of the homeserver
+// remembered, so that retried transactions aren't bridged twice.
+const matrixTransactionsKept = 1000
+
+// MatrixRoom maps a Signal conversation of a number, with a contact or a
+// group, to a Matrix room.
+type MatrixRoom struct {
+	Number string `json:"number"`
+	// Peer is the number of the contact or the id of the group.
+	Peer   string `json:"peer"`
+	RoomID string `json:"room_id"`
+}
+
+// MatrixBridge bridges Signal conversations to a Matrix homeserver as
+// application service.
+type MatrixBridge struct {
+	// HomeserverURL is the client-server API of the homeserver, e.g.
+	// https://matrix.example.com.
+	HomeserverURL string `json:"homeserver_url"`
+	// ASToken authenticates the bridge with the homeserver, HSToken the
+	// homeserver with the bridge, both are given in the registration of the
+	// application service.
+	ASToken string `json:"as_token"`
+	HSToken string `json:"hs_token"`
+	// UserID is the user of the bridge, its messages aren't bridged back to
+	// Signal.
+	UserID string       `json:"user_id"`
+	Rooms  []MatrixRoom `json:"rooms"`
+}
+
+// LoadMatrixBridge reads the Matrix bridge from a JSON file.
+func LoadMatrixBridge(filename string) (*MatrixBridge, error) {
+	bridge := &MatrixBridge{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jsoniter.Unmarshal(data, bridge); err != nil {
+		return nil, errors.New("Couldn't parse Matrix bridge config: " + err.Error())
+	}
+	if err := bridge.init(); err != nil {
+		return nil, err
+	}
+	return bridge, nil
+}
+
+func (b *MatrixBridge) init() error {
+	u, err := url.Parse(b.HomeserverURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Invalid homeserver url " + b.HomeserverURL)
+	}
+	b.HomeserverURL = strings.TrimSuffix(b.HomeserverURL, "/")
+	if b.ASToken == "" || b.HSToken == "" {
+		return errors.New("The Matrix bridge needs the as_token and the hs_token of the application service")
+	}
+	for _, room := range b.Rooms {
+		if room.Number == "" || room.Peer == "" || !strings.HasPrefix(room.RoomID, "!") {
+			return errors.New("Invalid Matrix room " + room.RoomID + ", it needs a number, a peer and a room id (!...)")
+		}
+	}
+	return nil
+}
+
+// matrixEvent is the part of a Matrix room event the bridge looks at.
+type matrixEvent struct {
+	Type    string `json:"type"`
+	RoomID  string `json:"room_id"`
+	Sender  string `json:"sender"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+type matrixTransaction struct {
+	Events []matrixEvent `json:"events"`
+}
+
+type matrixMessage struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+}
+
+// matrixBridge posts the messages received in the mapped conversations to
+// their rooms and sends the messages posted in the rooms to Signal.
+type matrixBridge struct {
+	config *MatrixBridge
+	client *http.Client
+
+	mutex        sync.Mutex
+	transactions map[string]bool
+	order        []string
+	sequence     int64
+}
+
+func newMatrixBridge(config *MatrixBridge, client *http.Client) *matrixBridge {
+	return &matrixBridge{config: config, client: client, transactions: make(map[string]bool)}
+}
+
+// room returns the room of a conversation of number.
+func (b *matrixBridge) room(number string, peer string) (string, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, room := range b.config.Rooms {
+		if room.Number == number && room.Peer == peer {
+			return room.RoomID, true
+		}
+	}
+	return "", false
+}
+
+// conversation returns the conversation a room is mapped to.
+func (b *matrixBridge) conversation(roomID string) (MatrixRoom, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, room := range b.config.Rooms {
+		if room.RoomID == roomID {
+			return room, true
+		}
+	}
+	return MatrixRoom{}, false
+}
+
+func (b *matrixBridge) purgerName() string {
+	return "matrix rooms"
+}
+
+// purgeContact stops bridging the conversation of number with contact. The
+// room stays in the config file of the bridge, the operator has to remove it
+// there as well, or it is bridged again after a restart.
+func (b *matrixBridge) purgeContact(number string, contact string) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rooms := []MatrixRoom{}
+	for _, room := range b.config.Rooms {
+		if room.Number != number || room.Peer != contact {
+			rooms = append(rooms, room)
+		}
+	}
+	deleted := len(b.config.Rooms) - len(rooms)
+	b.config.Rooms = rooms
+	if deleted > 0 {
+		return deleted, errors.New("Remove the Matrix room of " + contact + " from the config file of the bridge")
+	}
+	return 0, nil
+}
+
+// seen records the id of a transaction and returns whether it was handled
+// before.
+func (b *matrixBridge) seen(id string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.transactions[id] {
+		return true
+	}
+	b.transactions[id] = true
+	b.order = append(b.order, id)
+	if len(b.order) > matrixTransactionsKept {
+		delete(b.transactions, b.order[0])
+		b.order = b.order[1:]
+	}
+	return false
+}
+
+func (b *matrixBridge) transactionID() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.sequence++
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatInt(b.sequence, 36)
+}
+
+// post sends a text message to a room as the bridge user.
+func (b *matrixBridge) post(roomID string, text string) error {
+	body, err := jsoniter.Marshal(matrixMessage{MsgType: "m.text", Body: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, b.config.HomeserverURL+"/_matrix/client/v3/rooms/"+
+		url.PathEscape(roomID)+"/send/m.room.message/"+b.transactionID(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.config.ASToken)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("The homeserver responded with " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+// bridgeReceived posts a message received in a mapped conversation to its
+// room. Messages in groups are prefixed with their sender.
+func (a *Api) bridgeReceived(e busEvent) {
+	msg, ok := e.Data.(incomingMessage)
+	if !ok || msg.Reaction != nil || msg.Typing != nil {
+		return
+	}
+	env, err := msg.envelope()
+	if err != nil || env.DataMessage == nil {
+		return
+	}
+	roomID, ok := a.matrix.room(e.Number, conversationPeer(env))
+	if !ok {
+		return
+	}
+
+	text := env.DataMessage.Message
+	if n := len(env.DataMessage.Attachments); n > 0 {
+		text = strings.TrimSpace(text + " [" + strconv.Itoa(n) + " attachment(s)]")
+	}
+	if text == "" {
+		return
+	}
+	if env.DataMessage.GroupInfo != nil {
+		text = env.Source.Number + ": " + text
+	}
+	if err := a.matrix.post(roomID, text); err != nil {
+		log.Error("Couldn't bridge a message of ", e.Number, " to Matrix room ", roomID, ": ", err.Error())
+	}
+}
+
+// bridgeToSignal sends a message posted in a mapped room to its conversation.
+func (a *Api) bridgeToSignal(event matrixEvent) {
+	if event.Type != "m.room.message" || event.Sender == a.matrix.config.UserID || event.Content.Body == "" {
+		return
+	}
+	room, ok := a.matrix.conversation(event.RoomID)
+	if !ok {
+		return
+	}
+
+	body := event.Content.Body
+	if event.Content.MsgType == "m.emote" {
+		body = "* " + body
+	}
+	_, err := a.service.Submit(service.Send{
+		Number:     room.Number,
+		Message:    body,
+		Recipients: []string{room.Peer},
+		IsGroup:    strings.HasPrefix(room.Peer, groupPrefix),
+		Client:     matrixClient,
+	})
+	if err != nil {
+		log.Error("Couldn't bridge a message of ", event.Sender, " in Matrix room ", event.RoomID, " to Signal: ",
+			err.Error())
+	}
+}
+
+// matrixAuthorized checks the token of the homeserver, which newer
+// homeservers send as bearer token and older ones as query parameter.
+func (a *Api) matrixAuthorized(c *gin.Context) bool {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("access_token")
+	}
+	if token != a.matrix.config.HSToken {
+		c.JSON(403, gin.H{"errcode": "M_FORBIDDEN", "error": "Invalid homeserver token"})
+		return false
+	}
+	return true
+}
+
+// @Summary Receive a transaction of the Matrix homeserver.
+// @Tags Matrix
+// @Description The application service API the homeserver pushes the events of the bridged rooms to. The messages posted in the rooms are sent to the mapped Signal conversations.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} string "{}"
+// @Failure 400 {object} Error
+// @Failure 403 {object} Error
+// @Param txnId path string true "Transaction ID"
+// @Router /_matrix/app/v1/transactions/{txnId} [put]
+func (a *Api) MatrixTransaction(c *gin.Context) {
+	if !a.matrixAuthorized(c) {
+		return
+	}
+
+	txn := matrixTransaction{}
+	if err := c.BindJSON(&txn); err != nil {
+		c.JSON(400, gin.H{"errcode": "M_NOT_JSON", "error": "Couldn't process request - invalid request"})
+		return
+	}
+	if !a.matrix.seen(c.Param("txnId")) {
+		for _, event := range txn.Events {
+			a.bridgeToSignal(event)
+		}
+	}
+	c.JSON(200, gin.H{})
+}
+
+// @Summary Query a user or room alias of the Matrix bridge.
+// @Tags Matrix
+// @Description The bridge maps rooms statically and provides no users or aliases.
+// @Produce  json
+// @Failure 403 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "User ID or Room Alias"
+// @Router /_matrix/app/v1/users/{id} [get]
+func (a *Api) MatrixQuery(c *gin.Context) {
+	if !a.matrixAuthorized(c) {
+		return
+	}
+	c.JSON(404, gin.H{"errcode": "M_NOT_FOUND", "error": "Not provided by the bridge"})
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	messageRequestPending  = "pending"
+	messageRequestAccepted = "accepted"
+	messageRequestBlocked  = "blocked"
+)
+
+const (
+	// eventMessageRequest is published when a sender that isn't a contact
+	// writes for the first time.
+	eventMessageRequest = "message_request"
+	// eventSpamReported is published when a message request is reported as
+	// spam.
+	eventSpamReported = "spam_reported"
+)
+
+// messageRequest is the state of the conversation with a sender that isn't a
+// contact of the number.
+type messageRequest struct {
+	Sender       string    `json:"sender"`
+	State        string    `json:"state"`
+	ReportedSpam bool      `json:"reported_spam,omitempty"`
+	Since        time.Time `json:"since"`
+}
+
+// messageRequests keeps the message requests of the numbers and the decisions
+// about them. Messages of blocked senders are dropped.
+type messageRequests struct {
+	mutex    sync.Mutex
+	state    stateStore
+	requests map[string]map[string]messageRequest
+}
+
+func newMessageRequests(state stateStore) (*messageRequests, error) {
+	r := &messageRequests{state: state, requests: make(map[string]map[string]messageRequest)}
+	if err := state.load(&r.requests); err != nil {
+		return nil, errors.New("Couldn't load message requests: " + err.Error())
+	}
+	return r, nil
+}
+
+// refresh loads the message requests other replicas changed, the caller
+// needs to hold the mutex.
+func (r *messageRequests) refresh() {
+	if _, err := r.state.refresh(&r.requests); err != nil {
+		log.Error("Couldn't load message requests: ", err.Error())
+	}
+}
+
+func (r *messageRequests) get(number string, sender string) (messageRequest, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	request, ok := r.requests[number][sender]
+	return request, ok
+}
+
+// set changes the state of the conversation with sender. Unless force is set
+// only a new conversation is changed.
+func (r *messageRequests) set(number string, sender string, state string, reportedSpam bool, force bool) (messageRequest, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	request := messageRequest{Sender: sender, State: state, ReportedSpam: reportedSpam, Since: time.Now()}
+	err := r.state.update(&r.requests, func(bool) error {
+		if _, ok := r.requests[number][sender]; ok && !force {
+			return errStateUnchanged
+		}
+		if r.requests[number] == nil {
+			r.requests[number] = make(map[string]messageRequest)
+		}
+		r.requests[number][sender] = request
+		return nil
+	})
+	if err == errStateUnchanged {
+		return messageRequest{}, false, nil
+	}
+	return request, true, err
+}
+
+func (r *messageRequests) list(number string, state string) []messageRequest {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	requests := []messageRequest{}
+	for _, request := range r.requests[number] {
+		if state == "" || request.State == state {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Since.Before(requests[j].Since) })
+	return requests
+}
+
+func (r *messageRequests) purgerName() string {
+	return "message requests"
+}
+
+func (r *messageRequests) purgeContact(number string, contact string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err := r.state.update(&r.requests, func(bool) error {
+		if _, ok := r.requests[number][contact]; !ok {
+			return errStateUnchanged
+		}
+		delete(r.requests[number], contact)
+		return nil
+	})
+	if err == errStateUnchanged {
+		return 0, nil
+	}
+	return 1, err
+}
+
+// consume accepts the conversations the number sends messages to, like the
+// Signal apps do.
+func (r *messageRequests) consume(e busEvent) {
+	sent, ok := e.Data.(sentMessage)
+	if !ok || sent.Recipient == "" || sent.GroupID != "" {
+		return
+	}
+	if _, _, err := r.set(e.Number, sent.Recipient, messageRequestAccepted, false, false); err != nil {
+		log.Error("Couldn't save message requests: ", err.Error())
+	}
+}
+
+// isContact returns whether sender is a contact of number.
+func (a *Api) isContact(number string, sender string) (bool, error) {
+	message, err := a.listings.get(contactsListingKey(number), func() (signald.Response, error) {
+		return a.client(number).ListContacts(number)
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, contact := range message.Data.Contacts {
+		if contact.Address.Number == sender {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// messageRequestStage returns the receive stage that flags the direct
+// messages of senders that aren't contacts as message requests and drops the
+// messages of blocked senders.
+func (a *Api) messageRequestStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		e, err := msg.envelope()
+		if err != nil || e.DataMessage == nil || e.DataMessage.GroupInfo != nil || e.Source.Number == "" || e.Source.Number == number {
+			return true
+		}
+
+		request, ok := a.messageRequests.get(number, e.Source.Number)
+		if ok && request.State == messageRequestBlocked {
+			return false
+		}
+		if ok {
+			msg.MessageRequest = request.State == messageRequestPending
+			return true
+		}
+
+		contact, err := a.isContact(number, e.Source.Number)
+		if err != nil {
+			log.Error("Couldn't check whether ", e.Source.Number, " is a contact: ", err.Error())
+			return true
+		}
+		if contact {
+			return true
+		}
+
+		msg.MessageRequest = true
+		request, created, err := a.messageRequests.set(number, e.Source.Number, messageRequestPending, false, false)
+		if err != nil {
+			log.Error("Couldn't save message requests: ", err.Error())
+		}
+		if created {
+			a.bus.publish(busEvent{Type: eventMessageRequest, Number: number, Data: request, Source: msg.Data})
+		}
+		return true
+	}
+}
+
+// @Summary List the message requests of a number.
+// @Tags Messages
+// @Description List the conversations with senders that aren't contacts, by default the pending ones. Messages of pending senders are received with message_request set.
+// @Produce  json
+// @Success 200 {object} []messageRequest
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param state query string false "pending (default), accepted, blocked or all"
+// @Router /v1/message-requests/{number} [get]
+func (a *Api) GetMessageRequests(c *gin.Context) {
+	state := c.DefaultQuery("state", messageRequestPending)
+	switch state {
+	case messageRequestPending, messageRequestAccepted, messageRequestBlocked:
+	case "all":
+		state = ""
+	default:
+		c.JSON(400, gin.H{"error": "Invalid state " + state + " (supported: pending, accepted, blocked, all)"})
+		return
+	}
+	c.JSON(200, a.messageRequests.list(c.Param("number"), state))
+}
+
+func (a *Api) decideMessageRequest(c *gin.Context, state string, reportSpam bool) {
+	number := c.Param("number")
+	request, _, err := a.messageRequests.set(number, c.Param("sender"), state, reportSpam, true)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save message request: " + err.Error()})
+		return
+	}
+	if reportSpam {
+		log.Warn("Messages of ", request.Sender, " to ", number, " reported as spam")
+		a.bus.publish(busEvent{Type: eventSpamReported, Number: number, Data: request})
+	}
+	c.JSON(200, request)
+}
+
+// @Summary Accept a message request.
+// @Tags Messages
+// @Description Accept the conversation with a sender that isn't a contact, its messages are no longer flagged as message request. Accepting a blocked sender unblocks it. Sending a message to a sender accepts it as well.
+// @Produce  json
+// @Success 200 {object} messageRequest
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param sender path string true "Phone Number of the Sender"
+// @Router /v1/message-requests/{number}/{sender}/accept [post]
+func (a *Api) AcceptMessageRequest(c *gin.Context) {
+	a.decideMessageRequest(c, messageRequestAccepted, false)
+}
+
+// @Summary Block a sender.
+// @Tags Messages
+// @Description Block a sender, its messages are dropped when they are received.
+// @Produce  json
+// @Success 200 {object} messageRequest
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param sender path string true "Phone Number of the Sender"
+// @Router /v1/message-requests/{number}/{sender}/block [post]
+func (a *Api) BlockMessageRequest(c *gin.Context) {
+	a.decideMessageRequest(c, messageRequestBlocked, false)
+}
+
+// @Summary Block a sender and report it as spam.
+// @Tags Messages
+// @Description Block a sender and report it as spam. signald can't pass the report on to Signal, so it is logged and emitted as spam_reported event to the webhooks.
+// @Produce  json
+// @Success 200 {object} messageRequest
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param sender path string true "Phone Number of the Sender"
+// @Router /v1/message-requests/{number}/{sender}/report-spam [post]
+func (a *Api) ReportSpam(c *gin.Context) {
+	a.decideMessageRequest(c, messageRequestBlocked, true)
+}
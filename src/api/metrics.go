@@ -0,0 +1,350 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+// The classes of the errors of signald and the Signal servers, so that
+// alerting can tell throttling by Signal from a broken configuration.
+const (
+	errorRateLimited        = "rate_limited"
+	errorCaptchaRequired    = "captcha_required"
+	errorProofRequired      = "proof_required"
+	errorUnregistered       = "unregistered"
+	errorUntrustedIdentity  = "untrusted_identity"
+	errorNetwork            = "network"
+	errorSignaldUnavailable = "signald_unavailable"
+	errorOther              = "other"
+)
+
+// the texts the error classes are recognized by, without spaces and
+// underscores
+var errorClassTexts = []struct {
+	class string
+	texts []string
+}{
+	{errorRateLimited, []string{"ratelimit", "toomanyrequests"}},
+	{errorProofRequired, []string{"proofrequired"}},
+	{errorCaptchaRequired, []string{"captcha"}},
+	{errorUnregistered, []string{"unregistered"}},
+	{errorUntrustedIdentity, []string{"untrustedidentity"}},
+	{errorSignaldUnavailable, []string{"dialunix", "connectionrefused", "nosuchfileordirectory", "brokenpipe"}},
+	{errorNetwork, []string{"networkfailure", "network", "timeout"}},
+}
+
+// The optional labels of the metrics. Every label multiplies the number of
+// time series, with many numbers or recipients they can overwhelm Prometheus.
+const (
+	MetricsLabelNumber    = "number"
+	MetricsLabelRecipient = "recipient"
+)
+
+// DefaultSendDurationBuckets are the buckets of the send duration histogram
+// in seconds.
+var DefaultSendDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+const (
+	contentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// ParseMetricsBuckets parses a comma separated list of increasing histogram
+// buckets in seconds.
+func ParseMetricsBuckets(value string) ([]float64, error) {
+	buckets := []float64{}
+	for _, b := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if err != nil || v <= 0 || (len(buckets) > 0 && v <= buckets[len(buckets)-1]) {
+			return nil, errors.New("Invalid metrics bucket " + b + " (positive and increasing numbers of seconds)")
+		}
+		buckets = append(buckets, v)
+	}
+	return buckets, nil
+}
+
+// signalErrorClass returns the class of an error signald responded with.
+func signalErrorClass(resp signald.Response, err error) string {
+	if resp.Type == "untrusted_identity" {
+		return errorUntrustedIdentity
+	}
+
+	text := strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(err.Error() + resp.Type))
+	for _, c := range errorClassTexts {
+		for _, t := range c.texts {
+			if strings.Contains(text, t) {
+				return c.class
+			}
+		}
+	}
+	return errorOther
+}
+
+type sentKey struct {
+	number    string
+	recipient string
+}
+
+type errorKey struct {
+	number    string
+	operation string
+	class     string
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// metrics counts the sent and received messages and the errors of signald by
+// class, exposed in the Prometheus or OpenMetrics text format. The counters
+// are only labeled with the number and the recipient if these labels are
+// enabled, otherwise they are summed up.
+type metrics struct {
+	mutex          sync.Mutex
+	numberLabel    bool
+	recipientLabel bool
+	sent           map[sentKey]int64
+	received       map[string]int64
+	errors         map[errorKey]int64
+	sendDuration   histogram
+}
+
+func newMetrics(buckets []float64, labels []string) (*metrics, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultSendDurationBuckets
+	}
+	m := &metrics{
+		sent:         make(map[sentKey]int64),
+		received:     make(map[string]int64),
+		errors:       make(map[errorKey]int64),
+		sendDuration: histogram{buckets: buckets, counts: make([]int64, len(buckets))},
+	}
+	for _, l := range labels {
+		switch strings.TrimSpace(l) {
+		case MetricsLabelNumber:
+			m.numberLabel = true
+		case MetricsLabelRecipient:
+			m.recipientLabel = true
+		default:
+			return nil, errors.New("Invalid metrics label " + l + " (supported: number, recipient)")
+		}
+	}
+	return m, nil
+}
+
+// number returns the value of the number label, empty if it is disabled.
+func (m *metrics) number(number string) string {
+	if !m.numberLabel {
+		return ""
+	}
+	return number
+}
+
+// observeSend records a send request to signald. The results of the
+// recipients are checked as well, as signald reports failures of single
+// recipients without an error.
+func (m *metrics) observeSend(number string, recipient string, duration time.Duration, resp signald.Response, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	number = m.number(number)
+	if !m.recipientLabel {
+		recipient = ""
+	}
+
+	m.sendDuration.observe(duration.Seconds())
+	if err != nil {
+		m.errors[errorKey{number, "send", signalErrorClass(resp, err)}]++
+		return
+	}
+	m.sent[sentKey{number, recipient}]++
+	for _, result := range resp.Data.SendResults {
+		if result.UnregisteredFailure {
+			m.errors[errorKey{number, "send", errorUnregistered}]++
+		} else if result.NetworkFailure {
+			m.errors[errorKey{number, "send", errorNetwork}]++
+		}
+	}
+}
+
+func (m *metrics) purgerName() string {
+	return "metrics"
+}
+
+// purgeContact drops the counters of the messages number sent to contact,
+// they only exist if the recipient label is enabled.
+func (m *metrics) purgeContact(number string, contact string) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	for key := range m.sent {
+		if key.recipient == contact && key.number == m.number(number) {
+			delete(m.sent, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *metrics) observeReceived(number string, n int) {
+	if n == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.received[m.number(number)] += int64(n)
+}
+
+// observeError records a failed request to signald other than sending.
+func (m *metrics) observeError(number string, operation string, resp signald.Response, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.errors[errorKey{m.number(number), operation, signalErrorClass(resp, err)}]++
+}
+
+// exposition writes the metrics in the Prometheus text format or, if
+// openMetrics is set, in the OpenMetrics text format.
+type exposition struct {
+	b           *bytes.Buffer
+	openMetrics bool
+}
+
+// header writes the metadata of a metric. In OpenMetrics the name of a
+// counter doesn't include the _total suffix of its samples.
+func (x exposition) header(name string, kind string, unit string, help string) {
+	if x.openMetrics && kind == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	x.b.WriteString("# HELP " + name + " " + help + "\n")
+	x.b.WriteString("# TYPE " + name + " " + kind + "\n")
+	if x.openMetrics && unit != "" {
+		x.b.WriteString("# UNIT " + name + " " + unit + "\n")
+	}
+}
+
+// sample writes a sample with the labels given as pairs of name and value,
+// labels with an empty value are left out.
+func (x exposition) sample(name string, value string, labels ...string) {
+	set := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] != "" {
+			set = append(set, labels[i]+`="`+labelValue(labels[i+1])+`"`)
+		}
+	}
+	if len(set) > 0 {
+		name += "{" + strings.Join(set, ",") + "}"
+	}
+	x.b.WriteString(name + " " + value + "\n")
+}
+
+func labelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (m *metrics) write(x exposition) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	x.header("signald_rest_api_messages_sent_total", "counter", "", "Messages sent to signald successfully.")
+	sent := []sentKey{}
+	for k := range m.sent {
+		sent = append(sent, k)
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		if sent[i].number != sent[j].number {
+			return sent[i].number < sent[j].number
+		}
+		return sent[i].recipient < sent[j].recipient
+	})
+	for _, k := range sent {
+		x.sample("signald_rest_api_messages_sent_total", strconv.FormatInt(m.sent[k], 10), "number", k.number, "recipient", k.recipient)
+	}
+
+	x.header("signald_rest_api_messages_received_total", "counter", "", "Messages received from signald.")
+	numbers := []string{}
+	for number := range m.received {
+		numbers = append(numbers, number)
+	}
+	sort.Strings(numbers)
+	for _, number := range numbers {
+		x.sample("signald_rest_api_messages_received_total", strconv.FormatInt(m.received[number], 10), "number", number)
+	}
+
+	x.header("signald_rest_api_signal_errors_total", "counter", "",
+		"Errors of signald and the Signal servers by operation and class (rate_limited, proof_required, captcha_required, unregistered, untrusted_identity, network, signald_unavailable, other).")
+	keys := []errorKey{}
+	for k := range m.errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].number != keys[j].number {
+			return keys[i].number < keys[j].number
+		}
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].class < keys[j].class
+	})
+	for _, k := range keys {
+		x.sample("signald_rest_api_signal_errors_total", strconv.FormatInt(m.errors[k], 10),
+			"number", k.number, "operation", k.operation, "class", k.class)
+	}
+
+	name := "signald_rest_api_send_duration_seconds"
+	x.header(name, "histogram", "seconds", "Duration of the send requests to signald.")
+	for i, bucket := range m.sendDuration.buckets {
+		x.sample(name+"_bucket", strconv.FormatInt(m.sendDuration.counts[i], 10), "le", formatFloat(bucket))
+	}
+	x.sample(name+"_bucket", strconv.FormatInt(m.sendDuration.count, 10), "le", "+Inf")
+	x.sample(name+"_sum", formatFloat(m.sendDuration.sum))
+	x.sample(name+"_count", strconv.FormatInt(m.sendDuration.count, 10))
+
+	if x.openMetrics {
+		x.b.WriteString("# EOF\n")
+	}
+}
+
+// @Summary Get the metrics of the API.
+// @Tags General
+// @Description Metrics in the Prometheus text format, or in the OpenMetrics text format if the Accept header asks for it: the sent and received messages, the errors of signald and the Signal servers by class (e.g. rate_limited when Signal throttles) and the duration of send requests. The metrics are labeled by number and recipient if the labels are enabled with -metrics-labels.
+// @Produce  plain
+// @Success 200 {string} string "Metrics"
+// @Router /metrics [get]
+func (a *Api) Metrics(c *gin.Context) {
+	x := exposition{b: &bytes.Buffer{}, openMetrics: strings.Contains(c.GetHeader("Accept"), "application/openmetrics-text")}
+	a.metrics.write(x)
+	if x.openMetrics {
+		c.Data(200, contentTypeOpenMetrics, x.b.Bytes())
+		return
+	}
+	c.Data(200, contentTypePrometheus, x.b.Bytes())
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// page is the part of a listing requested with the limit and offset query
+// parameters.
+type page struct {
+	offset int
+	limit  int
+}
+
+func parsePage(c *gin.Context) (page, error) {
+	p := page{}
+
+	var err error
+	if value := c.Query("offset"); value != "" {
+		if p.offset, err = strconv.Atoi(value); err != nil || p.offset < 0 {
+			return p, errors.New("Invalid offset " + value)
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if p.limit, err = strconv.Atoi(value); err != nil || p.limit < 1 {
+			return p, errors.New("Invalid limit " + value)
+		}
+	}
+	return p, nil
+}
+
+// bounds returns the slice bounds of the page in a listing with total
+// entries and sets the X-Total-Count header.
+func (p page) bounds(c *gin.Context, total int) (int, int) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	start := p.offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if p.limit > 0 && start+p.limit < end {
+		end = start + p.limit
+	}
+	return start, end
+}
+
+// matchesName implements the name filter of listings, a case-insensitive
+// substring match.
+func matchesName(name string, filter string) bool {
+	return filter == "" || strings.Contains(strings.ToLower(name), strings.ToLower(filter))
+}
@@ -0,0 +1,416 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pollMinOptions = 2
+	pollMaxOptions = 10
+)
+
+type createPollRequest struct {
+	Number   string   `json:"number"`
+	Group    string   `json:"group"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Duration after which the poll stops counting votes, e.g. "24h". Polls
+	// without duration are open until they are deleted.
+	Duration string `json:"duration,omitempty"`
+}
+
+// poll is a question sent to a group. Replies with the number (or keycap
+// emoji, or text) of an option count as votes, the last vote of every member
+// counts.
+type poll struct {
+	ID              string         `json:"id"`
+	Number          string         `json:"number"`
+	InternalGroupID string         `json:"internal_group_id"`
+	Question        string         `json:"question"`
+	Options         []string       `json:"options"`
+	Votes           map[string]int `json:"votes"`
+	Created         time.Time      `json:"created"`
+	Closes          *time.Time     `json:"closes,omitempty"`
+}
+
+type pollOption struct {
+	Option string `json:"option"`
+	Votes  int    `json:"votes"`
+}
+
+type pollResults struct {
+	ID       string       `json:"id"`
+	Number   string       `json:"number"`
+	GroupID  string       `json:"group_id"`
+	Question string       `json:"question"`
+	Open     bool         `json:"open"`
+	Created  time.Time    `json:"created"`
+	Closes   *time.Time   `json:"closes,omitempty"`
+	Voters   int          `json:"voters"`
+	Options  []pollOption `json:"options"`
+}
+
+func (p *poll) open(now time.Time) bool {
+	return p.Closes == nil || now.Before(*p.Closes)
+}
+
+// text returns the message the poll is sent as.
+func (p *poll) text() string {
+	lines := []string{"📊 " + p.Question}
+	for i, option := range p.Options {
+		lines = append(lines, strconv.Itoa(i+1)+". "+option)
+	}
+	lines = append(lines, "", "Reply with the number of your answer.")
+	return strings.Join(lines, "\n")
+}
+
+// keycapReplacer turns keycap emoji (digit, variation selector, combining
+// enclosing keycap) into plain digits.
+var keycapReplacer = strings.NewReplacer("\ufe0f", "", "\u20e3", "", "\U0001f51f", "10")
+
+// vote returns the option a reply votes for.
+func (p *poll) vote(reply string) (int, bool) {
+	reply = strings.TrimSpace(keycapReplacer.Replace(reply))
+	if n, err := strconv.Atoi(strings.TrimSuffix(reply, ".")); err == nil {
+		return n - 1, n >= 1 && n <= len(p.Options)
+	}
+	for i, option := range p.Options {
+		if strings.EqualFold(reply, option) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (p *poll) results(now time.Time) pollResults {
+	r := pollResults{
+		ID:       p.ID,
+		Number:   p.Number,
+		GroupID:  convertInternalGroupIDToGroupID(p.InternalGroupID),
+		Question: p.Question,
+		Open:     p.open(now),
+		Created:  p.Created,
+		Closes:   p.Closes,
+		Voters:   len(p.Votes),
+		Options:  []pollOption{},
+	}
+	for _, option := range p.Options {
+		r.Options = append(r.Options, pollOption{Option: option})
+	}
+	for _, vote := range p.Votes {
+		r.Options[vote].Votes++
+	}
+	return r
+}
+
+// pollRegistry holds the polls, they are persisted in the data dir.
+type pollRegistry struct {
+	mutex sync.Mutex
+	state stateStore
+	polls map[string]*poll
+}
+
+func newPollRegistry(state stateStore) (*pollRegistry, error) {
+	r := &pollRegistry{state: state, polls: make(map[string]*poll)}
+
+	polls := []*poll{}
+	if err := state.load(&polls); err != nil {
+		return nil, errors.New("Couldn't load polls: " + err.Error())
+	}
+	r.setPolls(polls)
+	return r, nil
+}
+
+// setPolls replaces the polls, the caller needs to hold the mutex.
+func (r *pollRegistry) setPolls(polls []*poll) {
+	r.polls = make(map[string]*poll)
+	for _, p := range polls {
+		r.polls[p.ID] = p
+	}
+}
+
+// document returns the polls as they are persisted, the caller needs to hold
+// the mutex.
+func (r *pollRegistry) document() []*poll {
+	polls := []*poll{}
+	for _, p := range r.polls {
+		polls = append(polls, p)
+	}
+	sort.Slice(polls, func(i, j int) bool { return polls[i].Created.Before(polls[j].Created) })
+	return polls
+}
+
+// refresh loads the polls other replicas changed, the caller needs to hold
+// the mutex.
+func (r *pollRegistry) refresh() {
+	polls := []*poll{}
+	reloaded, err := r.state.refresh(&polls)
+	if err != nil {
+		log.Error("Couldn't load polls: ", err.Error())
+		return
+	}
+	if reloaded {
+		r.setPolls(polls)
+	}
+}
+
+// update changes the polls and saves them, the caller needs to hold the
+// mutex.
+func (r *pollRegistry) update(change func() error) error {
+	polls := r.document()
+	return r.state.update(&polls, func(reloaded bool) error {
+		if reloaded {
+			r.setPolls(polls)
+		}
+		if err := change(); err != nil {
+			return err
+		}
+		polls = r.document()
+		return nil
+	})
+}
+
+func (r *pollRegistry) put(p *poll) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() error {
+		r.polls[p.ID] = p
+		return nil
+	})
+}
+
+func (r *pollRegistry) results(id string) (pollResults, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	p, ok := r.polls[id]
+	if !ok {
+		return pollResults{}, false
+	}
+	return p.results(time.Now()), true
+}
+
+func (r *pollRegistry) remove(id string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err := r.update(func() error {
+		if _, ok := r.polls[id]; !ok {
+			return errStateUnchanged
+		}
+		delete(r.polls, id)
+		return nil
+	})
+	if err == errStateUnchanged {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// vote counts a reply in the newest open poll of the group it was sent to and
+// returns the id of the poll.
+func (r *pollRegistry) vote(number string, internalGroupID string, voter string, reply string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := ""
+	err := r.update(func() error {
+		now := time.Now()
+		var newest *poll
+		for _, p := range r.polls {
+			if p.Number == number && p.InternalGroupID == internalGroupID && p.open(now) &&
+				(newest == nil || p.Created.After(newest.Created)) {
+				newest = p
+			}
+		}
+		if newest == nil {
+			return errStateUnchanged
+		}
+
+		option, ok := newest.vote(reply)
+		if !ok {
+			return errStateUnchanged
+		}
+		newest.Votes[voter] = option
+		id = newest.ID
+		return nil
+	})
+	if err == errStateUnchanged {
+		return "", nil
+	}
+	return id, err
+}
+
+func (r *pollRegistry) purgerName() string {
+	return "polls"
+}
+
+func (r *pollRegistry) purgeContact(number string, contact string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := 0
+	err := r.update(func() error {
+		deleted = 0
+		for _, p := range r.polls {
+			if _, ok := p.Votes[contact]; ok && p.Number == number {
+				delete(p.Votes, contact)
+				deleted++
+			}
+		}
+		if deleted == 0 {
+			return errStateUnchanged
+		}
+		return nil
+	})
+	if err == errStateUnchanged {
+		return 0, nil
+	}
+	return deleted, err
+}
+
+// pollStage returns the receive stage that counts replies to polls.
+func (a *Api) pollStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		e, err := msg.envelope()
+		if err != nil || e.DataMessage == nil || e.DataMessage.GroupInfo == nil || e.Source.Number == "" {
+			return true
+		}
+
+		id, err := a.polls.vote(number, e.DataMessage.GroupInfo.GroupID, e.Source.Number, e.DataMessage.Message)
+		if err != nil {
+			log.Error("Couldn't save poll vote: ", err.Error())
+		}
+		if id != "" {
+			msg.Tags = append(msg.Tags, "poll_vote:"+id)
+		}
+		return true
+	}
+}
+
+// @Summary Create a poll.
+// @Tags Polls
+// @Description Send a question with numbered options to a group. Replies of the members with the number of an option (also as keycap emoji) or its text are counted as votes, the last vote of every member counts.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} pollResults
+// @Failure 400 {object} Error
+// @Param data body createPollRequest true "Poll"
+// @Router /v1/polls [post]
+func (a *Api) CreatePoll(c *gin.Context) {
+	req := createPollRequest{}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	if req.Number == "" || req.Group == "" || req.Question == "" {
+		c.JSON(400, gin.H{"error": "Please provide a number, group and question"})
+		return
+	}
+	if len(req.Options) < pollMinOptions || len(req.Options) > pollMaxOptions {
+		c.JSON(400, gin.H{"error": "Please provide " + strconv.Itoa(pollMinOptions) + " to " +
+			strconv.Itoa(pollMaxOptions) + " options"})
+		return
+	}
+
+	release, ok := a.acquireBackend(c, req.Number)
+	if !ok {
+		return
+	}
+	defer release()
+
+	internalID, err := a.resolveGroupID(req.Number, req.Group)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	p := &poll{
+		ID:              id,
+		Number:          req.Number,
+		InternalGroupID: internalID,
+		Question:        req.Question,
+		Options:         req.Options,
+		Votes:           make(map[string]int),
+		Created:         time.Now(),
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			c.JSON(400, gin.H{"error": "Invalid duration " + req.Duration})
+			return
+		}
+		closes := p.Created.Add(d)
+		p.Closes = &closes
+	}
+
+	start := time.Now()
+	_, err = a.sendMessage(req.Number, signald.RequestAddress{}, internalID, p.text(), nil, milliseconds(start))
+	a.stats.sent(req.Number, time.Since(start), err)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := a.polls.put(p); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save poll: " + err.Error()})
+		return
+	}
+	c.JSON(201, p.results(time.Now()))
+}
+
+// @Summary Get the results of a poll.
+// @Tags Polls
+// @Description Get the number of votes of every option.
+// @Produce  json
+// @Success 200 {object} pollResults
+// @Failure 404 {object} Error
+// @Param id path string true "Poll ID"
+// @Router /v1/polls/{id} [get]
+func (a *Api) GetPoll(c *gin.Context) {
+	results, ok := a.polls.results(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such poll"})
+		return
+	}
+	c.JSON(200, results)
+}
+
+// @Summary Delete a poll.
+// @Tags Polls
+// @Description Delete a poll and its votes.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Poll ID"
+// @Router /v1/polls/{id} [delete]
+func (a *Api) DeletePoll(c *gin.Context) {
+	ok, err := a.polls.remove(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save polls: " + err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(404, gin.H{"error": "No such poll"})
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// The phone number privacy modes of Signal, shared with or discoverable by
+// everybody or nobody.
+const (
+	privacyEverybody = "everybody"
+	privacyNobody    = "nobody"
+)
+
+// privacySettings are the phone number privacy settings of a Signal account:
+// who sees the number in chats and who can find the account by the number.
+type privacySettings struct {
+	PhoneNumberSharing   string `json:"phone_number_sharing"`
+	DiscoverableByNumber string `json:"discoverable_by_number"`
+}
+
+// privacyUpdate changes the privacy settings given.
+type privacyUpdate struct {
+	// PhoneNumberSharing is everybody or nobody.
+	PhoneNumberSharing *string `json:"phone_number_sharing"`
+	// DiscoverableByNumber is everybody or nobody.
+	DiscoverableByNumber *string `json:"discoverable_by_number"`
+}
+
+// defaultPrivacySettings are the settings of Signal accounts that weren't
+// changed.
+var defaultPrivacySettings = privacySettings{PhoneNumberSharing: privacyEverybody, DiscoverableByNumber: privacyEverybody}
+
+func validPrivacyMode(mode string) bool {
+	return mode == privacyEverybody || mode == privacyNobody
+}
+
+// apply returns the settings with the update applied.
+func (s privacySettings) apply(u privacyUpdate) (privacySettings, error) {
+	if u.PhoneNumberSharing != nil {
+		if !validPrivacyMode(*u.PhoneNumberSharing) {
+			return s, errors.New("Invalid phone number sharing " + *u.PhoneNumberSharing + " (supported: everybody, nobody)")
+		}
+		s.PhoneNumberSharing = *u.PhoneNumberSharing
+	}
+	if u.DiscoverableByNumber != nil {
+		if !validPrivacyMode(*u.DiscoverableByNumber) {
+			return s, errors.New("Invalid discoverability " + *u.DiscoverableByNumber + " (supported: everybody, nobody)")
+		}
+		s.DiscoverableByNumber = *u.DiscoverableByNumber
+	}
+	// Signal only hides the number from people who can find the account by
+	// it if it isn't shared
+	if s.DiscoverableByNumber == privacyNobody && s.PhoneNumberSharing != privacyNobody {
+		return s, errors.New("An account can only be hidden from discovery by number if its phone number isn't shared")
+	}
+	return s, nil
+}
+
+// privacyRegistry remembers the privacy settings last set for the numbers,
+// signald can't read them back.
+type privacyRegistry struct {
+	mutex    sync.Mutex
+	state    stateStore
+	settings map[string]privacySettings
+}
+
+func newPrivacyRegistry(state stateStore) (*privacyRegistry, error) {
+	r := &privacyRegistry{state: state, settings: make(map[string]privacySettings)}
+	if err := state.load(&r.settings); err != nil {
+		return nil, errors.New("Couldn't load privacy settings: " + err.Error())
+	}
+	return r, nil
+}
+
+func (r *privacyRegistry) get(number string) privacySettings {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, err := r.state.refresh(&r.settings); err != nil {
+		log.Error("Couldn't load privacy settings: ", err.Error())
+	}
+	if settings, ok := r.settings[number]; ok {
+		return settings
+	}
+	return defaultPrivacySettings
+}
+
+func (r *privacyRegistry) put(number string, settings privacySettings) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.state.update(&r.settings, func(bool) error {
+		r.settings[number] = settings
+		return nil
+	})
+}
+
+// updatePrivacy changes the privacy settings of number in signald.
+func (a *Api) updatePrivacy(number string, update privacyUpdate) (privacySettings, privacySettings, error) {
+	previous := a.privacy.get(number)
+	settings, err := previous.apply(update)
+	if err != nil {
+		return previous, previous, err
+	}
+	if err := a.supports(number, capabilityPrivacySettings); err != nil {
+		return previous, previous, err
+	}
+
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":                   "set_phone_number_privacy",
+		"account":                number,
+		"phone_number_sharing":   settings.PhoneNumberSharing,
+		"discoverable_by_number": settings.DiscoverableByNumber == privacyEverybody,
+	})
+	if err != nil {
+		return previous, previous, service.NewError(service.Failed, err.Error())
+	}
+	if resp.Type != "set_phone_number_privacy" {
+		return previous, previous, service.NewError(service.Failed,
+			"Couldn't change the privacy settings: "+signaldError(resp).Error())
+	}
+
+	if err := a.privacy.put(number, settings); err != nil {
+		log.Error("Couldn't save privacy settings: ", err.Error())
+	}
+	return previous, settings, nil
+}
+
+// @Summary Get the privacy settings of a number.
+// @Tags Accounts
+// @Description Get the phone number privacy settings last set via the API: who sees the phone number (phone_number_sharing) and who can find the account by it (discoverable_by_number). Numbers whose settings weren't changed via the API show the defaults of Signal.
+// @Produce  json
+// @Success 200 {object} privacySettings
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Router /v1/accounts/{number}/settings [get]
+func (a *Api) GetPrivacySettings(c *gin.Context) {
+	c.JSON(200, a.privacy.get(c.Param("number")))
+}
+
+// @Summary Change the privacy settings of a number.
+// @Tags Accounts
+// @Description Change who sees the phone number (phone_number_sharing: everybody or nobody) and who can find the account by it (discoverable_by_number: everybody or nobody). Settings that aren't given are left unchanged. An account can only be hidden from discovery if its number isn't shared. Backends that don't support the settings respond with 501.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} privacySettings
+// @Failure 400 {object} Error
+// @Failure 501 {object} Error
+// @Failure 502 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body privacyUpdate true "Privacy Settings"
+// @Router /v1/accounts/{number}/settings [put]
+func (a *Api) UpdatePrivacySettings(c *gin.Context) {
+	number := c.Param("number")
+
+	update := privacyUpdate{}
+	if err := c.BindJSON(&update); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	previous, settings, err := a.updatePrivacy(number, update)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	a.audit.record(c, "account_privacy.update", number, previous, settings)
+	c.JSON(200, settings)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultProcessorTimeout = 5 * time.Second
+
+// ReceiveProcessor is an HTTP endpoint incoming messages are posted to. If
+// Match is set only messages whose text matches the regular expression are
+// posted, which allows routing e.g. "/status" to a dedicated handler.
+type ReceiveProcessor struct {
+	URL     string `json:"url"`
+	Match   string `json:"match"`
+	Timeout string `json:"timeout"`
+
+	match  *regexp.Regexp
+	client *http.Client
+}
+
+type processorRequest struct {
+	Number   string      `json:"number"`
+	Envelope interface{} `json:"envelope"`
+	Tags     []string    `json:"tags,omitempty"`
+}
+
+// processorResponse is the (optional) answer of a processor. An empty response
+// leaves the message as is.
+type processorResponse struct {
+	Drop     bool        `json:"drop"`
+	Tags     []string    `json:"tags"`
+	Envelope interface{} `json:"envelope"`
+}
+
+// LoadReceiveProcessors reads the list of receive processors from a JSON file.
+func LoadReceiveProcessors(filename string) ([]ReceiveProcessor, error) {
+	processors := []ReceiveProcessor{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return processors, err
+	}
+
+	if err := jsoniter.Unmarshal(data, &processors); err != nil {
+		return processors, errors.New("Couldn't parse receive processors config: " + err.Error())
+	}
+
+	return processors, nil
+}
+
+func (p *ReceiveProcessor) init(e *egress) error {
+	if p.URL == "" {
+		return errors.New("Receive processor without url")
+	}
+
+	if p.Match != "" {
+		match, err := regexp.Compile(p.Match)
+		if err != nil {
+			return errors.New("Invalid match expression of receive processor " + p.URL + ": " + err.Error())
+		}
+		p.match = match
+	}
+
+	timeout := defaultProcessorTimeout
+	if p.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return errors.New("Invalid timeout of receive processor " + p.URL + ": " + err.Error())
+		}
+	}
+	p.client = e.client(timeout)
+
+	return nil
+}
+
+func (p *ReceiveProcessor) call(number string, msg *incomingMessage) (processorResponse, error) {
+	procResp := processorResponse{}
+
+	body, err := jsoniter.Marshal(processorRequest{Number: number, Envelope: msg.Data, Tags: msg.Tags})
+	if err != nil {
+		return procResp, err
+	}
+
+	resp, err := p.client.Post(p.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return procResp, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return procResp, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return procResp, errors.New("Failed with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	if len(bytes.TrimSpace(data)) != 0 {
+		err = jsoniter.Unmarshal(data, &procResp)
+	}
+	return procResp, err
+}
+
+// stage returns the receive stage for the processor. Messages are passed on
+// unchanged if the processor can't be reached, so that nothing gets lost.
+func (p *ReceiveProcessor) stage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		if p.match != nil && !p.match.MatchString(msg.body()) {
+			return true
+		}
+
+		resp, err := p.call(number, msg)
+		if err != nil {
+			log.Error("Receive processor ", p.URL, " failed: ", err.Error())
+			return true
+		}
+
+		if resp.Drop {
+			return false
+		}
+		if resp.Envelope != nil {
+			msg.Data = resp.Envelope
+		}
+		msg.Tags = append(msg.Tags, resp.Tags...)
+		return true
+	}
+}
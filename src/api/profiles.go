@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/filetype"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// recipientAddress returns the signald address of a recipient given as phone
+// number or UUID.
+func recipientAddress(recipient string) map[string]string {
+	if uuidPattern.MatchString(recipient) {
+		return map[string]string{"uuid": recipient}
+	}
+	return map[string]string{"number": recipient}
+}
+
+// getProfile fetches the profile of recipient, signald decrypts the avatar
+// and stores it in its avatar directory.
+func (a *Api) getProfile(number string, recipient string) (map[string]interface{}, error) {
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":    "get_profile",
+		"account": number,
+		"address": recipientAddress(recipient),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Type != "get_profile" {
+		return nil, errors.New("Couldn't get profile: " + signaldError(resp).Error())
+	}
+
+	profile, _ := resp.Data.(map[string]interface{})
+	return profile, nil
+}
+
+// @Summary Get the profile avatar of a contact.
+// @Tags Contacts
+// @Description Get the profile avatar of a contact as image. signald's avatar directory needs to be accessible by the REST API. Supports If-None-Match with the returned ETag.
+// @Produce  image/jpeg
+// @Success 200 {string} string "Avatar"
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param recipient path string true "Phone Number or UUID of the Contact"
+// @Router /v1/profiles/{number}/{recipient}/avatar [get]
+func (a *Api) GetAvatar(c *gin.Context) {
+	profile, err := a.getProfile(c.Param("number"), c.Param("recipient"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename, _ := profile["avatar"].(string)
+	if filename == "" {
+		c.JSON(404, gin.H{"error": "The contact has no avatar"})
+		return
+	}
+
+	avatar, err := ioutil.ReadFile(filename)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't read avatar: " + err.Error()})
+		return
+	}
+
+	contentType := "application/octet-stream"
+	if kind, err := filetype.Match(avatar); err == nil && kind != filetype.Unknown {
+		contentType = kind.MIME.Value
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	respondDataWithETag(c, contentType, avatar)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// autoLinkTimeout is how long the provisioner may take to add the API as
+	// device.
+	autoLinkTimeout = 30 * time.Second
+	// autoLinkWait is how long auto linking waits for signald to come up.
+	autoLinkWait     = 5 * time.Minute
+	autoLinkInterval = 5 * time.Second
+)
+
+// importAccountArchive extracts a tar.gz archive of a signald data directory
+// (e.g. exported from another container) into dataDir, so that signald comes
+// up with the accounts. Files that already exist are kept, so that restarting
+// a container doesn't reset its accounts.
+func importAccountArchive(archive string, dataDir string) error {
+	if dataDir == "" {
+		return errors.New("Importing an account archive needs the signald data dir (-signald-data-dir)")
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return errors.New("Couldn't open account archive: " + err.Error())
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.New("Couldn't read account archive: " + err.Error())
+	}
+	defer gz.Close()
+
+	imported, kept := 0, 0
+	r := tar.NewReader(gz)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("Couldn't read account archive: " + err.Error())
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return errors.New("Invalid path " + header.Name + " in account archive")
+		}
+		target := filepath.Join(dataDir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if _, err := os.Stat(target); err == nil {
+				kept++
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, r)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(target)
+				return errors.New("Couldn't extract " + header.Name + " from account archive: " + err.Error())
+			}
+			imported++
+		default:
+			// links and devices have no place in a signald data dir
+			log.Warn("Skipped ", header.Name, " in account archive, it isn't a file or directory")
+		}
+	}
+	log.Info("Imported account archive: ", imported, " files extracted, ", kept, " existing files kept")
+	return nil
+}
+
+// autoLink links the API as device of an existing account on startup, if
+// signald has no accounts yet. The linking URI is posted as {"uri": "..."} to
+// the provisioner, e.g. /v1/devices/{number} of the API the primary device
+// of the account runs on, which adds the device.
+func (a *Api) autoLink(provisionerURL string, deviceName string, client *http.Client) {
+	deadline := time.Now().Add(autoLinkWait)
+	for {
+		message, err := a.listAccounts()
+		if err == nil {
+			if len(message.Data.Accounts) > 0 {
+				log.Info("Not linking on startup, signald already has an account")
+				return
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			log.Error("Couldn't link on startup, signald isn't reachable: ", err.Error())
+			return
+		}
+		time.Sleep(autoLinkInterval)
+	}
+
+	session, uri, err := a.links.start(deviceName)
+	if err != nil {
+		log.Error("Couldn't link on startup: ", err.Error())
+		return
+	}
+
+	body, err := jsoniter.Marshal(addDeviceRequest{URI: uri})
+	if err != nil {
+		log.Error("Couldn't link on startup: ", err.Error())
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, provisionerURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error("Couldn't link on startup, invalid provisioner URL: ", err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Error("Couldn't link on startup, the provisioner failed: ", err.Error())
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Error("Couldn't link on startup, the provisioner responded with " + strconv.Itoa(resp.StatusCode))
+		return
+	}
+	log.Info("Linking on startup as ", deviceName, ", link session ", session.ID)
+}
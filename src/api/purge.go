@@ -0,0 +1,189 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dataPurger is implemented by every subsystem that keeps data about the
+// contacts of an account, so that a data subject deletion request reaches
+// all of them.
+type dataPurger interface {
+	purgerName() string
+	purgeContact(number string, contact string) (int, error)
+}
+
+// mentions returns whether text contains value on its own, not as part of a
+// longer number or identifier. Values in URL encoded text are found as well.
+func mentions(text string, value string) bool {
+	if value == "" {
+		return false
+	}
+	alphanumeric := func(i int) bool {
+		if i < 0 || i >= len(text) {
+			return false
+		}
+		b := text[i]
+		return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	for _, v := range []string{value, url.QueryEscape(value)} {
+		for offset := 0; offset < len(text); {
+			i := strings.Index(text[offset:], v)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			if !alphanumeric(start-1) && !alphanumeric(start+len(v)) {
+				return true
+			}
+			offset = start + 1
+		}
+	}
+	return false
+}
+
+type purgeResult struct {
+	Subsystem string `json:"subsystem"`
+	Deleted   int    `json:"deleted"`
+	Error     string `json:"error,omitempty"`
+}
+
+type deletionReport struct {
+	Number     string        `json:"number"`
+	Contact    string        `json:"contact"`
+	Subsystems []purgeResult `json:"subsystems"`
+}
+
+type purgerRegistry struct {
+	mutex   sync.Mutex
+	purgers []dataPurger
+}
+
+func (r *purgerRegistry) register(p dataPurger) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.purgers = append(r.purgers, p)
+}
+
+func (r *purgerRegistry) purge(number string, contact string) (deletionReport, bool) {
+	r.mutex.Lock()
+	purgers := append([]dataPurger{}, r.purgers...)
+	r.mutex.Unlock()
+
+	report := deletionReport{Number: number, Contact: contact, Subsystems: []purgeResult{}}
+	ok := true
+	for _, p := range purgers {
+		deleted, err := p.purgeContact(number, contact)
+		result := purgeResult{Subsystem: p.purgerName(), Deleted: deleted}
+		if err != nil {
+			result.Error = err.Error()
+			ok = false
+		}
+		report.Subsystems = append(report.Subsystems, result)
+	}
+	return report, ok
+}
+
+// @Summary Delete all data stored about a contact.
+// @Tags Data
+// @Description Purges all data the API keeps about the given contact of the registered number and returns a deletion report. The request needs to be confirmed with confirm=true or a token from a preflight request (preflight=true).
+// @Produce  json
+// @Success 200 {object} deletionReport
+// @Failure 400 {object} Error
+// @Failure 428 {object} Error
+// @Failure 500 {object} deletionReport
+// @Param number path string true "Registered Phone Number"
+// @Param contact path string true "Contact Phone Number"
+// @Param preflight query bool false "Return a confirmation token instead of deleting"
+// @Param confirmation query string false "Confirmation token of a preflight request"
+// @Param confirm query bool false "Confirm the deletion without a token"
+// @Router /v1/data/{number}/{contact} [delete]
+func (a *Api) DeleteContactData(c *gin.Context) {
+	number := c.Param("number")
+	if number == "" {
+		c.JSON(400, gin.H{"error": "Please provide a number"})
+		return
+	}
+
+	contact := c.Param("contact")
+	if contact == "" {
+		c.JSON(400, gin.H{"error": "Please provide a contact"})
+		return
+	}
+
+	report, ok := a.purgers.purge(number, contact)
+	if !ok {
+		c.JSON(500, report)
+		return
+	}
+	c.JSON(200, report)
+}
+
+// keepsNoContactData are the subsystems of the Api that don't need a purger,
+// with the reason why.
+var keepsNoContactData = map[string]string{
+	"backends":      "routes numbers to signald sockets",
+	"attachments":   "stages files by hash, without their recipients",
+	"uploads":       "holds partial uploads by id, without their recipients",
+	"sendHooks":     "keeps no data",
+	"commands":      "keeps the commands of the accounts",
+	"groupStates":   "keeps the members of groups like signald, removing one would emit a leave event",
+	"accounts":      "keeps the settings of the accounts",
+	"stats":         "counts messages per account",
+	"bus":           "keeps no data",
+	"links":         "keeps the sessions of devices being linked",
+	"directory":     "keeps the registered numbers",
+	"confirmations": "keeps tokens for minutes, the one of a deletion is used by it",
+	"captchas":      "keeps the captcha challenges of registrations",
+	"videos":        "keeps no data",
+	"supervisor":    "runs signald",
+	"idempotency":   "keeps the responses of sends, which only have timestamps and ids",
+	"leader":        "keeps no data",
+	"muxes":         "keeps connections to signald",
+	"signaldChecks": "keeps the results of the signald checks",
+	"service":       "keeps no data",
+	"versions":      "keeps the versions of signald",
+	"verifications": "keeps the verification attempts of registrations",
+	"privacy":       "keeps the privacy settings of the accounts",
+	"faults":        "keeps no data",
+	"quotas":        "counts sends per client",
+	"xmpp":          "maps accounts and groups, the JIDs of contacts are derived from their numbers",
+	"mediaClient":   "keeps no data",
+	"smtp":          "keeps no data",
+	"syslog":        "keeps no data",
+}
+
+var dataPurgerType = reflect.TypeOf((*dataPurger)(nil)).Elem()
+
+// checkPurgers fails if a subsystem of the Api may keep contact data without
+// a registered purger, so that a new store can't be left out of deletion
+// requests unnoticed.
+func (a *Api) checkPurgers() error {
+	registered := map[uintptr]bool{}
+	for _, p := range a.purgers.purgers {
+		registered[reflect.ValueOf(p).Pointer()] = true
+	}
+
+	v := reflect.ValueOf(a).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type.Kind() != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if _, ok := keepsNoContactData[field.Name]; ok {
+			continue
+		}
+		if !field.Type.Implements(dataPurgerType) {
+			return errors.New("The " + field.Name + " subsystem may keep contact data but can't purge it")
+		}
+		if !v.Field(i).IsNil() && !registered[v.Field(i).Pointer()] {
+			return errors.New("The " + field.Name + " subsystem may keep contact data but its purger isn't registered")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// The formats QR codes are rendered in.
+const (
+	qrFormatPNG   = "png"
+	qrFormatSVG   = "svg"
+	qrFormatASCII = "ascii"
+	qrFormatANSI  = "ansi"
+)
+
+// The default, minimum and maximum size of QR code images in pixels.
+const (
+	qrCodeSize    = 256
+	qrCodeMinSize = 64
+	qrCodeMaxSize = 2048
+)
+
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// qrOptions are the error correction level and the size of a QR code.
+type qrOptions struct {
+	format string
+	level  qrcode.RecoveryLevel
+	size   int
+}
+
+// parseQROptions returns the options of a QR code given with ?format=,
+// ?level= (L, M, Q or H) and ?size= (in pixels, for PNG and SVG).
+func parseQROptions(c *gin.Context) (qrOptions, error) {
+	o := qrOptions{level: qrcode.Medium, size: qrCodeSize}
+
+	var err error
+	if o.format, err = qrFormat(c); err != nil {
+		return o, err
+	}
+	if value := c.Query("level"); value != "" {
+		level, ok := qrLevels[strings.ToUpper(value)]
+		if !ok {
+			return o, errors.New("Invalid level " + value + " (supported: L, M, Q, H)")
+		}
+		o.level = level
+	}
+	if value := c.Query("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < qrCodeMinSize || size > qrCodeMaxSize {
+			return o, errors.New("Invalid size " + value + " (" + strconv.Itoa(qrCodeMinSize) + " to " +
+				strconv.Itoa(qrCodeMaxSize) + " pixels)")
+		}
+		o.size = size
+	}
+	return o, nil
+}
+
+// qrFormat returns the format requested with ?format=, or else by the Accept
+// header. PNG is the default.
+func qrFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		switch format {
+		case qrFormatPNG, qrFormatSVG, qrFormatASCII, qrFormatANSI:
+			return format, nil
+		}
+		return "", errors.New("Invalid format " + format + " (supported: png, svg, ascii, ansi)")
+	}
+
+	switch c.NegotiateFormat("image/png", "image/svg+xml", "text/plain") {
+	case "image/svg+xml":
+		return qrFormatSVG, nil
+	case "text/plain":
+		return qrFormatASCII, nil
+	}
+	return qrFormatPNG, nil
+}
+
+// renderQRCode renders content as QR code with the given options and returns
+// it with its content type. The text formats have a quiet zone around the
+// code, so that it can be scanned off a dark terminal.
+func renderQRCode(content string, o qrOptions) ([]byte, string, error) {
+	q, err := qrcode.New(content, o.level)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch o.format {
+	case qrFormatSVG:
+		q.DisableBorder = true
+		return qrSVG(q.Bitmap(), o.size), "image/svg+xml", nil
+	case qrFormatASCII:
+		return qrText(q.Bitmap(), "##", "  "), "text/plain; charset=utf-8", nil
+	case qrFormatANSI:
+		return qrText(q.Bitmap(), "\x1b[40m  \x1b[0m", "\x1b[47m  \x1b[0m"), "text/plain; charset=utf-8", nil
+	}
+
+	q.DisableBorder = true
+	png, err := q.PNG(o.size)
+	return png, "image/png", err
+}
+
+// qrSVG renders a QR code as SVG with a path of the runs of dark modules.
+func qrSVG(bits [][]bool, pixels int) []byte {
+	n := strconv.Itoa(len(bits))
+	size := strconv.Itoa(pixels)
+
+	buf := bytes.Buffer{}
+	buf.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="` + size + `" height="` + size +
+		`" viewBox="0 0 ` + n + ` ` + n + `" shape-rendering="crispEdges">`)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+	for y, row := range bits {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			buf.WriteString("M" + strconv.Itoa(start) + " " + strconv.Itoa(y) + "h" + strconv.Itoa(x-start) + "v1H" +
+				strconv.Itoa(start) + "z")
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+// qrText renders a QR code as text, two characters per module.
+func qrText(bits [][]bool, dark string, light string) []byte {
+	lines := []string{}
+	for _, row := range bits {
+		line := strings.Builder{}
+		for _, set := range row {
+			if set {
+				line.WriteString(dark)
+			} else {
+				line.WriteString(light)
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,467 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueStateWaitingForTrust = "waiting_for_trust"
+	queueStateFailed          = "failed"
+)
+
+const queueCheckInterval = 30 * time.Second
+
+// queuedMessage is a message to a single recipient that couldn't be sent
+// because the identity of the recipient isn't trusted.
+type queuedMessage struct {
+	ID          string    `json:"id"`
+	Number      string    `json:"number"`
+	Recipient   string    `json:"recipient"`
+	Message     string    `json:"message"`
+	Attachments int       `json:"attachments"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	State       string    `json:"state"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error,omitempty"`
+	Created     time.Time `json:"created"`
+
+	hashes    []string
+	filenames []string
+}
+
+// storedQueuedMessage is a queued message as it is persisted, with the staged
+// attachments it holds and the host of the replica that parked it.
+type storedQueuedMessage struct {
+	queuedMessage
+	Hashes    []string `json:"hashes"`
+	Filenames []string `json:"filenames"`
+	Owner     string   `json:"owner,omitempty"`
+}
+
+// queuedMessages is returned by the send endpoints if messages were queued.
+type queuedMessages struct {
+	Queued []string `json:"queued"`
+}
+
+// sendQueue parks messages until the identity of their recipient is trusted.
+// The queue is persisted one message each, the attachments of parked messages
+// stay staged until the message is sent or removed from the queue. If replicas
+// share their state, the messages are also stored in Redis so that every
+// replica can list and remove them, they are sent by the replica that parked
+// them. If the replicas share the store, a replica only loads the messages it
+// parked itself, its attachments are staged in its data dir.
+type sendQueue struct {
+	mutex    sync.Mutex
+	stager   *attachmentStager
+	shared   *sharedState
+	store    entityStore
+	owner    string
+	messages map[string]*queuedMessage
+}
+
+func newSendQueue(stager *attachmentStager, shared *sharedState, store entityStore) (*sendQueue, error) {
+	owner, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	q := &sendQueue{
+		stager:   stager,
+		shared:   shared,
+		store:    store,
+		owner:    owner,
+		messages: make(map[string]*queuedMessage),
+	}
+
+	stored := []storedQueuedMessage{}
+	err = store.loadAll(func(data []byte) error {
+		s := storedQueuedMessage{}
+		if err := jsoniter.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if store.shared() && s.Owner != "" && s.Owner != owner {
+			// parked by another replica
+			return nil
+		}
+		stored = append(stored, s)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("Couldn't load the trust queue: " + err.Error())
+	}
+
+	for _, s := range stored {
+		m := s.queuedMessage
+		q.messages[m.ID] = &m
+		q.restore(&m, s.Hashes, s.Filenames)
+	}
+	return q, nil
+}
+
+// restore takes the references on the staged attachments of a message that
+// was queued before the API restarted. If an attachment was removed in the
+// meantime the message fails.
+func (q *sendQueue) restore(m *queuedMessage, hashes []string, filenames []string) {
+	for i, hash := range hashes {
+		if i >= len(filenames) || !q.stager.adopt(hash, filenames[i]) {
+			q.releaseAttachments(m)
+			m.filenames = nil
+			m.State = queueStateFailed
+			m.Error = "The attachments were removed while the API restarted"
+			q.persist(m)
+			return
+		}
+		m.hashes = append(m.hashes, hash)
+		m.filenames = append(m.filenames, filenames[i])
+	}
+}
+
+// persist stores m, the caller needs to hold the mutex.
+func (q *sendQueue) persist(m *queuedMessage) {
+	stored := storedQueuedMessage{queuedMessage: *m, Hashes: m.hashes, Filenames: m.filenames, Owner: q.owner}
+	if err := q.store.put(m.ID, stored); err != nil {
+		log.Error("Couldn't store queued message ", m.ID, ": ", err.Error())
+	}
+}
+
+// forget removes m from the store.
+func (q *sendQueue) forget(m *queuedMessage) {
+	if err := q.store.remove(m.ID); err != nil {
+		log.Error("Couldn't remove queued message ", m.ID, ": ", err.Error())
+	}
+}
+
+// share stores m in Redis, the caller needs to hold the mutex.
+func (q *sendQueue) share(m *queuedMessage) {
+	if q.shared == nil {
+		return
+	}
+	if err := q.shared.putQueued(m); err != nil {
+		log.Error("Couldn't store queued message ", m.ID, ": ", err.Error())
+	}
+}
+
+// unshare removes m from Redis.
+func (q *sendQueue) unshare(m *queuedMessage) {
+	if q.shared == nil {
+		return
+	}
+	if _, err := q.shared.removeQueued(m.Number, m.ID); err != nil {
+		log.Error("Couldn't remove queued message ", m.ID, ": ", err.Error())
+	}
+}
+
+// park adds a message to the queue. It takes its own reference on the staged
+// attachments.
+func (q *sendQueue) park(number string, recipient string, message string, hashes []string,
+	fingerprint string) (*queuedMessage, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &queuedMessage{
+		ID:          id,
+		Number:      number,
+		Recipient:   recipient,
+		Message:     message,
+		Fingerprint: fingerprint,
+		State:       queueStateWaitingForTrust,
+		Attempts:    1,
+		Created:     time.Now(),
+	}
+	for _, hash := range hashes {
+		filename, ok := q.stager.lookup(hash)
+		if !ok {
+			q.releaseAttachments(m)
+			return nil, errors.New("Attachment " + hash + " is no longer staged")
+		}
+		m.hashes = append(m.hashes, hash)
+		m.filenames = append(m.filenames, filename)
+	}
+	m.Attachments = len(m.filenames)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.messages[id] = m
+	q.persist(m)
+	q.share(m)
+
+	return m, nil
+}
+
+func (q *sendQueue) releaseAttachments(m *queuedMessage) {
+	for _, hash := range m.hashes {
+		q.stager.release(hash)
+	}
+	m.hashes = nil
+}
+
+// list returns copies of the queued messages of number, oldest first.
+func (q *sendQueue) list(number string) []queuedMessage {
+	if q.shared != nil {
+		messages, err := q.shared.listQueued(number)
+		if err == nil {
+			sort.Slice(messages, func(i, j int) bool {
+				return messages[i].Created.Before(messages[j].Created)
+			})
+			return messages
+		}
+		log.Error("Couldn't list the shared queue: ", err.Error())
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	messages := []queuedMessage{}
+	for _, m := range q.messages {
+		if m.Number == number {
+			messages = append(messages, *m)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Created.Before(messages[j].Created)
+	})
+	return messages
+}
+
+// waiting returns the messages waiting for the identity of a recipient to be
+// trusted. If recipient is empty the messages of all recipients of number are
+// returned.
+func (q *sendQueue) waiting(number string, recipient string) []*queuedMessage {
+	q.mutex.Lock()
+	messages := []*queuedMessage{}
+	for _, m := range q.messages {
+		if m.State != queueStateWaitingForTrust {
+			continue
+		}
+		if (number == "" || m.Number == number) && (recipient == "" || m.Recipient == recipient) {
+			messages = append(messages, m)
+		}
+	}
+	q.mutex.Unlock()
+
+	if q.shared != nil {
+		// drop the messages another replica removed
+		kept := messages[:0]
+		for _, m := range messages {
+			if queued, err := q.shared.isQueued(m.Number, m.ID); err == nil && !queued {
+				q.drop(m)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		messages = kept
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Created.Before(messages[j].Created)
+	})
+	return messages
+}
+
+func (q *sendQueue) remove(number string, id string) bool {
+	removed := false
+	if q.shared != nil {
+		var err error
+		if removed, err = q.shared.removeQueued(number, id); err != nil {
+			log.Error("Couldn't remove queued message ", id, ": ", err.Error())
+		}
+	}
+
+	q.mutex.Lock()
+	m, ok := q.messages[id]
+	if ok && m.Number == number {
+		delete(q.messages, id)
+	}
+	q.mutex.Unlock()
+
+	if !ok || m.Number != number {
+		return removed
+	}
+	q.forget(m)
+	q.releaseAttachments(m)
+	return true
+}
+
+func (q *sendQueue) purgerName() string {
+	return "trust queue"
+}
+
+// purgeContact removes the messages of number to contact from the queue.
+func (q *sendQueue) purgeContact(number string, contact string) (int, error) {
+	deleted := 0
+	for _, m := range q.list(number) {
+		if m.Recipient == contact && q.remove(number, m.ID) {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// drop removes a message of this replica that was removed from the shared
+// queue.
+func (q *sendQueue) drop(m *queuedMessage) {
+	q.mutex.Lock()
+	_, ok := q.messages[m.ID]
+	delete(q.messages, m.ID)
+	q.mutex.Unlock()
+
+	if ok {
+		q.forget(m)
+		q.releaseAttachments(m)
+	}
+}
+
+// expire removes the messages that are older than the retention of their
+// number.
+func (q *sendQueue) expire(retention func(number string) time.Duration) {
+	q.mutex.Lock()
+	expired := []*queuedMessage{}
+	for id, m := range q.messages {
+		if r := retention(m.Number); r > 0 && time.Since(m.Created) > r {
+			delete(q.messages, id)
+			expired = append(expired, m)
+		}
+	}
+	q.mutex.Unlock()
+
+	for _, m := range expired {
+		log.Info("Dropped queued message ", m.ID, " after the retention period")
+		q.unshare(m)
+		q.forget(m)
+		q.releaseAttachments(m)
+	}
+}
+
+func (q *sendQueue) update(m *queuedMessage, f func(m *queuedMessage)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	f(m)
+	if q.messages[m.ID] == m {
+		// the message wasn't removed in the meantime
+		q.persist(m)
+	}
+	q.share(m)
+}
+
+// resendQueued sends the messages parked for recipient (or for all recipients
+// of number if it is empty) whose identity is trusted by now.
+func (a *Api) resendQueued(number string, recipient string) {
+	if a.queue == nil {
+		return
+	}
+
+	untrusted := make(map[string]bool)
+	for _, m := range a.queue.waiting(number, recipient) {
+		key := m.Number + " " + m.Recipient
+		if _, checked := untrusted[key]; !checked {
+			message, err := a.client(m.Number).ListIdentities(m.Number, signald.RequestAddress{Number: m.Recipient})
+			if err != nil {
+				log.Error("Couldn't list identities: ", err.Error())
+				untrusted[key] = true
+				continue
+			}
+
+			identity, ok := newestUntrustedIdentity(message.Data.Identities)
+			untrusted[key] = ok
+			if ok {
+				a.queue.update(m, func(m *queuedMessage) { m.Fingerprint = identity.Fingerprint })
+			}
+		}
+		if untrusted[key] {
+			continue
+		}
+
+		attachments := []signald.RequestAttachment{}
+		for _, filename := range m.filenames {
+			attachments = append(attachments, signald.RequestAttachment{Filename: filename})
+		}
+
+		start := time.Now()
+		timestamp := milliseconds(start)
+		resp, err := a.signaldSend(m.Number, signald.RequestAddress{Number: m.Recipient}, "", m.Message,
+			attachments, timestamp)
+		a.stats.sent(m.Number, time.Since(start), err)
+		if err == nil {
+			log.Info("Sent queued message ", m.ID)
+			a.publishSent(m.Number, m.Recipient, "", m.Message, len(m.filenames), timestamp)
+			a.queue.remove(m.Number, m.ID)
+			continue
+		}
+
+		// if the identity changed again in the meantime the message keeps
+		// waiting
+		a.queue.update(m, func(m *queuedMessage) {
+			m.Attempts++
+			m.Error = err.Error()
+			if resp.Type != "untrusted_identity" {
+				m.State = queueStateFailed
+			}
+		})
+		if resp.Type == "untrusted_identity" {
+			untrusted[key] = true
+		} else {
+			log.Error("Couldn't send queued message ", m.ID, ": ", err.Error())
+		}
+	}
+}
+
+// runQueue periodically checks whether the identities the parked messages are
+// waiting for were trusted manually.
+func (a *Api) runQueue() {
+	for range time.Tick(queueCheckInterval) {
+		a.queue.expire(func(number string) time.Duration {
+			return a.accounts.get(number).retention
+		})
+		a.resendQueued("", "")
+	}
+}
+
+// @Summary List the queued messages.
+// @Tags Messages
+// @Description List the messages of a number that are waiting for the identity of their recipient to be trusted, or that failed when they were sent again.
+// @Produce  json
+// @Success 200 {object} []queuedMessage
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Router /v1/queue/{number} [get]
+func (a *Api) GetQueue(c *gin.Context) {
+	if a.queue == nil {
+		c.JSON(400, gin.H{"error": "Resending after trust is disabled"})
+		return
+	}
+
+	c.JSON(200, a.queue.list(c.Param("number")))
+}
+
+// @Summary Remove a queued message.
+// @Tags Messages
+// @Description Remove a message from the queue without sending it.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param id path string true "Queued Message ID"
+// @Router /v1/queue/{number}/{id} [delete]
+func (a *Api) DeleteQueuedMessage(c *gin.Context) {
+	if a.queue == nil {
+		c.JSON(400, gin.H{"error": "Resending after trust is disabled"})
+		return
+	}
+
+	if !a.queue.remove(c.Param("number"), c.Param("id")) {
+		c.JSON(404, gin.H{"error": "No such queued message"})
+		return
+	}
+	c.Status(204)
+}
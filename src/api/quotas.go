@@ -0,0 +1,352 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// quotaDefaultClient is the key of the quotas of the clients that have none of
+// their own.
+const quotaDefaultClient = "*"
+
+// QuotaLimits are the quotas of a client, which is identified by the user
+// the authenticating reverse proxy passes on (e.g. the name of its API key)
+// like the actor of the audit log. 0 means unlimited.
+type QuotaLimits struct {
+	// MessagesPerHour and MessagesPerDay limit the messages sent, every
+	// recipient counts.
+	MessagesPerHour int `json:"messages_per_hour,omitempty"`
+	MessagesPerDay  int `json:"messages_per_day,omitempty"`
+	// AttachmentBytesPerHour and AttachmentBytesPerDay limit the size of the
+	// attachments sent, the attachments count once per recipient.
+	AttachmentBytesPerHour int64 `json:"attachment_bytes_per_hour,omitempty"`
+	AttachmentBytesPerDay  int64 `json:"attachment_bytes_per_day,omitempty"`
+}
+
+// LoadQuotas reads the quotas from a JSON file that maps clients to their
+// quotas, * to the quotas of all other clients.
+func LoadQuotas(filename string) (map[string]QuotaLimits, error) {
+	quotas := make(map[string]QuotaLimits)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return quotas, err
+	}
+
+	if err := jsoniter.Unmarshal(data, &quotas); err != nil {
+		return quotas, errors.New("Couldn't parse quotas config: " + err.Error())
+	}
+	for client, limits := range quotas {
+		if limits.MessagesPerHour < 0 || limits.MessagesPerDay < 0 || limits.AttachmentBytesPerHour < 0 ||
+			limits.AttachmentBytesPerDay < 0 {
+			return quotas, errors.New("Invalid quotas of " + client + ", they can't be negative")
+		}
+	}
+	return quotas, nil
+}
+
+// quotaWindow counts the messages and attachment bytes sent in an hour or a
+// day. The windows start at the full hour and at midnight UTC.
+type quotaWindow struct {
+	start    time.Time
+	messages int
+	bytes    int64
+}
+
+// current resets the window if it has passed.
+func (w *quotaWindow) current(now time.Time, length time.Duration) {
+	if start := now.UTC().Truncate(length); !w.start.Equal(start) {
+		*w = quotaWindow{start: start}
+	}
+}
+
+// quotaCounter counts what a client sent in the current hour and day.
+type quotaCounter struct {
+	hour quotaWindow
+	day  quotaWindow
+}
+
+// quotaUsage is the usage of the quotas of a client.
+type quotaUsage struct {
+	Client              string      `json:"client"`
+	Limits              QuotaLimits `json:"limits"`
+	MessagesHour        int         `json:"messages_hour"`
+	MessagesDay         int         `json:"messages_day"`
+	AttachmentBytesHour int64       `json:"attachment_bytes_hour"`
+	AttachmentBytesDay  int64       `json:"attachment_bytes_day"`
+	HourReset           time.Time   `json:"hour_reset"`
+	DayReset            time.Time   `json:"day_reset"`
+}
+
+// quotaLeft is what is left of a quota in the window that runs out first.
+type quotaLeft struct {
+	limited   bool
+	remaining int64
+	reset     time.Time
+}
+
+func quotaRemaining(hourLimit int64, hourUsed int64, hourReset time.Time, dayLimit int64, dayUsed int64,
+	dayReset time.Time) quotaLeft {
+	l := quotaLeft{}
+	windows := []struct {
+		limit int64
+		used  int64
+		reset time.Time
+	}{{hourLimit, hourUsed, hourReset}, {dayLimit, dayUsed, dayReset}}
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		remaining := w.limit - w.used
+		if remaining < 0 {
+			remaining = 0
+		}
+		if !l.limited || remaining < l.remaining {
+			l = quotaLeft{limited: true, remaining: remaining, reset: w.reset}
+		}
+	}
+	return l
+}
+
+func (u quotaUsage) messagesLeft() quotaLeft {
+	return quotaRemaining(int64(u.Limits.MessagesPerHour), int64(u.MessagesHour), u.HourReset,
+		int64(u.Limits.MessagesPerDay), int64(u.MessagesDay), u.DayReset)
+}
+
+func (u quotaUsage) attachmentBytesLeft() quotaLeft {
+	return quotaRemaining(u.Limits.AttachmentBytesPerHour, u.AttachmentBytesHour, u.HourReset,
+		u.Limits.AttachmentBytesPerDay, u.AttachmentBytesDay, u.DayReset)
+}
+
+// quotaSeconds returns the seconds until t, rounded up.
+func quotaSeconds(t time.Time, now time.Time) int {
+	return int((t.Sub(now) + time.Second - 1) / time.Second)
+}
+
+// headers returns the quota headers of a response to the client.
+func (u quotaUsage) headers(now time.Time) map[string]string {
+	headers := make(map[string]string)
+	if l := u.messagesLeft(); l.limited {
+		headers["X-Quota-Messages-Remaining"] = strconv.FormatInt(l.remaining, 10)
+		headers["X-Quota-Messages-Reset"] = strconv.Itoa(quotaSeconds(l.reset, now))
+	}
+	if l := u.attachmentBytesLeft(); l.limited {
+		headers["X-Quota-Attachment-Bytes-Remaining"] = strconv.FormatInt(l.remaining, 10)
+		headers["X-Quota-Attachment-Bytes-Reset"] = strconv.Itoa(quotaSeconds(l.reset, now))
+	}
+	return headers
+}
+
+// quotas counts the messages and attachment bytes the clients send and
+// rejects sends that exceed their quotas. The usage is only kept in memory
+// and counted per replica.
+type quotas struct {
+	mutex    sync.Mutex
+	limits   map[string]QuotaLimits
+	counters map[string]*quotaCounter
+}
+
+func newQuotas(limits map[string]QuotaLimits) *quotas {
+	return &quotas{limits: limits, counters: make(map[string]*quotaCounter)}
+}
+
+func (q *quotas) enabled() bool {
+	return len(q.limits) > 0
+}
+
+// limitsOf returns the quotas of client and whether it has any.
+func (q *quotas) limitsOf(client string) (QuotaLimits, bool) {
+	if limits, ok := q.limits[client]; ok {
+		return limits, true
+	}
+	limits, ok := q.limits[quotaDefaultClient]
+	return limits, ok
+}
+
+// counter returns the counter of client in the current windows, the caller
+// needs to hold the mutex.
+func (q *quotas) counter(client string, now time.Time) *quotaCounter {
+	counter, ok := q.counters[client]
+	if !ok {
+		counter = &quotaCounter{}
+		q.counters[client] = counter
+	}
+	counter.hour.current(now, time.Hour)
+	counter.day.current(now, 24*time.Hour)
+	return counter
+}
+
+// usage returns the usage of client, the caller needs to hold the mutex.
+func (q *quotas) usage(client string, now time.Time) quotaUsage {
+	counter := quotaCounter{}
+	if c, ok := q.counters[client]; ok {
+		counter = *c
+	}
+	counter.hour.current(now, time.Hour)
+	counter.day.current(now, 24*time.Hour)
+	limits, _ := q.limitsOf(client)
+	return quotaUsage{
+		Client:              client,
+		Limits:              limits,
+		MessagesHour:        counter.hour.messages,
+		MessagesDay:         counter.day.messages,
+		AttachmentBytesHour: counter.hour.bytes,
+		AttachmentBytesDay:  counter.day.bytes,
+		HourReset:           counter.hour.start.Add(time.Hour),
+		DayReset:            counter.day.start.Add(24 * time.Hour),
+	}
+}
+
+// allow counts messages and attachment bytes sent by client if they are
+// within its quotas, and fails with 429 otherwise. It returns the quota
+// headers of the response.
+func (q *quotas) allow(client string, messages int, bytes int64) (map[string]string, error) {
+	if _, ok := q.limitsOf(client); !ok {
+		return nil, nil
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	u := q.usage(client, now)
+	exceeded := quotaLeft{}
+	if l := u.messagesLeft(); l.limited && int64(messages) > l.remaining {
+		exceeded = l
+	} else if l := u.attachmentBytesLeft(); l.limited && bytes > l.remaining {
+		exceeded = l
+	}
+	if exceeded.limited {
+		headers := u.headers(now)
+		return headers, &service.Error{
+			Kind:       service.RateLimited,
+			Message:    "Quota of " + client + " exceeded",
+			RetryAfter: quotaSeconds(exceeded.reset, now),
+			Metadata:   headers,
+		}
+	}
+
+	counter := q.counter(client, now)
+	counter.hour.messages += messages
+	counter.day.messages += messages
+	counter.hour.bytes += bytes
+	counter.day.bytes += bytes
+	return q.usage(client, now).headers(now), nil
+}
+
+// list returns the usage of the clients that sent messages and of the
+// clients that have quotas of their own.
+func (q *quotas) list() []quotaUsage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	clients := make(map[string]bool)
+	for client := range q.counters {
+		clients[client] = true
+	}
+	for client := range q.limits {
+		if client != quotaDefaultClient {
+			clients[client] = true
+		}
+	}
+	usage := []quotaUsage{}
+	for client := range clients {
+		usage = append(usage, q.usage(client, now))
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Client < usage[j].Client
+	})
+	return usage
+}
+
+func (q *quotas) show(client string) quotaUsage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.usage(client, time.Now())
+}
+
+// reset drops the usage of client and returns it.
+func (q *quotas) reset(client string) quotaUsage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	u := q.usage(client, time.Now())
+	delete(q.counters, client)
+	return u
+}
+
+// attachmentBytes returns the size of the staged attachment files.
+func attachmentBytes(filenames []string) int64 {
+	size := int64(0)
+	for _, filename := range filenames {
+		if info, err := os.Stat(filename); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+func (a *Api) quotasEnabled(c *gin.Context) bool {
+	if !a.quotas.enabled() {
+		c.JSON(404, gin.H{"error": "No quotas are configured, start the API with -quotas-config to enable them"})
+		return false
+	}
+	return true
+}
+
+// @Summary List the quota usage.
+// @Tags Admin
+// @Description List the messages and attachment bytes the clients sent in the current hour and day (the windows start at the full hour and at midnight UTC) with their quotas.
+// @Produce  json
+// @Success 200 {object} []quotaUsage
+// @Failure 404 {object} Error
+// @Router /admin/quotas [get]
+func (a *Api) GetQuotas(c *gin.Context) {
+	if !a.quotasEnabled(c) {
+		return
+	}
+	c.JSON(200, a.quotas.list())
+}
+
+// @Summary Show the quota usage of a client.
+// @Tags Admin
+// @Description Show the messages and attachment bytes a client sent in the current hour and day with its quotas.
+// @Produce  json
+// @Success 200 {object} quotaUsage
+// @Failure 404 {object} Error
+// @Param client path string true "Client"
+// @Router /admin/quotas/{client} [get]
+func (a *Api) GetQuota(c *gin.Context) {
+	if !a.quotasEnabled(c) {
+		return
+	}
+	c.JSON(200, a.quotas.show(c.Param("client")))
+}
+
+// @Summary Reset the quota usage of a client.
+// @Tags Admin
+// @Description Reset the messages and attachment bytes a client sent in the current hour and day, e.g. after a runaway job was stopped.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 404 {object} Error
+// @Param client path string true "Client"
+// @Router /admin/quotas/{client} [delete]
+func (a *Api) ResetQuota(c *gin.Context) {
+	if !a.quotasEnabled(c) {
+		return
+	}
+	previous := a.quotas.reset(c.Param("client"))
+	a.audit.record(c, "quotas.reset", c.Param("client"), previous, nil)
+	c.Status(204)
+}
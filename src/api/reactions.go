@@ -0,0 +1,144 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/abaskin/signald-go/signald"
+)
+
+// recentMessagesSize is the number of received messages remembered per
+// number to resolve the targets of reactions.
+const recentMessagesSize = 100
+
+type envelopeReaction struct {
+	Emoji               string                 `json:"emoji"`
+	Remove              bool                   `json:"remove"`
+	TargetAuthor        signald.RequestAddress `json:"targetAuthor"`
+	TargetSentTimestamp int64                  `json:"targetSentTimestamp"`
+}
+
+// messageReaction is a reaction to a message. Target is the message reacted
+// to, if it is in the message store, so that consumers don't need to look it
+// up by the timestamp.
+type messageReaction struct {
+	Emoji  string `json:"emoji"`
+	Remove bool   `json:"remove,omitempty"`
+	// TargetAuthor is the number (or the UUID, if signald doesn't know the
+	// number) of the sender of the message reacted to.
+	TargetAuthor    string         `json:"target_author"`
+	TargetTimestamp int64          `json:"target_timestamp"`
+	Target          *storedMessage `json:"target,omitempty"`
+}
+
+type recentMessage struct {
+	peer    string
+	message storedMessage
+}
+
+// recentMessages remembers the last messages received for every number. The
+// message store is filled from the event bus, a reaction received right after
+// the message it targets could miss it there.
+type recentMessages struct {
+	mutex    sync.Mutex
+	messages map[string][]recentMessage
+}
+
+func newRecentMessages() *recentMessages {
+	return &recentMessages{messages: make(map[string][]recentMessage)}
+}
+
+func (r *recentMessages) add(number string, peer string, m storedMessage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	messages := append(r.messages[number], recentMessage{peer: peer, message: m})
+	if len(messages) > recentMessagesSize {
+		messages = messages[len(messages)-recentMessagesSize:]
+	}
+	r.messages[number] = messages
+}
+
+func (r *recentMessages) find(number string, peer string, sender string, timestamp int64) (storedMessage, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	messages := r.messages[number]
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if m.peer == peer && m.message.Timestamp == timestamp && (sender == "" || m.message.Sender == sender) {
+			return m.message, true
+		}
+	}
+	return storedMessage{}, false
+}
+
+func (r *recentMessages) purgerName() string {
+	return "recent messages"
+}
+
+// purgeContact forgets the messages of number in the conversation with
+// contact and the ones contact sent to groups.
+func (r *recentMessages) purgeContact(number string, contact string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kept := []recentMessage{}
+	for _, m := range r.messages[number] {
+		if m.peer != contact && m.message.Sender != contact {
+			kept = append(kept, m)
+		}
+	}
+	deleted := len(r.messages[number]) - len(kept)
+	r.messages[number] = kept
+	return deleted, nil
+}
+
+// conversationPeer returns the conversation of the message store an incoming
+// message belongs to, the group or the sender.
+func conversationPeer(e envelope) string {
+	if e.DataMessage != nil && e.DataMessage.GroupInfo != nil {
+		return convertInternalGroupIDToGroupID(e.DataMessage.GroupInfo.GroupID)
+	}
+	return e.Source.Number
+}
+
+// reaction returns the reaction of an incoming message to number, if the
+// message is one, with the message it targets if there is a message store.
+// Other data messages are remembered as targets of later reactions.
+func (a *Api) reaction(number string, msg *incomingMessage) *messageReaction {
+	e, err := msg.envelope()
+	if err != nil || e.DataMessage == nil {
+		return nil
+	}
+	peer := conversationPeer(e)
+
+	r := e.DataMessage.Reaction
+	if r == nil {
+		if m, ok := receivedMessage(e); ok && a.store != nil {
+			a.recent.add(number, peer, m)
+		}
+		return nil
+	}
+
+	reaction := &messageReaction{
+		Emoji:           r.Emoji,
+		Remove:          r.Remove,
+		TargetAuthor:    r.TargetAuthor.Number,
+		TargetTimestamp: r.TargetSentTimestamp,
+	}
+	if reaction.TargetAuthor == "" {
+		reaction.TargetAuthor = r.TargetAuthor.UUID
+	}
+
+	if a.store == nil {
+		return reaction
+	}
+	target, ok := a.store.find(number, peer, r.TargetAuthor.Number, r.TargetSentTimestamp)
+	if !ok {
+		target, ok = a.recent.find(number, peer, r.TargetAuthor.Number, r.TargetSentTimestamp)
+	}
+	if ok {
+		reaction.Target = &target
+	}
+	return reaction
+}
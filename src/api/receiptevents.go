@@ -0,0 +1,91 @@
+package api
+
+import "strings"
+
+// eventReceipt is published for every message a receipt was received for,
+// the data is the receiptEvent.
+const eventReceipt = "receipt"
+
+// The types of receipts.
+const (
+	receiptDelivery = "delivery"
+	receiptRead     = "read"
+	receiptViewed   = "viewed"
+)
+
+type envelopeReceipt struct {
+	Type       string  `json:"type"`
+	Timestamps []int64 `json:"timestamps"`
+	When       int64   `json:"when"`
+}
+
+// receiptEvent is the data of a receipt event: a recipient received, read or
+// viewed a message sent by the number.
+type receiptEvent struct {
+	Type   string `json:"type"`
+	Sender string `json:"sender"`
+	// Timestamp is the timestamp of the message the receipt is for, MessageID
+	// its ID in the message store.
+	Timestamp int64  `json:"timestamp"`
+	MessageID string `json:"message_id"`
+	// When is when the message was received, read or viewed.
+	When int64 `json:"when,omitempty"`
+}
+
+// receiptEvents returns the receipts of an incoming message to number, one
+// for every message it refers to. Envelopes of the type RECEIPT without
+// receipt are the delivery receipts of older signald versions.
+func receiptEvents(number string, e envelope) []receiptEvent {
+	sender := e.Source.Number
+	if sender == "" {
+		sender = e.Source.UUID
+	}
+
+	receipt := e.Receipt
+	if receipt == nil {
+		if e.Type != "RECEIPT" {
+			return nil
+		}
+		receipt = &envelopeReceipt{Type: "DELIVERY", Timestamps: []int64{e.Timestamp}}
+	}
+
+	receiptType := strings.ToLower(receipt.Type)
+	switch receiptType {
+	case receiptDelivery, receiptRead, receiptViewed:
+	default:
+		return nil
+	}
+
+	events := []receiptEvent{}
+	seen := make(map[int64]bool)
+	for _, timestamp := range receipt.Timestamps {
+		if timestamp <= 0 || seen[timestamp] {
+			continue
+		}
+		seen[timestamp] = true
+		events = append(events, receiptEvent{
+			Type:      receiptType,
+			Sender:    sender,
+			Timestamp: timestamp,
+			MessageID: messageID(number, timestamp),
+			When:      receipt.When,
+		})
+	}
+	return events
+}
+
+// receiptStage returns the receive stage that publishes a receipt event for
+// every message a received receipt refers to.
+func (a *Api) receiptStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		e, err := msg.envelope()
+		if err != nil {
+			return true
+		}
+
+		for _, r := range receiptEvents(number, e) {
+			a.bus.publish(busEvent{Type: eventReceipt, Number: number, Data: r, Source: msg.Data})
+		}
+		return true
+	}
+}
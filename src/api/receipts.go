@@ -0,0 +1,97 @@
+package api
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkReceipts = 1000
+
+type bulkReceipt struct {
+	Recipient string `json:"recipient"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type bulkReceiptsRequest struct {
+	Receipts []bulkReceipt `json:"receipts"`
+}
+
+type failedReceipts struct {
+	Recipient string `json:"recipient"`
+	Error     string `json:"error"`
+}
+
+type bulkReceiptsResult struct {
+	Receipts int              `json:"receipts"`
+	Failed   []failedReceipts `json:"failed,omitempty"`
+}
+
+// groupReceipts returns the distinct timestamps of the receipts by recipient.
+func groupReceipts(receipts []bulkReceipt) map[string][]int64 {
+	seen := make(map[bulkReceipt]bool)
+	timestamps := make(map[string][]int64)
+	for _, r := range receipts {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		timestamps[r.Recipient] = append(timestamps[r.Recipient], r.Timestamp)
+	}
+	return timestamps
+}
+
+// @Summary Mark many messages as read.
+// @Tags Messages
+// @Description Send read receipts for many messages, given as pairs of the sender and the timestamp of the message. The receipts for the messages of a sender are sent with a single signald request. Senders whose receipts couldn't be sent are listed with the error.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} bulkReceiptsResult
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body bulkReceiptsRequest true "Receipts"
+// @Router /v1/receipts/{number}/bulk [post]
+func (a *Api) SendBulkReceipts(c *gin.Context) {
+	number := c.Param("number")
+
+	req := bulkReceiptsRequest{}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+	if len(req.Receipts) == 0 {
+		c.JSON(400, gin.H{"error": "Please provide at least one receipt"})
+		return
+	}
+	if len(req.Receipts) > maxBulkReceipts {
+		c.JSON(400, gin.H{"error": "At most " + strconv.Itoa(maxBulkReceipts) + " receipts can be sent at once"})
+		return
+	}
+	for _, r := range req.Receipts {
+		if r.Recipient == "" || r.Timestamp <= 0 {
+			c.JSON(400, gin.H{"error": "Every receipt needs a recipient and the timestamp of the message"})
+			return
+		}
+	}
+
+	timestamps := groupReceipts(req.Receipts)
+	recipients := []string{}
+	for recipient := range timestamps {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+
+	result := bulkReceiptsResult{}
+	for _, recipient := range recipients {
+		err := a.markRead(a.client(number), number, signald.RequestAddress{Number: recipient}, timestamps[recipient])
+		if err != nil {
+			result.Failed = append(result.Failed, failedReceipts{Recipient: recipient, Error: err.Error()})
+			continue
+		}
+		result.Receipts += len(timestamps[recipient])
+	}
+	c.JSON(200, result)
+}
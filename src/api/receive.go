@@ -0,0 +1,134 @@
+package api
+
+import (
+	"github.com/abaskin/signald-go/signald"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxReceiveTimeout is the longest time in seconds the receive endpoint can
+// be asked to wait for messages.
+const maxReceiveTimeout = 120
+
+// incomingMessage is a message received from signald as it is returned by the
+// receive endpoint. Tags can be attached by the receive pipeline. Messages of
+// subscribed numbers have a cursor. SealedSender is set if signald reports
+// whether the message was received with sealed sender.
+type incomingMessage struct {
+	Type         string
+	ID           string
+	Data         interface{}
+	Error        error    `json:"error,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Cursor       string   `json:"cursor,omitempty"`
+	SealedSender *bool    `json:"sealed_sender,omitempty"`
+	// MessageRequest is set for the messages of senders that aren't
+	// contacts and weren't accepted yet.
+	MessageRequest bool `json:"message_request,omitempty"`
+	// Edit is set for edits of messages, the new body is in the data.
+	Edit *messageEdit `json:"edit,omitempty"`
+	// Reaction is set for reactions, with the message reacted to.
+	Reaction *messageReaction `json:"reaction,omitempty"`
+	// Typing is set for typing messages, they are only received by numbers
+	// with typing events enabled.
+	Typing *typingIndicator `json:"typing,omitempty"`
+}
+
+// receiveResults is the response of the receive endpoint.
+type receiveResults struct {
+	Type string
+	ID   string
+	Data []incomingMessage
+	Done bool `json:"done,omitempty"`
+}
+
+// envelope contains the parts of a signald message envelope the REST API
+// looks at.
+type envelope struct {
+	Username    string                 `json:"username"`
+	Type        string                 `json:"type"`
+	Source      signald.RequestAddress `json:"source"`
+	Timestamp   int64                  `json:"timestamp"`
+	DataMessage *envelopeDataMessage   `json:"dataMessage"`
+	EditMessage *envelopeEditMessage   `json:"editMessage"`
+	Receipt     *envelopeReceipt       `json:"receipt"`
+	Typing      *envelopeTyping        `json:"typing"`
+}
+
+type envelopeDataMessage struct {
+	Timestamp   int64                    `json:"timestamp"`
+	Message     string                   `json:"message"`
+	GroupInfo   *envelopeGroupInfo       `json:"groupInfo"`
+	Attachments []map[string]interface{} `json:"attachments"`
+	Reaction    *envelopeReaction        `json:"reaction"`
+}
+
+type envelopeGroupInfo struct {
+	GroupID string                   `json:"groupId"`
+	Type    string                   `json:"type"`
+	Name    string                   `json:"name"`
+	Members []signald.RequestAddress `json:"members"`
+}
+
+func (m *incomingMessage) envelope() (envelope, error) {
+	e := envelope{}
+	data, err := jsoniter.Marshal(m.Data)
+	if err != nil {
+		return e, err
+	}
+	err = jsoniter.Unmarshal(data, &e)
+	return e, err
+}
+
+// body returns the text of a data message or an empty string.
+func (m *incomingMessage) body() string {
+	e, err := m.envelope()
+	if err != nil || e.DataMessage == nil {
+		return ""
+	}
+	return e.DataMessage.Message
+}
+
+// receiveStage is one step of the pipeline incoming messages pass through
+// before they reach the caller. A stage can modify the message, returning
+// false drops it.
+type receiveStage func(number string, msg *incomingMessage) bool
+
+// processReceived passes incoming messages through the receive pipeline and
+// publishes the ones that are kept on the event bus.
+func (a *Api) processReceived(number string, messages []signald.RawResponse) []incomingMessage {
+	result := []incomingMessage{}
+	received := 0
+	for _, m := range messages {
+		msg := incomingMessage{Type: m.Type, ID: m.ID, Data: m.Data, Error: m.Error}
+		msg.SealedSender = msg.sealedSender()
+		msg.Edit = msg.edit()
+		msg.Reaction = a.reaction(number, &msg)
+
+		if msg.Type == "untrusted_identity" {
+			if identity, err := untrustedIdentityFromData(msg.Data); err == nil {
+				a.handleUntrustedIdentity(number, identity)
+			}
+		}
+		if failure, ok := decryptionFailureFrom(m); ok {
+			a.handleDecryptionFailure(number, failure, m.Data)
+		}
+
+		keep := true
+		if msg.Type == "message" {
+			received++
+			for _, stage := range a.pipeline {
+				if keep = stage(number, &msg); !keep {
+					break
+				}
+			}
+		}
+
+		if keep {
+			result = append(result, msg)
+			a.bus.publish(busEvent{Type: eventReceived, Number: number, Data: msg, Source: m.Data})
+		}
+	}
+	a.stats.received(number, received)
+	a.metrics.observeReceived(number, received)
+	return result
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const receiveBufferSize = 1000
+
+// receiveBuffer buffers the received messages of a number until they are
+// fetched with the receive endpoint, in Redis if replicas share their state
+// and in the state database otherwise, one row per message, so that the
+// messages and their cursors survive a restart. Every message gets a cursor.
+type receiveBuffer struct {
+	mutex    sync.Mutex
+	number   string
+	shared   *sharedState
+	state    stateStore
+	store    entityStore
+	messages []incomingMessage
+	last     cursor
+	sequence int64
+	arrived  chan struct{}
+}
+
+// receiveBufferState is the persisted cursor of the last message of a local
+// receive buffer, the cursors go on from it after a restart.
+type receiveBufferState struct {
+	Last string `json:"last,omitempty"`
+}
+
+// newReceiveBuffer returns the buffer name of number. It is kept in memory
+// if the state database is shared by replicas, they buffer in Redis.
+func newReceiveBuffer(shared *sharedState, db *stateDB, name string, number string) *receiveBuffer {
+	b := &receiveBuffer{
+		number:  number,
+		shared:  shared,
+		state:   stateFile{},
+		store:   memoryEntities{},
+		arrived: make(chan struct{}, 1),
+	}
+	if db == nil || db.shared() {
+		return b
+	}
+
+	name += ":" + number
+	b.state, b.store = newStateStore(db, "", name), newEntityStore(db, name)
+	if err := b.load(); err != nil {
+		log.Error("Couldn't load the receive buffer of ", number, ": ", err.Error())
+	}
+	return b
+}
+
+// load reads the buffered messages and the cursor of the last message.
+func (b *receiveBuffer) load() error {
+	state := receiveBufferState{}
+	if err := b.state.load(&state); err != nil {
+		return err
+	}
+	if state.Last != "" {
+		last, err := parseCursor(state.Last)
+		if err != nil {
+			return err
+		}
+		b.last = last
+		b.sequence, _ = strconv.ParseInt(last.id, 10, 64)
+	}
+
+	return b.store.loadAll(func(data []byte) error {
+		m := incomingMessage{}
+		if err := jsoniter.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		b.messages = append(b.messages, m)
+		return nil
+	})
+}
+
+// persist stores a buffered message and its cursor, the caller needs to hold
+// the mutex.
+func (b *receiveBuffer) persist(message incomingMessage) {
+	// errors can't be decoded again
+	message.Error = nil
+	if err := b.store.put(b.last.id, message); err != nil {
+		log.Error("Couldn't store the received message: ", err.Error())
+	}
+	if err := b.state.save(receiveBufferState{Last: b.last.String()}); err != nil {
+		log.Error("Couldn't store the receive cursor: ", err.Error())
+	}
+}
+
+// forget removes buffered messages from the store.
+func (b *receiveBuffer) forget(messages []incomingMessage) error {
+	ids := []string{}
+	for _, m := range messages {
+		if c, err := parseCursor(m.Cursor); err == nil {
+			ids = append(ids, c.id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return b.store.remove(ids...)
+}
+
+func (b *receiveBuffer) push(message incomingMessage) {
+	if b.shared != nil {
+		err := b.shared.push(b.number, message, receiveBufferSize)
+		if err == nil {
+			return
+		}
+		log.Error("Couldn't add message to the shared receive buffer: ", err.Error())
+	}
+
+	b.mutex.Lock()
+	// the cursors increase strictly, even if the clock doesn't
+	b.sequence++
+	b.last.timestamp = maxInt64(b.last.timestamp, milliseconds(time.Now()))
+	b.last.id = sequenceID(b.sequence)
+	message.Cursor = b.last.String()
+	if len(b.messages) >= receiveBufferSize {
+		log.Warn("Receive buffer of ", b.number, " is full, dropping the oldest message")
+		if err := b.forget(b.messages[:1]); err != nil {
+			log.Error("Couldn't remove the dropped message from the store: ", err.Error())
+		}
+		b.messages = b.messages[1:]
+	}
+	b.messages = append(b.messages, message)
+	b.persist(message)
+	b.mutex.Unlock()
+
+	select {
+	case b.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// fetch removes the buffered messages and returns them. If there are none it
+// waits up to timeout for messages to arrive.
+func (b *receiveBuffer) fetch(timeout time.Duration) []incomingMessage {
+	if b.shared != nil {
+		messages, err := b.shared.fetch(b.number, timeout)
+		if err == nil {
+			// messages buffered locally while Redis wasn't available
+			return append(messages, b.fetchLocal(0)...)
+		}
+		log.Error("Couldn't read the shared receive buffer: ", err.Error())
+	}
+	return b.fetchLocal(timeout)
+}
+
+func (b *receiveBuffer) fetchLocal(timeout time.Duration) []incomingMessage {
+	b.wait(timeout)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	messages := b.messages
+	b.messages = nil
+	if err := b.forget(messages); err != nil {
+		log.Error("Couldn't remove the fetched messages from the store: ", err.Error())
+	}
+	select {
+	case <-b.arrived:
+	default:
+	}
+	if messages == nil {
+		messages = []incomingMessage{}
+	}
+	return messages
+}
+
+// read acknowledges the buffered messages up to the cursor after, they are
+// removed, and returns up to limit of the messages that follow without
+// removing them. If there are none it waits up to timeout for messages to
+// arrive.
+func (b *receiveBuffer) read(after *cursor, limit int, timeout time.Duration) []incomingMessage {
+	if b.shared != nil {
+		messages, err := b.shared.read(b.number, after, limit, timeout)
+		if err == nil && limit > 0 && len(messages) >= limit {
+			return messages
+		}
+		if err == nil {
+			// messages buffered locally while Redis wasn't available
+			return append(messages, b.readLocal(after, limit-len(messages), 0)...)
+		}
+		log.Error("Couldn't read the shared receive buffer: ", err.Error())
+	}
+	return b.readLocal(after, limit, timeout)
+}
+
+func (b *receiveBuffer) readLocal(after *cursor, limit int, timeout time.Duration) []incomingMessage {
+	b.mutex.Lock()
+	if after != nil {
+		acknowledged := 0
+		for _, m := range b.messages {
+			c, _ := parseCursor(m.Cursor)
+			if after.before(c) {
+				break
+			}
+			acknowledged++
+		}
+		if err := b.forget(b.messages[:acknowledged]); err != nil {
+			log.Error("Couldn't remove the acknowledged messages from the store: ", err.Error())
+		}
+		b.messages = b.messages[acknowledged:]
+	}
+	b.mutex.Unlock()
+
+	b.wait(timeout)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	messages := append([]incomingMessage{}, b.messages...)
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+// wait waits up to timeout for messages to arrive if none are buffered.
+func (b *receiveBuffer) wait(timeout time.Duration) {
+	b.mutex.Lock()
+	empty := len(b.messages) == 0
+	b.mutex.Unlock()
+
+	if empty && timeout > 0 {
+		select {
+		case <-b.arrived:
+		case <-time.After(timeout):
+		}
+	}
+}
+
+// purgeContact removes the buffered messages that mention contact.
+func (b *receiveBuffer) purgeContact(contact string) (int, error) {
+	deleted := 0
+	if b.shared != nil {
+		n, err := b.shared.purgeReceived(b.number, contact)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	kept := []incomingMessage{}
+	removed := []incomingMessage{}
+	for _, m := range b.messages {
+		mentioned, err := messageMentions(m, contact)
+		if err != nil {
+			return deleted, err
+		}
+		if mentioned {
+			removed = append(removed, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	deleted += len(removed)
+	b.messages = kept
+	return deleted, b.forget(removed)
+}
+
+// messageMentions returns whether a received message mentions contact, as
+// sender, recipient or in the message reacted to.
+func messageMentions(m incomingMessage, contact string) (bool, error) {
+	m.Error = nil
+	data, err := jsoniter.Marshal(m)
+	if err != nil {
+		return false, err
+	}
+	return mentions(string(data), contact), nil
+}
+
+// sequenceID formats a sequence number as cursor ID, padded so that the IDs
+// compare like the numbers.
+func sequenceID(sequence int64) string {
+	return fmt.Sprintf("%020d", sequence)
+}
+
+func maxInt64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
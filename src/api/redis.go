@@ -0,0 +1,414 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gomodule/redigo/redis"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	redisKeyPrefix      = "signald-rest-api:"
+	redisDialTimeout    = 5 * time.Second
+	redisMaxIdle        = 10
+	redisIdleTimeout    = 5 * time.Minute
+	receiveDedupWindow  = time.Hour
+	receivePollInterval = 100 * time.Millisecond
+)
+
+// rateLimitScript counts ARGV[4] sends at ARGV[1] (ms) in the sliding window
+// of ARGV[2] ms if they stay within the limit ARGV[3].
+var rateLimitScript = redis.NewScript(1, `
+local now, window, limit, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) + n > limit then
+	return 0
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], now, ARGV[5] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], window)
+return 1
+`)
+
+// sharedState keeps the state that has to be consistent between replicas of
+// the API behind a load balancer in Redis: the send rate limits, the receive
+// buffers of subscribed numbers, the send queue and the idempotency keys.
+type sharedState struct {
+	pool *redis.Pool
+}
+
+func newSharedState(url string) (*sharedState, error) {
+	s := &sharedState{
+		pool: &redis.Pool{
+			MaxIdle:     redisMaxIdle,
+			IdleTimeout: redisIdleTimeout,
+			Dial: func() (redis.Conn, error) {
+				return redis.DialURL(url, redis.DialConnectTimeout(redisDialTimeout))
+			},
+		},
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		return nil, errors.New("Couldn't connect to Redis: " + err.Error())
+	}
+	return s, nil
+}
+
+func redisKey(parts ...string) string {
+	key := redisKeyPrefix
+	for i, part := range parts {
+		if i > 0 {
+			key += ":"
+		}
+		key += part
+	}
+	return key
+}
+
+func milliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// allow checks the send rate limit of number across all replicas and counts n
+// messages as sent if they are within it.
+func (s *sharedState) allow(number string, limit int, n int) (bool, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return false, err
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	allowed, err := redis.Int(rateLimitScript.Do(conn, redisKey("ratelimit", number),
+		milliseconds(time.Now()), int64(time.Minute/time.Millisecond), limit, n, id))
+	return allowed == 1, err
+}
+
+// claim reports whether an incoming message of number is received for the
+// first time. As every replica subscribes the number, only the first one
+// processes it.
+func (s *sharedState) claim(number string, message signald.RawResponse) (bool, error) {
+	// the keys are sorted, so that every replica gets the same hash
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(rawMessage{
+		Type: message.Type,
+		ID:   message.ID,
+		Data: message.Data,
+	})
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err = redis.String(conn.Do("SET", redisKey("received", number, hex.EncodeToString(sum[:])), 1,
+		"NX", "EX", int(receiveDedupWindow/time.Second)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pushReceivedScript adds the message ARGV[2] received at ARGV[1] (ms) to the
+// receive buffer KEYS[1] of at most ARGV[3] messages. The message is prefixed
+// with its cursor, the timestamp and a sequence number kept in KEYS[2], so that
+// the cursors increase strictly even if the clocks of the replicas differ.
+var pushReceivedScript = redis.NewScript(2, `
+local last = redis.call('HMGET', KEYS[2], 'timestamp', 'sequence')
+local timestamp = math.max(tonumber(ARGV[1]), tonumber(last[1]) or 0)
+local sequence = (tonumber(last[2]) or 0) + 1
+redis.call('HMSET', KEYS[2], 'timestamp', timestamp, 'sequence', sequence)
+redis.call('RPUSH', KEYS[1], string.format('%d.%d', timestamp, sequence) .. '\n' .. ARGV[2])
+redis.call('LTRIM', KEYS[1], -tonumber(ARGV[3]), -1)
+return 1
+`)
+
+// push adds a processed incoming message to the receive buffer of number.
+func (s *sharedState) push(number string, message incomingMessage, size int) error {
+	// errors can't be decoded again
+	message.Error = nil
+	data, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err = pushReceivedScript.Do(conn, redisKey("receive", number), redisKey("receive", number, "cursor"),
+		milliseconds(time.Now()), data, size)
+	return err
+}
+
+// decodeReceived decodes the messages of a receive buffer.
+func decodeReceived(buffered [][]byte) ([]incomingMessage, error) {
+	messages := []incomingMessage{}
+	for _, entry := range buffered {
+		parts := bytes.SplitN(entry, []byte("\n"), 2)
+		if len(parts) != 2 {
+			return nil, errors.New("Invalid entry in receive buffer")
+		}
+		position := strings.SplitN(string(parts[0]), ".", 2)
+		timestamp, err := strconv.ParseInt(position[0], 10, 64)
+		if err != nil || len(position) != 2 {
+			return nil, errors.New("Invalid entry in receive buffer")
+		}
+		sequence, err := strconv.ParseInt(position[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("Invalid entry in receive buffer")
+		}
+
+		m := incomingMessage{}
+		if err := jsoniter.Unmarshal(parts[1], &m); err != nil {
+			return nil, err
+		}
+		m.Cursor = cursor{timestamp: timestamp, id: sequenceID(sequence)}.String()
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// fetch removes the buffered messages of number and returns them. If there are
+// none it waits up to timeout (rounded up to full seconds) for messages to
+// arrive.
+func (s *sharedState) fetch(number string, timeout time.Duration) ([]incomingMessage, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	key := redisKey("receive", number)
+	buffered := [][]byte{}
+	if seconds := int((timeout + time.Second - 1) / time.Second); seconds > 0 {
+		// BLPOP waits forever with a timeout of 0
+		first, err := redis.ByteSlices(conn.Do("BLPOP", key, seconds))
+		if err == redis.ErrNil {
+			return []incomingMessage{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buffered = append(buffered, first[1])
+	}
+
+	conn.Send("MULTI")
+	conn.Send("LRANGE", key, 0, -1)
+	conn.Send("DEL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+	rest, err := redis.ByteSlices(replies[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceived(append(buffered, rest...))
+}
+
+// purgeReceived removes the buffered messages of number that mention contact.
+func (s *sharedState) purgeReceived(number string, contact string) (int, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	key := redisKey("receive", number)
+	buffered, err := redis.ByteSlices(conn.Do("LRANGE", key, 0, -1))
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, entry := range buffered {
+		parts := bytes.SplitN(entry, []byte("\n"), 2)
+		if len(parts) != 2 || !mentions(string(parts[1]), contact) {
+			continue
+		}
+		removed, err := redis.Int(conn.Do("LREM", key, 1, entry))
+		if err != nil {
+			return deleted, err
+		}
+		deleted += removed
+	}
+	return deleted, nil
+}
+
+// readReceivedScript removes the messages up to the cursor ARGV[1].ARGV[2]
+// from the receive buffer KEYS[1] and returns the messages up to the index
+// ARGV[3] of the rest.
+var readReceivedScript = redis.NewScript(1, `
+local timestamp, sequence = tonumber(ARGV[1]), tonumber(ARGV[2])
+while true do
+	local head = redis.call('LINDEX', KEYS[1], 0)
+	if not head then
+		break
+	end
+	local t, s = string.match(head, '^(%d+)%.(%d+)')
+	t, s = tonumber(t), tonumber(s)
+	if t > timestamp or (t == timestamp and s > sequence) then
+		break
+	end
+	redis.call('LPOP', KEYS[1])
+end
+return redis.call('LRANGE', KEYS[1], 0, tonumber(ARGV[3]))
+`)
+
+// read removes the buffered messages of number up to the cursor after and
+// returns up to limit of the messages that follow. If there are none it waits
+// up to timeout for messages to arrive.
+func (s *sharedState) read(number string, after *cursor, limit int, timeout time.Duration) ([]incomingMessage, error) {
+	timestamp, sequence := int64(-1), int64(-1)
+	if after != nil {
+		timestamp = after.timestamp
+		// cursors of other buffers don't acknowledge anything
+		sequence, _ = strconv.ParseInt(after.id, 10, 64)
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		buffered, err := redis.ByteSlices(readReceivedScript.Do(conn, redisKey("receive", number),
+			timestamp, sequence, limit-1))
+		if err != nil {
+			return nil, err
+		}
+		if len(buffered) > 0 || !time.Now().Before(deadline) {
+			return decodeReceived(buffered)
+		}
+		time.Sleep(receivePollInterval)
+	}
+}
+
+// putQueued stores a queued message, so that every replica can list and
+// remove it.
+func (s *sharedState) putQueued(m *queuedMessage) error {
+	data, err := jsoniter.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("HSET", redisKey("queue", m.Number), m.ID, data)
+	return err
+}
+
+func (s *sharedState) removeQueued(number string, id string) (bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	removed, err := redis.Int(conn.Do("HDEL", redisKey("queue", number), id))
+	return removed > 0, err
+}
+
+func (s *sharedState) isQueued(number string, id string) (bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("HEXISTS", redisKey("queue", number), id))
+}
+
+func (s *sharedState) listQueued(number string) ([]queuedMessage, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("HVALS", redisKey("queue", number)))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []queuedMessage{}
+	for _, data := range values {
+		m := queuedMessage{}
+		if err := jsoniter.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// reserve marks an idempotency key as in progress. It returns the stored
+// response if the key was used before, or false if another request with the
+// key is still in progress.
+func (s *sharedState) reserve(key string) (*idempotentResponse, bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := redis.String(conn.Do("SET", redisKey("idempotency", key), "", "NX", "EX",
+		int(idempotencyKeyTTL/time.Second)))
+	if err == nil {
+		return nil, true, nil
+	}
+	if err != redis.ErrNil {
+		return nil, false, err
+	}
+
+	data, err := redis.Bytes(conn.Do("GET", redisKey("idempotency", key)))
+	if err == redis.ErrNil || len(data) == 0 {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	response := &idempotentResponse{}
+	if err := jsoniter.Unmarshal(data, response); err != nil {
+		return nil, false, err
+	}
+	return response, false, nil
+}
+
+func (s *sharedState) complete(key string, response *idempotentResponse) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if response == nil {
+		_, err := conn.Do("DEL", redisKey("idempotency", key))
+		return err
+	}
+
+	data, err := jsoniter.Marshal(response)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("SET", redisKey("idempotency", key), data, "EX", int(idempotencyKeyTTL/time.Second))
+	return err
+}
+
+// renewLockScript extends the lock KEYS[1] by ARGV[2] ms if it is held by
+// ARGV[1].
+var renewLockScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// acquireLock takes the lock name for owner if it isn't held, or extends it if
+// owner holds it already. It returns whether owner holds the lock.
+func (s *sharedState) acquireLock(name string, owner string, ttl time.Duration) (bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	key := redisKey("lock", name)
+	renewed, err := redis.Int(renewLockScript.Do(conn, key, owner, int64(ttl/time.Millisecond)))
+	if err != nil || renewed == 1 {
+		return renewed == 1, err
+	}
+
+	_, err = redis.String(conn.Do("SET", key, owner, "NX", "PX", int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// The states of the registration of a number.
+const (
+	registrationUnregistered    = "unregistered"
+	registrationCaptchaRequired = "captcha_required"
+	registrationPending         = "pending_verification"
+	registrationActive          = "active"
+)
+
+// registrationStatus is the state of the registration of a number with a
+// hint what to do next.
+type registrationStatus struct {
+	Number   string `json:"number"`
+	State    string `json:"state"`
+	DeviceID int    `json:"device_id,omitempty"`
+	Hint     string `json:"hint"`
+	// Verification is the state of the verification codes requested for a
+	// number that isn't active yet.
+	Verification *verificationState `json:"verification,omitempty"`
+}
+
+// registrationConflictError is returned when registering a number that is
+// already registered and verified.
+type registrationConflictError struct {
+	status registrationStatus
+}
+
+func (e *registrationConflictError) Error() string {
+	return "The number " + e.status.Number + " is already registered and verified"
+}
+
+// registrationStatus returns the state of the registration of number.
+func (a *Api) registrationStatus(number string) (registrationStatus, error) {
+	account, found, err := a.getAccount(number)
+	if err != nil {
+		return registrationStatus{}, err
+	}
+
+	status := registrationStatus{Number: number, DeviceID: account.DeviceID}
+	if found && account.Registered {
+		a.verifications.verified(number)
+	} else if verification, ok := a.verifications.state(number); ok {
+		status.Verification = &verification
+	}
+
+	_, challenged := a.captchas.challenge(number)
+	switch {
+	case found && account.Registered:
+		status.State = registrationActive
+		status.Hint = "The number is active. Register it with force=true to register it again, " +
+			"which replaces the existing registration."
+	case challenged:
+		status.State = registrationCaptchaRequired
+		status.Hint = "Signal requires a captcha, solve it at " + captchaPageURL + " and register again with the captcha."
+	case found || status.Verification != nil:
+		status.State = registrationPending
+		status.Hint = "Verify the number with the code it received, or request a new code by SMS or voice call."
+		if status.Verification != nil && status.Verification.AttemptsRemaining == 0 {
+			status.Hint = "Verify the number with one of the codes it received, no more codes can be requested."
+		}
+	default:
+		status.State = registrationUnregistered
+		status.Hint = "Register the number to receive a verification code."
+	}
+	return status, nil
+}
+
+// registerNumber registers number unless it is already active. A pending
+// registration is started again, which sends a new code by SMS or voice call,
+// as long as the limits of requesting codes allow it.
+func (a *Api) registerNumber(number string, captcha string, voice bool, force bool) error {
+	if !force {
+		status, err := a.registrationStatus(number)
+		if err != nil {
+			return err
+		}
+		if status.State == registrationActive {
+			return &registrationConflictError{status: status}
+		}
+	}
+
+	if err := a.verifications.allow(number); err != nil {
+		return err
+	}
+	if err := a.register(number, captcha, voice); err != nil {
+		return err
+	}
+	a.verifications.requested(number, voice)
+	a.listings.invalidate(accountsListingKey)
+	return nil
+}
+
+// @Summary Show the registration state of a number.
+// @Tags Devices
+// @Description Show whether a number is unregistered, needs a captcha, waits for its verification code or is active, with a hint what to do next. For a number waiting for its code the codes requested, how the last one was delivered (SMS or voice call), the number of codes that can still be requested and when the next one can be requested are shown.
+// @Produce  json
+// @Success 200 {object} registrationStatus
+// @Failure 400 {object} Error
+// @Param number path string true "Phone Number"
+// @Router /v1/register/{number}/status [get]
+func (a *Api) GetRegistrationStatus(c *gin.Context) {
+	status, err := a.registrationStatus(c.Param("number"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, status)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// sendRequest is the send request of signald with the options signald-go
+// doesn't know. signald versions that don't support an option ignore it.
+type sendRequest struct {
+	signald.Request
+	// Timestamp is the timestamp of the message, which identifies it in
+	// receipts and reactions.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// signaldSend sends a message with the given timestamp via signald.
+func (a *Api) signaldSend(number string, to signald.RequestAddress, groupID string, message string,
+	attachments []signald.RequestAttachment, timestamp int64) (signald.Response, error) {
+	return a.observedSend(number, a.newSendRequest(number, to, groupID, message, attachments, timestamp))
+}
+
+func (a *Api) newSendRequest(number string, to signald.RequestAddress, groupID string, message string,
+	attachments []signald.RequestAttachment, timestamp int64) sendRequest {
+	request := sendRequest{
+		Request: signald.Request{
+			Type:             "send",
+			Username:         number,
+			RecipientGroupID: groupID,
+			MessageBody:      message,
+			Attachments:      attachments,
+		},
+		Timestamp: timestamp,
+	}
+	if !to.Empty() {
+		request.RecipientAddress = &to
+	}
+	return request
+}
+
+// observedSend sends request and records it in the metrics.
+func (a *Api) observedSend(number string, request sendRequest) (signald.Response, error) {
+	start := time.Now()
+	resp, err := a.requestSend(number, request)
+	recipient := convertInternalGroupIDToGroupID(request.RecipientGroupID)
+	if request.RecipientGroupID == "" && request.RecipientAddress != nil {
+		recipient = request.RecipientAddress.Number
+	}
+	a.metrics.observeSend(number, recipient, time.Since(start), resp, err)
+	return resp, err
+}
+
+// requestSend sends a send request on the shared connection to the backend of
+// number and returns the response like signald-go does.
+func (a *Api) requestSend(number string, request sendRequest) (signald.Response, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return signald.Response{}, err
+	}
+	request.ID = id
+
+	raw, err := a.muxes.get(a.backends.socketPath(number)).request(id, request)
+	if err != nil {
+		return signald.Response{}, err
+	}
+
+	response := signald.Response{}
+	data, err := jsoniter.Marshal(raw)
+	if err == nil {
+		err = jsoniter.Unmarshal(data, &response)
+	}
+	if err != nil {
+		return response, errors.New("Couldn't decode the response of signald: " + err.Error())
+	}
+	if proof, ok := proofRequiredFrom(raw); ok {
+		return response, proof
+	}
+	if response.Type != "send_results" {
+		return response, signaldError(raw)
+	}
+	return response, nil
+}
+
+// sealedSender returns whether the message was received with sealed sender
+// (unidentified delivery), if signald reports it.
+func (m *incomingMessage) sealedSender() *bool {
+	data, ok := m.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	// the legacy and the versioned protocol of signald name it differently
+	for _, key := range []string{"isUnidentifiedSender", "unidentified_sender"} {
+		if sealed, ok := data[key].(bool); ok {
+			return &sealed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// searchQuery is a search in the message store. All terms need to match the
+// body of a message.
+type searchQuery struct {
+	terms  []string
+	sender string
+	group  string
+	from   int64
+	to     int64
+}
+
+// matches returns whether a message of the conversation with peer passes the
+// filters of the query.
+func (q searchQuery) matches(peer string, isGroup bool, m storedMessage) bool {
+	if m.Deleted != nil {
+		return false
+	}
+	if q.sender != "" && m.Sender != q.sender {
+		return false
+	}
+	if q.group != "" && (!isGroup || peer != q.group) {
+		return false
+	}
+	if q.from != 0 && m.Timestamp < q.from {
+		return false
+	}
+	if q.to != 0 && m.Timestamp > q.to {
+		return false
+	}
+	return true
+}
+
+// matchesTerms returns whether all terms are contained in body, ignoring the
+// case. It is used when there is no full-text index.
+func (q searchQuery) matchesTerms(body string) bool {
+	body = strings.ToLower(body)
+	for _, t := range q.terms {
+		if !strings.Contains(body, strings.ToLower(t)) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchHit is a message found in the message store with its conversation.
+type searchHit struct {
+	storedMessage
+	Peer    string `json:"peer"`
+	IsGroup bool   `json:"is_group"`
+}
+
+// searchIndex is a SQLite FTS5 full-text index of the bodies of the messages
+// in the message store. It only yields candidates, the message store remains
+// the source of the messages and of their state.
+type searchIndex struct {
+	db *sql.DB
+}
+
+// newSearchIndex creates the full-text index in the SQLite database. Without a
+// SQLite database no index is used, nil is returned.
+func newSearchIndex(db *stateDB) (*searchIndex, error) {
+	if db == nil || db.driver != "sqlite3" {
+		return nil, nil
+	}
+	_, err := db.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS message_search USING fts5(
+		body,
+		number UNINDEXED,
+		peer UNINDEXED,
+		id UNINDEXED
+	)`)
+	if err != nil {
+		return nil, errors.New("Couldn't create full-text index (SQLite without FTS5?): " + err.Error())
+	}
+	return &searchIndex{db: db.db}, nil
+}
+
+// dropSearchIndex removes the full-text index, which has the bodies of the
+// messages in plain text, when the message store is encrypted.
+func dropSearchIndex(db *stateDB) error {
+	if db == nil || db.driver != "sqlite3" {
+		return nil
+	}
+	_, err := db.db.Exec("DROP TABLE IF EXISTS message_search")
+	return err
+}
+
+func (i *searchIndex) put(number string, peer string, m storedMessage) error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM message_search WHERE number = ? AND id = ?", number, m.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO message_search (body, number, peer, id) VALUES (?, ?, ?, ?)", m.Body, number, peer, m.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (i *searchIndex) remove(number string, ids []string) error {
+	for _, id := range ids {
+		if _, err := i.db.Exec("DELETE FROM message_search WHERE number = ? AND id = ?", number, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuild replaces the index with the messages of accounts.
+func (i *searchIndex) rebuild(accounts map[string]map[string]*storedConversation) error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM message_search"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for number, conversations := range accounts {
+		for peer, c := range conversations {
+			for _, m := range c.Messages {
+				if _, err := tx.Exec("INSERT INTO message_search (body, number, peer, id) VALUES (?, ?, ?, ?)", m.Body, number, peer, m.ID); err != nil {
+					tx.Rollback()
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// match returns the peers of the messages of number that contain all terms,
+// by message ID.
+func (i *searchIndex) match(number string, terms []string) (map[string]string, error) {
+	// every term is quoted, so that the query syntax of FTS5 doesn't apply
+	quoted := make([]string, len(terms))
+	for n, t := range terms {
+		quoted[n] = `"` + strings.Replace(t, `"`, `""`, -1) + `"`
+	}
+
+	rows, err := i.db.Query("SELECT peer, id FROM message_search WHERE message_search MATCH ? AND number = ?",
+		strings.Join(quoted, " "), number)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[string]string)
+	for rows.Next() {
+		var peer, id string
+		if err := rows.Scan(&peer, &id); err != nil {
+			return nil, err
+		}
+		matches[id] = peer
+	}
+	return matches, rows.Err()
+}
+
+// search returns the messages of number that match q, the newest first.
+func (s *messageStore) search(number string, q searchQuery) ([]searchHit, error) {
+	var matches map[string]string
+	if s.index != nil {
+		var err error
+		if matches, err = s.index.match(number, q.terms); err != nil {
+			return nil, errors.New("Couldn't search the full-text index: " + err.Error())
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	hits := []searchHit{}
+	for peer, c := range s.accounts[number] {
+		for _, m := range c.Messages {
+			if matches != nil {
+				if matches[m.ID] != peer {
+					continue
+				}
+			} else if !q.matchesTerms(m.Body) {
+				continue
+			}
+			if q.matches(peer, c.IsGroup, m) {
+				hits = append(hits, searchHit{storedMessage: m, Peer: peer, IsGroup: c.IsGroup})
+			}
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[j].cursor().before(hits[i].cursor()) })
+	return hits, nil
+}
+
+// setIndex makes the message store keep index up to date, which is rebuilt
+// from the messages in the store.
+func (s *messageStore) setIndex(index *searchIndex) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := index.rebuild(s.accounts); err != nil {
+		return errors.New("Couldn't build full-text index: " + err.Error())
+	}
+	s.index = index
+	return nil
+}
+
+// indexed adds a message to the full-text index, if there is one.
+func (s *messageStore) indexed(number string, peer string, m storedMessage) {
+	if s.index == nil {
+		return
+	}
+	if err := s.index.put(number, peer, m); err != nil {
+		log.Error("Couldn't update full-text index: ", err.Error())
+	}
+}
+
+// unindexed removes messages from the full-text index, if there is one.
+func (s *messageStore) unindexed(number string, ids []string) {
+	if s.index == nil || len(ids) == 0 {
+		return
+	}
+	if err := s.index.remove(number, ids); err != nil {
+		log.Error("Couldn't update full-text index: ", err.Error())
+	}
+}
+
+// parseSearchTime parses a timestamp in milliseconds or an RFC 3339 time.
+func parseSearchTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, errors.New("Invalid time " + value + " (a timestamp in milliseconds or an RFC 3339 time)")
+	}
+	return milliseconds(t), nil
+}
+
+// @Summary Search the messages of a number.
+// @Tags Messages
+// @Description Full-text search in the messages of the message store, the newest first. All words of the query need to be contained in a message. The results can be filtered by sender, group and time. The listing is paginated, the total number of results is returned in the X-Total-Count header.
+// @Produce  json
+// @Success 200 {object} []searchHit
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param q query string true "Words to search for"
+// @Param sender query string false "Phone Number of the Sender"
+// @Param group query string false "Group ID"
+// @Param from query string false "Oldest message, timestamp in milliseconds or RFC 3339 time"
+// @Param to query string false "Newest message, timestamp in milliseconds or RFC 3339 time"
+// @Param offset query int false "Number of results to skip"
+// @Param limit query int false "Maximum number of results to return"
+// @Router /v1/search/messages/{number} [get]
+func (a *Api) SearchMessages(c *gin.Context) {
+	if a.store == nil {
+		c.JSON(400, gin.H{"error": "The message store is disabled"})
+		return
+	}
+
+	q := searchQuery{
+		terms:  strings.Fields(c.Query("q")),
+		sender: c.Query("sender"),
+		group:  c.Query("group"),
+	}
+	if len(q.terms) == 0 {
+		c.JSON(400, gin.H{"error": "Please specify the words to search for with q"})
+		return
+	}
+
+	var err error
+	if q.from, err = parseSearchTime(c.Query("from")); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if q.to, err = parseSearchTime(c.Query("to")); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := parsePage(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	hits, err := a.store.search(c.Param("number"), q)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	start, end := p.bounds(c, len(hits))
+	c.JSON(200, hits[start:end])
+}
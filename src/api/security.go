@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecuritySettings configure the security headers and the redirect to HTTPS.
+type SecuritySettings struct {
+	// Headers enables the security headers.
+	Headers bool
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header,
+	// which is only sent on HTTPS requests (0 disables it).
+	HSTSMaxAge time.Duration
+	// HTTPSRedirect redirects requests over plain HTTP to HTTPS.
+	HTTPSRedirect bool
+}
+
+// isHTTPS returns whether the request reached the API over HTTPS, directly or
+// through a reverse proxy that terminates TLS and sets X-Forwarded-Proto.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	proto := strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0]
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// SecurityHeaders adds the security headers to every response and redirects
+// requests over plain HTTP to HTTPS if enabled. The health checks are never
+// redirected, so that probes can keep using plain HTTP.
+func SecurityHeaders(s SecuritySettings) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		https := isHTTPS(c.Request)
+
+		if s.HTTPSRedirect && !https && !strings.HasPrefix(c.Request.URL.Path, "/v1/health") {
+			target := "https://" + c.Request.Host + c.Request.URL.RequestURI()
+			status := http.StatusMovedPermanently
+			if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				// keeps the method and the body
+				status = http.StatusPermanentRedirect
+			}
+			c.Redirect(status, target)
+			c.Abort()
+			return
+		}
+
+		if s.Headers {
+			h := c.Writer.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Content-Security-Policy", "frame-ancestors 'none'")
+			h.Set("Referrer-Policy", "no-referrer")
+			if https && s.HSTSMaxAge > 0 {
+				h.Set("Strict-Transport-Security", "max-age="+strconv.FormatInt(int64(s.HSTSMaxAge/time.Second), 10))
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const selfTestMessage = "signald-rest-api self-test"
+
+// selfTestClient is the client self-test messages are sent as, its quotas
+// apply.
+const selfTestClient = "selftest"
+
+type selfTestStep struct {
+	Name       string  `json:"name"`
+	Success    bool    `json:"success"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type selfTestReport struct {
+	Number     string         `json:"number"`
+	Success    bool           `json:"success"`
+	DurationMs float64        `json:"duration_ms"`
+	Steps      []selfTestStep `json:"steps"`
+}
+
+// step runs one step of the self-test and records its outcome. The
+// following steps are skipped once one failed.
+func (r *selfTestReport) step(name string, run func() error) {
+	if !r.Success {
+		return
+	}
+
+	start := time.Now()
+	err := run()
+	step := selfTestStep{Name: name, Success: err == nil, DurationMs: durationMs(time.Since(start))}
+	if err != nil {
+		step.Error = err.Error()
+		r.Success = false
+	}
+	r.Steps = append(r.Steps, step)
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sendResultsError returns an error if a send result reports a failure.
+func sendResultsError(results []signald.SendResult) error {
+	for _, result := range results {
+		if result.NetworkFailure {
+			return errors.New("Network failure sending to " + result.Address.Number)
+		}
+		if result.UnregisteredFailure {
+			return errors.New(result.Address.Number + " isn't registered")
+		}
+	}
+	return nil
+}
+
+// @Summary Run a self-test of a number.
+// @Tags General
+// @Description Sends a note to self from the number through signald and reports the duration and outcome of every step, as smoke test after a deploy. The message takes the same path as other messages (maintenance, rate limits, quotas of the client selftest, send hooks), the test fails if it is queued instead of sent.
+// @Produce  json
+// @Success 200 {object} selfTestReport
+// @Failure 503 {object} selfTestReport
+// @Param number path string true "Registered Phone Number"
+// @Router /v1/selftest/{number} [post]
+func (a *Api) SelfTest(c *gin.Context) {
+	number := c.Param("number")
+
+	start := time.Now()
+	report := selfTestReport{Number: number, Success: true, Steps: []selfTestStep{}}
+	report.step("account", func() error {
+		account, found, err := a.getAccount(number)
+		if err != nil {
+			return err
+		}
+		if !found || !account.Registered {
+			return errors.New(number + " isn't registered")
+		}
+		return nil
+	})
+	report.step("send", func() error {
+		message := selfTestMessage + " " + time.Now().UTC().Format(time.RFC3339)
+		result, err := a.service.Submit(service.Send{Number: number, Message: message, Recipients: []string{number},
+			Client: selfTestClient})
+		if err != nil {
+			return err
+		}
+		if len(result.Queued) > 0 {
+			return errors.New("The message was queued (" + strings.Join(result.Queued, ", ") + ") instead of sent")
+		}
+		return sendResultsError(result.Results)
+	})
+	report.DurationMs = durationMs(time.Since(start))
+
+	if !report.Success {
+		log.Warn("Self-test of ", number, " failed")
+		c.JSON(503, report)
+		return
+	}
+	c.JSON(200, report)
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// errorStatuses are the HTTP statuses of the kinds of service errors.
+var errorStatuses = map[service.Kind]int{
+	service.Invalid:     400,
+	service.Forbidden:   403,
+	service.NotFound:    404,
+	service.Conflict:    409,
+	service.RateLimited: 429,
+	service.Unsupported: 501,
+	service.Failed:      502,
+	service.Unavailable: 503,
+	service.Timeout:     504,
+	service.StorageFull: 507,
+}
+
+// errorStatus returns the HTTP status of an error of the service.
+func errorStatus(err error) int {
+	if _, ok := err.(*registrationConflictError); ok {
+		return 409
+	}
+	return errorStatuses[service.KindOf(err)]
+}
+
+// setErrorHeaders sets the headers of the response to an error of the
+// service.
+func setErrorHeaders(c *gin.Context, err error) {
+	if e, ok := err.(*service.Error); ok {
+		if e.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+		}
+		for name, value := range e.Metadata {
+			c.Header(name, value)
+		}
+	}
+}
+
+// respondError responds with an error of the service.
+func respondError(c *gin.Context, err error) {
+	setErrorHeaders(c, err)
+	c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+}
+
+// sendsTo returns the sends to a list of numbers and group ids: one to all
+// numbers and one to each group.
+func sendsTo(recipients []string) []service.Send {
+	sends := []service.Send{}
+	numbers := []string{}
+	for _, recipient := range recipients {
+		if hasGroupPrefix(recipient) {
+			sends = append(sends, service.Send{Recipients: []string{recipient}, IsGroup: true})
+		} else {
+			numbers = append(numbers, recipient)
+		}
+	}
+	if len(numbers) > 0 {
+		sends = append(sends, service.Send{Recipients: numbers})
+	}
+	return sends
+}
+
+// acquire tracks an operation of number on its backend, it fails while the
+// backend is draining or number isn't an account of this instance.
+func (a *Api) acquire(number string) (func(), error) {
+	release, err := a.backends.acquire(number)
+	if err != nil {
+		return nil, &service.Error{Kind: service.Unavailable, Message: err.Error(), RetryAfter: backendRetryAfter}
+	}
+	if err := a.knownAccount(number); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// serviceBackend is the backend of the service: signald and the subsystems of
+// the API a message passes.
+type serviceBackend struct {
+	a *Api
+}
+
+func (b serviceBackend) MaintenanceActive() bool {
+	return b.a.maintenance.active()
+}
+
+func (b serviceBackend) Hold(send service.Send) (string, error) {
+	return b.a.maintenance.hold(send)
+}
+
+func (b serviceBackend) KnownAccount(number string) error {
+	return b.a.knownAccount(number)
+}
+
+func (b serviceBackend) Acquire(number string) (func(), error) {
+	return b.a.acquire(number)
+}
+
+func (b serviceBackend) AllowSend(number string, recipients int) bool {
+	return b.a.accounts.allow(number, recipients)
+}
+
+func (b serviceBackend) ResolveGroup(number string, group string) (string, error) {
+	internalID, err := b.a.resolveGroupID(number, group)
+	if err != nil {
+		return "", groupError(err)
+	}
+	return internalID, nil
+}
+
+func (b serviceBackend) GroupID(internalID string) string {
+	return convertInternalGroupIDToGroupID(internalID)
+}
+
+func (b serviceBackend) ApplyHooks(number string, recipients []string, isGroup bool, message string,
+	attachments int) (string, error) {
+	msg, err := b.a.sendHooks.apply(outgoingMessage{
+		Number:      number,
+		Recipients:  recipients,
+		IsGroup:     isGroup,
+		Message:     message,
+		Attachments: attachments,
+	})
+	if err != nil {
+		if _, ok := err.(*vetoError); ok {
+			return "", service.NewError(service.Forbidden, err.Error())
+		}
+		return "", err
+	}
+	return msg.Message, nil
+}
+
+func (b serviceBackend) StageAttachment(ctx context.Context, encoded string) (service.Attachment, error) {
+	hash, filename, err := b.a.attachments.stageBase64(ctx, encoded)
+	return service.Attachment{Hash: hash, Filename: filename}, err
+}
+
+func (b serviceBackend) LookupAttachment(token string) (service.Attachment, bool) {
+	filename, ok := b.a.attachments.lookup(token)
+	return service.Attachment{Hash: token, Filename: filename}, ok
+}
+
+func (b serviceBackend) ReleaseAttachment(hash string) {
+	b.a.attachments.release(hash)
+}
+
+func (b serviceBackend) TranscodeVideo(ctx context.Context, attachment service.Attachment) (service.Attachment,
+	bool, error) {
+	if b.a.videos == nil || !isVideo(attachment.Filename) {
+		return service.Attachment{}, false, nil
+	}
+	transcoded, err := b.a.videos.check(ctx, attachment.Filename)
+	if err != nil || transcoded == "" {
+		return service.Attachment{}, false, err
+	}
+	hash, filename, err := b.a.attachments.stageFile(transcoded, "mp4")
+	if err != nil {
+		return service.Attachment{}, false, err
+	}
+	return service.Attachment{Hash: hash, Filename: filename}, true, nil
+}
+
+func (b serviceBackend) SyncAttachments(ctx context.Context) error {
+	return b.a.attachments.syncer.await(ctx)
+}
+
+func (b serviceBackend) AllowQuota(client string, recipients int, attachments []service.Attachment) (map[string]string,
+	error) {
+	return b.a.quotas.allow(client, recipients, int64(recipients)*attachmentBytes(filenamesOf(attachments)))
+}
+
+func (b serviceBackend) Deliver(number string, d service.Delivery) (service.Outcome, error) {
+	start := time.Now()
+	attachments := []signald.RequestAttachment{}
+	for _, filename := range filenamesOf(d.Attachments) {
+		attachments = append(attachments, signald.RequestAttachment{Filename: filename})
+	}
+	resp, err := b.a.sendMessage(number, signald.RequestAddress{Number: d.To}, d.GroupID, d.Message,
+		attachments, d.Timestamp)
+	b.a.stats.sent(number, time.Since(start), err)
+
+	_, proofRequired := err.(*proofRequiredError)
+	return service.Outcome{
+		Results:       resp.Data.SendResults,
+		ProofRequired: proofRequired,
+		Untrusted:     err != nil && resp.Type == "untrusted_identity",
+	}, err
+}
+
+func (b serviceBackend) ParkChallenged(number string, cause error, d service.Delivery) (string, error) {
+	return b.a.parkChallenged(number, cause.(*proofRequiredError), parkedSend{
+		Recipient:       d.To,
+		internalGroupID: d.GroupID,
+		message:         d.Message,
+		hashes:          hashesOf(d.Attachments),
+	})
+}
+
+func (b serviceBackend) QueueUntrusted(number string, d service.Delivery) (string, bool) {
+	if b.a.queue == nil {
+		return "", false
+	}
+	identity := b.a.findUntrustedIdentity(number, signald.RequestAddress{Number: d.To})
+	m, err := b.a.queue.park(number, d.To, d.Message, hashesOf(d.Attachments), identity.Fingerprint)
+	if err != nil {
+		log.Error("Couldn't queue message: ", err.Error())
+		return "", false
+	}
+	log.Info("Queued message ", m.ID, " until the identity of the recipient is trusted")
+	return m.ID, true
+}
+
+func (b serviceBackend) Verify(number string, code string, pin string) error {
+	if resp, err := b.a.client(number).Verify(number, code, pin); err != nil {
+		b.a.metrics.observeError(number, "verify", resp, err)
+		return err
+	}
+	b.a.verifications.verified(number)
+	b.a.listings.invalidate(accountsListingKey)
+	b.a.directory.invalidate()
+	return nil
+}
+
+func (b serviceBackend) CreateGroup(number string, name string, members []string) error {
+	_, err := b.a.client(number).CreateGroup(number, "", name, members, "")
+	return err
+}
+
+func (b serviceBackend) ListGroups(number string) ([]service.Group, error) {
+	message, err := b.a.client(number).ListGroups(number)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []service.Group{}
+	for _, group := range message.Data.Groups {
+		groups = append(groups, service.Group{InternalID: group.GroupID, Name: group.Name})
+	}
+	return groups, nil
+}
+
+func (b serviceBackend) LeaveGroup(number string, internalID string) error {
+	if _, err := b.a.client(number).LeaveGroup(number, internalID); err != nil {
+		return groupError(err)
+	}
+	return nil
+}
+
+func (b serviceBackend) RenameGroup(number string, internalID string, name string) error {
+	if _, err := b.a.client(number).CreateGroup(number, internalID, name, nil, ""); err != nil {
+		return groupError(err)
+	}
+	return nil
+}
+
+func (b serviceBackend) SetGroupExpiration(number string, internalID string, seconds int) error {
+	_, err := b.a.client(number).SetExpiration(number, signald.RequestAddress{}, internalID, seconds)
+	if err != nil {
+		return groupError(err)
+	}
+	return nil
+}
+
+func (b serviceBackend) PromoteGroupMember(number string, internalID string, member string) error {
+	return b.a.promoteGroupMember(number, internalID, member)
+}
+
+func (b serviceBackend) LastGroupAdmin(number string, internalID string) bool {
+	return b.a.lastGroupAdmin(number, internalID)
+}
+
+func (b serviceBackend) GroupsChanged(number string) {
+	b.a.listings.invalidate(groupsListingKey(number))
+}
+
+func filenamesOf(attachments []service.Attachment) []string {
+	filenames := []string{}
+	for _, attachment := range attachments {
+		filenames = append(filenames, attachment.Filename)
+	}
+	return filenames
+}
+
+func hashesOf(attachments []service.Attachment) []string {
+	hashes := []string{}
+	for _, attachment := range attachments {
+		hashes = append(hashes, attachment.Hash)
+	}
+	return hashes
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// resetSession ends the Signal session of number with recipient. signald
+// sends an end session message and drops the session state, both sides start
+// a new session with the next message.
+func (a *Api) resetSession(number string, recipient string) error {
+	if err := a.supports(number, capabilitySessionReset); err != nil {
+		return err
+	}
+
+	resp, err := a.requestSignald(number, map[string]interface{}{
+		"type":    "reset_session",
+		"account": number,
+		"address": recipientAddress(recipient),
+	})
+	if err != nil {
+		return service.NewError(service.Failed, err.Error())
+	}
+	if resp.Type != "reset_session" {
+		return service.NewError(service.Invalid, "Couldn't reset the session: "+signaldError(resp).Error())
+	}
+	log.Info("Reset the session of ", number, " with ", recipient)
+	return nil
+}
+
+// @Summary Reset the session with a recipient.
+// @Tags Messages
+// @Description Send an end session message to the recipient and drop the session state, e.g. when messages of the recipient can't be decrypted anymore. A new session is started with the next message.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Failure 501 {object} Error
+// @Failure 502 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param recipient path string true "Phone Number or UUID of the Recipient"
+// @Router /v1/sessions/{number}/{recipient}/reset [post]
+func (a *Api) ResetSession(c *gin.Context) {
+	if err := a.resetSession(c.Param("number"), c.Param("recipient")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(204)
+}
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// AccountSettings are the settings of a single registered number.
+type AccountSettings struct {
+	// WebhookURLs receive the events of the number in addition to the
+	// global webhooks.
+	WebhookURLs []string `json:"webhook_urls,omitempty"`
+	// SendRateLimit is the maximum number of messages sent per minute, 0
+	// means unlimited.
+	SendRateLimit int `json:"send_rate_limit,omitempty"`
+	// Retention is how long queued messages are kept.
+	Retention string `json:"retention,omitempty"`
+	// AutoReadReceipts marks received messages as read.
+	AutoReadReceipts bool `json:"auto_read_receipts,omitempty"`
+	// SyncInterval is how often contacts, groups and configuration are
+	// synced from the primary device.
+	SyncInterval string `json:"sync_interval,omitempty"`
+	// DrainInterval is how often the messages are received in the background
+	// if the number only sends.
+	DrainInterval string `json:"drain_interval,omitempty"`
+	// TypingEvents keeps the typing messages in the received messages, they
+	// are dropped otherwise.
+	TypingEvents bool `json:"typing_events,omitempty"`
+
+	retention     time.Duration
+	syncInterval  time.Duration
+	drainInterval time.Duration
+}
+
+// LoadAccountSettings reads the settings of the numbers from a JSON file that
+// maps numbers to their settings.
+func LoadAccountSettings(filename string) (map[string]AccountSettings, error) {
+	settings := make(map[string]AccountSettings)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return settings, err
+	}
+
+	if err := jsoniter.Unmarshal(data, &settings); err != nil {
+		return settings, errors.New("Couldn't parse account settings config: " + err.Error())
+	}
+
+	return settings, nil
+}
+
+func (s *AccountSettings) init() error {
+	if s.SendRateLimit < 0 {
+		return errors.New("Invalid send rate limit")
+	}
+
+	var err error
+	if s.Retention != "" {
+		if s.retention, err = time.ParseDuration(s.Retention); err != nil || s.retention <= 0 {
+			return errors.New("Invalid retention " + s.Retention)
+		}
+	}
+	if s.SyncInterval != "" {
+		if s.syncInterval, err = time.ParseDuration(s.SyncInterval); err != nil || s.syncInterval < time.Minute {
+			return errors.New("Invalid sync interval " + s.SyncInterval + " (minimum 1m)")
+		}
+	}
+	if s.DrainInterval != "" {
+		if s.drainInterval, err = time.ParseDuration(s.DrainInterval); err != nil || s.drainInterval < minDrainInterval {
+			return errors.New("Invalid drain interval " + s.DrainInterval + " (minimum 10s)")
+		}
+	}
+	return nil
+}
+
+// accountRegistry holds the settings of the numbers. Settings changed via the
+// admin API are persisted and take precedence over the config file.
+type accountRegistry struct {
+	mutex     sync.Mutex
+	state     stateStore
+	defaults  map[string]AccountSettings
+	overrides map[string]AccountSettings
+	sent      map[string][]time.Time
+	synced    map[string]time.Time
+	shared    *sharedState
+}
+
+func newAccountRegistry(defaults map[string]AccountSettings, state stateStore) (*accountRegistry, error) {
+	r := &accountRegistry{
+		state:     state,
+		defaults:  make(map[string]AccountSettings),
+		overrides: make(map[string]AccountSettings),
+		sent:      make(map[string][]time.Time),
+		synced:    make(map[string]time.Time),
+	}
+
+	for number, settings := range defaults {
+		if err := settings.init(); err != nil {
+			return nil, errors.New("Invalid settings of " + number + ": " + err.Error())
+		}
+		r.defaults[number] = settings
+	}
+
+	overrides := make(map[string]AccountSettings)
+	if err := state.load(&overrides); err != nil {
+		return nil, errors.New("Couldn't load account settings: " + err.Error())
+	}
+	for number, settings := range overrides {
+		if err := settings.init(); err != nil {
+			return nil, errors.New("Invalid settings of " + number + ": " + err.Error())
+		}
+		r.overrides[number] = settings
+	}
+
+	return r, nil
+}
+
+// initOverrides parses the settings another instance saved, the caller
+// needs to hold the mutex.
+func (r *accountRegistry) initOverrides() {
+	for number, settings := range r.overrides {
+		if err := settings.init(); err != nil {
+			log.Error("Invalid settings of ", number, ": ", err.Error())
+			delete(r.overrides, number)
+			continue
+		}
+		r.overrides[number] = settings
+	}
+}
+
+// refresh loads the settings other instances changed, the caller needs to
+// hold the mutex.
+func (r *accountRegistry) refresh() {
+	reloaded, err := r.state.refresh(&r.overrides)
+	if err != nil {
+		log.Error("Couldn't load account settings: ", err.Error())
+		return
+	}
+	if reloaded {
+		r.initOverrides()
+	}
+}
+
+// update changes the settings set via the admin API and saves them, the
+// caller needs to hold the mutex.
+func (r *accountRegistry) update(change func()) error {
+	return r.state.update(&r.overrides, func(reloaded bool) error {
+		if reloaded {
+			r.initOverrides()
+		}
+		change()
+		return nil
+	})
+}
+
+func (r *accountRegistry) get(number string) AccountSettings {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	if settings, ok := r.overrides[number]; ok {
+		return settings
+	}
+	return r.defaults[number]
+}
+
+func (r *accountRegistry) put(number string, settings AccountSettings) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() { r.overrides[number] = settings })
+}
+
+// overridden returns the settings set via the admin API.
+func (r *accountRegistry) overridden() map[string]AccountSettings {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	overrides := make(map[string]AccountSettings)
+	for number, settings := range r.overrides {
+		overrides[number] = settings
+	}
+	return overrides
+}
+
+// replace replaces all settings set via the admin API.
+func (r *accountRegistry) replace(overrides map[string]AccountSettings) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() { r.overrides = overrides })
+}
+
+// reset drops the settings set via the admin API, so that the ones of the
+// config file apply again.
+func (r *accountRegistry) reset(number string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.update(func() { delete(r.overrides, number) })
+}
+
+// numbers returns all numbers with settings.
+func (r *accountRegistry) numbers() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refresh()
+	numbers := []string{}
+	for number := range r.defaults {
+		numbers = append(numbers, number)
+	}
+	for number := range r.overrides {
+		if _, ok := r.defaults[number]; !ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Strings(numbers)
+	return numbers
+}
+
+// allow checks the send rate limit of number and counts n messages as sent if
+// they are within it.
+func (r *accountRegistry) allow(number string, n int) bool {
+	limit := r.get(number).SendRateLimit
+	if limit > 0 && r.shared != nil {
+		allowed, err := r.shared.allow(number, limit, n)
+		if err == nil {
+			return allowed
+		}
+		log.Error("Couldn't check the shared send rate limit of ", number, ": ", err.Error())
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	sent := r.sent[number][:0]
+	for _, t := range r.sent[number] {
+		if now.Sub(t) < time.Minute {
+			sent = append(sent, t)
+		}
+	}
+	if limit > 0 && len(sent)+n > limit {
+		r.sent[number] = sent
+		return false
+	}
+	for i := 0; i < n; i++ {
+		sent = append(sent, now)
+	}
+	if limit > 0 {
+		r.sent[number] = sent
+	} else {
+		delete(r.sent, number)
+	}
+	return true
+}
+
+// syncDue returns whether number should be synced now and records the sync.
+func (r *accountRegistry) syncDue(number string) bool {
+	interval := r.get(number).syncInterval
+	if interval <= 0 {
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if time.Since(r.synced[number]) < interval {
+		return false
+	}
+	r.synced[number] = time.Now()
+	return true
+}
+
+// runAccountSync requests a sync from the primary device for every number
+// with a sync interval. With several replicas only the leader syncs.
+func (a *Api) runAccountSync() {
+	for range time.Tick(time.Minute) {
+		if !a.leader.isLeader() {
+			continue
+		}
+		for _, number := range a.accounts.numbers() {
+			if !a.accounts.syncDue(number) {
+				continue
+			}
+			if _, err := a.client(number).SyncAll(number); err != nil {
+				log.Error("Couldn't sync ", number, ": ", err.Error())
+			}
+		}
+	}
+}
+
+// readReceiptStage returns the receive stage that marks the received messages
+// of numbers with auto read receipts as read.
+func (a *Api) readReceiptStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		if !a.accounts.get(number).AutoReadReceipts {
+			return true
+		}
+
+		e, err := msg.envelope()
+		if err != nil || e.DataMessage == nil || e.Source.Number == "" {
+			return true
+		}
+
+		go func() {
+			if err := a.markRead(a.client(number), number, e.Source, []int64{e.DataMessage.Timestamp}); err != nil {
+				log.Error("Couldn't mark message as read: ", err.Error())
+			}
+		}()
+		return true
+	}
+}
+
+// markRead sends read receipts for messages of sender.
+func (a *Api) markRead(s *signald.Signald, number string, sender signald.RequestAddress, timestamps []int64) error {
+	// signald-go's MarkRead doesn't set the request type and recipient, so
+	// the request is built here
+	_, err := s.SendAndListen(signald.Request{
+		Type:             "mark_read",
+		Username:         number,
+		RecipientAddress: &sender,
+		Timestamps:       timestamps,
+		When:             time.Now().UnixNano() / int64(time.Millisecond),
+	}, []string{"marked_read"})
+	return err
+}
+
+// @Summary Get the settings of a number.
+// @Tags Admin
+// @Description Get the settings that apply to a registered number.
+// @Produce  json
+// @Success 200 {object} AccountSettings
+// @Param number path string true "Registered Phone Number"
+// @Router /admin/accounts/{number}/settings [get]
+func (a *Api) GetAccountSettings(c *gin.Context) {
+	c.JSON(200, a.accounts.get(c.Param("number")))
+}
+
+// @Summary Change the settings of a number.
+// @Tags Admin
+// @Description Replace the settings of a registered number. The settings take precedence over the ones in the config file.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} AccountSettings
+// @Failure 400 {object} Error
+// @Failure 501 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body AccountSettings true "Settings"
+// @Router /admin/accounts/{number}/settings [put]
+func (a *Api) UpdateAccountSettings(c *gin.Context) {
+	settings := AccountSettings{}
+	if err := c.BindJSON(&settings); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+		return
+	}
+
+	if err := settings.init(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := a.accounts.get(c.Param("number"))
+	if err := a.accounts.put(c.Param("number"), settings); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save settings: " + err.Error()})
+		return
+	}
+	a.audit.record(c, "account_settings.update", c.Param("number"), previous, settings)
+	c.JSON(200, settings)
+}
+
+// @Summary Reset the settings of a number.
+// @Tags Admin
+// @Description Drop the settings changed via the API, so that the ones in the config file apply again.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Router /admin/accounts/{number}/settings [delete]
+func (a *Api) ResetAccountSettings(c *gin.Context) {
+	previous := a.accounts.get(c.Param("number"))
+	if err := a.accounts.reset(c.Param("number")); err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't save settings: " + err.Error()})
+		return
+	}
+	a.audit.record(c, "account_settings.reset", c.Param("number"), previous, a.accounts.get(c.Param("number")))
+	c.Status(204)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/abaskin/signald-go/signald"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// rawMessage is a message read from the signald socket.
+type rawMessage struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id"`
+	Data interface{} `json:"data"`
+}
+
+// signaldConn is a connection to signald for a single long running request
+// (e.g. a subscription or linking a device). Unlike signald-go it reads the
+// socket with a single decoder, so no messages get lost.
+type signaldConn struct {
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// dialSignald connects to signald and sends request.
+func dialSignald(socketPath string, request interface{}) (*signaldConn, error) {
+	data, err := jsoniter.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &signaldConn{conn: conn, decoder: json.NewDecoder(conn)}, nil
+}
+
+// read returns the next message, skipping the version signald sends when a
+// connection is opened.
+func (c *signaldConn) read() (signald.RawResponse, error) {
+	for {
+		raw := rawMessage{}
+		if err := c.decoder.Decode(&raw); err != nil {
+			return signald.RawResponse{}, err
+		}
+		if raw.Type != "version" {
+			return signald.RawResponse{Type: raw.Type, ID: raw.ID, Data: raw.Data}, nil
+		}
+	}
+}
+
+// await returns the next response to the request with the given ID.
+func (c *signaldConn) await(id string) (signald.RawResponse, error) {
+	for {
+		message, err := c.read()
+		if err != nil || message.ID == id {
+			return message, err
+		}
+	}
+}
+
+func (c *signaldConn) close() error {
+	return c.conn.Close()
+}
+
+// requestSignald sends a single request in the versioned protocol of signald
+// on the shared connection to the backend of number and returns the
+// response.
+func (a *Api) requestSignald(number string, request map[string]interface{}) (signald.RawResponse, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return signald.RawResponse{}, err
+	}
+	request["id"] = id
+	request["version"] = "v1"
+
+	return a.muxes.get(a.backends.socketPath(number)).request(id, request)
+}
+
+// signaldError returns the error signald responded with.
+func signaldError(message signald.RawResponse) error {
+	text := message.Type
+	if data, ok := message.Data.(map[string]interface{}); ok {
+		if m, ok := data["message"].(string); ok {
+			text += ": " + m
+		}
+	}
+	return errors.New(text)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// signaldRequestTimeout is how long a request waits for its response on a
+// shared connection.
+const signaldRequestTimeout = 2 * time.Minute
+
+var errSignaldConnectionLost = errors.New("The connection to signald was lost")
+
+// signaldMux shares a connection to signald between concurrent requests.
+// Every request carries an ID, and a single reader routes the responses to
+// the requests by their ID, so that requests don't get the responses of
+// other requests. Messages that aren't a response to a pending request are
+// dropped. If the connection fails, the pending requests fail and the next
+// request connects again.
+type signaldMux struct {
+	socketPath string
+	mutex      sync.Mutex
+	conn       net.Conn
+	pending    map[string]chan signald.RawResponse
+}
+
+func newSignaldMux(socketPath string) *signaldMux {
+	return &signaldMux{socketPath: socketPath, pending: make(map[string]chan signald.RawResponse)}
+}
+
+// request sends request with the given ID and returns the response to it.
+func (m *signaldMux) request(id string, request interface{}) (signald.RawResponse, error) {
+	data, err := jsoniter.Marshal(request)
+	if err != nil {
+		return signald.RawResponse{}, err
+	}
+
+	responses := make(chan signald.RawResponse, 1)
+	if err := m.send(id, append(data, '\n'), responses); err != nil {
+		return signald.RawResponse{}, err
+	}
+
+	timer := time.NewTimer(signaldRequestTimeout)
+	defer timer.Stop()
+	select {
+	case response, ok := <-responses:
+		if !ok {
+			return signald.RawResponse{}, errSignaldConnectionLost
+		}
+		return response, nil
+	case <-timer.C:
+		m.mutex.Lock()
+		delete(m.pending, id)
+		m.mutex.Unlock()
+		return signald.RawResponse{}, errors.New("No response from signald to request " + id)
+	}
+}
+
+// send registers responses for the response to id and writes data to the
+// connection, connecting first if there is none.
+func (m *signaldMux) send(id string, data []byte, responses chan signald.RawResponse) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.pending[id]; ok {
+		return errors.New("A request with ID " + id + " is already pending")
+	}
+	if m.conn == nil {
+		conn, err := net.Dial("unix", m.socketPath)
+		if err != nil {
+			return err
+		}
+		m.conn = conn
+		go m.read(conn)
+	}
+
+	m.pending[id] = responses
+	if _, err := m.conn.Write(data); err != nil {
+		delete(m.pending, id)
+		m.drop(m.conn, err)
+		return err
+	}
+	return nil
+}
+
+// read routes the messages of conn until it fails.
+func (m *signaldMux) read(conn net.Conn) {
+	reader := &signaldConn{conn: conn, decoder: json.NewDecoder(conn)}
+	for {
+		message, err := reader.read()
+		if err != nil {
+			m.lost(conn, err)
+			return
+		}
+
+		m.mutex.Lock()
+		responses, ok := m.pending[message.ID]
+		delete(m.pending, message.ID)
+		m.mutex.Unlock()
+		if ok {
+			responses <- message
+		}
+	}
+}
+
+// lost fails the pending requests of conn.
+func (m *signaldMux) lost(conn net.Conn, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.drop(conn, err)
+}
+
+// drop closes conn and fails the pending requests, if conn is still the
+// connection of the mux. The mutex needs to be held.
+func (m *signaldMux) drop(conn net.Conn, err error) {
+	if m.conn != conn {
+		return
+	}
+	log.Warn("Lost the connection to signald at ", m.socketPath, ": ", err.Error())
+	conn.Close()
+	m.conn = nil
+	for id, responses := range m.pending {
+		close(responses)
+		delete(m.pending, id)
+	}
+}
+
+// signaldMuxes holds a shared connection for every signald socket.
+type signaldMuxes struct {
+	mutex sync.Mutex
+	muxes map[string]*signaldMux
+}
+
+func newSignaldMuxes() *signaldMuxes {
+	return &signaldMuxes{muxes: make(map[string]*signaldMux)}
+}
+
+func (s *signaldMuxes) get(socketPath string) *signaldMux {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.muxes[socketPath]
+	if !ok {
+		m = newSignaldMux(socketPath)
+		s.muxes[socketPath] = m
+	}
+	return m
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/filetype"
+)
+
+// simpleMessage is a received message in the flat format of the simple API.
+type simpleMessage struct {
+	Sender      string `json:"sender"`
+	GroupID     string `json:"group_id,omitempty"`
+	Message     string `json:"message"`
+	Timestamp   int64  `json:"timestamp"`
+	Attachments int    `json:"attachments"`
+}
+
+// simpleGroup is a group in the flat format of the simple API.
+type simpleGroup struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// simpleNumber returns the number given with ?number= (or the form field) if
+// it is an account of this instance.
+func (a *Api) simpleNumber(c *gin.Context) (string, bool) {
+	number := c.Request.FormValue("number")
+	if number == "" {
+		c.JSON(400, gin.H{"error": "Please provide a number"})
+		return "", false
+	}
+	return number, a.checkAccount(c, number)
+}
+
+// simpleList returns the values of a parameter that can be repeated or
+// contain comma separated values.
+func simpleList(values []string) []string {
+	list := []string{}
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+	return list
+}
+
+// stageFormAttachments stages the files uploaded as attachment form fields
+// and returns their tokens, which the caller needs to release.
+func (a *Api) stageFormAttachments(c *gin.Context) ([]string, error) {
+	tokens := []string{}
+	if c.Request.MultipartForm == nil {
+		return tokens, nil
+	}
+
+	release := func() {
+		for _, token := range tokens {
+			a.attachments.release(token)
+		}
+	}
+	for _, header := range c.Request.MultipartForm.File["attachment"] {
+		f, err := header.Open()
+		if err != nil {
+			release()
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			release()
+			return nil, err
+		}
+		kind, err := filetype.Get(data)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		hash, _, err := a.attachments.stage(data, kind.Extension)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		tokens = append(tokens, hash)
+	}
+	return tokens, nil
+}
+
+// receiveMessages receives the messages of number like the receive endpoint.
+func (a *Api) receiveMessages(number string, timeout int) ([]incomingMessage, error) {
+	if a.webhooks.enabled(number) {
+		a.refreshGroupStates(number)
+	}
+	if sub, ok := a.subscriptions.get(number); ok {
+		return sub.fetch(time.Duration(timeout) * time.Second), nil
+	}
+
+	unlock := a.drains.lock(number)
+	defer unlock()
+	message := a.receiveOnce(number, timeout)
+
+	messages, ok := message.Data.([]signald.RawResponse)
+	if !ok {
+		if message.Error != nil {
+			return nil, message.Error
+		}
+		return nil, errors.New("Couldn't receive the messages of " + number)
+	}
+	received := a.processReceived(number, messages)
+	if a.drainInterval(number) > 0 {
+		received = append(a.drains.buffer(number).fetch(0), received...)
+	}
+	return received, nil
+}
+
+// @Summary Send a message with query parameters.
+// @Tags Simple
+// @Description Send a message without JSON body for low-code tools that can only make GET requests. to can be repeated or contain comma separated numbers, or a single group id.
+// @Produce  json
+// @Success 201 {object} sentMessageResponse
+// @Success 202 {object} queuedMessages
+// @Failure 400 {object} Error
+// @Param number query string true "Registered Phone Number"
+// @Param to query string true "Recipients (numbers or a group id)"
+// @Param message query string false "Message"
+// @Router /v1/simple/send [get]
+func (a *Api) SimpleSendQuery(c *gin.Context) {
+	a.simpleSend(c)
+}
+
+// @Summary Send a message with form parameters.
+// @Tags Simple
+// @Description Send a message without JSON body for low-code tools: the parameters are given as form fields (URL encoded or multipart) or in the query. to can be repeated or contain comma separated numbers, or a single group id. Attachments are uploaded as files in multipart form fields named attachment.
+// @Accept  x-www-form-urlencoded
+// @Accept  mpfd
+// @Produce  json
+// @Success 201 {object} sentMessageResponse
+// @Success 202 {object} queuedMessages
+// @Failure 400 {object} Error
+// @Param number formData string true "Registered Phone Number"
+// @Param to formData string true "Recipients (numbers or a group id)"
+// @Param message formData string false "Message"
+// @Router /v1/simple/send [post]
+func (a *Api) SimpleSend(c *gin.Context) {
+	a.simpleSend(c)
+}
+
+// simpleSend sends the message given by the query or form parameters of a
+// request.
+func (a *Api) simpleSend(c *gin.Context) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			c.JSON(400, gin.H{"error": "Couldn't process request - invalid form"})
+			return
+		}
+	}
+	number, ok := a.simpleNumber(c)
+	if !ok {
+		return
+	}
+
+	c.Request.ParseForm()
+	recipients := simpleList(c.Request.Form["to"])
+	if len(recipients) == 0 {
+		c.JSON(400, gin.H{"error": "Please specify at least one recipient"})
+		return
+	}
+	isGroup := false
+	for _, recipient := range recipients {
+		if hasGroupPrefix(recipient) {
+			isGroup = true
+		}
+	}
+	if isGroup && len(recipients) > 1 {
+		c.JSON(400, gin.H{"error": "Please specify either numbers or a single group"})
+		return
+	}
+
+	tokens, err := a.stageFormAttachments(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Couldn't process attachment: " + err.Error()})
+		return
+	}
+	for _, token := range tokens {
+		defer a.attachments.release(token)
+	}
+
+	a.send(c, number, c.Request.FormValue("message"), recipients, []string{}, tokens, isGroup, 0)
+}
+
+// @Summary Receive messages as a flat list.
+// @Tags Simple
+// @Description Receive the messages of a number as a flat list of the sender, the group, the text and the number of attachments. Other messages (receipts, typing, group updates) are left out.
+// @Produce  json
+// @Success 200 {object} []simpleMessage
+// @Failure 400 {object} Error
+// @Param number query string true "Registered Phone Number"
+// @Param timeout query int false "Receive timeout in seconds"
+// @Router /v1/simple/receive [get]
+func (a *Api) SimpleReceive(c *gin.Context) {
+	number, ok := a.simpleNumber(c)
+	if !ok {
+		return
+	}
+	timeout := a.receiveTimeout
+	if value := c.Query("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 || seconds > maxReceiveTimeout {
+			c.JSON(400, gin.H{"error": "Invalid timeout " + value + " (1 to " + strconv.Itoa(maxReceiveTimeout) + " seconds)"})
+			return
+		}
+		timeout = seconds
+	}
+
+	received, err := a.receiveMessages(number, timeout)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	messages := []simpleMessage{}
+	for i := range received {
+		if received[i].Reaction != nil || received[i].Typing != nil {
+			continue
+		}
+		e, err := received[i].envelope()
+		if err != nil || e.DataMessage == nil {
+			continue
+		}
+		if e.DataMessage.Message == "" && len(e.DataMessage.Attachments) == 0 {
+			continue
+		}
+		m := simpleMessage{
+			Sender:      e.Source.Number,
+			Message:     e.DataMessage.Message,
+			Timestamp:   e.DataMessage.Timestamp,
+			Attachments: len(e.DataMessage.Attachments),
+		}
+		if e.DataMessage.GroupInfo != nil {
+			m.GroupID = convertInternalGroupIDToGroupID(e.DataMessage.GroupInfo.GroupID)
+		}
+		messages = append(messages, m)
+	}
+	c.JSON(200, messages)
+}
+
+// @Summary List the groups as a flat list.
+// @Tags Simple
+// @Description List the ids and names of the groups of a number, e.g. to pick the group to send to in a low-code tool.
+// @Produce  json
+// @Success 200 {object} []simpleGroup
+// @Failure 400 {object} Error
+// @Param number query string true "Registered Phone Number"
+// @Router /v1/simple/groups [get]
+func (a *Api) SimpleGroups(c *gin.Context) {
+	number, ok := a.simpleNumber(c)
+	if !ok {
+		return
+	}
+	entries, err := a.getGroups(number)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups := []simpleGroup{}
+	for _, g := range entries {
+		groups = append(groups, simpleGroup{ID: g.ID, Name: g.Name})
+	}
+	c.JSON(200, groups)
+}
@@ -0,0 +1,683 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/h2non/filetype"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// smtpClient is the client emails are sent as if the SMTP server needs no
+// authentication, otherwise the authenticated user is. Their quotas apply.
+const smtpClient = "smtp"
+
+const (
+	// smtpMaxSize is the maximum size of an email, if none is configured.
+	smtpMaxSize = 25 << 20
+	// smtpMaxRecipients is the maximum number of recipients of an email.
+	smtpMaxRecipients = 100
+	// smtpTimeout is the time a client has for each command.
+	smtpTimeout = 5 * time.Minute
+	// smtpRetryWindow is how long the sends of an email are remembered, so
+	// that a client retrying it after a partial failure only sends it to the
+	// recipients that failed.
+	smtpRetryWindow = 24 * time.Hour
+)
+
+// SMTPUser is a user the SMTP server accepts.
+type SMTPUser struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SMTPRoute sends the emails to an address as Signal messages of a number.
+type SMTPRoute struct {
+	Address string `json:"address"`
+	Number  string `json:"number"`
+	// Recipients are numbers or group ids.
+	Recipients []string `json:"recipients"`
+}
+
+// SMTPServer receives emails and sends them as Signal messages, for systems
+// that can only send alerts by email.
+type SMTPServer struct {
+	// Listen is the address the SMTP server listens on, e.g. :2525.
+	Listen string `json:"listen"`
+	// Domain is the name the server greets with, the host name if it isn't
+	// set.
+	Domain string `json:"domain"`
+	// Users need to authenticate (AUTH PLAIN or LOGIN) before sending. They
+	// are required unless the server only listens on a loopback address.
+	Users []SMTPUser `json:"users"`
+	// TLSCert and TLSKey are the certificate and key files of STARTTLS, the
+	// clients need to use it before they authenticate.
+	TLSCert string      `json:"tls_cert"`
+	TLSKey  string      `json:"tls_key"`
+	MaxSize int64       `json:"max_size"`
+	Routes  []SMTPRoute `json:"routes"`
+}
+
+// LoadSMTPServer reads the SMTP server from a JSON file.
+func LoadSMTPServer(filename string) (*SMTPServer, error) {
+	server := &SMTPServer{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jsoniter.Unmarshal(data, server); err != nil {
+		return nil, errors.New("Couldn't parse SMTP server config: " + err.Error())
+	}
+	if err := server.init(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func (s *SMTPServer) init() error {
+	host, _, err := net.SplitHostPort(s.Listen)
+	if err != nil {
+		return errors.New("Invalid SMTP listen address " + s.Listen + " (host:port)")
+	}
+	if ip := net.ParseIP(host); len(s.Users) == 0 && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errors.New("The SMTP server listens on " + s.Listen + ", which isn't a loopback address, it needs users")
+	}
+	if s.Domain == "" {
+		s.Domain, _ = os.Hostname()
+	}
+	if s.MaxSize <= 0 {
+		s.MaxSize = smtpMaxSize
+	}
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return errors.New("STARTTLS needs both the tls_cert and the tls_key")
+	}
+	for _, user := range s.Users {
+		if user.Username == "" || user.Password == "" {
+			return errors.New("Invalid SMTP user " + user.Username + ", it needs a username and a password")
+		}
+	}
+	for i, route := range s.Routes {
+		if !strings.Contains(route.Address, "@") || route.Number == "" || len(route.Recipients) == 0 {
+			return errors.New("Invalid SMTP route " + route.Address + ", it needs an address, a number and recipients")
+		}
+		s.Routes[i].Address = strings.ToLower(route.Address)
+	}
+	return nil
+}
+
+// smtpMail is an email accepted by the SMTP server.
+type smtpMail struct {
+	// User is the authenticated user, if any.
+	User   string
+	From   string
+	Routes []SMTPRoute
+	Data   []byte
+}
+
+// smtpServer accepts emails to the addresses of the routes.
+type smtpServer struct {
+	config   *SMTPServer
+	tls      *tls.Config
+	listener net.Listener
+
+	mutex sync.Mutex
+	// sent are the sends of emails that succeeded, by email and send.
+	sent map[string]time.Time
+}
+
+func newSMTPServer(config *SMTPServer) (*smtpServer, error) {
+	s := &smtpServer{config: config, sent: make(map[string]time.Time)}
+	if config.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, errors.New("Couldn't load the SMTP certificate: " + err.Error())
+		}
+		s.tls = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return nil, err
+	}
+	s.listener = listener
+	return s, nil
+}
+
+func (s *smtpServer) route(address string) (SMTPRoute, bool) {
+	for _, route := range s.config.Routes {
+		if route.Address == strings.ToLower(address) {
+			return route, true
+		}
+	}
+	return SMTPRoute{}, false
+}
+
+// wasSent returns whether an email was sent with a send before.
+func (s *smtpServer) wasSent(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.sent[key]
+	return ok
+}
+
+// markSent records that an email was sent with a send, and forgets the sends
+// that are too old to be retried.
+func (s *smtpServer) markSent(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sent[key] = time.Now()
+	for k, sent := range s.sent {
+		if time.Since(sent) > smtpRetryWindow {
+			delete(s.sent, k)
+		}
+	}
+}
+
+func (s *smtpServer) authenticate(username string, password string) bool {
+	for _, user := range s.config.Users {
+		if subtle.ConstantTimeCompare([]byte(user.Username), []byte(username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// serve accepts connections until the listener is closed and passes the
+// emails received to deliver.
+func (s *smtpServer) serve(deliver func(m smtpMail) error) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			log.Error("The SMTP server stopped: ", err.Error())
+			return
+		}
+		go s.session(conn, deliver)
+	}
+}
+
+// smtpAddress returns the address of a MAIL FROM or RCPT TO argument, e.g.
+// FROM:<alerts@example.com> SIZE=1000, and its parameters.
+func smtpAddress(arg string, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(arg, "<") {
+		return "", nil, false
+	}
+	end := strings.Index(arg, ">")
+	if end < 0 {
+		return "", nil, false
+	}
+	return arg[1:end], strings.Fields(arg[end+1:]), true
+}
+
+// smtpSession is the state of a connection to the SMTP server.
+type smtpSession struct {
+	server *smtpServer
+	conn   net.Conn
+	text   *textproto.Conn
+
+	greeted bool
+	secure  bool
+	user    string
+	from    string
+	hasFrom bool
+	routes  []SMTPRoute
+}
+
+func (s *smtpSession) reply(code int, message string) {
+	s.text.PrintfLine("%d %s", code, message)
+}
+
+func (s *smtpSession) hasRoute(address string) bool {
+	for _, route := range s.routes {
+		if route.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *smtpSession) reset() {
+	s.from, s.hasFrom, s.routes = "", false, nil
+}
+
+// readResponse reads the response to an AUTH challenge.
+func (s *smtpSession) readResponse(challenge string) (string, bool) {
+	s.reply(334, challenge)
+	line, err := s.text.ReadLine()
+	if err != nil || line == "*" {
+		return "", false
+	}
+	return line, true
+}
+
+// auth handles the AUTH command with the PLAIN or the LOGIN mechanism.
+func (s *smtpSession) auth(arg string) {
+	if len(s.server.config.Users) == 0 {
+		s.reply(502, "5.5.1 Authentication not enabled")
+		return
+	}
+	if s.server.tls != nil && !s.secure {
+		s.reply(538, "5.7.11 Encryption required for requested authentication mechanism")
+		return
+	}
+	if s.user != "" || s.hasFrom {
+		s.reply(503, "5.5.1 Bad sequence of commands")
+		return
+	}
+
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		s.reply(501, "5.5.4 Missing mechanism")
+		return
+	}
+	decode := func(value string) (string, bool) {
+		data, err := base64.StdEncoding.DecodeString(value)
+		return string(data), err == nil
+	}
+
+	username, password, ok := "", "", false
+	switch strings.ToUpper(fields[0]) {
+	case "PLAIN":
+		response := ""
+		if len(fields) > 1 {
+			response, ok = fields[1], true
+		} else {
+			response, ok = s.readResponse("")
+		}
+		if ok {
+			var plain string
+			if plain, ok = decode(response); ok {
+				// authorization id, user and password
+				parts := strings.Split(plain, "\x00")
+				if ok = len(parts) == 3; ok {
+					username, password = parts[1], parts[2]
+				}
+			}
+		}
+	case "LOGIN":
+		response := ""
+		if len(fields) > 1 {
+			response, ok = fields[1], true
+		} else {
+			response, ok = s.readResponse(base64.StdEncoding.EncodeToString([]byte("Username:")))
+		}
+		if ok {
+			if username, ok = decode(response); ok {
+				if response, ok = s.readResponse(base64.StdEncoding.EncodeToString([]byte("Password:"))); ok {
+					password, ok = decode(response)
+				}
+			}
+		}
+	default:
+		s.reply(504, "5.5.4 Unsupported mechanism")
+		return
+	}
+
+	if !ok {
+		s.reply(501, "5.5.2 Invalid authentication response")
+		return
+	}
+	if !s.server.authenticate(username, password) {
+		log.Warn("Failed SMTP authentication of ", username, " from ", s.conn.RemoteAddr())
+		s.reply(535, "5.7.8 Authentication credentials invalid")
+		return
+	}
+	s.user = username
+	s.reply(235, "2.7.0 Authentication successful")
+}
+
+// data reads the content of an email and delivers it.
+func (s *smtpSession) data(deliver func(m smtpMail) error) {
+	if !s.hasFrom || len(s.routes) == 0 {
+		s.reply(503, "5.5.1 Need MAIL and RCPT first")
+		return
+	}
+	s.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+
+	maxSize := s.server.config.MaxSize
+	reader := s.text.DotReader()
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		s.reply(451, "4.3.0 Couldn't read the message")
+		return
+	}
+	if int64(len(data)) > maxSize {
+		io.Copy(ioutil.Discard, reader)
+		s.reply(552, "5.3.4 Message exceeds the maximum size of "+strconv.FormatInt(maxSize, 10)+" bytes")
+		s.reset()
+		return
+	}
+
+	err = deliver(smtpMail{User: s.user, From: s.from, Routes: s.routes, Data: data})
+	s.reset()
+	if err != nil {
+		s.reply(451, "4.3.0 "+err.Error())
+		return
+	}
+	s.reply(250, "2.0.0 OK")
+}
+
+// session talks SMTP with a client until it quits.
+func (s *smtpServer) session(conn net.Conn, deliver func(m smtpMail) error) {
+	session := &smtpSession{server: s, conn: conn, text: textproto.NewConn(conn)}
+	defer func() {
+		session.conn.Close()
+	}()
+
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	session.reply(220, s.config.Domain+" ESMTP signald-rest-api")
+	for {
+		session.conn.SetDeadline(time.Now().Add(smtpTimeout))
+		line, err := session.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			session.greeted = true
+			session.reset()
+			session.reply(250, s.config.Domain)
+		case "EHLO":
+			session.greeted = true
+			session.reset()
+			lines := []string{s.config.Domain, "SIZE " + strconv.FormatInt(s.config.MaxSize, 10), "8BITMIME", "PIPELINING"}
+			if s.tls != nil && !session.secure {
+				lines = append(lines, "STARTTLS")
+			}
+			// the credentials are only sent encrypted if the server can
+			if len(s.config.Users) > 0 && (s.tls == nil || session.secure) {
+				lines = append(lines, "AUTH PLAIN LOGIN")
+			}
+			for i, l := range lines {
+				if i < len(lines)-1 {
+					session.text.PrintfLine("250-%s", l)
+				} else {
+					session.text.PrintfLine("250 %s", l)
+				}
+			}
+		case "STARTTLS":
+			if s.tls == nil || session.secure {
+				session.reply(502, "5.5.1 STARTTLS not available")
+				continue
+			}
+			session.reply(220, "2.0.0 Ready to start TLS")
+			tlsConn := tls.Server(session.conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				log.Warn("Failed SMTP TLS handshake with ", conn.RemoteAddr(), ": ", err.Error())
+				return
+			}
+			// the client starts over after STARTTLS
+			*session = smtpSession{server: s, conn: tlsConn, text: textproto.NewConn(tlsConn), secure: true}
+		case "AUTH":
+			if !session.greeted {
+				session.reply(503, "5.5.1 Send EHLO first")
+				continue
+			}
+			session.auth(arg)
+		case "MAIL":
+			address, params, ok := smtpAddress(arg, "FROM:")
+			switch {
+			case !session.greeted:
+				session.reply(503, "5.5.1 Send HELO or EHLO first")
+			case len(s.config.Users) > 0 && session.user == "":
+				session.reply(530, "5.7.0 Authentication required")
+			case session.hasFrom:
+				session.reply(503, "5.5.1 Sender already specified")
+			case !ok:
+				session.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+			default:
+				for _, param := range params {
+					if strings.HasPrefix(strings.ToUpper(param), "SIZE=") {
+						if size, err := strconv.ParseInt(param[5:], 10, 64); err == nil && size > s.config.MaxSize {
+							session.reply(552, "5.3.4 Message exceeds the maximum size of "+strconv.FormatInt(s.config.MaxSize, 10)+" bytes")
+							ok = false
+						}
+					}
+				}
+				if ok {
+					session.from, session.hasFrom = address, true
+					session.reply(250, "2.1.0 OK")
+				}
+			}
+		case "RCPT":
+			address, _, ok := smtpAddress(arg, "TO:")
+			if !session.hasFrom {
+				session.reply(503, "5.5.1 Need MAIL first")
+				continue
+			}
+			if !ok {
+				session.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+				continue
+			}
+			if len(session.routes) >= smtpMaxRecipients {
+				session.reply(452, "4.5.3 Too many recipients")
+				continue
+			}
+			route, ok := s.route(address)
+			if !ok {
+				session.reply(550, "5.1.1 No route for <"+address+">")
+				continue
+			}
+			if !session.hasRoute(route.Address) {
+				session.routes = append(session.routes, route)
+			}
+			session.reply(250, "2.1.5 OK")
+		case "DATA":
+			session.data(deliver)
+		case "RSET":
+			session.reset()
+			session.reply(250, "2.0.0 OK")
+		case "NOOP":
+			session.reply(250, "2.0.0 OK")
+		case "VRFY":
+			session.reply(252, "2.5.0 Cannot verify the user")
+		case "QUIT":
+			session.reply(221, "2.0.0 Bye")
+			return
+		default:
+			session.reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+// mailFile is an attachment of an email.
+type mailFile struct {
+	filename string
+	data     []byte
+}
+
+var htmlTags = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// decodeTransfer decodes a body with its Content-Transfer-Encoding.
+func decodeTransfer(body io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, body))
+	case "quoted-printable":
+		return ioutil.ReadAll(quotedprintable.NewReader(body))
+	}
+	return ioutil.ReadAll(body)
+}
+
+// mailContent collects the plain text, the HTML and the attachments of a
+// MIME entity and its parts.
+type mailContent struct {
+	text  []string
+	html  []string
+	files []mailFile
+}
+
+func (m *mailContent) add(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.add(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := decodeTransfer(body, header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return err
+	}
+	switch {
+	case disposition != "attachment" && mediaType == "text/plain":
+		m.text = append(m.text, strings.TrimSpace(string(data)))
+	case disposition != "attachment" && mediaType == "text/html":
+		m.html = append(m.html, strings.TrimSpace(html.UnescapeString(htmlTags.ReplaceAllString(string(data), ""))))
+	default:
+		filename := dispositionParams["filename"]
+		if filename == "" {
+			filename = params["name"]
+		}
+		m.files = append(m.files, mailFile{filename: filename, data: data})
+	}
+	return nil
+}
+
+// parseMail returns the text of an email, its subject and body, and its
+// attachments. The HTML body is only used if there is no plain text one.
+func parseMail(data []byte) (string, []mailFile, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, err
+	}
+	content := &mailContent{}
+	if err := content.add(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return "", nil, err
+	}
+
+	body := content.text
+	if len(body) == 0 {
+		body = content.html
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	text := strings.TrimSpace(strings.Join(body, "\n\n"))
+	if subject != "" {
+		text = strings.TrimSpace(subject + "\n\n" + text)
+	}
+	return text, content.files, nil
+}
+
+// mailToSignal sends an email as Signal message to the recipients of its
+// routes. It fails if it couldn't be sent to some of them, so that the client
+// retries, the retry is only sent to the recipients that failed.
+func (a *Api) mailToSignal(m smtpMail) error {
+	text, files, err := parseMail(m.Data)
+	if err != nil {
+		log.Error("Couldn't parse an email of ", m.From, ": ", err.Error())
+		return errors.New("Couldn't parse the message")
+	}
+
+	tokens := []string{}
+	defer func() {
+		for _, token := range tokens {
+			a.attachments.release(token)
+		}
+	}()
+	for _, file := range files {
+		extension := strings.TrimPrefix(filepath.Ext(file.filename), ".")
+		if kind, err := filetype.Match(file.data); err == nil && kind != filetype.Unknown {
+			extension = kind.Extension
+		}
+		token, _, err := a.attachments.stage(file.data, extension)
+		if err != nil {
+			return errors.New("Couldn't store the attachment " + file.filename + ": " + err.Error())
+		}
+		tokens = append(tokens, token)
+	}
+	if text == "" && len(tokens) == 0 {
+		return nil
+	}
+
+	client := m.User
+	if client == "" {
+		client = smtpClient
+	}
+	sends := []service.Send{}
+	for _, route := range m.Routes {
+		for _, out := range sendsTo(route.Recipients) {
+			out.Number = route.Number
+			sends = append(sends, out)
+		}
+	}
+
+	hash := sha256.Sum256(m.Data)
+	failed := 0
+	for _, out := range sends {
+		key := hex.EncodeToString(hash[:]) + "/" + out.Number + "/" + strings.Join(out.Recipients, ",")
+		if a.smtp.wasSent(key) {
+			continue
+		}
+		out.Message, out.AttachmentTokens, out.Client = text, tokens, client
+		if _, err := a.service.Submit(out); err != nil {
+			log.Error("Couldn't send an email of ", m.From, " from ", out.Number, " to ",
+				strings.Join(out.Recipients, ","), ": ", err.Error())
+			failed++
+			continue
+		}
+		a.smtp.markSent(key)
+	}
+	if failed > 0 {
+		return errors.New("Couldn't send the message to " + strconv.Itoa(failed) + " of " +
+			strconv.Itoa(len(sends)) + " recipients")
+	}
+	return nil
+}
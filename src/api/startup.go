@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The startup check modes: strict refuses to start if signald can't be used,
+// degraded starts anyway and reports the problem in the readiness check.
+const (
+	SignaldCheckStrict   = "strict"
+	SignaldCheckDegraded = "degraded"
+	SignaldCheckOff      = "off"
+)
+
+// minSignaldVersion is the oldest signald that speaks the versioned protocol
+// (v1) the API uses next to the legacy one of signald-go.
+const minSignaldVersion = "0.10.0"
+
+const signaldCheckTimeout = 2 * time.Second
+
+func validateSignaldCheck(mode string) error {
+	switch mode {
+	case "", SignaldCheckStrict, SignaldCheckDegraded, SignaldCheckOff:
+		return nil
+	}
+	return errors.New("Invalid signald check " + mode + " (supported: strict, degraded, off)")
+}
+
+// signaldCheck is the outcome of checking a signald socket.
+type signaldCheck struct {
+	SocketPath string `json:"socket_path"`
+	OK         bool   `json:"ok"`
+	Version    string `json:"version,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// checkSignald checks that the socket exists, accepts connections and that
+// signald greets with a supported version.
+func checkSignald(socketPath string) signaldCheck {
+	check := signaldCheck{SocketPath: socketPath}
+	version, err := probeSignald(socketPath)
+	check.Version = version
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.OK = true
+	return check
+}
+
+func probeSignald(socketPath string) (string, error) {
+	info, err := os.Stat(socketPath)
+	if os.IsNotExist(err) {
+		return "", errors.New("The signald socket " + socketPath + " doesn't exist (is signald running?)")
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return "", errors.New(socketPath + " isn't a socket")
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, signaldCheckTimeout)
+	if errors.Is(err, os.ErrPermission) {
+		return "", errors.New("No permission to connect to the signald socket " + socketPath +
+			" (the user of the API needs write access to it)")
+	}
+	if err != nil {
+		return "", errors.New("Couldn't connect to the signald socket " + socketPath + ": " + err.Error())
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(signaldCheckTimeout))
+	greeting := struct {
+		Type string `json:"type"`
+		Data struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(conn).Decode(&greeting); err != nil {
+		return "", errors.New("signald at " + socketPath + " didn't send its version: " + err.Error())
+	}
+	if greeting.Type != "version" {
+		return "", errors.New("Unexpected greeting " + greeting.Type + " from " + socketPath + " (is it signald?)")
+	}
+	version := greeting.Data.Version
+	if !versionAtLeast(version, minSignaldVersion) {
+		return version, errors.New("signald " + version + " at " + socketPath + " is too old, at least " +
+			minSignaldVersion + " is required")
+	}
+	return version, nil
+}
+
+// versionAtLeast compares the numeric parts of a version like 0.11.0 or
+// 0.11.0-12-g1234abc with min. Versions that can't be parsed (e.g. of
+// development builds) are accepted.
+func versionAtLeast(version string, min string) bool {
+	parse := func(v string) ([]int, bool) {
+		v = strings.SplitN(strings.TrimPrefix(v, "v"), "-", 2)[0]
+		parts := []int{}
+		for _, p := range strings.Split(v, ".") {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, false
+			}
+			parts = append(parts, n)
+		}
+		return parts, true
+	}
+
+	have, ok := parse(version)
+	if !ok {
+		return true
+	}
+	want, _ := parse(min)
+	for i := range want {
+		n := 0
+		if i < len(have) {
+			n = have[i]
+		}
+		if n != want[i] {
+			return n > want[i]
+		}
+	}
+	return true
+}
+
+// signaldChecks keeps the outcome of the startup check of the signald
+// sockets. Failed sockets are checked again when the readiness is queried,
+// so that the API becomes ready once signald is fixed.
+type signaldChecks struct {
+	mutex  sync.Mutex
+	checks map[string]signaldCheck
+}
+
+func newSignaldChecks() *signaldChecks {
+	return &signaldChecks{checks: make(map[string]signaldCheck)}
+}
+
+// run checks the sockets and returns the error of the first one that failed.
+func (s *signaldChecks) run(socketPaths []string) error {
+	var failed error
+	for _, socketPath := range socketPaths {
+		check := checkSignald(socketPath)
+		s.mutex.Lock()
+		s.checks[socketPath] = check
+		s.mutex.Unlock()
+		if !check.OK && failed == nil {
+			failed = errors.New(check.Error)
+		}
+	}
+	return failed
+}
+
+// status returns the checks, checking the failed ones again.
+func (s *signaldChecks) status() []signaldCheck {
+	s.mutex.Lock()
+	failed := []string{}
+	for socketPath, check := range s.checks {
+		if !check.OK {
+			failed = append(failed, socketPath)
+		}
+	}
+	s.mutex.Unlock()
+	s.run(failed)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	checks := []signaldCheck{}
+	for _, check := range s.checks {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].SocketPath < checks[j].SocketPath })
+	return checks
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// stateFile is a JSON file in the data dir that is used to persist server side
+// entities. Without a data dir the state is only kept in memory.
+type stateFile struct {
+	filename string
+}
+
+func newStateFile(dataDir string, name string) stateFile {
+	if dataDir == "" {
+		return stateFile{}
+	}
+	return stateFile{filename: filepath.Join(dataDir, name)}
+}
+
+// load reads the state into v. A missing file is not an error.
+func (f stateFile) load(v interface{}) error {
+	if f.filename == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(f.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(data, v)
+}
+
+// save atomically replaces the state with v.
+func (f stateFile) save(v interface{}) error {
+	if f.filename == "" {
+		return nil
+	}
+
+	data, err := jsoniter.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.filename), filepath.Base(f.filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.filename)
+}
+
+// update changes the state and saves it, the file is only written by this
+// instance.
+func (f stateFile) update(v interface{}, change func(reloaded bool) error) error {
+	if err := change(false); err != nil {
+		return err
+	}
+	return f.save(v)
+}
+
+func (f stateFile) refresh(v interface{}) (bool, error) {
+	return false, nil
+}
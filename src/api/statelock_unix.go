@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package api
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on a file next to the SQLite database at
+// source, the lock is held until the returned file is closed.
+func lockFile(source string) (io.Closer, error) {
+	path := strings.TrimPrefix(source, "file:")
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" || path == ":memory:" {
+		return nopCloser{}, nil
+	}
+
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.New("Couldn't lock storage: " + err.Error())
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errStateLocked
+		}
+		return nil, errors.New("Couldn't lock storage: " + err.Error())
+	}
+	return file, nil
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsBucket counts the activity of a number during one minute.
+type statsBucket struct {
+	minute   int64
+	sent     int
+	received int
+	failed   int
+	latency  time.Duration
+}
+
+type accountStats struct {
+	buckets      []statsBucket
+	lastSent     time.Time
+	lastReceived time.Time
+}
+
+type windowStats struct {
+	Window           string  `json:"window"`
+	Sent             int     `json:"sent"`
+	Received         int     `json:"received"`
+	Failed           int     `json:"failed"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+type statsReport struct {
+	Number       string        `json:"number"`
+	LastActivity *time.Time    `json:"last_activity"`
+	LastSent     *time.Time    `json:"last_sent"`
+	LastReceived *time.Time    `json:"last_received"`
+	QueueDepth   int           `json:"queue_depth"`
+	Windows      []windowStats `json:"windows"`
+}
+
+// statsRecorder keeps per minute counters of the sent and received messages
+// of every number for the longest window stats can be requested for.
+type statsRecorder struct {
+	mutex    sync.Mutex
+	windows  []time.Duration
+	size     int
+	accounts map[string]*accountStats
+}
+
+// ParseStatsWindows parses a comma separated list of durations.
+func ParseStatsWindows(value string) ([]time.Duration, error) {
+	windows := []time.Duration{}
+	for _, w := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(w))
+		if err != nil || d < time.Minute {
+			return nil, errors.New("Invalid stats window " + w + " (minimum 1m)")
+		}
+		windows = append(windows, d)
+	}
+	return windows, nil
+}
+
+func formatWindow(w time.Duration) string {
+	if w%time.Hour == 0 {
+		return strconv.Itoa(int(w/time.Hour)) + "h"
+	}
+	return strconv.Itoa(int(w/time.Minute)) + "m"
+}
+
+func newStatsRecorder(windows []time.Duration) *statsRecorder {
+	size := 1
+	for _, w := range windows {
+		if n := int(w / time.Minute); n > size {
+			size = n
+		}
+	}
+	return &statsRecorder{
+		windows:  windows,
+		size:     size,
+		accounts: make(map[string]*accountStats),
+	}
+}
+
+// bucket returns the bucket of the current minute, the caller needs to hold
+// the mutex.
+func (r *statsRecorder) bucket(number string, now time.Time) (*accountStats, *statsBucket) {
+	stats, ok := r.accounts[number]
+	if !ok {
+		stats = &accountStats{buckets: make([]statsBucket, r.size)}
+		r.accounts[number] = stats
+	}
+
+	minute := now.Unix() / 60
+	b := &stats.buckets[minute%int64(r.size)]
+	if b.minute != minute {
+		*b = statsBucket{minute: minute}
+	}
+	return stats, b
+}
+
+func (r *statsRecorder) sent(number string, latency time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	stats, b := r.bucket(number, now)
+	if err != nil {
+		b.failed++
+		return
+	}
+	b.sent++
+	b.latency += latency
+	stats.lastSent = now
+}
+
+func (r *statsRecorder) received(number string, n int) {
+	if n == 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	stats, b := r.bucket(number, now)
+	b.received += n
+	stats.lastReceived = now
+}
+
+func (r *statsRecorder) report(number string, windows []time.Duration) statsReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report := statsReport{Number: number, Windows: []windowStats{}}
+	stats, ok := r.accounts[number]
+	if ok {
+		if !stats.lastSent.IsZero() {
+			lastSent := stats.lastSent
+			report.LastSent = &lastSent
+			report.LastActivity = &lastSent
+		}
+		if !stats.lastReceived.IsZero() {
+			lastReceived := stats.lastReceived
+			report.LastReceived = &lastReceived
+			if report.LastActivity == nil || lastReceived.After(*report.LastActivity) {
+				report.LastActivity = &lastReceived
+			}
+		}
+	}
+
+	now := time.Now().Unix() / 60
+	for _, w := range windows {
+		ws := windowStats{Window: formatWindow(w)}
+		var latency time.Duration
+		if ok {
+			for _, b := range stats.buckets {
+				if b.minute > now-int64(w/time.Minute) {
+					ws.Sent += b.sent
+					ws.Received += b.received
+					ws.Failed += b.failed
+					latency += b.latency
+				}
+			}
+		}
+		if ws.Sent > 0 {
+			ws.AverageLatencyMs = float64(latency/time.Duration(ws.Sent)) / float64(time.Millisecond)
+		}
+		report.Windows = append(report.Windows, ws)
+	}
+	return report
+}
+
+// @Summary Show statistics of a number.
+// @Tags Accounts
+// @Description Show the number of sent, received and failed messages and the average send latency of a registered number over time windows (by default the ones given with -stats-windows), the time of the last activity and the number of queued messages.
+// @Produce  json
+// @Success 200 {object} statsReport
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param windows query string false "Comma separated list of windows, e.g. 5m,1h"
+// @Router /v1/accounts/{number}/stats [get]
+func (a *Api) GetAccountStats(c *gin.Context) {
+	number := c.Param("number")
+
+	windows := a.stats.windows
+	if value := c.Query("windows"); value != "" {
+		var err error
+		if windows, err = ParseStatsWindows(value); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		for _, w := range windows {
+			if int(w/time.Minute) > a.stats.size {
+				c.JSON(400, gin.H{"error": "Stats window " + formatWindow(w) + " exceeds the longest configured window"})
+				return
+			}
+		}
+	}
+
+	report := a.stats.report(number, windows)
+	if a.queue != nil {
+		report.QueueDepth = len(a.queue.list(number))
+	}
+	c.JSON(200, report)
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// stateStore persists the server side state of a subsystem (e.g. the message
+// store or the chat commands) as a single document.
+type stateStore interface {
+	// load reads the state into v. Missing state is not an error.
+	load(v interface{}) error
+	// save replaces the state with v.
+	save(v interface{}) error
+	// update calls change and saves v. If other instances write the state as
+	// well, it is locked while it is changed and v is loaded again first if
+	// they changed it, change is told so.
+	update(v interface{}, change func(reloaded bool) error) error
+	// refresh loads the state into v again if another instance changed it
+	// since it was last loaded or saved, and returns whether it did.
+	refresh(v interface{}) (bool, error)
+}
+
+// entityStore persists the entities of a subsystem (e.g. the entries of the
+// audit log) one row each, so that a change only writes the entity it changes.
+type entityStore interface {
+	// loadAll calls load with every entity, ordered by id.
+	loadAll(load func(data []byte) error) error
+	// put adds or replaces the entity with the given id.
+	put(id string, v interface{}) error
+	// remove deletes the entities with the given ids.
+	remove(ids ...string) error
+	// shared returns whether other instances write the entities as well.
+	shared() bool
+}
+
+// stateDB is a SQL database the state is kept in, one row per subsystem or
+// entity. A Postgres database is shared by the replicas of the API, the
+// documents are locked while they are changed. A SQLite database is only
+// written by one instance, which holds a lock on it while it is running.
+type stateDB struct {
+	db      *sql.DB
+	driver  string
+	dataDir string
+	lock    io.Closer
+}
+
+// stateLockClass is the first key of the Postgres advisory locks of the
+// documents, the second one is the hash of their name.
+const stateLockClass = 0x7369676e
+
+// stateRefreshInterval is how often a shared document is checked for changes
+// of other instances when it is read.
+const stateRefreshInterval = time.Second
+
+// errStateUnchanged is returned by the change of an update that didn't change
+// the state, it isn't saved then.
+var errStateUnchanged = errors.New("The state is unchanged")
+
+var errStateLocked = errors.New("The SQLite state database is in use by another instance of the API, " +
+	"replicas need to share a Postgres database")
+
+// openStateDB opens the database given by dsn: a postgres:// URL or a
+// sqlite: path. Without dsn the state is kept in a SQLite database in the data
+// dir. Without either the state is only kept in memory and nil is returned.
+func openStateDB(dsn string, dataDir string) (*stateDB, error) {
+	driver := ""
+	source := ""
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		driver, source = "postgres", dsn
+	case strings.HasPrefix(dsn, "sqlite:"):
+		driver, source = "sqlite3", strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite:"), "//")
+	case dsn == "" && dataDir != "":
+		driver, source = "sqlite3", filepath.Join(dataDir, "state.db")
+	case dsn == "":
+		return nil, nil
+	default:
+		return nil, errors.New("Unsupported storage DSN (supported: postgres://, sqlite:)")
+	}
+
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return nil, errors.New("Couldn't open storage: " + err.Error())
+	}
+	if driver == "sqlite3" {
+		// SQLite doesn't allow concurrent writers
+		db.SetMaxOpenConns(1)
+	}
+
+	d := &stateDB{db: db, driver: driver, dataDir: dataDir, lock: nopCloser{}}
+	if driver == "sqlite3" {
+		if d.lock, err = lockFile(source); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	tables := []string{`CREATE TABLE IF NOT EXISTS state (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	)`, `CREATE TABLE IF NOT EXISTS entities (
+		kind TEXT NOT NULL,
+		id TEXT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (kind, id)
+	)`}
+	if d.shared() {
+		// the version tells the replicas whether a document changed
+		tables = append(tables, "ALTER TABLE state ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0")
+	}
+	for _, table := range tables {
+		if _, err = db.Exec(table); err != nil {
+			d.close()
+			return nil, errors.New("Couldn't initialize storage: " + err.Error())
+		}
+	}
+	return d, nil
+}
+
+func (d *stateDB) close() error {
+	d.lock.Close()
+	return d.db.Close()
+}
+
+// nopCloser is the lock of a database that isn't locked.
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// shared returns whether the replicas of the API share the database.
+func (d *stateDB) shared() bool {
+	return d.driver == "postgres"
+}
+
+// placeholder returns the placeholder of the nth query argument.
+func (d *stateDB) placeholder(n int) string {
+	if d.driver == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// newStateStore returns the store of the state with the given name, in the
+// database if there is one and in a JSON file in the data dir otherwise.
+func newStateStore(db *stateDB, dataDir string, name string) stateStore {
+	if db == nil {
+		return newStateFile(dataDir, name+".json")
+	}
+	return &sqlState{db: db, name: name}
+}
+
+// sqlState is the state of a subsystem in a stateDB. In a shared database it
+// remembers the version of the document it last loaded or saved.
+type sqlState struct {
+	mutex   sync.Mutex
+	db      *stateDB
+	name    string
+	version int64
+	checked time.Time
+}
+
+// load reads the state into v. If there is no state in the database yet, the
+// JSON file a previous version kept it in is read.
+func (s *sqlState) load(v interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data := ""
+	var err error
+	if s.db.shared() {
+		err = s.db.db.QueryRow("SELECT version, data FROM state WHERE name = $1", s.name).Scan(&s.version, &data)
+	} else {
+		err = s.db.db.QueryRow("SELECT data FROM state WHERE name = "+s.db.placeholder(1), s.name).Scan(&data)
+	}
+	if err == sql.ErrNoRows {
+		return newStateFile(s.db.dataDir, s.name+".json").load(v)
+	}
+	if err != nil {
+		return err
+	}
+	return jsoniter.UnmarshalFromString(data, v)
+}
+
+func (s *sqlState) save(v interface{}) error {
+	data, err := jsoniter.MarshalToString(v)
+	if err != nil {
+		return err
+	}
+
+	if !s.db.shared() {
+		_, err = s.db.db.Exec("INSERT INTO state (name, data) VALUES ("+s.db.placeholder(1)+", "+
+			s.db.placeholder(2)+") ON CONFLICT (name) DO UPDATE SET data = excluded.data", s.name, data)
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.db.db.QueryRow("INSERT INTO state (name, data, version) VALUES ($1, $2, 1) "+
+		"ON CONFLICT (name) DO UPDATE SET data = excluded.data, version = state.version + 1 RETURNING version",
+		s.name, data).Scan(&s.version)
+}
+
+func (s *sqlState) update(v interface{}, change func(reloaded bool) error) error {
+	if !s.db.shared() {
+		if err := change(false); err != nil {
+			return err
+		}
+		return s.save(v)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tx, err := s.db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the lock is released when the transaction ends
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1, hashtext($2))", stateLockClass, s.name); err != nil {
+		return err
+	}
+	version := int64(0)
+	data := ""
+	err = tx.QueryRow("SELECT version, data FROM state WHERE name = $1", s.name).Scan(&version, &data)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	reloaded := err == nil && version != s.version
+	if reloaded {
+		if err := reloadState(data, v); err != nil {
+			return err
+		}
+	}
+	if err := change(reloaded); err != nil {
+		return err
+	}
+
+	if data, err = jsoniter.MarshalToString(v); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO state (name, data, version) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (name) DO UPDATE SET data = excluded.data, version = excluded.version", s.name, data, version+1)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.version = version + 1
+	return nil
+}
+
+func (s *sqlState) refresh(v interface{}) (bool, error) {
+	if !s.db.shared() {
+		return false, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if time.Since(s.checked) < stateRefreshInterval {
+		return false, nil
+	}
+	s.checked = time.Now()
+
+	version := int64(0)
+	data := ""
+	err := s.db.db.QueryRow("SELECT version, data FROM state WHERE name = $1 AND version <> $2",
+		s.name, s.version).Scan(&version, &data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := reloadState(data, v); err != nil {
+		return false, err
+	}
+	s.version = version
+	return true, nil
+}
+
+// reloadState replaces v with the state in data.
+func reloadState(data string, v interface{}) error {
+	value := reflect.ValueOf(v).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if value.Kind() == reflect.Map {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+	return jsoniter.UnmarshalFromString(data, v)
+}
+
+// newEntityStore returns the store of the entities of the given kind. Without
+// a database the entities are only kept in memory.
+func newEntityStore(db *stateDB, kind string) entityStore {
+	if db == nil {
+		return memoryEntities{}
+	}
+	return sqlEntities{db: db, kind: kind}
+}
+
+// memoryEntities doesn't persist anything, the subsystem keeps the entities
+// in memory.
+type memoryEntities struct{}
+
+func (memoryEntities) loadAll(load func(data []byte) error) error {
+	return nil
+}
+
+func (memoryEntities) put(id string, v interface{}) error {
+	return nil
+}
+
+func (memoryEntities) remove(ids ...string) error {
+	return nil
+}
+
+func (memoryEntities) shared() bool {
+	return false
+}
+
+// sqlEntities are the entities of a kind in a stateDB.
+type sqlEntities struct {
+	db   *stateDB
+	kind string
+}
+
+func (e sqlEntities) loadAll(load func(data []byte) error) error {
+	rows, err := e.db.db.Query("SELECT data FROM entities WHERE kind = "+e.db.placeholder(1)+" ORDER BY id", e.kind)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		data := ""
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := load([]byte(data)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (e sqlEntities) put(id string, v interface{}) error {
+	data, err := jsoniter.MarshalToString(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.db.db.Exec("INSERT INTO entities (kind, id, data) VALUES ("+e.db.placeholder(1)+", "+
+		e.db.placeholder(2)+", "+e.db.placeholder(3)+") ON CONFLICT (kind, id) DO UPDATE SET data = excluded.data",
+		e.kind, id, data)
+	return err
+}
+
+func (e sqlEntities) remove(ids ...string) error {
+	tx, err := e.db.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		_, err := tx.Exec("DELETE FROM entities WHERE kind = "+e.db.placeholder(1)+" AND id = "+e.db.placeholder(2),
+			e.kind, id)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (e sqlEntities) shared() bool {
+	return e.db.shared()
+}
@@ -0,0 +1,540 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const messageStoreFlushInterval = 5 * time.Second
+
+// storedMessage is a message of a conversation as the message store keeps it.
+// Deleted messages are hidden until they are purged.
+type storedMessage struct {
+	ID          string     `json:"id"`
+	Timestamp   int64      `json:"timestamp"`
+	Sender      string     `json:"sender"`
+	Outgoing    bool       `json:"outgoing"`
+	Body        string     `json:"body"`
+	Attachments int        `json:"attachments,omitempty"`
+	Deleted     *time.Time `json:"deleted,omitempty"`
+	// Edited is the timestamp of the latest edit of the message.
+	Edited int64 `json:"edited,omitempty"`
+}
+
+// messageID returns the ID of a message. Like Signal it identifies messages
+// by their sender and timestamp.
+func messageID(sender string, timestamp int64) string {
+	sum := sha256.Sum256([]byte(sender + ":" + strconv.FormatInt(timestamp, 10)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cursor returns the position of the message in its conversation.
+func (m storedMessage) cursor() cursor {
+	return cursor{timestamp: m.Timestamp, id: m.ID}
+}
+
+// sortMessages orders messages by timestamp and ID, so that every message has
+// a distinct position even if several share a timestamp.
+func sortMessages(messages []storedMessage) {
+	sort.Slice(messages, func(i, j int) bool { return messages[i].cursor().before(messages[j].cursor()) })
+}
+
+// storedConversation holds the latest messages exchanged with a contact or in
+// a group. ReadUpTo is the timestamp of the newest incoming message that was
+// read.
+type storedConversation struct {
+	Peer     string          `json:"peer"`
+	IsGroup  bool            `json:"is_group"`
+	Name     string          `json:"name,omitempty"`
+	ReadUpTo int64           `json:"read_up_to"`
+	Messages []storedMessage `json:"messages"`
+}
+
+// last returns the newest message that isn't deleted.
+func (c *storedConversation) last() (storedMessage, bool) {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Deleted == nil {
+			return c.Messages[i], true
+		}
+	}
+	return storedMessage{}, false
+}
+
+func (c *storedConversation) unread() int {
+	unread := 0
+	for _, m := range c.Messages {
+		if !m.Outgoing && m.Deleted == nil && m.Timestamp > c.ReadUpTo {
+			unread++
+		}
+	}
+	return unread
+}
+
+// messageStore keeps the latest messages of every conversation of the
+// numbers, so that clients don't need to replay the full history. It is
+// persisted, changes are written every few seconds. Deleted messages are
+// purged after purgeAfter. If the replicas share the store, the changes that
+// weren't written yet are applied again to the messages another replica
+// wrote.
+type messageStore struct {
+	mutex      sync.Mutex
+	state      stateStore
+	size       int
+	purgeAfter time.Duration
+	accounts   map[string]map[string]*storedConversation
+	// pending are the changes since the messages were last written.
+	pending []func() bool
+	// index is the full-text index of the messages, if there is one.
+	index *searchIndex
+}
+
+func newMessageStore(size int, purgeAfter time.Duration, state stateStore) (*messageStore, error) {
+	s := &messageStore{
+		state:      state,
+		size:       size,
+		purgeAfter: purgeAfter,
+		accounts:   make(map[string]map[string]*storedConversation),
+	}
+
+	accounts := make(map[string][]*storedConversation)
+	if err := state.load(&accounts); err != nil {
+		return nil, errors.New("Couldn't load message store: " + err.Error())
+	}
+	s.setAccounts(accounts)
+	return s, nil
+}
+
+// setAccounts replaces the messages with the ones that were written, the
+// caller needs to hold the mutex.
+func (s *messageStore) setAccounts(accounts map[string][]*storedConversation) {
+	s.accounts = make(map[string]map[string]*storedConversation)
+	for number, conversations := range accounts {
+		s.accounts[number] = make(map[string]*storedConversation)
+		for _, c := range conversations {
+			for i := range c.Messages {
+				if c.Messages[i].ID == "" {
+					c.Messages[i].ID = messageID(c.Messages[i].Sender, c.Messages[i].Timestamp)
+				}
+			}
+			sortMessages(c.Messages)
+			s.accounts[number][c.Peer] = c
+		}
+	}
+}
+
+// document returns the messages as they are written, the caller needs to hold
+// the mutex.
+func (s *messageStore) document() map[string][]*storedConversation {
+	accounts := make(map[string][]*storedConversation)
+	for number, conversations := range s.accounts {
+		for _, c := range conversations {
+			accounts[number] = append(accounts[number], c)
+		}
+	}
+	return accounts
+}
+
+// change applies a change that returns whether it changed anything and
+// remembers it until the messages are written. The caller needs to hold the
+// mutex.
+func (s *messageStore) change(f func() bool) {
+	if f() {
+		s.pending = append(s.pending, f)
+	}
+}
+
+// replay applies the pending changes to the messages another replica wrote,
+// the caller needs to hold the mutex.
+func (s *messageStore) replay(accounts map[string][]*storedConversation) {
+	s.setAccounts(accounts)
+	for _, f := range s.pending {
+		f()
+	}
+}
+
+// refresh loads the messages other replicas wrote, the caller needs to hold
+// the mutex.
+func (s *messageStore) refresh() {
+	accounts := make(map[string][]*storedConversation)
+	reloaded, err := s.state.refresh(&accounts)
+	if err != nil {
+		log.Error("Couldn't load message store: ", err.Error())
+		return
+	}
+	if reloaded {
+		s.replay(accounts)
+	}
+}
+
+// conversation returns the conversation of number with peer, creating it if
+// needed. The caller needs to hold the mutex.
+func (s *messageStore) conversation(number string, peer string, isGroup bool) *storedConversation {
+	conversations, ok := s.accounts[number]
+	if !ok {
+		conversations = make(map[string]*storedConversation)
+		s.accounts[number] = conversations
+	}
+	c, ok := conversations[peer]
+	if !ok {
+		c = &storedConversation{Peer: peer, IsGroup: isGroup, Messages: []storedMessage{}}
+		conversations[peer] = c
+	}
+	return c
+}
+
+func (s *messageStore) add(number string, peer string, isGroup bool, name string, m storedMessage) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.change(func() bool {
+		s.insert(number, peer, isGroup, name, m)
+		return true
+	})
+}
+
+// insert adds a message to its conversation, the caller needs to hold the
+// mutex.
+func (s *messageStore) insert(number string, peer string, isGroup bool, name string, m storedMessage) {
+	c := s.conversation(number, peer, isGroup)
+	if name != "" {
+		c.Name = name
+	}
+	m.ID = messageID(m.Sender, m.Timestamp)
+	c.Messages = append(c.Messages, m)
+	if n := len(c.Messages); n > 1 && m.cursor().before(c.Messages[n-2].cursor()) {
+		sortMessages(c.Messages)
+	}
+	s.indexed(number, peer, m)
+	if len(c.Messages) > s.size {
+		evicted := []string{}
+		for _, e := range c.Messages[:len(c.Messages)-s.size] {
+			evicted = append(evicted, e.ID)
+		}
+		s.unindexed(number, evicted)
+		c.Messages = c.Messages[len(c.Messages)-s.size:]
+	}
+}
+
+// edit replaces the body of the message of sender with the timestamp target.
+// If the message isn't in the store (anymore), the edit is added in its
+// place.
+func (s *messageStore) edit(number string, peer string, isGroup bool, sender string, target int64, body string, edited int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.change(func() bool {
+		c := s.conversation(number, peer, isGroup)
+		id := messageID(sender, target)
+		for i := range c.Messages {
+			if c.Messages[i].ID == id {
+				c.Messages[i].Body = body
+				c.Messages[i].Edited = edited
+				s.indexed(number, peer, c.Messages[i])
+				return true
+			}
+		}
+
+		s.insert(number, peer, isGroup, "", storedMessage{
+			Timestamp: target,
+			Sender:    sender,
+			Outgoing:  sender == number,
+			Body:      body,
+			Edited:    edited,
+		})
+		return true
+	})
+}
+
+// list returns copies of the conversations of number, the most recently
+// active first.
+func (s *messageStore) list(number string) []storedConversation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	conversations := []storedConversation{}
+	for _, c := range s.accounts[number] {
+		conversations = append(conversations, *c)
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		a, _ := conversations[i].last()
+		b, _ := conversations[j].last()
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp > b.Timestamp
+		}
+		return conversations[i].Peer < conversations[j].Peer
+	})
+	return conversations
+}
+
+// markRead advances the read cursor of a conversation to upTo, or to the
+// newest message if it is 0. It returns the timestamps of the messages that
+// were unread by their sender.
+func (s *messageStore) markRead(number string, peer string, upTo int64) (map[string][]int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	c, ok := s.accounts[number][peer]
+	if !ok {
+		return nil, false
+	}
+	if m, ok := c.last(); ok && (upTo == 0 || upTo > m.Timestamp) {
+		upTo = m.Timestamp
+	}
+
+	read := make(map[string][]int64)
+	for _, m := range c.Messages {
+		if !m.Outgoing && m.Deleted == nil && m.Timestamp > c.ReadUpTo && m.Timestamp <= upTo && m.Sender != "" {
+			read[m.Sender] = append(read[m.Sender], m.Timestamp)
+		}
+	}
+	s.change(func() bool {
+		c, ok := s.accounts[number][peer]
+		if !ok || upTo <= c.ReadUpTo {
+			return false
+		}
+		c.ReadUpTo = upTo
+		return true
+	})
+	return read, true
+}
+
+// messages returns copies of the messages of a conversation, oldest first.
+func (s *messageStore) messages(number string, peer string, deleted bool) ([]storedMessage, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	c, ok := s.accounts[number][peer]
+	if !ok {
+		return nil, false
+	}
+	messages := []storedMessage{}
+	for _, m := range c.Messages {
+		if deleted || m.Deleted == nil {
+			messages = append(messages, m)
+		}
+	}
+	return messages, true
+}
+
+// find returns the message with the given timestamp in the conversation of
+// number with peer. Messages are matched by sender too if it is given.
+func (s *messageStore) find(number string, peer string, sender string, timestamp int64) (storedMessage, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	c, ok := s.accounts[number][peer]
+	if !ok {
+		return storedMessage{}, false
+	}
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		m := c.Messages[i]
+		if m.Timestamp == timestamp && (sender == "" || m.Sender == sender) && m.Deleted == nil {
+			return m, true
+		}
+	}
+	return storedMessage{}, false
+}
+
+// remove soft-deletes the message with the given ID, it is hidden until it is
+// purged.
+func (s *messageStore) remove(number string, id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refresh()
+	now := time.Now()
+	removed := false
+	s.change(func() bool {
+		for _, c := range s.accounts[number] {
+			for i := range c.Messages {
+				if c.Messages[i].ID == id && c.Messages[i].Deleted == nil {
+					c.Messages[i].Deleted = &now
+					removed = true
+					return true
+				}
+			}
+		}
+		return false
+	})
+	return removed
+}
+
+// purge removes the messages that were deleted before cutoff.
+func (s *messageStore) purge(cutoff time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	purged := 0
+	s.change(func() bool {
+		purged = 0
+		for number, conversations := range s.accounts {
+			ids := []string{}
+			for _, c := range conversations {
+				messages := c.Messages[:0]
+				for _, m := range c.Messages {
+					if m.Deleted != nil && m.Deleted.Before(cutoff) {
+						ids = append(ids, m.ID)
+					} else {
+						messages = append(messages, m)
+					}
+				}
+				c.Messages = messages
+			}
+			s.unindexed(number, ids)
+			purged += len(ids)
+		}
+		return purged > 0
+	})
+	return purged
+}
+
+func (s *messageStore) flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	accounts := s.document()
+	err := s.state.update(&accounts, func(reloaded bool) error {
+		if reloaded {
+			s.replay(accounts)
+			accounts = s.document()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.pending = nil
+	return nil
+}
+
+func (s *messageStore) run() {
+	for range time.Tick(messageStoreFlushInterval) {
+		if n := s.purge(time.Now().Add(-s.purgeAfter)); n > 0 {
+			log.Info("Purged ", n, " deleted messages from the message store")
+		}
+		if err := s.flush(); err != nil {
+			log.Error("Couldn't save message store: ", err.Error())
+		}
+	}
+}
+
+func (s *messageStore) purgerName() string {
+	return "messages"
+}
+
+// purgeContact deletes the conversation with contact and the messages contact
+// sent to groups.
+func (s *messageStore) purgeContact(number string, contact string) (int, error) {
+	s.mutex.Lock()
+	deleted := 0
+	s.change(func() bool {
+		ids := []string{}
+		for peer, c := range s.accounts[number] {
+			if peer == contact {
+				for _, m := range c.Messages {
+					ids = append(ids, m.ID)
+				}
+				delete(s.accounts[number], peer)
+				continue
+			}
+
+			messages := c.Messages[:0]
+			for _, m := range c.Messages {
+				if m.Sender == contact {
+					ids = append(ids, m.ID)
+				} else {
+					messages = append(messages, m)
+				}
+			}
+			c.Messages = messages
+		}
+		s.unindexed(number, ids)
+		deleted = len(ids)
+		return deleted > 0
+	})
+	s.mutex.Unlock()
+
+	return deleted, s.flush()
+}
+
+// storeEvent adds received and sent messages to the message store.
+func (a *Api) storeEvent(e busEvent) {
+	switch data := e.Data.(type) {
+	case incomingMessage:
+		a.storeReceived(e.Number, data)
+	case sentMessage:
+		m := storedMessage{
+			Timestamp:   data.Timestamp,
+			Sender:      e.Number,
+			Outgoing:    true,
+			Body:        data.Message,
+			Attachments: data.Attachments,
+		}
+		if data.GroupID != "" {
+			a.store.add(e.Number, convertInternalGroupIDToGroupID(data.GroupID), true, "", m)
+		} else {
+			a.store.add(e.Number, data.Recipient, false, "", m)
+		}
+	}
+}
+
+func (a *Api) storeReceived(number string, msg incomingMessage) {
+	e, err := msg.envelope()
+	if err != nil {
+		return
+	}
+	if e.EditMessage != nil && e.EditMessage.DataMessage != nil && e.Source.Number != "" {
+		d := e.EditMessage.DataMessage
+		if g := d.GroupInfo; g != nil {
+			a.store.edit(number, convertInternalGroupIDToGroupID(g.GroupID), true, e.Source.Number, e.EditMessage.TargetSentTimestamp, d.Message, e.Timestamp)
+		} else {
+			a.store.edit(number, e.Source.Number, false, e.Source.Number, e.EditMessage.TargetSentTimestamp, d.Message, e.Timestamp)
+		}
+		return
+	}
+	m, ok := receivedMessage(e)
+	if !ok {
+		return
+	}
+
+	if g := e.DataMessage.GroupInfo; g != nil {
+		a.store.add(number, convertInternalGroupIDToGroupID(g.GroupID), true, g.Name, m)
+	} else if e.Source.Number != "" {
+		a.store.add(number, e.Source.Number, false, "", m)
+	}
+}
+
+// receivedMessage returns an incoming data message as the message store keeps
+// it. Reactions aren't messages of their own.
+func receivedMessage(e envelope) (storedMessage, bool) {
+	if e.DataMessage == nil || e.DataMessage.Reaction != nil {
+		return storedMessage{}, false
+	}
+
+	m := storedMessage{
+		Timestamp:   e.DataMessage.Timestamp,
+		Sender:      e.Source.Number,
+		Body:        e.DataMessage.Message,
+		Attachments: len(e.DataMessage.Attachments),
+	}
+	if m.Timestamp == 0 {
+		m.Timestamp = e.Timestamp
+	}
+	m.ID = messageID(m.Sender, m.Timestamp)
+	return m, true
+}
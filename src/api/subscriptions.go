@@ -0,0 +1,244 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	subscriptionConnecting   = "connecting"
+	subscriptionConnected    = "connected"
+	subscriptionDisconnected = "disconnected"
+)
+
+const subscriptionMaxBackoff = 30 * time.Second
+
+type subscriptionStatus struct {
+	Number string    `json:"number"`
+	State  string    `json:"state"`
+	Error  string    `json:"error,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+type readiness struct {
+	Ready    bool                 `json:"ready"`
+	Signald  *supervisorStatus    `json:"signald,omitempty"`
+	Checks   []signaldCheck       `json:"signald_checks,omitempty"`
+	Leader   *bool                `json:"leader,omitempty"`
+	Accounts []subscriptionStatus `json:"accounts"`
+}
+
+// subscription keeps a number subscribed to incoming messages on a connection
+// of its own. The messages pass the receive pipeline when they arrive and are
+// buffered until they are fetched with the receive endpoint.
+type subscription struct {
+	*receiveBuffer
+	mutex      sync.Mutex
+	socketPath func(number string) string
+	process    func(number string, messages []signald.RawResponse) []incomingMessage
+	shared     *sharedState
+	status     subscriptionStatus
+}
+
+func newSubscription(socketPath func(number string) string,
+	process func(number string, messages []signald.RawResponse) []incomingMessage,
+	shared *sharedState, db *stateDB, number string) *subscription {
+	return &subscription{
+		receiveBuffer: newReceiveBuffer(shared, db, "receive_buffer", number),
+		socketPath:    socketPath,
+		process:       process,
+		shared:        shared,
+		status: subscriptionStatus{
+			Number: number,
+			State:  subscriptionConnecting,
+			Since:  time.Now(),
+		},
+	}
+}
+
+func (s *subscription) setState(state string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.status.State != state {
+		s.status.Since = time.Now()
+	}
+	s.status.State = state
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+}
+
+func (s *subscription) getStatus() subscriptionStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.status
+}
+
+// receive passes an incoming message through the receive pipeline, which
+// publishes it on the event bus the receive buffer consumes. As every replica
+// subscribes the number, a message is only processed by the first one.
+func (s *subscription) receive(message signald.RawResponse) {
+	number := s.getStatus().Number
+	if s.shared != nil {
+		first, err := s.shared.claim(number, message)
+		if err != nil {
+			log.Error("Couldn't check the shared receive buffer: ", err.Error())
+		} else if !first {
+			return
+		}
+	}
+
+	s.process(number, []signald.RawResponse{message})
+}
+
+// consume buffers the received messages of the number.
+func (s *subscription) consume(e busEvent) {
+	if m, ok := e.Data.(incomingMessage); ok && e.Number == s.getStatus().Number {
+		s.push(m)
+	}
+}
+
+// run keeps the subscription alive, reconnecting with an increasing backoff.
+func (s *subscription) run() {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := s.subscribe()
+		s.setState(subscriptionDisconnected, err)
+		log.Error("Subscription of ", s.status.Number, " failed: ", err.Error())
+
+		if time.Since(start) > subscriptionMaxBackoff {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > subscriptionMaxBackoff {
+			backoff = subscriptionMaxBackoff
+		}
+		s.setState(subscriptionConnecting, nil)
+	}
+}
+
+// subscribe subscribes on a new connection and reads messages until the
+// connection fails.
+func (s *subscription) subscribe() error {
+	id, err := newUploadID()
+	if err != nil {
+		return err
+	}
+	number := s.getStatus().Number
+	request := signald.Request{Type: "subscribe", ID: id, Username: number}
+	conn, err := dialSignald(s.socketPath(number), request)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	for {
+		message, err := conn.read()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case message.ID == request.ID && message.Type == "subscribed":
+			log.Info("Subscribed ", s.getStatus().Number)
+			s.setState(subscriptionConnected, nil)
+		case message.ID == request.ID:
+			return signaldError(message)
+		default:
+			s.receive(message)
+		}
+	}
+}
+
+// subscriptions holds the subscriptions of the numbers that are subscribed on
+// startup.
+type subscriptions struct {
+	numbers map[string]*subscription
+}
+
+func newSubscriptions(socketPath func(number string) string,
+	process func(number string, messages []signald.RawResponse) []incomingMessage,
+	bus *eventBus, shared *sharedState, db *stateDB, numbers []string) *subscriptions {
+	s := &subscriptions{numbers: make(map[string]*subscription)}
+	for _, number := range numbers {
+		sub := newSubscription(socketPath, process, shared, db, number)
+		bus.subscribe("receive buffer of "+number, sub.consume, eventReceived)
+		s.numbers[number] = sub
+	}
+	return s
+}
+
+func (s *subscriptions) start() {
+	for _, sub := range s.numbers {
+		go sub.run()
+	}
+}
+
+func (s *subscriptions) get(number string) (*subscription, bool) {
+	sub, ok := s.numbers[number]
+	return sub, ok
+}
+
+func (s *subscriptions) purgerName() string {
+	return "receive buffers"
+}
+
+// purgeContact removes the buffered messages of number that mention contact.
+func (s *subscriptions) purgeContact(number string, contact string) (int, error) {
+	sub, ok := s.numbers[number]
+	if !ok {
+		return 0, nil
+	}
+	return sub.receiveBuffer.purgeContact(contact)
+}
+
+func (s *subscriptions) readiness() readiness {
+	r := readiness{Ready: true, Accounts: []subscriptionStatus{}}
+	for _, sub := range s.numbers {
+		status := sub.getStatus()
+		if status.State != subscriptionConnected {
+			r.Ready = false
+		}
+		r.Accounts = append(r.Accounts, status)
+	}
+	sort.Slice(r.Accounts, func(i, j int) bool { return r.Accounts[i].Number < r.Accounts[j].Number })
+	return r
+}
+
+// @Summary Check whether the API is ready.
+// @Tags General
+// @Description Reports ready once all numbers given with -subscribe-number are subscribed to incoming messages and, if signald is run by the API, signald is ready. The connection state of every number is listed, as well as the outcome of the startup check of the signald sockets, which failed sockets are checked again for.
+// @Produce  json
+// @Success 200 {object} readiness
+// @Failure 503 {object} readiness
+// @Router /v1/health/ready [get]
+func (a *Api) Ready(c *gin.Context) {
+	r := a.subscriptions.readiness()
+	if a.supervisor != nil {
+		status := a.supervisor.getStatus()
+		r.Signald = &status
+		r.Ready = r.Ready && status.State == supervisorReady
+	}
+	for _, check := range a.signaldChecks.status() {
+		r.Checks = append(r.Checks, check)
+		r.Ready = r.Ready && check.OK
+	}
+	if a.leader.shared != nil {
+		leader := a.leader.isLeader()
+		r.Leader = &leader
+	}
+	if !r.Ready {
+		c.JSON(503, r)
+		return
+	}
+	c.JSON(200, r)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	supervisorStarting = "starting"
+	supervisorReady    = "ready"
+	supervisorStopped  = "stopped"
+)
+
+const (
+	supervisorMaxBackoff   = 30 * time.Second
+	supervisorProbeTimeout = 2 * time.Second
+	supervisorStopTimeout  = 10 * time.Second
+)
+
+type supervisorStatus struct {
+	State    string    `json:"state"`
+	PID      int       `json:"pid,omitempty"`
+	Restarts int       `json:"restarts"`
+	Error    string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// supervisor runs signald (or another daemon providing the signald socket) as
+// child process, restarts it when it exits and pipes its output into the log.
+type supervisor struct {
+	mutex      sync.Mutex
+	command    []string
+	socketPath string
+	status     supervisorStatus
+	cmd        *exec.Cmd
+	stopping   bool
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+func newSupervisor(command []string, socketPath string) *supervisor {
+	return &supervisor{
+		command:    command,
+		socketPath: socketPath,
+		status:     supervisorStatus{State: supervisorStarting, Since: time.Now()},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+func (s *supervisor) setState(state string, pid int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.status.State != state {
+		s.status.Since = time.Now()
+	}
+	s.status.State = state
+	s.status.PID = pid
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+}
+
+func (s *supervisor) getStatus() supervisorStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.status
+}
+
+// probe checks whether the daemon accepts connections and greets them with its
+// version.
+func (s *supervisor) probe() error {
+	conn, err := net.DialTimeout("unix", s.socketPath, supervisorProbeTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(supervisorProbeTimeout))
+	raw := rawMessage{}
+	if err := json.NewDecoder(conn).Decode(&raw); err != nil {
+		return err
+	}
+	if raw.Type != "version" {
+		return errors.New("Unexpected greeting " + raw.Type)
+	}
+	return nil
+}
+
+func pipeLog(r io.Reader, name string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.WithField("process", name).Info(scanner.Text())
+	}
+}
+
+// runOnce starts the daemon and waits until it exits.
+func (s *supervisor) runOnce() error {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if s.stopping {
+		s.mutex.Unlock()
+		return errors.New("stopping")
+	}
+	if err := cmd.Start(); err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	s.cmd = cmd
+	s.mutex.Unlock()
+	s.setState(supervisorStarting, cmd.Process.Pid, nil)
+	log.Info("Started ", s.command[0], " (pid ", cmd.Process.Pid, ")")
+
+	go pipeLog(stdout, s.command[0])
+	go pipeLog(stderr, s.command[0])
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ready := false
+	interval := 500 * time.Millisecond
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return err
+		case <-time.After(interval):
+		}
+
+		if err := s.probe(); err != nil {
+			if ready {
+				log.Warn(s.command[0], " doesn't respond: ", err.Error())
+				s.setState(supervisorStarting, cmd.Process.Pid, err)
+				ready = false
+			}
+			continue
+		}
+		if !ready {
+			log.Info(s.command[0], " is ready")
+			s.setState(supervisorReady, cmd.Process.Pid, nil)
+			ready = true
+			interval = 10 * time.Second
+		}
+	}
+}
+
+// run keeps the daemon running, restarting it with an increasing backoff.
+func (s *supervisor) run() {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := s.runOnce()
+
+		s.mutex.Lock()
+		stopping := s.stopping
+		s.mutex.Unlock()
+		if stopping {
+			close(s.stopped)
+			return
+		}
+
+		log.Error(s.command[0], " failed: ", err.Error())
+		s.setState(supervisorStopped, 0, err)
+
+		if time.Since(start) > supervisorMaxBackoff {
+			backoff = time.Second
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			close(s.stopped)
+			return
+		}
+		if backoff *= 2; backoff > supervisorMaxBackoff {
+			backoff = supervisorMaxBackoff
+		}
+
+		s.mutex.Lock()
+		s.status.Restarts++
+		s.mutex.Unlock()
+	}
+}
+
+// stopOnSignal stops the daemon and exits when the API is asked to terminate.
+func (s *supervisor) stopOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	s.mutex.Lock()
+	s.stopping = true
+	cmd := s.cmd
+	s.mutex.Unlock()
+	close(s.stop)
+
+	log.Info("Stopping ", s.command[0])
+	if cmd != nil {
+		cmd.Process.Signal(syscall.SIGTERM)
+	}
+	select {
+	case <-s.stopped:
+	case <-time.After(supervisorStopTimeout):
+		if cmd != nil {
+			cmd.Process.Kill()
+		}
+	}
+	os.Exit(0)
+}
+
+func (s *supervisor) start() {
+	go s.run()
+	go s.stopOnSignal()
+}
@@ -0,0 +1,542 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// syslogClient is the client syslog messages are sent as, its quotas apply.
+const syslogClient = "syslog"
+
+const (
+	// syslogTemplate is the template of the Signal messages, if a rule has
+	// none.
+	syslogTemplate = "{{.Hostname}} {{.Severity}}: {{.Message}}"
+	// syslogMaxSize is the maximum size of a syslog message.
+	syslogMaxSize = 64 << 10
+	// syslogTimeout closes TCP connections without messages for this long.
+	syslogTimeout = 10 * time.Minute
+	// syslogQueueSize is the number of received messages waiting to be
+	// forwarded, messages received while the queue is full are dropped.
+	syslogQueueSize = 1000
+	// syslogDefaultWindow is the window of a rule, if it has none.
+	syslogDefaultWindow = time.Minute
+	// syslogCoalesced is the number of messages of a window that are listed in
+	// the message sent at its end, the others are only counted.
+	syslogCoalesced = 10
+)
+
+// syslogFacilities are the names of the facilities by their code.
+var syslogFacilities = []string{"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron",
+	"authpriv", "ftp", "ntp", "security", "console", "solaris-cron", "local0", "local1", "local2", "local3", "local4",
+	"local5", "local6", "local7"}
+
+// syslogSeverities are the names of the severities by their code, the most
+// severe first.
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// syslogSeverity returns the code of a severity, e.g. of warning or warn.
+func syslogSeverity(name string) (int, bool) {
+	switch strings.ToLower(name) {
+	case "emergency", "panic":
+		name = "emerg"
+	case "critical":
+		name = "crit"
+	case "error":
+		name = "err"
+	case "warn":
+		name = "warning"
+	}
+	for code, severity := range syslogSeverities {
+		if severity == strings.ToLower(name) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// SyslogRule forwards the syslog messages that match it to Signal recipients.
+type SyslogRule struct {
+	// Facilities are the names of the facilities forwarded, e.g. auth or
+	// local0, all if it is empty.
+	Facilities []string `json:"facilities"`
+	// Severity is the least severe level forwarded, e.g. warning forwards
+	// warning, err, crit, alert and emerg. All levels if it is empty.
+	Severity string `json:"severity"`
+	// Match is a regular expression the message needs to match.
+	Match  string `json:"match"`
+	Number string `json:"number"`
+	// Recipients are numbers or group ids.
+	Recipients []string `json:"recipients"`
+	// Template is a Go template of the Signal message, executed with the
+	// syslog message.
+	Template string `json:"template"`
+	// Window limits the Signal messages of the rule: the first message that
+	// matches is sent right away, the ones that follow within the window are
+	// sent together at its end. 1m if it isn't set.
+	Window string `json:"window"`
+
+	facilities map[int]bool
+	severity   int
+	match      *regexp.Regexp
+	template   *template.Template
+	window     time.Duration
+}
+
+// SyslogListener receives syslog messages on UDP and TCP and forwards the
+// matching ones as Signal messages.
+type SyslogListener struct {
+	// UDP and TCP are the addresses the listener receives messages on, e.g.
+	// :5514. At least one of them needs to be set.
+	UDP   string       `json:"udp"`
+	TCP   string       `json:"tcp"`
+	Rules []SyslogRule `json:"rules"`
+}
+
+// LoadSyslogListener reads the syslog listener from a JSON file.
+func LoadSyslogListener(filename string) (*SyslogListener, error) {
+	listener := &SyslogListener{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jsoniter.Unmarshal(data, listener); err != nil {
+		return nil, errors.New("Couldn't parse syslog config: " + err.Error())
+	}
+	if err := listener.init(); err != nil {
+		return nil, err
+	}
+	return listener, nil
+}
+
+func (l *SyslogListener) init() error {
+	if l.UDP == "" && l.TCP == "" {
+		return errors.New("The syslog listener needs a udp or a tcp address")
+	}
+	for _, address := range []string{l.UDP, l.TCP} {
+		if _, _, err := net.SplitHostPort(address); address != "" && err != nil {
+			return errors.New("Invalid syslog listen address " + address + " (host:port)")
+		}
+	}
+	for i := range l.Rules {
+		if err := l.Rules[i].init(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SyslogRule) init() error {
+	if r.Number == "" || len(r.Recipients) == 0 {
+		return errors.New("Invalid syslog rule, it needs a number and recipients")
+	}
+
+	r.facilities = make(map[int]bool)
+	for _, name := range r.Facilities {
+		found := false
+		for code, facility := range syslogFacilities {
+			if facility == strings.ToLower(name) {
+				r.facilities[code], found = true, true
+			}
+		}
+		if !found {
+			return errors.New("Invalid syslog facility " + name + " (supported: " + strings.Join(syslogFacilities, ", ") + ")")
+		}
+	}
+
+	r.severity = len(syslogSeverities) - 1
+	if r.Severity != "" {
+		severity, ok := syslogSeverity(r.Severity)
+		if !ok {
+			return errors.New("Invalid syslog severity " + r.Severity + " (supported: " + strings.Join(syslogSeverities, ", ") + ")")
+		}
+		r.severity = severity
+	}
+
+	if r.Match != "" {
+		match, err := regexp.Compile(r.Match)
+		if err != nil {
+			return errors.New("Invalid match of syslog rule: " + err.Error())
+		}
+		r.match = match
+	}
+
+	text := r.Template
+	if text == "" {
+		text = syslogTemplate
+	}
+	t, err := template.New("syslog").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return errors.New("Invalid template of syslog rule: " + err.Error())
+	}
+	r.template = t
+
+	r.window = syslogDefaultWindow
+	if r.Window != "" {
+		if r.window, err = time.ParseDuration(r.Window); err != nil || r.window < time.Second {
+			return errors.New("Invalid window of syslog rule " + r.Window + " (minimum 1s)")
+		}
+	}
+	return nil
+}
+
+func (r *SyslogRule) matches(m syslogMessage) bool {
+	if len(r.facilities) > 0 && !r.facilities[m.facility] {
+		return false
+	}
+	if m.severity > r.severity {
+		return false
+	}
+	return r.match == nil || r.match.MatchString(m.Message)
+}
+
+// text returns the Signal message of a syslog message, empty if the template
+// has no output.
+func (r *SyslogRule) text(m syslogMessage) (string, error) {
+	buf := bytes.Buffer{}
+	if err := r.template.Execute(&buf, m); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// syslogMessage is a received syslog message, the templates of the rules are
+// executed with it.
+type syslogMessage struct {
+	Facility  string
+	Severity  string
+	Timestamp time.Time
+	Hostname  string
+	App       string
+	Message   string
+	// Raw is the message as it was received.
+	Raw string
+
+	facility int
+	severity int
+}
+
+var syslogBSDTimestamp = regexp.MustCompile(`^[A-Z][a-z]{2} [ 0-9][0-9] [0-9]{2}:[0-9]{2}:[0-9]{2} `)
+
+// parseSyslog parses a message in the format of RFC 5424 or RFC 3164. Parts
+// that are missing are left empty, the host is the sender then.
+func parseSyslog(raw string, sender string) syslogMessage {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	m := syslogMessage{Raw: raw, Hostname: sender, Timestamp: time.Now(), facility: 1, severity: 5}
+
+	rest := raw
+	if strings.HasPrefix(rest, "<") {
+		if end := strings.Index(rest, ">"); end > 1 && end <= 4 {
+			if pri, err := strconv.Atoi(rest[1:end]); err == nil && pri < len(syslogFacilities)*8 {
+				m.facility, m.severity = pri/8, pri%8
+				rest = rest[end+1:]
+			}
+		}
+	}
+	m.Facility, m.Severity = syslogFacilities[m.facility], syslogSeverities[m.severity]
+
+	if strings.HasPrefix(rest, "1 ") {
+		// RFC 5424: VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+		fields := strings.SplitN(rest[2:], " ", 6)
+		if len(fields) == 6 {
+			if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+				m.Timestamp = t
+			}
+			if fields[1] != "-" {
+				m.Hostname = fields[1]
+			}
+			if fields[2] != "-" {
+				m.App = fields[2]
+			}
+			m.Message = strings.TrimPrefix(skipStructuredData(fields[5]), "\ufeff")
+			return m
+		}
+	}
+
+	if syslogBSDTimestamp.MatchString(rest) {
+		// RFC 3164: TIMESTAMP HOSTNAME TAG: MSG
+		if t, err := time.ParseInLocation(time.Stamp, rest[:15], time.Local); err == nil {
+			m.Timestamp = t.AddDate(time.Now().Year(), 0, 0)
+		}
+		rest = rest[16:]
+		if i := strings.Index(rest, " "); i > 0 {
+			m.Hostname, rest = rest[:i], rest[i+1:]
+		}
+	}
+	if i := strings.Index(rest, ": "); i > 0 && !strings.ContainsAny(rest[:i], " ") {
+		m.App, rest = rest[:i], rest[i+2:]
+		if j := strings.Index(m.App, "["); j > 0 {
+			m.App = m.App[:j]
+		}
+	}
+	m.Message = strings.TrimSpace(rest)
+	return m
+}
+
+// skipStructuredData returns the message after the structured data of a
+// RFC 5424 message.
+func skipStructuredData(rest string) string {
+	if strings.HasPrefix(rest, "-") {
+		return strings.TrimPrefix(rest[1:], " ")
+	}
+	for strings.HasPrefix(rest, "[") {
+		i := 1
+		for ; i < len(rest); i++ {
+			if rest[i] == '\\' {
+				i++
+			} else if rest[i] == ']' {
+				break
+			}
+		}
+		if i >= len(rest) {
+			return ""
+		}
+		rest = rest[i+1:]
+	}
+	return strings.TrimPrefix(rest, " ")
+}
+
+// syslogWindow collects the messages of a rule that follow the one that was
+// sent, until the window ends.
+type syslogWindow struct {
+	end      time.Time
+	messages []string
+	count    int
+}
+
+// syslogListener receives the syslog messages and queues them for a worker,
+// which forwards them, so that a burst of messages doesn't stall the
+// listeners.
+type syslogListener struct {
+	// dropped is the number of messages dropped since the last warning, first
+	// for the alignment of its atomic access.
+	dropped int64
+	config  *SyslogListener
+	udp     net.PacketConn
+	tcp     net.Listener
+	queue   chan syslogMessage
+	// windows are the open windows of the rules, only used by the worker.
+	windows map[*SyslogRule]*syslogWindow
+}
+
+func newSyslogListener(config *SyslogListener) (*syslogListener, error) {
+	l := &syslogListener{
+		config:  config,
+		queue:   make(chan syslogMessage, syslogQueueSize),
+		windows: make(map[*SyslogRule]*syslogWindow),
+	}
+	var err error
+	if config.UDP != "" {
+		if l.udp, err = net.ListenPacket("udp", config.UDP); err != nil {
+			return nil, err
+		}
+	}
+	if config.TCP != "" {
+		if l.tcp, err = net.Listen("tcp", config.TCP); err != nil {
+			if l.udp != nil {
+				l.udp.Close()
+			}
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func senderHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// serve receives messages until the listeners are closed and passes the
+// Signal messages of the rules they match to forward.
+func (l *syslogListener) serve(forward func(rule *SyslogRule, text string)) {
+	go l.work(forward)
+	if l.udp != nil {
+		go l.serveUDP(l.enqueue)
+	}
+	if l.tcp != nil {
+		go l.serveTCP(l.enqueue)
+	}
+}
+
+// enqueue queues a received message for the worker, it is dropped if the
+// queue is full.
+func (l *syslogListener) enqueue(m syslogMessage) {
+	select {
+	case l.queue <- m:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// work forwards the queued messages and ends the windows of the rules.
+func (l *syslogListener) work(forward func(rule *SyslogRule, text string)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-l.queue:
+			l.handle(m, forward)
+		case now := <-ticker.C:
+			if dropped := atomic.SwapInt64(&l.dropped, 0); dropped > 0 {
+				log.Warn("Dropped ", dropped, " syslog messages, the queue was full")
+			}
+			l.endWindows(now, forward)
+		}
+	}
+}
+
+// handle forwards a message for the rules it matches, unless the window of a
+// rule is open, then the message is collected for the end of the window.
+func (l *syslogListener) handle(m syslogMessage, forward func(rule *SyslogRule, text string)) {
+	for i := range l.config.Rules {
+		rule := &l.config.Rules[i]
+		if !rule.matches(m) {
+			continue
+		}
+		text, err := rule.text(m)
+		if err != nil {
+			log.Error("Couldn't execute the template of a syslog rule: ", err.Error())
+			continue
+		}
+		if text == "" {
+			continue
+		}
+
+		if w, ok := l.windows[rule]; ok {
+			w.count++
+			if len(w.messages) < syslogCoalesced {
+				w.messages = append(w.messages, text)
+			}
+			continue
+		}
+		l.windows[rule] = &syslogWindow{end: time.Now().Add(rule.window)}
+		forward(rule, text)
+	}
+}
+
+// endWindows sends the messages collected in the windows that ended as one
+// message, which opens a new window.
+func (l *syslogListener) endWindows(now time.Time, forward func(rule *SyslogRule, text string)) {
+	for rule, w := range l.windows {
+		if now.Before(w.end) {
+			continue
+		}
+		delete(l.windows, rule)
+		if w.count == 0 {
+			continue
+		}
+
+		text := strconv.Itoa(w.count) + " more messages:\n" + strings.Join(w.messages, "\n")
+		if more := w.count - len(w.messages); more > 0 {
+			text += "\n... and " + strconv.Itoa(more) + " more"
+		}
+		l.windows[rule] = &syslogWindow{end: now.Add(rule.window)}
+		forward(rule, text)
+	}
+}
+
+func (l *syslogListener) serveUDP(handle func(m syslogMessage)) {
+	buf := make([]byte, syslogMaxSize)
+	for {
+		n, addr, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			log.Error("The syslog UDP listener stopped: ", err.Error())
+			return
+		}
+		handle(parseSyslog(string(buf[:n]), senderHost(addr)))
+	}
+}
+
+func (l *syslogListener) serveTCP(handle func(m syslogMessage)) {
+	for {
+		conn, err := l.tcp.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			log.Error("The syslog TCP listener stopped: ", err.Error())
+			return
+		}
+		go l.connection(conn, handle)
+	}
+}
+
+// connection reads the messages of a TCP connection, framed by octet counting
+// or by newlines (RFC 6587).
+func (l *syslogListener) connection(conn net.Conn, handle func(m syslogMessage)) {
+	defer conn.Close()
+	sender := senderHost(conn.RemoteAddr())
+	reader := bufio.NewReaderSize(conn, syslogMaxSize)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(syslogTimeout))
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+
+		var raw []byte
+		if first[0] >= '1' && first[0] <= '9' {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil || n > syslogMaxSize {
+				log.Warn("Invalid syslog frame from ", sender, ", closing the connection")
+				return
+			}
+			raw = make([]byte, n)
+			if _, err := io.ReadFull(reader, raw); err != nil {
+				return
+			}
+		} else {
+			raw, err = reader.ReadSlice('\n')
+			if err == bufio.ErrBufferFull {
+				log.Warn("Too long syslog message from ", sender, ", closing the connection")
+				return
+			}
+			if err != nil && len(raw) == 0 {
+				return
+			}
+		}
+		if len(bytes.TrimSpace(raw)) > 0 {
+			handle(parseSyslog(string(raw), sender))
+		}
+	}
+}
+
+// syslogToSignal sends the Signal message of a syslog rule to its
+// recipients.
+func (a *Api) syslogToSignal(rule *SyslogRule, text string) {
+	for _, out := range sendsTo(rule.Recipients) {
+		out.Number, out.Message, out.Client = rule.Number, text, syslogClient
+		if _, err := a.service.Submit(out); err != nil {
+			log.Error("Couldn't forward a syslog message to ", strings.Join(out.Recipients, ","), ": ", err.Error())
+		}
+	}
+}
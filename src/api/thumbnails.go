@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/filetype"
+)
+
+const (
+	thumbnailDefaultSize = 256
+	thumbnailMaxSize     = 1024
+	// thumbnailIndexSize is the number of received attachments per number
+	// thumbnails can be requested for.
+	thumbnailIndexSize = 10000
+	// thumbnailCacheSize is the number of thumbnails kept in memory.
+	thumbnailCacheSize = 500
+)
+
+// receivedAttachment is an image or video attachment of a received message.
+type receivedAttachment struct {
+	source      string
+	contentType string
+	filename    string
+}
+
+// thumbnails indexes the image and video attachments of received messages and
+// caches the thumbnails generated for them.
+type thumbnails struct {
+	mutex         sync.Mutex
+	attachmentDir string
+	ffmpegPath    string
+	attachments   map[string]map[string]receivedAttachment
+	order         map[string][]string
+	cache         map[string][]byte
+	cacheOrder    []string
+}
+
+func newThumbnails(attachmentDir string, ffmpegPath string) *thumbnails {
+	return &thumbnails{
+		attachmentDir: attachmentDir,
+		ffmpegPath:    ffmpegPath,
+		attachments:   make(map[string]map[string]receivedAttachment),
+		order:         make(map[string][]string),
+		cache:         make(map[string][]byte),
+	}
+}
+
+func isThumbnailContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/")
+}
+
+func (t *thumbnails) add(number string, id string, attachment receivedAttachment) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	attachments, ok := t.attachments[number]
+	if !ok {
+		attachments = make(map[string]receivedAttachment)
+		t.attachments[number] = attachments
+	}
+	if _, ok := attachments[id]; !ok {
+		t.order[number] = append(t.order[number], id)
+	}
+	attachments[id] = attachment
+
+	if order := t.order[number]; len(order) > thumbnailIndexSize {
+		delete(attachments, order[0])
+		t.order[number] = order[1:]
+	}
+}
+
+// lookup returns the attachment with the given id. Attachments that weren't
+// received through the API are looked up in signald's attachment directory.
+func (t *thumbnails) lookup(number string, id string) (receivedAttachment, bool) {
+	t.mutex.Lock()
+	attachment, ok := t.attachments[number][id]
+	t.mutex.Unlock()
+	if ok {
+		return attachment, true
+	}
+
+	if t.attachmentDir == "" || id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return attachment, false
+	}
+	filename := filepath.Join(t.attachmentDir, id)
+	if _, err := os.Stat(filename); err != nil {
+		return attachment, false
+	}
+	contentType := ""
+	if kind, err := filetype.MatchFile(filename); err == nil {
+		contentType = kind.MIME.Value
+	}
+	return receivedAttachment{contentType: contentType, filename: filename}, true
+}
+
+func (t *thumbnails) cached(key string) ([]byte, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	thumbnail, ok := t.cache[key]
+	return thumbnail, ok
+}
+
+func (t *thumbnails) store(key string, thumbnail []byte) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.cache[key]; !ok {
+		t.cacheOrder = append(t.cacheOrder, key)
+	}
+	t.cache[key] = thumbnail
+	if len(t.cacheOrder) > thumbnailCacheSize {
+		delete(t.cache, t.cacheOrder[0])
+		t.cacheOrder = t.cacheOrder[1:]
+	}
+}
+
+func (t *thumbnails) purgerName() string {
+	return "thumbnails"
+}
+
+func (t *thumbnails) purgeContact(number string, contact string) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	deleted := 0
+	order := t.order[number][:0]
+	for _, id := range t.order[number] {
+		if t.attachments[number][id].source != contact {
+			order = append(order, id)
+			continue
+		}
+		delete(t.attachments[number], id)
+		prefix := number + "/" + id + "/"
+		for key := range t.cache {
+			if strings.HasPrefix(key, prefix) {
+				delete(t.cache, key)
+			}
+		}
+		deleted++
+	}
+	t.order[number] = order
+
+	cacheOrder := t.cacheOrder[:0]
+	for _, key := range t.cacheOrder {
+		if _, ok := t.cache[key]; ok {
+			cacheOrder = append(cacheOrder, key)
+		}
+	}
+	t.cacheOrder = cacheOrder
+	return deleted, nil
+}
+
+// generate creates a JPEG thumbnail whose longer edge is size pixels.
+func (t *thumbnails) generate(attachment receivedAttachment, size int) ([]byte, error) {
+	var img image.Image
+	if strings.HasPrefix(attachment.contentType, "video/") {
+		frame, err := t.videoFrame(attachment.filename)
+		if err != nil {
+			return nil, err
+		}
+		img = frame
+	} else {
+		f, err := os.Open(attachment.filename)
+		if err != nil {
+			return nil, errors.New("Couldn't read attachment")
+		}
+		defer f.Close()
+
+		if img, _, err = image.Decode(f); err != nil {
+			return nil, errors.New("Unsupported image format")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleImage(img, size), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// videoFrame extracts the first frame of a video with ffmpeg.
+func (t *thumbnails) videoFrame(filename string) (image.Image, error) {
+	if t.ffmpegPath == "" {
+		return nil, errors.New("Thumbnails of videos need ffmpeg")
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(t.ffmpegPath, "-v", "error", "-i", filename, "-frames:v", "1",
+		"-f", "image2pipe", "-vcodec", "png", "-")
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, errors.New("Couldn't extract video frame: " + err.Error())
+	}
+
+	img, _, err := image.Decode(&out)
+	if err != nil {
+		return nil, errors.New("Couldn't extract video frame: " + err.Error())
+	}
+	return img, nil
+}
+
+// scaleImage scales an image down so that its longer edge is at most size
+// pixels, averaging the source pixels covered by every target pixel.
+func scaleImage(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+// thumbnailStage returns the receive stage that indexes the image and video
+// attachments of received messages.
+func (a *Api) thumbnailStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		data, _ := msg.Data.(map[string]interface{})
+		dataMessage, _ := data["dataMessage"].(map[string]interface{})
+		attachments, _ := dataMessage["attachments"].([]interface{})
+		if len(attachments) == 0 {
+			return true
+		}
+
+		source := ""
+		if e, err := msg.envelope(); err == nil {
+			source = e.Source.Number
+		}
+		for _, att := range attachments {
+			attachment, _ := att.(map[string]interface{})
+			id, _ := attachment["id"].(string)
+			contentType, _ := attachment["contentType"].(string)
+			filename, _ := attachment["storedFilename"].(string)
+			if id != "" && filename != "" && isThumbnailContentType(contentType) {
+				a.thumbnails.add(number, id, receivedAttachment{
+					source:      source,
+					contentType: contentType,
+					filename:    filename,
+				})
+			}
+		}
+		return true
+	}
+}
+
+// @Summary Get the thumbnail of a received attachment.
+// @Tags Attachments
+// @Description Get a JPEG thumbnail of a received image or video attachment. Thumbnails are generated on the first request and cached.
+// @Produce  image/jpeg
+// @Success 200 {string} string "Thumbnail"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param id path string true "Attachment ID"
+// @Param size query int false "Length of the longer edge in pixels (default 256, maximum 1024)"
+// @Router /v1/attachments/{number}/{id}/thumbnail [get]
+func (a *Api) GetThumbnail(c *gin.Context) {
+	number := c.Param("number")
+	id := c.Param("id")
+
+	size := thumbnailDefaultSize
+	if value := c.Query("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 1 || size > thumbnailMaxSize {
+			c.JSON(400, gin.H{"error": "Invalid size " + value + " (1 to " + strconv.Itoa(thumbnailMaxSize) + ")"})
+			return
+		}
+	}
+
+	key := number + "/" + id + "/" + strconv.Itoa(size)
+	etag := `"` + key[len(number)+1:] + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(304)
+		return
+	}
+
+	thumbnail, ok := a.thumbnails.cached(key)
+	if !ok {
+		attachment, found := a.thumbnails.lookup(number, id)
+		if !found {
+			c.JSON(404, gin.H{"error": "No such attachment"})
+			return
+		}
+
+		var err error
+		if thumbnail, err = a.thumbnails.generate(attachment, size); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		a.thumbnails.store(key, thumbnail)
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("ETag", etag)
+	c.Data(200, "image/jpeg", thumbnail)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/filetype"
+)
+
+// twilioMediaTimeout is the timeout of fetching a MediaUrl.
+const twilioMediaTimeout = 30 * time.Second
+
+// twilioMediaMaxSize is the maximum size of a media file fetched from a
+// MediaUrl, the size limit of Signal attachments.
+const twilioMediaMaxSize = 100 << 20
+
+// twilioAPIVersion is the version of the Twilio API the shim implements.
+const twilioAPIVersion = "2010-04-01"
+
+// Error codes of the Twilio API, SDKs of Twilio report them to their callers.
+const (
+	twilioErrorInvalidTo       = 21211
+	twilioErrorNoBody          = 21602
+	twilioErrorInvalidFrom     = 21606
+	twilioErrorToRequired      = 21604
+	twilioErrorInvalidMedia    = 11200
+	twilioErrorTooManyRequests = 20429
+	twilioErrorUnknown         = 30008
+)
+
+// twilioMessage is a message resource of the Twilio Messages API.
+type twilioMessage struct {
+	Sid         string  `json:"sid"`
+	AccountSid  string  `json:"account_sid"`
+	APIVersion  string  `json:"api_version"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Body        string  `json:"body"`
+	Status      string  `json:"status"`
+	Direction   string  `json:"direction"`
+	NumMedia    string  `json:"num_media"`
+	NumSegments string  `json:"num_segments"`
+	DateCreated string  `json:"date_created"`
+	DateSent    *string `json:"date_sent"`
+	DateUpdated string  `json:"date_updated"`
+	ErrorCode   *int    `json:"error_code"`
+	Price       *string `json:"price"`
+	URI         string  `json:"uri"`
+}
+
+// twilioError responds with an error in the format of the Twilio API.
+func twilioError(c *gin.Context, status int, code int, message string) {
+	c.JSON(status, gin.H{
+		"code":      code,
+		"message":   message,
+		"more_info": "https://www.twilio.com/docs/errors/" + strconv.Itoa(code),
+		"status":    status,
+	})
+}
+
+// fetchMedia downloads a MediaUrl and stages it as attachment. The caller
+// needs to release the returned token.
+func (a *Api) fetchMedia(mediaURL string) (string, error) {
+	if !strings.HasPrefix(mediaURL, "http://") && !strings.HasPrefix(mediaURL, "https://") {
+		return "", errors.New("Invalid MediaUrl " + mediaURL)
+	}
+	resp, err := a.mediaClient.Get(mediaURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", errors.New("Fetching " + mediaURL + " failed with " + strconv.Itoa(resp.StatusCode))
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, twilioMediaMaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > twilioMediaMaxSize {
+		return "", errors.New("The media at " + mediaURL + " exceeds " + strconv.Itoa(twilioMediaMaxSize) + " bytes")
+	}
+	kind, err := filetype.Get(data)
+	if err != nil {
+		return "", err
+	}
+	hash, _, err := a.attachments.stage(data, kind.Extension)
+	return hash, err
+}
+
+// @Summary Send a message with the Twilio Messages API.
+// @Tags Twilio
+// @Description Accepts the requests of the Messages API of Twilio, so that code sending SMS with Twilio can send Signal messages by changing the base URL. From is the number of the account, To the recipient (a number or a group id), Body the message and MediaUrl (can be repeated) the URLs of attachments, which are fetched by the API. The account SID and auth token aren't checked, the SID is the client of the quotas when it is sent as user of the basic authentication like the SDKs of Twilio do.
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Success 201 {object} twilioMessage
+// @Failure 400 {object} Error
+// @Param sid path string true "Account SID"
+// @Param From formData string true "Registered Phone Number"
+// @Param To formData string true "Recipient"
+// @Param Body formData string false "Message"
+// @Param MediaUrl formData string false "Media URL"
+// @Router /2010-04-01/Accounts/{sid}/Messages.json [post]
+func (a *Api) TwilioSend(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		twilioError(c, 400, twilioErrorUnknown, "Couldn't process request - invalid form")
+		return
+	}
+	from := c.Request.PostForm.Get("From")
+	to := c.Request.PostForm.Get("To")
+	body := c.Request.PostForm.Get("Body")
+	mediaURLs := c.Request.PostForm["MediaUrl"]
+
+	if to == "" {
+		twilioError(c, 400, twilioErrorToRequired, "A 'To' phone number is required.")
+		return
+	}
+	if from == "" {
+		twilioError(c, 400, twilioErrorInvalidFrom, "A 'From' phone number is required.")
+		return
+	}
+	if err := a.knownAccount(from); err != nil {
+		twilioError(c, 400, twilioErrorInvalidFrom, "The 'From' phone number "+from+" is not a valid account: "+err.Error())
+		return
+	}
+	if body == "" && len(mediaURLs) == 0 {
+		twilioError(c, 400, twilioErrorNoBody, "Message body is required.")
+		return
+	}
+
+	tokens := []string{}
+	defer func() {
+		for _, token := range tokens {
+			a.attachments.release(token)
+		}
+	}()
+	for _, mediaURL := range mediaURLs {
+		token, err := a.fetchMedia(mediaURL)
+		if err != nil {
+			twilioError(c, 400, twilioErrorInvalidMedia, "Couldn't fetch MediaUrl: "+err.Error())
+			return
+		}
+		tokens = append(tokens, token)
+	}
+
+	result, err := a.service.Submit(service.Send{
+		Number:           from,
+		Message:          body,
+		Recipients:       []string{to},
+		AttachmentTokens: tokens,
+		IsGroup:          hasGroupPrefix(to),
+		Client:           a.audit.actor(c),
+		Context:          c.Request.Context(),
+	})
+	if err != nil {
+		setErrorHeaders(c, err)
+		code := twilioErrorInvalidTo
+		if status := errorStatus(err); status == 429 {
+			code = twilioErrorTooManyRequests
+		} else if status >= 500 {
+			code = twilioErrorUnknown
+		}
+		twilioError(c, errorStatus(err), code, err.Error())
+		return
+	}
+	for name, value := range result.Quota {
+		c.Header(name, value)
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		twilioError(c, 500, twilioErrorUnknown, err.Error())
+		return
+	}
+	sid := "SM" + id
+	now := time.Now().UTC().Format(time.RFC1123Z)
+	msg := twilioMessage{
+		Sid:         sid,
+		AccountSid:  c.Param("sid"),
+		APIVersion:  twilioAPIVersion,
+		From:        from,
+		To:          to,
+		Body:        body,
+		Status:      "sent",
+		Direction:   "outbound-api",
+		NumMedia:    strconv.Itoa(len(tokens)),
+		NumSegments: "1",
+		DateCreated: now,
+		DateUpdated: now,
+		URI:         "/" + twilioAPIVersion + "/Accounts/" + c.Param("sid") + "/Messages/" + sid + ".json",
+	}
+	if len(result.Queued) > 0 {
+		msg.Status = "queued"
+	} else {
+		msg.DateSent = &now
+	}
+	c.JSON(201, msg)
+}
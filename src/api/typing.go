@@ -0,0 +1,43 @@
+package api
+
+import "strings"
+
+type envelopeTyping struct {
+	Action    string `json:"action"`
+	Timestamp int64  `json:"timestamp"`
+	GroupID   string `json:"groupId"`
+}
+
+// typingIndicator is set on received typing messages: a sender started or
+// stopped typing to the number, or in a group.
+type typingIndicator struct {
+	// Action is started or stopped.
+	Action    string `json:"action"`
+	GroupID   string `json:"group_id,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// typingStage returns the receive stage that drops the typing messages of
+// numbers that don't have typing_events enabled, so that consumers that don't
+// care about presence aren't flooded with them. The typing messages that are
+// kept have typing set.
+func (a *Api) typingStage() receiveStage {
+	return func(number string, msg *incomingMessage) bool {
+		e, err := msg.envelope()
+		if err != nil || e.Typing == nil {
+			return true
+		}
+		if !a.accounts.get(number).TypingEvents {
+			return false
+		}
+
+		msg.Typing = &typingIndicator{
+			Action:    strings.ToLower(e.Typing.Action),
+			Timestamp: e.Typing.Timestamp,
+		}
+		if e.Typing.GroupID != "" {
+			msg.Typing.GroupID = convertInternalGroupIDToGroupID(e.Typing.GroupID)
+		}
+		return true
+	}
+}
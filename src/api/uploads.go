@@ -0,0 +1,411 @@
+package api
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/filetype"
+)
+
+// uploadBufferSize is the size of the buffer chunks are written with.
+const uploadBufferSize = 64 * 1024
+
+type upload struct {
+	mutex    sync.Mutex
+	id       string
+	filename string
+	offset   int64
+	size     int64
+	modified time.Time
+}
+
+type uploadStatus struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+type uploadToken struct {
+	Token string `json:"token"`
+}
+
+type createUploadRequest struct {
+	Size int64 `json:"size"`
+}
+
+// uploadManager keeps track of resumable uploads. An upload is written chunk
+// by chunk to a file in the tmp dir and, once finalized, handed over to the
+// attachment stager. The SHA-256 hash of the upload is returned as token that
+// can be used instead of a base64 encoded attachment when sending.
+type uploadManager struct {
+	mutex   sync.Mutex
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	stager  *attachmentStager
+	uploads map[string]*upload
+	tokens  map[string]time.Time
+}
+
+func newUploadManager(dir string, ttl time.Duration, maxSize int64, stager *attachmentStager) *uploadManager {
+	return &uploadManager{
+		dir:     dir,
+		ttl:     ttl,
+		maxSize: maxSize,
+		stager:  stager,
+		uploads: make(map[string]*upload),
+		tokens:  make(map[string]time.Time),
+	}
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (m *uploadManager) create(size int64) (*upload, error) {
+	if size > m.maxSize {
+		return nil, m.tooLarge()
+	}
+	if err := m.stager.checkSpace(size); err != nil {
+		return nil, err
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(m.dir, "signald-rest-api-upload-"+id+".partial")
+	if err := ioutil.WriteFile(filename, nil, 0600); err != nil {
+		return nil, err
+	}
+
+	u := &upload{id: id, filename: filename, size: size, modified: time.Now()}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.uploads[id] = u
+
+	return u, nil
+}
+
+func (m *uploadManager) get(id string) (*upload, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	u, ok := m.uploads[id]
+	return u, ok
+}
+
+func (m *uploadManager) remove(id string) {
+	m.mutex.Lock()
+	u, ok := m.uploads[id]
+	delete(m.uploads, id)
+	m.mutex.Unlock()
+
+	if ok {
+		os.Remove(u.filename)
+	}
+}
+
+// tooLarge is the error of an upload that exceeds the maximum size.
+func (m *uploadManager) tooLarge() error {
+	return errors.New("The upload exceeds the maximum size of " + strconv.FormatInt(m.maxSize, 10) + " bytes")
+}
+
+// chunkTooLarge is the error of a chunk that exceeds the size of the upload,
+// or the maximum size if it has none.
+func (m *uploadManager) chunkTooLarge(u *upload) error {
+	if u.size > 0 {
+		return errors.New("The chunk exceeds the size of the upload (" + strconv.FormatInt(u.size, 10) + " bytes)")
+	}
+	return m.tooLarge()
+}
+
+// write appends the chunk in r of length bytes (-1 if unknown) to the upload.
+// The offset needs to match the number of bytes already received. Chunks
+// beyond the size of the upload, or the maximum size if it has none, are
+// rejected.
+func (m *uploadManager) write(u *upload, offset int64, length int64, r io.Reader) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if offset != u.offset {
+		return errOffsetMismatch
+	}
+	size := m.maxSize
+	if u.size > 0 {
+		size = u.size
+	}
+	remaining := size - u.offset
+	if length > remaining {
+		return m.chunkTooLarge(u)
+	}
+	if length < 0 {
+		length = remaining
+	}
+	if err := m.stager.checkSpace(length); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(u.filename, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(u.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriterSize(f, uploadBufferSize)
+	n, err := io.Copy(w, io.LimitReader(r, remaining))
+	if err == nil && n == remaining {
+		// a chunk of unknown length may go on
+		if _, rerr := io.ReadFull(r, make([]byte, 1)); rerr == nil {
+			err = m.chunkTooLarge(u)
+		}
+	}
+	// what was received is kept, even if the chunk is incomplete
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err == nil {
+		err = m.stager.syncer.sync(f)
+	}
+	// bytes still in the buffer weren't written
+	u.offset += n - int64(w.Buffered())
+	u.modified = time.Now()
+	return err
+}
+
+func (m *uploadManager) finalize(u *upload) (string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.size > 0 && u.offset != u.size {
+		return "", errors.New("Upload is incomplete, received " + strconv.FormatInt(u.offset, 10) +
+			" of " + strconv.FormatInt(u.size, 10) + " bytes")
+	}
+
+	f, err := os.Open(u.filename)
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, 262)
+	n, _ := io.ReadFull(f, head)
+	f.Close()
+
+	fType, err := filetype.Match(head[:n])
+	if err != nil {
+		return "", err
+	}
+
+	hash, _, err := m.stager.stageFile(u.filename, fType.Extension)
+	if err != nil {
+		return "", err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.uploads, u.id)
+	if _, ok := m.tokens[hash]; ok {
+		// the token already holds a reference on the staged attachment
+		m.stager.release(hash)
+	}
+	m.tokens[hash] = time.Now().Add(m.ttl)
+
+	return hash, nil
+}
+
+// cleanup removes abandoned uploads and releases expired tokens.
+func (m *uploadManager) cleanup() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, u := range m.uploads {
+		if time.Since(u.modified) >= m.ttl {
+			os.Remove(u.filename)
+			delete(m.uploads, id)
+		}
+	}
+
+	for hash, expires := range m.tokens {
+		if time.Now().After(expires) {
+			m.stager.release(hash)
+			delete(m.tokens, hash)
+		}
+	}
+}
+
+func (m *uploadManager) run() {
+	for range time.Tick(time.Minute) {
+		m.cleanup()
+	}
+}
+
+var errOffsetMismatch = errors.New("Upload offset mismatch")
+
+func (u *upload) status() uploadStatus {
+	return uploadStatus{ID: u.id, Offset: u.offset, Size: u.size}
+}
+
+// @Summary Start a resumable attachment upload.
+// @Tags Attachments
+// @Description Creates a new upload session. The size is optional, if provided the upload can only be finalized once all bytes are received. Uploads can't exceed the maximum upload size.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} uploadStatus
+// @Failure 400 {object} Error
+// @Param data body createUploadRequest false "Upload Settings"
+// @Router /v1/attachments/uploads [post]
+func (a *Api) CreateUpload(c *gin.Context) {
+	req := createUploadRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+			return
+		}
+	}
+
+	if req.Size < 0 {
+		c.JSON(400, gin.H{"error": "Please provide a valid size"})
+		return
+	}
+
+	u, err := a.uploads.create(req.Size)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(201, u.status())
+}
+
+// @Summary Get the state of a resumable attachment upload.
+// @Tags Attachments
+// @Description Returns the number of bytes received so far, which is the offset the next chunk needs to start at.
+// @Produce  json
+// @Success 200 {object} uploadStatus
+// @Failure 404 {object} Error
+// @Param id path string true "Upload Id"
+// @Router /v1/attachments/uploads/{id} [get]
+func (a *Api) GetUpload(c *gin.Context) {
+	u, ok := a.uploads.get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	c.Header("Upload-Offset", strconv.FormatInt(u.offset, 10))
+	c.JSON(200, u.status())
+}
+
+// @Summary Upload a chunk of an attachment.
+// @Tags Attachments
+// @Description Appends the request body to the upload. The Upload-Offset header needs to contain the offset of the chunk, i.e. the number of bytes already received. A chunk beyond the size of the upload, or the maximum upload size if it has none, is rejected.
+// @Accept  application/octet-stream
+// @Produce  json
+// @Success 200 {object} uploadStatus
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} uploadStatus
+// @Param id path string true "Upload Id"
+// @Param Upload-Offset header int true "Offset of the chunk"
+// @Router /v1/attachments/uploads/{id} [put]
+func (a *Api) UploadChunk(c *gin.Context) {
+	u, ok := a.uploads.get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(400, gin.H{"error": "Please provide a valid Upload-Offset header"})
+		return
+	}
+
+	err = a.uploads.write(u, offset, c.Request.ContentLength, c.Request.Body)
+
+	u.mutex.Lock()
+	status := u.status()
+	u.mutex.Unlock()
+	c.Header("Upload-Offset", strconv.FormatInt(status.Offset, 10))
+
+	if err == errOffsetMismatch {
+		c.JSON(409, status)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+// @Summary Finalize a resumable attachment upload.
+// @Tags Attachments
+// @Description Completes the upload and returns a token that can be used in the attachment_tokens field when sending a message.
+// @Produce  json
+// @Success 201 {object} uploadToken
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Upload Id"
+// @Router /v1/attachments/uploads/{id}/finalize [post]
+func (a *Api) FinalizeUpload(c *gin.Context) {
+	u, ok := a.uploads.get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	token, err := a.uploads.finalize(u)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, uploadToken{Token: token})
+}
+
+// @Summary Abort a resumable attachment upload.
+// @Tags Attachments
+// @Description Aborts the upload and removes the data received so far.
+// @Produce  json
+// @Success 204 {string} string "OK"
+// @Param id path string true "Upload Id"
+// @Router /v1/attachments/uploads/{id} [delete]
+func (a *Api) DeleteUpload(c *gin.Context) {
+	a.uploads.remove(c.Param("id"))
+	c.Status(204)
+}
+
+// GetUploadOrNotFound serves GET /v1/attachments/{number}/{id}. gin doesn't
+// allow the static uploads segment next to the number of the thumbnail route,
+// so GET requests of uploads share the parameter.
+func (a *Api) GetUploadOrNotFound(c *gin.Context) {
+	if c.Param("number") != "uploads" {
+		c.JSON(404, gin.H{"error": "Not found"})
+		return
+	}
+	a.GetUpload(c)
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The v3 API models the resources of an account under
+// /v3/accounts/{number}, with plural resource names, group ids only in the
+// group.<id> form, the same error schema for every error and status codes by
+// the outcome (201 created, 202 accepted, 204 without content, 404 unknown).
+// It shares the implementation with v1 and v2.
+
+// v3Error is the error schema of the v3 API.
+type v3Error struct {
+	Error v3ErrorBody `json:"error"`
+}
+
+type v3ErrorBody struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var v3ErrorCodes = map[int]string{
+	400: "invalid_request",
+	403: "forbidden",
+	404: "not_found",
+	409: "conflict",
+	413: "too_large",
+	428: "confirmation_required",
+	429: "rate_limited",
+	501: "not_implemented",
+	502: "bad_gateway",
+	503: "unavailable",
+	504: "timeout",
+	507: "insufficient_storage",
+}
+
+func v3ErrorCode(status int) string {
+	if code, ok := v3ErrorCodes[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// v3Writer holds back the body of a response, so that errors can be
+// rewritten to the v3 error schema.
+type v3Writer struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *v3Writer) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *v3Writer) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// V3Errors rewrites the errors of the handlers shared with v1 and v2
+// ({"error": "<message>"}) to the v3 error schema.
+func (a *Api) V3Errors(c *gin.Context) {
+	w := &v3Writer{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+	c.Writer = w.ResponseWriter
+
+	body := w.body.Bytes()
+	if status := w.Status(); status >= 400 {
+		legacy := struct {
+			Error *string `json:"error"`
+		}{}
+		if jsoniter.Unmarshal(body, &legacy) == nil && legacy.Error != nil {
+			if rewritten, err := jsoniter.Marshal(v3Error{Error: v3ErrorBody{
+				Status:  status,
+				Code:    v3ErrorCode(status),
+				Message: *legacy.Error,
+			}}); err == nil {
+				body = rewritten
+			}
+		}
+	}
+	w.ResponseWriter.Write(body)
+}
+
+func v3Abort(c *gin.Context, status int, message string) {
+	c.JSON(status, v3Error{Error: v3ErrorBody{Status: status, Code: v3ErrorCode(status), Message: message}})
+}
+
+// v3GroupID returns the internal id of a group id in the group.<id> form.
+func v3GroupID(c *gin.Context, group string) (string, bool) {
+	if !hasGroupPrefix(group) {
+		v3Abort(c, 400, "Invalid group id "+group+", the v3 API only takes group ids of the form "+groupPrefix+"<id>")
+		return "", false
+	}
+	internalID, ok := parseGroupID(group)
+	if !ok {
+		v3Abort(c, 400, "Invalid group id "+group)
+		return "", false
+	}
+	return internalID, true
+}
+
+type v3SendRequest struct {
+	// Recipients are phone numbers, "self" sends a note to self.
+	Recipients []string `json:"recipients"`
+	GroupID    string   `json:"group_id"`
+	Message    string   `json:"message"`
+	// Attachments are base64 encoded.
+	Attachments      []string `json:"attachments"`
+	AttachmentTokens []string `json:"attachment_tokens"`
+	Timestamp        int64    `json:"timestamp"`
+}
+
+type v3Group struct {
+	ID                string             `json:"id"`
+	Name              string             `json:"name"`
+	Members           []string           `json:"members"`
+	MemberDetails     []groupMemberEntry `json:"member_details"`
+	PendingMembers    []groupMemberEntry `json:"pending_members"`
+	RequestingMembers []groupMemberEntry `json:"requesting_members"`
+	ExpirationTimer   int                `json:"expiration_timer"`
+	Active            bool               `json:"active"`
+}
+
+func newV3Group(g groupEntry) v3Group {
+	members := g.Members
+	if members == nil {
+		members = []string{}
+	}
+	return v3Group{
+		ID:                g.ID,
+		Name:              g.Name,
+		Members:           members,
+		MemberDetails:     g.MemberDetails,
+		PendingMembers:    g.PendingMembers,
+		RequestingMembers: g.RequestingMembers,
+		ExpirationTimer:   g.ExpirationTimer,
+		Active:            g.Active,
+	}
+}
+
+type v3Verification struct {
+	Code string `json:"code"`
+	Pin  string `json:"pin"`
+}
+
+type v3Registration struct {
+	Captcha  string `json:"captcha"`
+	UseVoice bool   `json:"use_voice"`
+	// Force registers a number again that is already active.
+	Force bool `json:"force"`
+}
+
+// @Summary Register a phone number.
+// @Tags v3
+// @Description Request a verification code for a phone number, which is sent by SMS or voice call. The number is then verified with the code. Numbers that are already active are rejected with 409 unless force is set.
+// @Accept  json
+// @Produce  json
+// @Success 202 {string} string "Accepted"
+// @Failure 400 {object} v3Error
+// @Failure 409 {object} v3Error
+// @Param number path string true "Phone Number"
+// @Param data body v3Registration false "Registration"
+// @Router /v3/accounts/{number}/registration [post]
+func (a *Api) V3Register(c *gin.Context) {
+	req := v3Registration{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			v3Abort(c, 400, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	if err := a.registerNumber(c.Param("number"), normalizeCaptcha(req.Captcha), req.UseVoice, req.Force); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// @Summary Show the registration state of a phone number.
+// @Tags v3
+// @Description Show whether a number is unregistered, needs a captcha, waits for its verification code or is active, with a hint what to do next.
+// @Produce  json
+// @Success 200 {object} registrationStatus
+// @Failure 400 {object} v3Error
+// @Param number path string true "Phone Number"
+// @Router /v3/accounts/{number}/registration [get]
+func (a *Api) V3GetRegistration(c *gin.Context) {
+	status, err := a.registrationStatus(c.Param("number"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, status)
+}
+
+// @Summary Verify a phone number.
+// @Tags v3
+// @Description Verify a registered phone number with the code it received, with the registration lock pin if it has one.
+// @Accept  json
+// @Produce  json
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} v3Error
+// @Param number path string true "Phone Number"
+// @Param data body v3Verification true "Verification"
+// @Router /v3/accounts/{number}/registration/verification [post]
+func (a *Api) V3Verify(c *gin.Context) {
+	number := c.Param("number")
+	req := v3Verification{}
+	if err := c.BindJSON(&req); err != nil || req.Code == "" {
+		v3Abort(c, 400, "Please provide the verification code")
+		return
+	}
+
+	if err := a.service.Verify(number, req.Code, req.Pin); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Send a message.
+// @Tags v3
+// @Description Send a message to recipients or to a group. Responds with 201 and the timestamp of the message, or with 202 and the ids of the messages queued until the identity of a recipient is trusted.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} sentMessageResponse
+// @Success 202 {object} queuedMessages
+// @Failure 400 {object} v3Error
+// @Failure 404 {object} v3Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body v3SendRequest true "Message"
+// @Router /v3/accounts/{number}/messages [post]
+func (a *Api) V3SendMessage(c *gin.Context) {
+	number := c.Param("number")
+	req := v3SendRequest{}
+	if err := c.BindJSON(&req); err != nil {
+		v3Abort(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+	if (len(req.Recipients) == 0) == (req.GroupID == "") {
+		v3Abort(c, 400, "Please specify either recipients or a group_id")
+		return
+	}
+	if req.Timestamp < 0 {
+		v3Abort(c, 400, "Invalid timestamp")
+		return
+	}
+
+	if req.GroupID != "" {
+		if _, ok := v3GroupID(c, req.GroupID); !ok {
+			return
+		}
+		a.send(c, number, req.Message, []string{req.GroupID}, req.Attachments, req.AttachmentTokens, true, req.Timestamp)
+		return
+	}
+	recipients := resolveNoteToSelf(number, req.Recipients, false)
+	a.send(c, number, req.Message, recipients, req.Attachments, req.AttachmentTokens, false, req.Timestamp)
+}
+
+// @Summary List the groups of an account.
+// @Tags v3
+// @Description List the groups of an account, optionally filtered by name. The listing is paginated, the total number of groups is returned in the X-Total-Count header.
+// @Produce  json
+// @Success 200 {object} []v3Group
+// @Failure 400 {object} v3Error
+// @Param number path string true "Registered Phone Number"
+// @Param name query string false "Only list groups whose name contains this text"
+// @Param offset query int false "Number of groups to skip"
+// @Param limit query int false "Maximum number of groups to return"
+// @Router /v3/accounts/{number}/groups [get]
+func (a *Api) V3GetGroups(c *gin.Context) {
+	p, err := parsePage(c)
+	if err != nil {
+		v3Abort(c, 400, err.Error())
+		return
+	}
+
+	groups, err := a.getGroups(c.Param("number"))
+	if err != nil {
+		v3Abort(c, 502, err.Error())
+		return
+	}
+
+	filtered := []v3Group{}
+	for _, group := range groups {
+		if matchesName(group.Name, c.Query("name")) {
+			filtered = append(filtered, newV3Group(group))
+		}
+	}
+	start, end := p.bounds(c, len(filtered))
+	respondWithETag(c, filtered[start:end])
+}
+
+// @Summary Show a group.
+// @Tags v3
+// @Produce  json
+// @Success 200 {object} v3Group
+// @Failure 400 {object} v3Error
+// @Failure 404 {object} v3Error
+// @Param number path string true "Registered Phone Number"
+// @Param group_id path string true "Group ID (group.<id>)"
+// @Router /v3/accounts/{number}/groups/{group_id} [get]
+func (a *Api) V3GetGroup(c *gin.Context) {
+	internalID, ok := v3GroupID(c, c.Param("group_id"))
+	if !ok {
+		return
+	}
+
+	groups, err := a.getGroups(c.Param("number"))
+	if err != nil {
+		v3Abort(c, 502, err.Error())
+		return
+	}
+	for _, group := range groups {
+		if id, ok := normalizeInternalGroupID(group.InternalID); ok && id == internalID {
+			respondWithETag(c, newV3Group(group))
+			return
+		}
+	}
+	v3Abort(c, 404, "Group "+c.Param("group_id")+" not found")
+}
+
+// @Summary Update a group.
+// @Tags v3
+// @Description Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it) of a group. Settings that aren't given are left unchanged.
+// @Accept  json
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} v3Error
+// @Failure 403 {object} v3Error
+// @Failure 404 {object} v3Error
+// @Param number path string true "Registered Phone Number"
+// @Param group_id path string true "Group ID (group.<id>)"
+// @Param data body service.GroupUpdate true "Group Settings"
+// @Router /v3/accounts/{number}/groups/{group_id} [patch]
+func (a *Api) V3UpdateGroup(c *gin.Context) {
+	if _, ok := v3GroupID(c, c.Param("group_id")); !ok {
+		return
+	}
+
+	update := service.GroupUpdate{}
+	if err := c.BindJSON(&update); err != nil {
+		v3Abort(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := a.service.UpdateGroup(c.Param("number"), c.Param("group_id"), update); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Leave a group.
+// @Tags v3
+// @Description Leave a group. If the account is the last admin of the group, new_admin (the number or UUID of a member) is made admin before, so that the group isn't left without admin. Without new_admin the last admin can only leave with force=true.
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} v3Error
+// @Failure 403 {object} v3Error
+// @Failure 404 {object} v3Error
+// @Failure 409 {object} v3Error
+// @Failure 501 {object} v3Error
+// @Param number path string true "Registered Phone Number"
+// @Param group_id path string true "Group ID (group.<id>)"
+// @Param new_admin query string false "Member to make admin before leaving"
+// @Param force query bool false "Leave the group without admin"
+// @Router /v3/accounts/{number}/groups/{group_id} [delete]
+func (a *Api) V3LeaveGroup(c *gin.Context) {
+	if _, ok := v3GroupID(c, c.Param("group_id")); !ok {
+		return
+	}
+
+	err := a.service.LeaveGroup(c.Param("number"), c.Param("group_id"), c.Query("new_admin"), c.Query("force") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
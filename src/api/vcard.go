@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/h2non/filetype"
+)
+
+// sharedContact is a contact sent along with a message. signald can't send
+// Signal's shared contact messages, its send request has no field for them, so
+// the contact is sent as vCard attachment instead. The Signal apps show it as
+// a file, not as a contact card.
+type sharedContact struct {
+	Name         string   `json:"name"`
+	Organization string   `json:"organization,omitempty"`
+	Phones       []string `json:"phones,omitempty"`
+	Emails       []string `json:"emails,omitempty"`
+	// Avatar is a base64 encoded JPEG or PNG image.
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// vcardLineLength is the maximum length of a vCard line in octets, longer
+// lines are folded.
+const vcardLineLength = 75
+
+// foldVCardLine folds a content line of a vCard: it is split into lines of at
+// most 75 octets, the continuation lines start with a space. Multi-byte
+// characters aren't split.
+func foldVCardLine(line string) string {
+	var b strings.Builder
+	limit := vcardLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space counts
+		limit = vcardLineLength - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+
+// vcard returns the contact as vCard 3.0.
+func (sc *sharedContact) vcard() ([]byte, error) {
+	if sc.Name == "" {
+		return nil, errors.New("Please provide the name of every shared contact")
+	}
+	if len(sc.Phones) == 0 && len(sc.Emails) == 0 {
+		return nil, errors.New("Please provide a phone number or email address of shared contact " + sc.Name)
+	}
+
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:" + vcardEscaper.Replace(sc.Name),
+		"N:;" + vcardEscaper.Replace(sc.Name) + ";;;",
+	}
+	if sc.Organization != "" {
+		lines = append(lines, "ORG:"+vcardEscaper.Replace(sc.Organization))
+	}
+	for _, phone := range sc.Phones {
+		lines = append(lines, "TEL;TYPE=CELL:"+vcardEscaper.Replace(phone))
+	}
+	for _, email := range sc.Emails {
+		lines = append(lines, "EMAIL;TYPE=INTERNET:"+vcardEscaper.Replace(email))
+	}
+	if sc.Avatar != "" {
+		avatar, err := base64.StdEncoding.DecodeString(sc.Avatar)
+		if err != nil {
+			return nil, errors.New("Invalid avatar of shared contact " + sc.Name + ": " + err.Error())
+		}
+		kind, _ := filetype.Match(avatar)
+		if kind.MIME.Value != "image/jpeg" && kind.MIME.Value != "image/png" {
+			return nil, errors.New("The avatar of shared contact " + sc.Name + " needs to be a JPEG or PNG image")
+		}
+		lines = append(lines, "PHOTO;ENCODING=b;TYPE="+strings.ToUpper(kind.Extension)+":"+sc.Avatar)
+	}
+	lines = append(lines, "END:VCARD")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldVCardLine(line))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// stageSharedContacts stages the vCards of the contacts as attachments and
+// returns their tokens. Every token needs to be released by the caller.
+func (a *Api) stageSharedContacts(contacts []sharedContact) ([]string, error) {
+	tokens := []string{}
+	for i := range contacts {
+		vcard, err := contacts[i].vcard()
+		if err == nil {
+			var hash string
+			if hash, _, err = a.attachments.stage(vcard, "vcf"); err == nil {
+				tokens = append(tokens, hash)
+				continue
+			}
+		}
+
+		for _, token := range tokens {
+			a.attachments.release(token)
+		}
+		return nil, err
+	}
+	return tokens, nil
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// The ways a verification code is delivered.
+const (
+	verificationSMS   = "sms"
+	verificationVoice = "voice"
+)
+
+// maxVerificationAttempts is the number of codes that can be requested for a
+// number until it is verified. Signal rate limits requesting codes, the
+// limit keeps the API from running into it.
+const maxVerificationAttempts = 5
+
+// verificationResendInterval is how long to wait before requesting another
+// code, Signal only places a voice call a minute after the SMS was sent.
+const verificationResendInterval = time.Minute
+
+// verificationCodeTTL is how long the attempts of a number are kept, the codes
+// have expired by then.
+const verificationCodeTTL = 24 * time.Hour
+
+// verificationAttempt is a request of a verification code.
+type verificationAttempt struct {
+	Method      string    `json:"method"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// verificationState is the state of the verification of a number that waits
+// for its code.
+type verificationState struct {
+	// Method is how the last code was delivered.
+	Method string `json:"method"`
+	// CallPlaced is set if the last code was delivered by a voice call.
+	CallPlaced        bool                  `json:"call_placed"`
+	Attempts          []verificationAttempt `json:"attempts"`
+	AttemptsRemaining int                   `json:"attempts_remaining"`
+	// ResendAfter is when another code can be requested.
+	ResendAfter time.Time `json:"resend_after"`
+}
+
+// verificationAttempts tracks the verification codes requested for numbers.
+type verificationAttempts struct {
+	mutex    sync.Mutex
+	attempts map[string][]verificationAttempt
+}
+
+func newVerificationAttempts() *verificationAttempts {
+	return &verificationAttempts{attempts: make(map[string][]verificationAttempt)}
+}
+
+func verificationMethod(voice bool) string {
+	if voice {
+		return verificationVoice
+	}
+	return verificationSMS
+}
+
+// current returns the attempts of number, the mutex needs to be held.
+func (v *verificationAttempts) current(number string) []verificationAttempt {
+	attempts := v.attempts[number]
+	if len(attempts) > 0 && time.Since(attempts[len(attempts)-1].RequestedAt) > verificationCodeTTL {
+		delete(v.attempts, number)
+		return nil
+	}
+	return attempts
+}
+
+// allow fails with 429 if no more codes can be requested for number yet.
+func (v *verificationAttempts) allow(number string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	attempts := v.current(number)
+	if len(attempts) >= maxVerificationAttempts {
+		return service.NewError(service.RateLimited, "No more verification codes can be requested for "+number+
+			", verify it with one of the codes already sent")
+	}
+	if len(attempts) > 0 {
+		wait := verificationResendInterval - time.Since(attempts[len(attempts)-1].RequestedAt)
+		if wait > 0 {
+			seconds := int(wait/time.Second) + 1
+			return &service.Error{
+				Kind:       service.RateLimited,
+				Message:    "A verification code was just requested, try again in " + strconv.Itoa(seconds) + " seconds",
+				RetryAfter: seconds,
+			}
+		}
+	}
+	return nil
+}
+
+// requested records that a code was requested for number.
+func (v *verificationAttempts) requested(number string, voice bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.attempts[number] = append(v.current(number), verificationAttempt{
+		Method:      verificationMethod(voice),
+		RequestedAt: time.Now(),
+	})
+}
+
+// verified forgets the attempts of number.
+func (v *verificationAttempts) verified(number string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	delete(v.attempts, number)
+}
+
+// state returns the state of the verification of number, if a code was
+// requested.
+func (v *verificationAttempts) state(number string) (verificationState, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	attempts := v.current(number)
+	if len(attempts) == 0 {
+		return verificationState{}, false
+	}
+	last := attempts[len(attempts)-1]
+	return verificationState{
+		Method:            last.Method,
+		CallPlaced:        last.Method == verificationVoice,
+		Attempts:          append([]verificationAttempt{}, attempts...),
+		AttemptsRemaining: maxVerificationAttempts - len(attempts),
+		ResendAfter:       last.RequestedAt.Add(verificationResendInterval),
+	}, true
+}
+
+// resendRequest requests another verification code.
+type resendRequest struct {
+	// UseVoice delivers the code by a voice call instead of SMS.
+	UseVoice bool   `json:"use_voice"`
+	Captcha  string `json:"captcha"`
+}
+
+// @Summary Request another verification code.
+// @Tags Devices
+// @Description Request another verification code for a number that waits for its code, by SMS or by voice call (use_voice). Another code can be requested a minute after the last one, and at most 5 codes until the number is verified. The state of the verification is shown by /v1/register/{number}/status.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} registrationStatus
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 429 {object} Error
+// @Param number path string true "Phone Number"
+// @Param data body resendRequest false "Delivery"
+// @Router /v1/register/{number}/resend [post]
+func (a *Api) ResendVerificationCode(c *gin.Context) {
+	number := c.Param("number")
+
+	req := resendRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Couldn't process request - invalid request"})
+			return
+		}
+	}
+
+	if _, ok := a.verifications.state(number); !ok {
+		c.JSON(404, gin.H{"error": "No verification code was requested for " + number + ", register it first"})
+		return
+	}
+
+	if err := a.registerNumber(number, normalizeCaptcha(req.Captcha), req.UseVoice, false); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status, err := a.registrationStatus(number)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, status)
+}
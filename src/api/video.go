@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/h2non/filetype"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// VideoLimits are the checks outgoing video attachments have to pass.
+type VideoLimits struct {
+	FFprobePath string
+	FFmpegPath  string
+	// MaxSize is the maximum size in bytes, 0 means unlimited.
+	MaxSize int64
+	// MaxBitrate is the maximum bitrate in bits per second, 0 means
+	// unlimited.
+	MaxBitrate int64
+	// Containers and Codecs are the accepted formats as ffprobe names them.
+	Containers []string
+	Codecs     []string
+	// Transcode converts videos that don't pass the checks to H.264/AAC in
+	// MP4 with ffmpeg instead of rejecting them.
+	Transcode bool
+}
+
+type videoProbe struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		BitRate    string `json:"bit_rate"`
+		Size       string `json:"size"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+	} `json:"streams"`
+}
+
+// videoGuard checks outgoing video attachments with ffprobe, so that videos
+// Signal wouldn't accept are rejected with a clear error before they are
+// handed to signald.
+type videoGuard struct {
+	limits VideoLimits
+	dir    string
+}
+
+func newVideoGuard(limits VideoLimits, dir string) *videoGuard {
+	return &videoGuard{limits: limits, dir: dir}
+}
+
+func isVideo(filename string) bool {
+	kind, err := filetype.MatchFile(filename)
+	return err == nil && kind.MIME.Type == "video"
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *videoGuard) probe(ctx context.Context, filename string) (videoProbe, error) {
+	p := videoProbe{}
+
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, g.limits.FFprobePath, "-v", "error", "-show_entries",
+		"format=format_name,bit_rate,size:stream=codec_type,codec_name", "-of", "json", filename)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return p, errors.New("Couldn't inspect video: " + strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+
+	if err := jsoniter.Unmarshal(out.Bytes(), &p); err != nil {
+		return p, errors.New("Couldn't inspect video: " + err.Error())
+	}
+	return p, nil
+}
+
+// violation returns why a probed video doesn't pass the checks, or an empty
+// string.
+func (g *videoGuard) violation(p videoProbe) string {
+	if len(g.limits.Containers) > 0 {
+		accepted := false
+		for _, name := range strings.Split(p.Format.FormatName, ",") {
+			if contains(g.limits.Containers, name) {
+				accepted = true
+			}
+		}
+		if !accepted {
+			return "Unsupported video container " + p.Format.FormatName +
+				" (supported: " + strings.Join(g.limits.Containers, ", ") + ")"
+		}
+	}
+
+	if len(g.limits.Codecs) > 0 {
+		for _, stream := range p.Streams {
+			if stream.CodecType != "video" && stream.CodecType != "audio" {
+				continue
+			}
+			if !contains(g.limits.Codecs, stream.CodecName) {
+				return "Unsupported " + stream.CodecType + " codec " + stream.CodecName +
+					" (supported: " + strings.Join(g.limits.Codecs, ", ") + ")"
+			}
+		}
+	}
+
+	size, _ := strconv.ParseInt(p.Format.Size, 10, 64)
+	if g.limits.MaxSize > 0 && size > g.limits.MaxSize {
+		return "Video size of " + strconv.FormatInt(size, 10) + " bytes exceeds the limit of " +
+			strconv.FormatInt(g.limits.MaxSize, 10) + " bytes"
+	}
+
+	bitrate, _ := strconv.ParseInt(p.Format.BitRate, 10, 64)
+	if g.limits.MaxBitrate > 0 && bitrate > g.limits.MaxBitrate {
+		return "Video bitrate of " + strconv.FormatInt(bitrate, 10) + " bit/s exceeds the limit of " +
+			strconv.FormatInt(g.limits.MaxBitrate, 10) + " bit/s"
+	}
+	return ""
+}
+
+// check checks a video attachment. If it doesn't pass and transcoding is
+// enabled, the path of a transcoded file that passes is returned. It is up
+// to the caller to stage it. ffprobe and ffmpeg are killed when ctx is done.
+func (g *videoGuard) check(ctx context.Context, filename string) (string, error) {
+	p, err := g.probe(ctx, filename)
+	if err != nil {
+		return "", err
+	}
+	violation := g.violation(p)
+	if violation == "" {
+		return "", nil
+	}
+	if !g.limits.Transcode {
+		return "", errors.New(violation)
+	}
+
+	log.Info("Transcoding video attachment: ", violation)
+	transcoded, err := g.transcode(ctx, filename)
+	if err != nil {
+		return "", errors.New(violation + ", transcoding failed: " + err.Error())
+	}
+
+	if p, err = g.probe(ctx, transcoded); err == nil {
+		if v := g.violation(p); v != "" {
+			err = errors.New(v)
+		}
+	}
+	if err != nil {
+		os.Remove(transcoded)
+		return "", errors.New(violation + ", transcoded video still fails: " + err.Error())
+	}
+	return transcoded, nil
+}
+
+func (g *videoGuard) transcode(ctx context.Context, filename string) (string, error) {
+	f, err := ioutil.TempFile(g.dir, "signald-rest-api-*.mp4")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+
+	args := []string{"-v", "error", "-y", "-i", filename, "-c:v", "libx264", "-preset", "veryfast",
+		"-pix_fmt", "yuv420p", "-c:a", "aac", "-movflags", "+faststart"}
+	if g.limits.MaxBitrate > 0 {
+		// leave room for the audio stream and the container
+		videoRate := strconv.FormatInt(g.limits.MaxBitrate*8/10, 10)
+		args = append(args, "-b:v", videoRate, "-maxrate", videoRate, "-bufsize", videoRate,
+			"-b:a", "96k")
+	}
+	args = append(args, f.Name())
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, g.limits.FFmpegPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(f.Name())
+		return "", errors.New(strings.TrimSpace(stderr.String() + " " + err.Error()))
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookFormatNormalized posts the event as it is documented.
+	WebhookFormatNormalized = "normalized"
+	// WebhookFormatRaw posts the signald message the event was caused by.
+	WebhookFormatRaw = "raw"
+	// WebhookFormatTemplate posts the output of a Go template.
+	WebhookFormatTemplate = "template"
+)
+
+const webhookRetryBackoff = time.Second
+
+// event is posted to the configured webhooks.
+type event struct {
+	Type      string      `json:"type"`
+	Number    string      `json:"number"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// templateEvent is what the template of a webhook is executed with.
+type templateEvent struct {
+	Type      string
+	Number    string
+	Timestamp int64
+	Data      interface{}
+	// Source is the signald message the event was caused by.
+	Source interface{}
+}
+
+// Webhook is an HTTP endpoint events are posted to, in the format it expects.
+// If Numbers is set only the events of these numbers are posted.
+type Webhook struct {
+	URL          string   `json:"url"`
+	Format       string   `json:"format"`
+	Template     string   `json:"template"`
+	TemplateFile string   `json:"template_file"`
+	ContentType  string   `json:"content_type"`
+	Numbers      []string `json:"numbers"`
+	// Digest batches the messages of groups instead of posting them.
+	Digest *WebhookDigest `json:"digest"`
+
+	template *template.Template
+}
+
+// LoadWebhooks reads the list of webhooks from a JSON file.
+func LoadWebhooks(filename string) ([]Webhook, error) {
+	hooks := []Webhook{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return hooks, err
+	}
+
+	if err := jsoniter.Unmarshal(data, &hooks); err != nil {
+		return hooks, errors.New("Couldn't parse webhooks config: " + err.Error())
+	}
+
+	return hooks, nil
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. to quote a string
+	"json": func(v interface{}) (string, error) {
+		data, err := jsoniter.Marshal(v)
+		return string(data), err
+	},
+}
+
+func (h *Webhook) init() error {
+	if h.URL == "" {
+		return errors.New("Webhook without url")
+	}
+
+	switch h.Format {
+	case "":
+		h.Format = WebhookFormatNormalized
+	case WebhookFormatNormalized, WebhookFormatRaw:
+	case WebhookFormatTemplate:
+		text := h.Template
+		if h.TemplateFile != "" {
+			data, err := ioutil.ReadFile(h.TemplateFile)
+			if err != nil {
+				return errors.New("Couldn't read template of webhook " + h.URL + ": " + err.Error())
+			}
+			text = string(data)
+		}
+		if text == "" {
+			return errors.New("Webhook " + h.URL + " has no template")
+		}
+		t, err := template.New(h.URL).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return errors.New("Invalid template of webhook " + h.URL + ": " + err.Error())
+		}
+		h.template = t
+	default:
+		return errors.New("Invalid format " + h.Format + " of webhook " + h.URL + " (supported: normalized, raw, template)")
+	}
+
+	if h.ContentType == "" {
+		h.ContentType = "application/json"
+	}
+	if h.Digest != nil {
+		return h.Digest.init(h.URL)
+	}
+	return nil
+}
+
+func (h *Webhook) matches(number string) bool {
+	if len(h.Numbers) == 0 {
+		return true
+	}
+	for _, n := range h.Numbers {
+		if n == number {
+			return true
+		}
+	}
+	return false
+}
+
+// payload returns the body posted to the webhook for an event. Events that
+// weren't caused by a signald message are posted with their data in the raw
+// format.
+func (h *Webhook) payload(e event, source interface{}) ([]byte, error) {
+	switch h.Format {
+	case WebhookFormatRaw:
+		if source == nil {
+			return jsoniter.Marshal(e.Data)
+		}
+		return jsoniter.Marshal(source)
+	case WebhookFormatTemplate:
+		// the template sees the data as it is encoded in the normalized format
+		data := map[string]interface{}{}
+		if encoded, err := jsoniter.Marshal(e.Data); err == nil {
+			jsoniter.Unmarshal(encoded, &data)
+		}
+		body := bytes.Buffer{}
+		err := h.template.Execute(&body, templateEvent{
+			Type:      e.Type,
+			Number:    e.Number,
+			Timestamp: e.Timestamp,
+			Data:      data,
+			Source:    source,
+		})
+		return body.Bytes(), err
+	}
+	return jsoniter.Marshal(e)
+}
+
+// webhooks posts events to the global webhooks and the ones configured for
+// the number the event belongs to. Failed deliveries are retried with an
+// increasing backoff, events that still fail are dead-lettered.
+type webhooks struct {
+	hooks       []Webhook
+	accountURLs func(number string) []string
+	client      *http.Client
+	retries     int
+	deliveries  *webhookDeliveries
+}
+
+func newWebhooks(hooks []Webhook, client *http.Client, retries int, deliveries *webhookDeliveries,
+	accountURLs func(number string) []string) (*webhooks, error) {
+	if retries < 0 {
+		return nil, errors.New("Invalid number of webhook retries")
+	}
+	for i := range hooks {
+		if err := hooks[i].init(); err != nil {
+			return nil, err
+		}
+	}
+	return &webhooks{
+		hooks:       hooks,
+		accountURLs: accountURLs,
+		client:      client,
+		retries:     retries,
+		deliveries:  deliveries,
+	}, nil
+}
+
+func (w *webhooks) purgerName() string {
+	return w.deliveries.purgerName()
+}
+
+// purgeContact removes the dead-lettered events that mention contact.
+func (w *webhooks) purgeContact(number string, contact string) (int, error) {
+	return w.deliveries.purgeContact(number, contact)
+}
+
+// targets returns the webhooks of number. The webhooks of the account
+// settings get the normalized format.
+func (w *webhooks) targets(number string) []*Webhook {
+	targets := []*Webhook{}
+	for i := range w.hooks {
+		if w.hooks[i].matches(number) {
+			targets = append(targets, &w.hooks[i])
+		}
+	}
+	for _, url := range w.accountURLs(number) {
+		targets = append(targets, &Webhook{URL: url, Format: WebhookFormatNormalized, ContentType: "application/json"})
+	}
+	return targets
+}
+
+func (w *webhooks) enabled(number string) bool {
+	return len(w.targets(number)) > 0
+}
+
+// emit posts the event to all webhooks in the background. source is the
+// signald message the event was caused by, if any.
+func (w *webhooks) emit(eventType string, number string, data interface{}, source interface{}) {
+	targets := w.targets(number)
+	if len(targets) == 0 {
+		return
+	}
+
+	e := event{
+		Type:      eventType,
+		Number:    number,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Data:      data,
+	}
+	for _, hook := range targets {
+		w.deliver(hook, e, source)
+	}
+}
+
+// deliver posts the event to hook in the background.
+func (w *webhooks) deliver(hook *Webhook, e event, source interface{}) {
+	body, err := hook.payload(e, source)
+	if err != nil {
+		log.Error("Couldn't encode ", e.Type, " event for webhook ", hook.URL, ": ", err.Error())
+		return
+	}
+	go w.post(hook.URL, hook.ContentType, e.Type, e.Number, body)
+}
+
+// post delivers an event to a webhook, retrying it with an increasing backoff.
+// If all attempts fail the event is dead-lettered.
+func (w *webhooks) post(url string, contentType string, eventType string, number string, body []byte) {
+	backoff := webhookRetryBackoff
+	delivery := webhookDelivery{}
+	for attempt := 1; attempt <= w.retries+1; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if delivery = w.attempt(url, contentType, eventType, number, body, attempt); delivery.Error == "" {
+			return
+		}
+	}
+
+	id, err := newUploadID()
+	if err == nil {
+		err = w.deliveries.addDeadLetter(deadLetter{
+			ID:          id,
+			URL:         url,
+			EventType:   eventType,
+			Number:      number,
+			ContentType: contentType,
+			Payload:     string(body),
+			Error:       delivery.Error,
+			Attempts:    delivery.Attempt,
+			Time:        time.Now(),
+		})
+	}
+	if err != nil {
+		log.Error("Couldn't dead-letter ", eventType, " event for webhook ", url, ": ", err.Error())
+	}
+}
+
+// attempt delivers an event to a webhook once and records the delivery.
+func (w *webhooks) attempt(url string, contentType string, eventType string, number string, body []byte,
+	attempt int) webhookDelivery {
+	start := time.Now()
+	delivery := webhookDelivery{EventType: eventType, Number: number, Time: start, Attempt: attempt}
+
+	resp, err := w.client.Post(url, contentType, bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+		delivery.Status = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			err = errors.New("Status " + strconv.Itoa(resp.StatusCode))
+		}
+	}
+	delivery.Duration = int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		delivery.Error = err.Error()
+		log.Error("Couldn't deliver ", eventType, " event to webhook ", url, " (attempt ", attempt, "): ", err.Error())
+	}
+
+	w.deliveries.record(url, delivery)
+	return delivery
+}
@@ -0,0 +1,400 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abaskin/signald-rest-api/service"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// xmppClient is the client messages bridged from XMPP are sent as, their
+// quotas apply to it.
+const xmppClient = "xmpp"
+
+const (
+	xmppReconnectMin = time.Second
+	xmppReconnectMax = 5 * time.Minute
+	// xmppNickname is the nickname the bridge joins the MUCs with, if none
+	// is configured.
+	xmppNickname = "Signal"
+)
+
+// XMPPAccount bridges the contacts of a number to an XMPP user. The contacts
+// appear as JIDs of the component domain, e.g. +4354546464654@signal.example.com.
+type XMPPAccount struct {
+	Number string `json:"number"`
+	JID    string `json:"jid"`
+}
+
+// XMPPGroup bridges a Signal group of a number to a MUC.
+type XMPPGroup struct {
+	Number string `json:"number"`
+	Group  string `json:"group"`
+	MUC    string `json:"muc"`
+}
+
+// XMPPBridge bridges Signal contacts and groups to an XMPP server as external
+// component (XEP-0114).
+type XMPPBridge struct {
+	// Server is the host and port the XMPP server accepts components on.
+	Server string `json:"server"`
+	// Domain is the domain of the component and Secret its shared secret,
+	// as configured on the XMPP server.
+	Domain   string        `json:"domain"`
+	Secret   string        `json:"secret"`
+	Nickname string        `json:"nickname"`
+	Accounts []XMPPAccount `json:"accounts"`
+	Groups   []XMPPGroup   `json:"groups"`
+}
+
+// LoadXMPPBridge reads the XMPP bridge from a JSON file.
+func LoadXMPPBridge(filename string) (*XMPPBridge, error) {
+	bridge := &XMPPBridge{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jsoniter.Unmarshal(data, bridge); err != nil {
+		return nil, errors.New("Couldn't parse XMPP bridge config: " + err.Error())
+	}
+	if err := bridge.init(); err != nil {
+		return nil, err
+	}
+	return bridge, nil
+}
+
+func (b *XMPPBridge) init() error {
+	if _, _, err := net.SplitHostPort(b.Server); err != nil {
+		return errors.New("Invalid XMPP server " + b.Server + " (host:port)")
+	}
+	if b.Domain == "" || b.Secret == "" {
+		return errors.New("The XMPP bridge needs the domain and the secret of the component")
+	}
+	if b.Nickname == "" {
+		b.Nickname = xmppNickname
+	}
+	for i, account := range b.Accounts {
+		if account.Number == "" || !strings.Contains(account.JID, "@") {
+			return errors.New("Invalid XMPP account " + account.JID + ", it needs a number and a jid")
+		}
+		b.Accounts[i].JID = bareJID(account.JID)
+	}
+	for i, group := range b.Groups {
+		if group.Number == "" || !strings.HasPrefix(group.Group, groupPrefix) || !strings.Contains(group.MUC, "@") {
+			return errors.New("Invalid XMPP group " + group.MUC + ", it needs a number, a group id and a muc")
+		}
+		b.Groups[i].MUC = bareJID(group.MUC)
+	}
+	return nil
+}
+
+// bareJID strips the resource of a JID.
+func bareJID(jid string) string {
+	if i := strings.Index(jid, "/"); i >= 0 {
+		jid = jid[:i]
+	}
+	return strings.ToLower(jid)
+}
+
+// xmppStanza is the part of a message stanza the bridge looks at.
+type xmppStanza struct {
+	XMLName xml.Name `xml:"message"`
+	From    string   `xml:"from,attr"`
+	To      string   `xml:"to,attr"`
+	Type    string   `xml:"type,attr"`
+	Body    string   `xml:"body"`
+	// Delay is set for messages of the history of a MUC.
+	Delay *struct{} `xml:"urn:xmpp:delay delay"`
+}
+
+// xmppBridge keeps the component connected to the XMPP server, posts the
+// messages received in the bridged conversations and sends the messages of
+// the XMPP users to Signal.
+type xmppBridge struct {
+	config *XMPPBridge
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	sequence int64
+}
+
+func newXMPPBridge(config *XMPPBridge) *xmppBridge {
+	return &xmppBridge{config: config}
+}
+
+func (b *xmppBridge) componentJID() string {
+	return "signal@" + b.config.Domain + "/bridge"
+}
+
+// contactJID returns the JID a contact appears as.
+func (b *xmppBridge) contactJID(number string) string {
+	return number + "@" + b.config.Domain
+}
+
+func (b *xmppBridge) account(number string) (XMPPAccount, bool) {
+	for _, account := range b.config.Accounts {
+		if account.Number == number {
+			return account, true
+		}
+	}
+	return XMPPAccount{}, false
+}
+
+func (b *xmppBridge) accountOf(jid string) (XMPPAccount, bool) {
+	for _, account := range b.config.Accounts {
+		if account.JID == bareJID(jid) {
+			return account, true
+		}
+	}
+	return XMPPAccount{}, false
+}
+
+func (b *xmppBridge) muc(number string, group string) (string, bool) {
+	for _, g := range b.config.Groups {
+		if g.Number == number && g.Group == group {
+			return g.MUC, true
+		}
+	}
+	return "", false
+}
+
+func (b *xmppBridge) groupOf(muc string) (XMPPGroup, bool) {
+	for _, g := range b.config.Groups {
+		if g.MUC == bareJID(muc) {
+			return g, true
+		}
+	}
+	return XMPPGroup{}, false
+}
+
+// write sends raw XML on the current connection.
+func (b *xmppBridge) write(data string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.conn == nil {
+		return errors.New("Not connected to the XMPP server")
+	}
+	_, err := io.WriteString(b.conn, data)
+	return err
+}
+
+func xmlAttr(value string) string {
+	buf := strings.Builder{}
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+// message sends a message stanza.
+func (b *xmppBridge) message(from string, to string, messageType string, body string) error {
+	b.mutex.Lock()
+	b.sequence++
+	id := "signal" + strconv.FormatInt(b.sequence, 10)
+	b.mutex.Unlock()
+
+	return b.write("<message from='" + xmlAttr(from) + "' to='" + xmlAttr(to) + "' type='" + messageType +
+		"' id='" + id + "'><body>" + xmlAttr(body) + "</body></message>")
+}
+
+// connect opens the component stream and authenticates with the handshake.
+func (b *xmppBridge) connect() (net.Conn, *xml.Decoder, error) {
+	conn, err := net.DialTimeout("tcp", b.config.Server, 10*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	fail := func(err error) (net.Conn, *xml.Decoder, error) {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	_, err = io.WriteString(conn, "<?xml version='1.0'?><stream:stream xmlns='jabber:component:accept' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' to='"+xmlAttr(b.config.Domain)+"'>")
+	if err != nil {
+		return fail(err)
+	}
+
+	decoder := xml.NewDecoder(conn)
+	streamID := ""
+	for streamID == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			return fail(err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "stream" {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "id" {
+					streamID = attr.Value
+				}
+			}
+			if streamID == "" {
+				return fail(errors.New("The XMPP server sent no stream id"))
+			}
+		}
+	}
+
+	sum := sha1.Sum([]byte(streamID + b.config.Secret))
+	if _, err := io.WriteString(conn, "<handshake>"+hex.EncodeToString(sum[:])+"</handshake>"); err != nil {
+		return fail(err)
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return fail(err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local == "handshake" {
+				decoder.Skip()
+				return conn, decoder, nil
+			}
+			return fail(errors.New("The XMPP server rejected the component (" + start.Name.Local + ")"))
+		}
+	}
+}
+
+// run keeps the component connected and passes the messages it receives to
+// handle.
+func (b *xmppBridge) run(handle func(stanza xmppStanza)) {
+	backoff := xmppReconnectMin
+	for {
+		conn, decoder, err := b.connect()
+		if err != nil {
+			log.Error("Couldn't connect to the XMPP server ", b.config.Server, ": ", err.Error(), ", retrying in ", backoff)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > xmppReconnectMax {
+				backoff = xmppReconnectMax
+			}
+			continue
+		}
+		backoff = xmppReconnectMin
+		log.Info("Connected to the XMPP server ", b.config.Server, " as ", b.config.Domain)
+
+		b.mutex.Lock()
+		b.conn = conn
+		b.mutex.Unlock()
+		b.join()
+
+		err = b.read(decoder, handle)
+
+		b.mutex.Lock()
+		b.conn = nil
+		b.mutex.Unlock()
+		conn.Close()
+		log.Warn("Lost the connection to the XMPP server: ", err.Error())
+	}
+}
+
+// join enters the MUCs of the bridged groups, without their history.
+func (b *xmppBridge) join() {
+	for _, g := range b.config.Groups {
+		err := b.write("<presence from='" + xmlAttr(b.componentJID()) + "' to='" + xmlAttr(g.MUC+"/"+b.config.Nickname) +
+			"'><x xmlns='http://jabber.org/protocol/muc'><history maxstanzas='0'/></x></presence>")
+		if err != nil {
+			log.Error("Couldn't join MUC ", g.MUC, ": ", err.Error())
+		}
+	}
+}
+
+// read decodes the stanzas of the stream until it ends.
+func (b *xmppBridge) read(decoder *xml.Decoder, handle func(stanza xmppStanza)) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "message" {
+			decoder.Skip()
+			continue
+		}
+		stanza := xmppStanza{}
+		if err := decoder.DecodeElement(&stanza, &start); err != nil {
+			return err
+		}
+		handle(stanza)
+	}
+}
+
+// bridgeReceivedToXMPP posts a message received in a bridged conversation to
+// the user of the number or to the MUC of the group.
+func (a *Api) bridgeReceivedToXMPP(e busEvent) {
+	msg, ok := e.Data.(incomingMessage)
+	if !ok || msg.Reaction != nil || msg.Typing != nil {
+		return
+	}
+	env, err := msg.envelope()
+	if err != nil || env.DataMessage == nil {
+		return
+	}
+
+	text := env.DataMessage.Message
+	if n := len(env.DataMessage.Attachments); n > 0 {
+		text = strings.TrimSpace(text + " [" + strconv.Itoa(n) + " attachment(s)]")
+	}
+	if text == "" {
+		return
+	}
+
+	if env.DataMessage.GroupInfo != nil {
+		muc, ok := a.xmpp.muc(e.Number, conversationPeer(env))
+		if !ok {
+			return
+		}
+		err = a.xmpp.message(a.xmpp.componentJID(), muc, "groupchat", env.Source.Number+": "+text)
+	} else {
+		account, ok := a.xmpp.account(e.Number)
+		if !ok || env.Source.Number == "" {
+			return
+		}
+		err = a.xmpp.message(a.xmpp.contactJID(env.Source.Number), account.JID, "chat", text)
+	}
+	if err != nil {
+		log.Error("Couldn't bridge a message of ", e.Number, " to XMPP: ", err.Error())
+	}
+}
+
+// bridgeFromXMPP sends a message of an XMPP user to a contact, or a message
+// posted in a MUC to its group.
+func (a *Api) bridgeFromXMPP(stanza xmppStanza) {
+	if stanza.Body == "" || stanza.Delay != nil || stanza.Type == "error" {
+		return
+	}
+
+	out := service.Send{Message: stanza.Body, Client: xmppClient}
+	if stanza.Type == "groupchat" {
+		g, ok := a.xmpp.groupOf(stanza.From)
+		if !ok || strings.HasSuffix(stanza.From, "/"+a.xmpp.config.Nickname) {
+			return
+		}
+		if i := strings.Index(stanza.From, "/"); i >= 0 {
+			out.Message = stanza.From[i+1:] + ": " + out.Message
+		}
+		out.Number, out.Recipients, out.IsGroup = g.Number, []string{g.Group}, true
+	} else {
+		account, ok := a.xmpp.accountOf(stanza.From)
+		to := bareJID(stanza.To)
+		if !ok || !strings.HasSuffix(to, "@"+strings.ToLower(a.xmpp.config.Domain)) {
+			return
+		}
+		out.Number, out.Recipients = account.Number, []string{strings.TrimSuffix(to, "@"+strings.ToLower(a.xmpp.config.Domain))}
+	}
+
+	if _, err := a.service.Submit(out); err != nil {
+		log.Error("Couldn't bridge a message of ", stanza.From, " from XMPP to Signal: ", err.Error())
+	}
+}
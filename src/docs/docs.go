@@ -25,56 +25,59 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/v1/about": {
-            "get": {
-                "description": "Returns the supported API versions and the internal build nr",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "General"
-                ],
-                "summary": "Lists general information about the API",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/api.About"
-                        }
-                    }
-                }
-            }
-        },
-        "/v1/groups/{number}": {
-            "get": {
-                "description": "List all Signal Groups.",
+        "/2010-04-01/Accounts/{sid}/Messages.json": {
+            "post": {
+                "description": "Accepts the requests of the Messages API of Twilio, so that code sending SMS with Twilio can send Signal messages by changing the base URL. From is the number of the account, To the recipient (a number or a group id), Body the message and MediaUrl (can be repeated) the URLs of attachments, which are fetched by the API. The account SID and auth token aren't checked, the SID is the client of the quotas when it is sent as user of the basic authentication like the SDKs of Twilio do.",
                 "consumes": [
-                    "application/json"
+                    "application/x-www-form-urlencoded"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Twilio"
                 ],
-                "summary": "List all Signal Groups.",
+                "summary": "Send a message with the Twilio Messages API.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Registered Phone Number",
-                        "name": "number",
+                        "description": "Account SID",
+                        "name": "sid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "From",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient",
+                        "name": "To",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message",
+                        "name": "Body",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Media URL",
+                        "name": "MediaUrl",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/api.GroupEntry"
-                            }
+                            "$ref": "#/definitions/api.twilioMessage"
                         }
                     },
                     "400": {
@@ -84,9 +87,11 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create a new Signal Group with the specified members.",
+            }
+        },
+        "/_matrix/app/v1/transactions/{txnId}": {
+            "put": {
+                "description": "The application service API the homeserver pushes the events of the bridged rooms to. The messages posted in the rooms are sent to the mapped Signal conversations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -94,23 +99,23 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Matrix"
                 ],
-                "summary": "Create a new Signal Group.",
+                "summary": "Receive a transaction of the Matrix homeserver.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Registered Phone Number",
-                        "name": "number",
+                        "description": "Transaction ID",
+                        "name": "txnId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "{}",
                         "schema": {
-                            "$ref": "#/definitions/api.CreateGroup"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -118,48 +123,44 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 }
             }
         },
-        "/v1/groups/{number}/{groupid}": {
-            "delete": {
-                "description": "Delete a Signal Group.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/_matrix/app/v1/users/{id}": {
+            "get": {
+                "description": "The bridge maps rooms statically and provides no users or aliases.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Groups"
+                    "Matrix"
                 ],
-                "summary": "Delete a Signal Group.",
+                "summary": "Query a user or room alias of the Matrix bridge.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Registered Phone Number",
-                        "name": "number",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Group Id",
-                        "name": "groupid",
+                        "description": "User ID or Room Alias",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Error"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
@@ -167,29 +168,36 @@ var doc = `{
                 }
             }
         },
-        "/v1/qrcodelink": {
+        "/admin/accounts/{number}/settings": {
             "get": {
-                "description": "test",
+                "description": "Get the settings that apply to a registered number.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
+                ],
+                "summary": "Get the settings of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Link device and generate QR code.",
                 "responses": {
                     "200": {
-                        "description": "Image",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.AccountSettings"
                         }
                     }
                 }
-            }
-        },
-        "/v1/receive/{number}": {
-            "get": {
-                "description": "Receives Signal Messages from the Signal Network.",
+            },
+            "put": {
+                "description": "Replace the settings of a registered number. The settings take precedence over the ones in the config file.",
                 "consumes": [
                     "application/json"
                 ],
@@ -197,9 +205,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Messages"
+                    "Admin"
                 ],
-                "summary": "Receive Signal Messages.",
+                "summary": "Change the settings of a number.",
                 "parameters": [
                     {
                         "type": "string",
@@ -207,16 +215,22 @@ var doc = `{
                         "name": "number",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Settings",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.AccountSettings"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.AccountSettings"
                         }
                     },
                     "400": {
@@ -224,23 +238,24 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 }
-            }
-        },
-        "/v1/register/{number}": {
-            "post": {
-                "description": "Register a phone number with the signal network.",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "Drop the settings changed via the API, so that the ones in the config file apply again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
                 ],
-                "summary": "Register a phone number.",
+                "summary": "Reset the settings of a number.",
                 "parameters": [
                     {
                         "type": "string",
@@ -251,7 +266,12 @@ var doc = `{
                     }
                 ],
                 "responses": {
-                    "201": {},
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -261,49 +281,62 @@ var doc = `{
                 }
             }
         },
-        "/v1/register/{number}/verify/{token}": {
-            "post": {
-                "description": "Verify a registered phone number with the signal network.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/audit": {
+            "get": {
+                "description": "List the changes made via /admin and the other configuration endpoints (chat commands), the newest first, with the actor and the values before and after the change. The listing is paginated, the total number of entries is returned in the X-Total-Count header.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "Admin"
                 ],
-                "summary": "Verify a registered phone number.",
+                "summary": "List the audit log.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Registered Phone Number",
-                        "name": "number",
-                        "in": "path",
-                        "required": true
+                        "description": "Only list the changes of this actor",
+                        "name": "actor",
+                        "in": "query"
                     },
                     {
-                        "description": "Additional Settings",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.VerifyNumberSettings"
-                        }
+                        "type": "string",
+                        "description": "Only list changes of this action (e.g. account_settings.update)",
+                        "name": "action",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Verification Code",
-                        "name": "token",
-                        "in": "path",
-                        "required": true
+                        "description": "Only list changes of this target (e.g. a number)",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list changes after this time, timestamp in milliseconds or RFC 3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries to return",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.auditEntry"
+                            }
                         }
                     },
                     "400": {
@@ -315,40 +348,57 @@ var doc = `{
                 }
             }
         },
-        "/v1/send": {
-            "post": {
-                "description": "Send a signal message",
-                "consumes": [
+        "/admin/backends": {
+            "get": {
+                "description": "List the signald backends with the numbers routed to them, whether they are draining and the number of requests in flight.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List the signald backends.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.backendStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/backends/{name}/drain": {
+            "post": {
+                "description": "Reject new requests for the numbers of a backend with 503, so that it can be replaced once the requests in flight finished.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Messages"
+                    "Admin"
                 ],
-                "summary": "Send a signal message.",
-                "deprecated": true,
+                "summary": "Drain a signald backend.",
                 "parameters": [
                     {
-                        "description": "Input Data",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.SendMessageV1"
-                        }
+                        "type": "string",
+                        "description": "Backend Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.backendStatus"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
@@ -356,92 +406,5591 @@ var doc = `{
                 }
             }
         },
-        "/v2/send": {
+        "/admin/backends/{name}/resume": {
             "post": {
-                "description": "Send a signal message",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Accept requests for the numbers of a drained backend again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Messages"
+                    "Admin"
                 ],
-                "summary": "Send a signal message.",
+                "summary": "Resume a signald backend.",
                 "parameters": [
                     {
-                        "description": "Input Data",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.SendMessageV2"
-                        }
+                        "type": "string",
+                        "description": "Backend Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.backendStatus"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.About": {
-            "type": "object",
-            "properties": {
-                "build": {
-                    "type": "integer"
-                },
-                "versions": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                }
-            }
         },
-        "api.CreateGroup": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "string"
+        "/admin/export": {
+            "get": {
+                "description": "Export the chat commands and the account settings changed via the API as JSON.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Export the configuration.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.configExport"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/faults": {
+            "get": {
+                "description": "Show the faults that are injected into requests and received messages. Fault injection needs to be enabled with -fault-injection.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Show the injected faults.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.faultSettings"
+                        }
+                    },
+                    "204": {
+                        "description": "No faults are injected",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Delay requests, fail a fraction of them with the given status and drop a fraction of the received messages, so that the retry and backoff logic of clients can be tested. The admin API and the health checks aren't affected unless they are listed in paths. Fault injection needs to be enabled with -fault-injection.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Inject faults.",
+                "parameters": [
+                    {
+                        "description": "Faults",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.faultSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.faultSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop injecting faults into requests and received messages.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Stop injecting faults.",
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/import": {
+            "post": {
+                "description": "Import an export of another instance. By default the imported entities are merged into the existing ones (entities with the same name are replaced), with mode=replace all existing entities are replaced.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Import the configuration.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "merge (default) or replace",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Export",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.configExport"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/maintenance": {
+            "get": {
+                "description": "Show whether the maintenance mode is enabled and the messages held until it ends, as well as the ones that failed when they were sent after it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Show the maintenance mode.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.maintenanceStatus"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "While the maintenance mode is enabled messages are accepted with 202 and the id they are held with, but they are only sent once the maintenance mode is disabled again. They are then sent in the order they were accepted. The messages are only held in memory by the replica that accepted them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Enable or disable the maintenance mode.",
+                "parameters": [
+                    {
+                        "description": "Maintenance Mode",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.maintenanceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.maintenanceStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/maintenance/held/{id}": {
+            "delete": {
+                "description": "Remove a message held during maintenance, or one that failed when it was sent after it, without sending it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Discard a held message.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Held Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/quotas": {
+            "get": {
+                "description": "List the messages and attachment bytes the clients sent in the current hour and day (the windows start at the full hour and at midnight UTC) with their quotas.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List the quota usage.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.quotaUsage"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/quotas/{client}": {
+            "get": {
+                "description": "Show the messages and attachment bytes a client sent in the current hour and day with its quotas.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Show the quota usage of a client.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client",
+                        "name": "client",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.quotaUsage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Reset the messages and attachment bytes a client sent in the current hour and day, e.g. after a runaway job was stopped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Reset the quota usage of a client.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client",
+                        "name": "client",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "description": "List the webhooks events were delivered to with the number of delivered events, failed delivery attempts and dead-lettered events, and the last error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List the webhooks.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dead-letters": {
+            "get": {
+                "description": "List the events that couldn't be delivered to a webhook after all retries, the oldest first. The listing is paginated, the total number of events is returned in the X-Total-Count header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List the dead-lettered webhook events.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of events to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of events to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.deadLetter"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dead-letters/{id}": {
+            "delete": {
+                "description": "Remove an event from the dead-letter queue without delivering it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Discard a dead-lettered webhook event.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dead-letters/{id}/replay": {
+            "post": {
+                "description": "Deliver a dead-lettered event to its webhook again. It is removed from the dead-letter queue if the webhook accepts it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Replay a dead-lettered webhook event.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/deliveries": {
+            "get": {
+                "description": "List the latest 100 delivery attempts of a webhook, the most recent first. The listing is paginated, the total number of attempts is returned in the X-Total-Count header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List the deliveries of a webhook.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook URL",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of attempts to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of attempts to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Metrics in the Prometheus text format, or in the OpenMetrics text format if the Accept header asks for it: the sent and received messages, the errors of signald and the Signal servers by class (e.g. rate_limited when Signal throttles) and the duration of send requests. The metrics are labeled by number and recipient if the labels are enabled with -metrics-labels.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "General"
+                ],
+                "summary": "Get the metrics of the API.",
+                "responses": {
+                    "200": {
+                        "description": "Metrics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/about": {
+            "get": {
+                "description": "Returns the supported API versions, the build (git commit and date), the mode (standalone or replicated with Redis), the storage, the enabled subsystems and the version of every signald backend with the capabilities it supports. Endpoints that need a capability the backend of a number doesn't support respond with 501.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "General"
+                ],
+                "summary": "Lists general information about the API",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.about"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/accounts": {
+            "get": {
+                "description": "List the numbers signald has accounts for. Supports If-None-Match with the returned ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "List the accounts.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.accountEntry"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/accounts/{number}/settings": {
+            "get": {
+                "description": "Get the phone number privacy settings last set via the API: who sees the phone number (phone_number_sharing) and who can find the account by it (discoverable_by_number). Numbers whose settings weren't changed via the API show the defaults of Signal.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "Get the privacy settings of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacySettings"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change who sees the phone number (phone_number_sharing: everybody or nobody) and who can find the account by it (discoverable_by_number: everybody or nobody). Settings that aren't given are left unchanged. An account can only be hidden from discovery if its number isn't shared. Backends that don't support the settings respond with 501.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "Change the privacy settings of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Privacy Settings",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.privacyUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacySettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/accounts/{number}/stats": {
+            "get": {
+                "description": "Show the number of sent, received and failed messages and the average send latency of a registered number over time windows (by default the ones given with -stats-windows), the time of the last activity and the number of queued messages.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "Show statistics of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated list of windows, e.g. 5m,1h",
+                        "name": "windows",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.statsReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/attachments/uploads": {
+            "post": {
+                "description": "Creates a new upload session. The size is optional, if provided the upload can only be finalized once all bytes are received. Uploads can't exceed the maximum upload size.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Start a resumable attachment upload.",
+                "parameters": [
+                    {
+                        "description": "Upload Settings",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.createUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.uploadStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/attachments/uploads/{id}": {
+            "get": {
+                "description": "Returns the number of bytes received so far, which is the offset the next chunk needs to start at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Get the state of a resumable attachment upload.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.uploadStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Appends the request body to the upload. The Upload-Offset header needs to contain the offset of the chunk, i.e. the number of bytes already received. A chunk beyond the size of the upload, or the maximum upload size if it has none, is rejected.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Upload a chunk of an attachment.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.uploadStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.uploadStatus"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Aborts the upload and removes the data received so far.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Abort a resumable attachment upload.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/attachments/uploads/{id}/finalize": {
+            "post": {
+                "description": "Completes the upload and returns a token that can be used in the attachment_tokens field when sending a message.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Finalize a resumable attachment upload.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.uploadToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/attachments/{number}/{id}/thumbnail": {
+            "get": {
+                "description": "Get a JPEG thumbnail of a received image or video attachment. Thumbnails are generated on the first request and cached.",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Get the thumbnail of a received attachment.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Length of the longer edge in pixels (default 256, maximum 1024)",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/challenges": {
+            "get": {
+                "description": "List the challenges Signal requires numbers to solve before it delivers further messages (proof required), with the messages held until they are solved. A challenge_required event is emitted for every new challenge.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List the challenges.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the challenges of this number",
+                        "name": "number",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.rateLimitChallenge"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/challenges/{id}": {
+            "get": {
+                "description": "Show a challenge Signal requires a number to solve, with the messages held until it is solved.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Show a challenge.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Challenge ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.rateLimitChallenge"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Submit the captcha solved at https://signalcaptchas.org/challenge/generate.html for a challenge, or an empty captcha if signald received the push challenge. The messages held for the challenge are then sent in the background, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Submit a solved challenge.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Challenge ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Solved Challenge",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.challengeSolution"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a challenge and drop the messages held for it without sending them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Discard a challenge.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Challenge ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/commands": {
+            "get": {
+                "description": "List all registered chat commands.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "List all chat commands.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.command"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers (or replaces) a chat command. Incoming messages starting with the command prefix and the command name are posted to the url, the reply is sent back to the chat.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "Register a chat command.",
+                "parameters": [
+                    {
+                        "description": "Command",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.command"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.command"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/commands/{name}": {
+            "delete": {
+                "description": "Delete a registered chat command.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "Delete a chat command.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Command Name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/contacts/{number}": {
+            "get": {
+                "description": "List the contacts of a registered number. The listing can be filtered by name and paginated, the total number of matching contacts is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Contacts"
+                ],
+                "summary": "List the contacts of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list contacts whose name contains this text",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of contacts to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of contacts to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.contactEntry"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversations/{number}": {
+            "get": {
+                "description": "List the contacts and groups a registered number exchanged messages with, the most recently active first, with a preview of the last message and the number of unread messages. The listing is paginated, the total number of conversations is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List the conversations of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of conversations to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of conversations to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.conversationEntry"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversations/{number}/unread": {
+            "get": {
+                "description": "Get the number of unread messages in total and of every conversation with unread messages.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Get the number of unread messages of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.unreadCounts"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/conversations/{number}/{peer}/read": {
+            "post": {
+                "description": "Mark the messages of a conversation up to the given timestamp (or all of them) as read and send read receipts to their senders.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Mark a conversation as read.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number or Group ID of the Conversation",
+                        "name": "peer",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Newest Read Message",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.markReadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.markReadResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/data/{number}/{contact}": {
+            "delete": {
+                "description": "Purges all data the API keeps about the given contact of the registered number and returns a deletion report. The request needs to be confirmed with confirm=true or a token from a preflight request (preflight=true).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Data"
+                ],
+                "summary": "Delete all data stored about a contact.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Contact Phone Number",
+                        "name": "contact",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return a confirmation token instead of deleting",
+                        "name": "preflight",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Confirmation token of a preflight request",
+                        "name": "confirmation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Confirm the deletion without a token",
+                        "name": "confirm",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.deletionReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.deletionReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/devices/{number}": {
+            "post": {
+                "description": "Link another device (e.g. a phone) to a number the API is registered with as primary device. The URI is the content of the QR code the new device shows.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Link a device to a registered number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Device URI",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.addDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/devices/{number}/{device_id}/name": {
+            "put": {
+                "description": "Change the name the device the API runs as was given when it was linked. signald can only rename the device it runs as, so device_id needs to be its device id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Change the name of a linked device.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New Name",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deviceNameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups/{number}": {
+            "get": {
+                "description": "List all Signal Groups with their members (also with their UUIDs and roles in member_details), the members who were invited but haven't joined yet and the ones who requested to join. The listing can be filtered by name and paginated, the total number of matching groups is returned in the X-Total-Count header. Supports If-None-Match with the returned ETag.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "List all Signal Groups.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list groups whose name contains this text",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of groups to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of groups to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.groupEntry"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new Signal Group with the specified members.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Create a new Signal Group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.createGroupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups/{number}/sync": {
+            "post": {
+                "description": "Create groups and update names and members of groups to match the given list. Groups are matched by id if given and by name otherwise, groups that aren't listed are left alone. Returns the changes made (or with dry_run the changes that would be made).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Sync groups with a desired state.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only report the changes",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Desired Groups",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.desiredGroup"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.groupSyncReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups/{number}/{groupid}": {
+            "delete": {
+                "description": "Delete a Signal Group. If the account is the last admin of the group, new_admin (the number or UUID of a member) is made admin before the group is left, so that it isn't left without admin. Without new_admin the last admin can only leave with force=true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Delete a Signal Group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group Id",
+                        "name": "groupid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member to make admin before leaving",
+                        "name": "new_admin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Leave the group without admin",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it) of a group. Settings that aren't given are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Groups"
+                ],
+                "summary": "Update a Signal Group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group Id",
+                        "name": "groupid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Group Settings",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.GroupUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/health/ready": {
+            "get": {
+                "description": "Reports ready once all numbers given with -subscribe-number are subscribed to incoming messages and, if signald is run by the API, signald is ready. The connection state of every number is listed, as well as the outcome of the startup check of the signald sockets, which failed sockets are checked again for.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "General"
+                ],
+                "summary": "Check whether the API is ready.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/link": {
+            "get": {
+                "description": "Start linking signald as device to an account and return the QR code the primary device scans. The QR code is a PNG image, an SVG image or text (ascii, or ansi with terminal colors), selected with format or the Accept header (image/png, image/svg+xml, text/plain). Scanners that struggle with long URIs may do better with a larger size or a higher error correction level.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Link device and generate QR code.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the device (default -default-device-name)",
+                        "name": "device_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "png (default), svg, ascii or ansi",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error correction level: L, M (default), Q or H",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size of PNG and SVG images in pixels (64 to 2048, default 256)",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/link/sessions": {
+            "get": {
+                "description": "List the devices that are being linked and the outcome of the recently finished link attempts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List the link sessions.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.linkSession"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/message-requests/{number}": {
+            "get": {
+                "description": "List the conversations with senders that aren't contacts, by default the pending ones. Messages of pending senders are received with message_request set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List the message requests of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending (default), accepted, blocked or all",
+                        "name": "state",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.messageRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/message-requests/{number}/{sender}/accept": {
+            "post": {
+                "description": "Accept the conversation with a sender that isn't a contact, its messages are no longer flagged as message request. Accepting a blocked sender unblocks it. Sending a message to a sender accepts it as well.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Accept a message request.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number of the Sender",
+                        "name": "sender",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.messageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/message-requests/{number}/{sender}/block": {
+            "post": {
+                "description": "Block a sender, its messages are dropped when they are received.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Block a sender.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number of the Sender",
+                        "name": "sender",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.messageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/message-requests/{number}/{sender}/report-spam": {
+            "post": {
+                "description": "Block a sender and report it as spam. signald can't pass the report on to Signal, so it is logged and emitted as spam_reported event to the webhooks.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Block a sender and report it as spam.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number of the Sender",
+                        "name": "sender",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.messageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/messages/{number}/{message_id}": {
+            "delete": {
+                "description": "Delete a message from the history of the API only, e.g. to moderate the archive. The recipients keep the message. The message is hidden right away and purged later.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Delete a message from the message store.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/messages/{number}/{peer}": {
+            "get": {
+                "description": "List the messages of a conversation in the message store, oldest first. Deleted messages are only listed with deleted=include until they are purged. The listing is paginated, the total number of messages is returned in the X-Total-Count header. Every message has a cursor, to continue after it pass it as after instead of an offset. The cursor of the last message is returned in the X-Next-Cursor header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List the messages of a conversation.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number or Group ID of the Conversation",
+                        "name": "peer",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "include lists the deleted messages as well",
+                        "name": "deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the message to continue after",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of messages to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of messages to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.historyMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/polls": {
+            "post": {
+                "description": "Send a question with numbered options to a group. Replies of the members with the number of an option (also as keycap emoji) or its text are counted as votes, the last vote of every member counts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Polls"
+                ],
+                "summary": "Create a poll.",
+                "parameters": [
+                    {
+                        "description": "Poll",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createPollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.pollResults"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/polls/{id}": {
+            "get": {
+                "description": "Get the number of votes of every option.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Polls"
+                ],
+                "summary": "Get the results of a poll.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.pollResults"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a poll and its votes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Polls"
+                ],
+                "summary": "Delete a poll.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/profiles/{number}/{recipient}/avatar": {
+            "get": {
+                "description": "Get the profile avatar of a contact as image. signald's avatar directory needs to be accessible by the REST API. Supports If-None-Match with the returned ETag.",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Contacts"
+                ],
+                "summary": "Get the profile avatar of a contact.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number or UUID of the Contact",
+                        "name": "recipient",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Avatar",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/queue/{number}": {
+            "get": {
+                "description": "List the messages of a number that are waiting for the identity of their recipient to be trusted, or that failed when they were sent again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "List the queued messages.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.queuedMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/queue/{number}/{id}": {
+            "delete": {
+                "description": "Remove a message from the queue without sending it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Remove a queued message.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Queued Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/receipts/{number}/bulk": {
+            "post": {
+                "description": "Send read receipts for many messages, given as pairs of the sender and the timestamp of the message. The receipts for the messages of a sender are sent with a single signald request. Senders whose receipts couldn't be sent are listed with the error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Mark many messages as read.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Receipts",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.bulkReceiptsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.bulkReceiptsResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/receive/{number}": {
+            "get": {
+                "description": "Receives Signal Messages from the Signal Network.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Receive Signal Messages.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "inline embeds attachments up to the inline size limit as base64",
+                        "name": "attachments",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seconds to wait for messages (default -receive-timeout)",
+                        "name": "timeout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the last processed message of a subscribed number, the messages up to it are acknowledged",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of messages to return with a cursor",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.receiveResults"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register/{number}": {
+            "post": {
+                "description": "Register a phone number with the signal network. If Signal requires a captcha it is passed to the captcha solver (-captcha-solver-url), or it can be solved manually and given as captcha. Numbers that are already registered and verified are rejected with 409 and their registration state, unless force is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Register a phone number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Register the number again if it is already active",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {},
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.registrationStatus"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register/{number}/captcha": {
+            "get": {
+                "description": "Show whether Signal requires a captcha to register the number, which couldn't be solved by the captcha solver. The captcha is solved at https://signalcaptchas.org/registration/generate.html, the number is then registered again with the captcha.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Show the captcha challenge of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.captchaChallenge"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register/{number}/resend": {
+            "post": {
+                "description": "Request another verification code for a number that waits for its code, by SMS or by voice call (use_voice). Another code can be requested a minute after the last one, and at most 5 codes until the number is verified. The state of the verification is shown by /v1/register/{number}/status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Request another verification code.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Delivery",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.resendRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.registrationStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register/{number}/status": {
+            "get": {
+                "description": "Show whether a number is unregistered, needs a captcha, waits for its verification code or is active, with a hint what to do next. For a number waiting for its code the codes requested, how the last one was delivered (SMS or voice call), the number of codes that can still be requested and when the next one can be requested are shown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Show the registration state of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.registrationStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/register/{number}/verify/{token}": {
+            "post": {
+                "description": "Verify a registered phone number with the signal network.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Verify a registered phone number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Additional Settings",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.verifyNumberSettings"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Verification Code",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/search/messages/{number}": {
+            "get": {
+                "description": "Full-text search in the messages of the message store, the newest first. All words of the query need to be contained in a message. The results can be filtered by sender, group and time. The listing is paginated, the total number of results is returned in the X-Total-Count header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Search the messages of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words to search for",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number of the Sender",
+                        "name": "sender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Oldest message, timestamp in milliseconds or RFC 3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Newest message, timestamp in milliseconds or RFC 3339 time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.searchHit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/selftest/{number}": {
+            "post": {
+                "description": "Sends a note to self from the number through signald and reports the duration and outcome of every step, as smoke test after a deploy. The message takes the same path as other messages (maintenance, rate limits, quotas of the client selftest, send hooks), the test fails if it is queued instead of sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "General"
+                ],
+                "summary": "Run a self-test of a number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.selfTestReport"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.selfTestReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/send": {
+            "post": {
+                "description": "Send a signal message",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Send a signal message.",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Input Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.sendMessageV1"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.sentMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sessions/{number}/{recipient}/reset": {
+            "post": {
+                "description": "Send an end session message to the recipient and drop the session state, e.g. when messages of the recipient can't be decrypted anymore. A new session is started with the next message.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Reset the session with a recipient.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone Number or UUID of the Recipient",
+                        "name": "recipient",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/simple/groups": {
+            "get": {
+                "description": "List the ids and names of the groups of a number, e.g. to pick the group to send to in a low-code tool.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Simple"
+                ],
+                "summary": "List the groups as a flat list.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.simpleGroup"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/simple/receive": {
+            "get": {
+                "description": "Receive the messages of a number as a flat list of the sender, the group, the text and the number of attachments. Other messages (receipts, typing, group updates) are left out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Simple"
+                ],
+                "summary": "Receive messages as a flat list.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Receive timeout in seconds",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.simpleMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/simple/send": {
+            "get": {
+                "description": "Send a message without JSON body for low-code tools that can only make GET requests. to can be repeated or contain comma separated numbers, or a single group id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Simple"
+                ],
+                "summary": "Send a message with query parameters.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipients (numbers or a group id)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message",
+                        "name": "message",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.sentMessageResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.queuedMessages"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Send a message without JSON body for low-code tools: the parameters are given as form fields (URL encoded or multipart) or in the query. to can be repeated or contain comma separated numbers, or a single group id. Attachments are uploaded as files in multipart form fields named attachment.",
+                "consumes": [
+                    "application/x-www-form-urlencoded",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Simple"
+                ],
+                "summary": "Send a message with form parameters.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipients (numbers or a group id)",
+                        "name": "to",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message",
+                        "name": "message",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.sentMessageResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.queuedMessages"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/send": {
+            "post": {
+                "description": "Send a signal message. Groups can be given as group id (\"group.\u003cbase64\u003e\"), as internal id (the base64 encoded id signald uses) or as group invite link. Shared contacts are sent as vCard attachments. Locations are rejected with 501, Signal has no location messages. The recipient \"self\" (or note_to_self without recipients) sends a note to self. The timestamp of the message can be given (e.g. to replay history), the one used is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Send a signal message.",
+                "parameters": [
+                    {
+                        "description": "Input Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.sendMessageV2"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.sentMessageResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.queuedMessages"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v3/accounts/{number}/groups": {
+            "get": {
+                "description": "List the groups of an account, optionally filtered by name. The listing is paginated, the total number of groups is returned in the X-Total-Count header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "List the groups of an account.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list groups whose name contains this text",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of groups to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of groups to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.v3Group"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v3/accounts/{number}/groups/{group_id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Show a group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group ID (group.\u003cid\u003e)",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Leave a group. If the account is the last admin of the group, new_admin (the number or UUID of a member) is made admin before, so that the group isn't left without admin. Without new_admin the last admin can only leave with force=true.",
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Leave a group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group ID (group.\u003cid\u003e)",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member to make admin before leaving",
+                        "name": "new_admin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Leave the group without admin",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change the name or the disappearing message timer (expiration_timer, in seconds, 0 disables it) of a group. Settings that aren't given are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Update a group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group ID (group.\u003cid\u003e)",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Group Settings",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.GroupUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v3/accounts/{number}/messages": {
+            "post": {
+                "description": "Send a message to recipients or to a group. Responds with 201 and the timestamp of the message, or with 202 and the ids of the messages queued until the identity of a recipient is trusted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Send a message.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registered Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.v3SendRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.sentMessageResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.queuedMessages"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v3/accounts/{number}/registration": {
+            "get": {
+                "description": "Show whether a number is unregistered, needs a captcha, waits for its verification code or is active, with a hint what to do next.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Show the registration state of a phone number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.registrationStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Request a verification code for a phone number, which is sent by SMS or voice call. The number is then verified with the code. Numbers that are already active are rejected with 409 unless force is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Register a phone number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Registration",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Registration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/v3/accounts/{number}/registration/verification": {
+            "post": {
+                "description": "Verify a registered phone number with the code it received, with the registration lock pin if it has one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v3"
+                ],
+                "summary": "Verify a phone number.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone Number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Verification",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Verification"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.v3Error"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.AccountSettings": {
+            "type": "object",
+            "properties": {
+                "auto_read_receipts": {
+                    "description": "AutoReadReceipts marks received messages as read.",
+                    "type": "boolean"
+                },
+                "drain_interval": {
+                    "description": "DrainInterval is how often the messages are received in the background\nif the number only sends.",
+                    "type": "string"
+                },
+                "retention": {
+                    "description": "Retention is how long queued messages are kept.",
+                    "type": "string"
+                },
+                "send_rate_limit": {
+                    "description": "SendRateLimit is the maximum number of messages sent per minute, 0\nmeans unlimited.",
+                    "type": "integer"
+                },
+                "sync_interval": {
+                    "description": "SyncInterval is how often contacts, groups and configuration are\nsynced from the primary device.",
+                    "type": "string"
+                },
+                "typing_events": {
+                    "description": "TypingEvents keeps the typing messages in the received messages, they\nare dropped otherwise.",
+                    "type": "boolean"
+                },
+                "webhook_urls": {
+                    "description": "WebhookURLs receive the events of the number in addition to the\nglobal webhooks.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.Error": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.QuotaLimits": {
+            "type": "object",
+            "properties": {
+                "attachment_bytes_per_day": {
+                    "type": "integer"
+                },
+                "attachment_bytes_per_hour": {
+                    "description": "AttachmentBytesPerHour and AttachmentBytesPerDay limit the size of the\nattachments sent, the attachments count once per recipient.",
+                    "type": "integer"
+                },
+                "messages_per_day": {
+                    "type": "integer"
+                },
+                "messages_per_hour": {
+                    "description": "MessagesPerHour and MessagesPerDay limit the messages sent, every\nrecipient counts.",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.about": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.aboutBackend"
+                },
+                "backends": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.backendCapabilities"
+                    }
+                },
+                "build": {
+                    "type": "integer"
+                },
+                "build_date": {
+                    "type": "string"
+                },
+                "git_commit": {
+                    "type": "string"
+                },
+                "go_version": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "storage": {
+                    "type": "string"
+                },
+                "subsystems": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.aboutBackend": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of configured signald backends.",
+                    "type": "integer"
+                },
+                "supervised": {
+                    "description": "Supervised is set if the API runs signald itself.",
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.accountEntry": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "integer"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "registered": {
+                    "type": "boolean"
+                },
+                "subscribed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.addDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.auditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "client_ip": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new": {
+                    "type": "object"
+                },
+                "previous": {
+                    "type": "object"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.backendCapabilities": {
+            "type": "object",
+            "properties": {
+                "capabilities": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "socket_path": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.backendStatus": {
+            "type": "object",
+            "properties": {
+                "draining": {
+                    "type": "boolean"
+                },
+                "in_flight": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "socket_path": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.bulkReceipt": {
+            "type": "object",
+            "properties": {
+                "recipient": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.bulkReceiptsRequest": {
+            "type": "object",
+            "properties": {
+                "receipts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.bulkReceipt"
+                    }
+                }
+            }
+        },
+        "api.bulkReceiptsResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.failedReceipts"
+                    }
+                },
+                "receipts": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.captchaChallenge": {
+            "type": "object",
+            "properties": {
+                "number": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "solver_error": {
+                    "description": "SolverError is the error of the captcha solver, if it couldn't solve\nthe captcha.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.challengeSolution": {
+            "type": "object",
+            "properties": {
+                "captcha": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.command": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.configExport": {
+            "type": "object",
+            "properties": {
+                "account_settings": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/api.AccountSettings"
+                    }
+                },
+                "commands": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.command"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.contactEntry": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "type": "string"
+                },
+                "message_expiration_time": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.conversationEntry": {
+            "type": "object",
+            "properties": {
+                "is_group": {
+                    "type": "boolean"
+                },
+                "last_message": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.messagePreview"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "peer": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "unread": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.createGroupResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createPollRequest": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "description": "Duration after which the poll stops counting votes, e.g. \"24h\". Polls\nwithout duration are open until they are deleted.",
+                    "type": "string"
+                },
+                "group": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createUploadRequest": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.deadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.deletionReport": {
+            "type": "object",
+            "properties": {
+                "contact": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "subsystems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.purgeResult"
+                    }
+                }
+            }
+        },
+        "api.desiredGroup": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.deviceNameRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.failedReceipts": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.faultSettings": {
+            "type": "object",
+            "properties": {
+                "delay": {
+                    "description": "Delay is added to every affected request, e.g. 2s.",
+                    "type": "string"
+                },
+                "delay_jitter": {
+                    "description": "DelayJitter adds a random delay of up to this duration.",
+                    "type": "string"
+                },
+                "drop_receive_rate": {
+                    "description": "DropReceiveRate is the fraction of received messages (0 to 1) that are\ndropped.",
+                    "type": "number"
+                },
+                "error_rate": {
+                    "description": "ErrorRate is the fraction of affected requests (0 to 1) that fail with\nErrorStatus.",
+                    "type": "number"
+                },
+                "error_status": {
+                    "description": "ErrorStatus is the status failed requests respond with, 503 if it isn't\nset.",
+                    "type": "integer"
+                },
+                "paths": {
+                    "description": "Paths are the path prefixes of the affected requests, all requests\nexcept the admin API and the health checks if it is empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.groupChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.groupEntry": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "blocked": {
+                    "type": "boolean"
+                },
+                "expiration_timer": {
+                    "description": "ExpirationTimer is the time in seconds after which messages disappear,\n0 if it is disabled. Only v2 groups report it.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "internal_id": {
+                    "type": "string"
+                },
+                "member_details": {
+                    "description": "MemberDetails are the members with their UUIDs and roles.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pending_members": {
+                    "description": "PendingMembers were invited but haven't joined yet.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
+                },
+                "requesting_members": {
+                    "description": "RequestingMembers requested to join via the invite link.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
+                }
+            }
+        },
+        "api.groupMemberEntry": {
+            "type": "object",
+            "properties": {
+                "invited_by": {
+                    "description": "InvitedBy is the UUID of the member who invited a pending member.",
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role is admin or default, v1 groups have no roles.",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Timestamp is when the member was invited or requested to join, in\nmilliseconds. Older signald versions don't report it.",
+                    "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.groupSyncReport": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupChange"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "unchanged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.heldMessage": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_group": {
+                    "type": "boolean"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.historyMessage": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "cursor": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "string"
+                },
+                "edited": {
+                    "description": "Edited is the timestamp of the latest edit of the message.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "outgoing": {
+                    "type": "boolean"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.incomingMessage": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "edit": {
+                    "description": "Edit is set for edits of messages, the new body is in the data.",
+                    "type": "object",
+                    "$ref": "#/definitions/api.messageEdit"
+                },
+                "error": {
+                    "type": "error"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_request": {
+                    "description": "MessageRequest is set for the messages of senders that aren't\ncontacts and weren't accepted yet.",
+                    "type": "boolean"
+                },
+                "reaction": {
+                    "description": "Reaction is set for reactions, with the message reacted to.",
+                    "type": "object",
+                    "$ref": "#/definitions/api.messageReaction"
+                },
+                "sealed_sender": {
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                },
+                "typing": {
+                    "description": "Typing is set for typing messages, they are only received by numbers\nwith typing events enabled.",
+                    "type": "object",
+                    "$ref": "#/definitions/api.typingIndicator"
+                }
+            }
+        },
+        "api.linkSession": {
+            "type": "object",
+            "properties": {
+                "device_name": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "started": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.location": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lon": {
+                    "type": "number"
+                }
+            }
+        },
+        "api.maintenanceRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.maintenanceStatus": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "held": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.heldMessage"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.markReadRequest": {
+            "type": "object",
+            "properties": {
+                "timestamp": {
+                    "description": "Timestamp of the newest message that was read, if it is 0 the whole\nconversation is read.",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.markReadResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "receipts": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.messageEdit": {
+            "type": "object",
+            "properties": {
+                "original_id": {
+                    "type": "string"
+                },
+                "target_timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.messagePreview": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "outgoing": {
+                    "type": "boolean"
+                },
+                "preview": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.messageReaction": {
+            "type": "object",
+            "properties": {
+                "emoji": {
+                    "type": "string"
+                },
+                "remove": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.storedMessage"
+                },
+                "target_author": {
+                    "description": "TargetAuthor is the number (or the UUID, if signald doesn't know the\nnumber) of the sender of the message reacted to.",
+                    "type": "string"
+                },
+                "target_timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.messageRequest": {
+            "type": "object",
+            "properties": {
+                "reported_spam": {
+                    "type": "boolean"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.parkedSend": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.pollOption": {
+            "type": "object",
+            "properties": {
+                "option": {
+                    "type": "string"
+                },
+                "votes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.pollResults": {
+            "type": "object",
+            "properties": {
+                "closes": {
+                    "type": "string"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "open": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.pollOption"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                },
+                "voters": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.privacySettings": {
+            "type": "object",
+            "properties": {
+                "discoverable_by_number": {
+                    "type": "string"
+                },
+                "phone_number_sharing": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.privacyUpdate": {
+            "type": "object",
+            "properties": {
+                "discoverable_by_number": {
+                    "description": "DiscoverableByNumber is everybody or nobody.",
+                    "type": "string"
+                },
+                "phone_number_sharing": {
+                    "description": "PhoneNumberSharing is everybody or nobody.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.purgeResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "subsystem": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.queuedMessage": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.queuedMessages": {
+            "type": "object",
+            "properties": {
+                "queued": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.quotaUsage": {
+            "type": "object",
+            "properties": {
+                "attachment_bytes_day": {
+                    "type": "integer"
+                },
+                "attachment_bytes_hour": {
+                    "type": "integer"
+                },
+                "client": {
+                    "type": "string"
+                },
+                "day_reset": {
+                    "type": "string"
+                },
+                "hour_reset": {
+                    "type": "string"
+                },
+                "limits": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.QuotaLimits"
+                },
+                "messages_day": {
+                    "type": "integer"
+                },
+                "messages_hour": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.rateLimitChallenge": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.parkedSend"
+                    }
+                },
+                "number": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options are the challenges Signal accepts, recaptcha and/or\npush_challenge.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "retry_after": {
+                    "description": "RetryAfter is the number of seconds after which Signal accepts messages\nagain without the challenge, if it tells.",
+                    "type": "integer"
+                },
+                "since": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.readiness": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.subscriptionStatus"
+                    }
+                },
+                "leader": {
+                    "type": "boolean"
+                },
+                "ready": {
+                    "type": "boolean"
+                },
+                "signald": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.supervisorStatus"
+                },
+                "signald_checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.signaldCheck"
+                    }
+                }
+            }
+        },
+        "api.receiveResults": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.incomingMessage"
+                    }
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.registrationStatus": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "integer"
+                },
+                "hint": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "verification": {
+                    "description": "Verification is the state of the verification codes requested for a\nnumber that isn't active yet.",
+                    "type": "object",
+                    "$ref": "#/definitions/api.verificationState"
+                }
+            }
+        },
+        "api.resendRequest": {
+            "type": "object",
+            "properties": {
+                "captcha": {
+                    "type": "string"
+                },
+                "use_voice": {
+                    "description": "UseVoice delivers the code by a voice call instead of SMS.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.searchHit": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "string"
+                },
+                "edited": {
+                    "description": "Edited is the timestamp of the latest edit of the message.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_group": {
+                    "type": "boolean"
+                },
+                "outgoing": {
+                    "type": "boolean"
+                },
+                "peer": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.selfTestReport": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "number"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.selfTestStep"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.selfTestStep": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.sendMessageV1": {
+            "type": "object",
+            "properties": {
+                "base64_attachment": {
+                    "type": "string"
+                },
+                "is_group": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.sendMessageV2": {
+            "type": "object",
+            "properties": {
+                "attachment_tokens": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "base64_attachments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "contacts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.sharedContact"
+                    }
+                },
+                "location": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.location"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "note_to_self": {
+                    "type": "boolean"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.sentMessageResponse": {
+            "type": "object",
+            "properties": {
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.sharedContact": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "description": "Avatar is a base64 encoded JPEG or PNG image.",
+                    "type": "string"
+                },
+                "emails": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "phones": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.signaldCheck": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "socket_path": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.simpleGroup": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.simpleMessage": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.statsReport": {
+            "type": "object",
+            "properties": {
+                "last_activity": {
+                    "type": "string"
+                },
+                "last_received": {
+                    "type": "string"
+                },
+                "last_sent": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "queue_depth": {
+                    "type": "integer"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.windowStats"
+                    }
+                }
+            }
+        },
+        "api.storedMessage": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "string"
+                },
+                "edited": {
+                    "description": "Edited is the timestamp of the latest edit of the message.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "outgoing": {
+                    "type": "boolean"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.subscriptionStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.supervisorStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "pid": {
+                    "type": "integer"
+                },
+                "restarts": {
+                    "type": "integer"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.twilioMessage": {
+            "type": "object",
+            "properties": {
+                "account_sid": {
+                    "type": "string"
+                },
+                "api_version": {
+                    "type": "string"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "date_created": {
+                    "type": "string"
+                },
+                "date_sent": {
+                    "type": "string"
+                },
+                "date_updated": {
+                    "type": "string"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "num_media": {
+                    "type": "string"
+                },
+                "num_segments": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "string"
+                },
+                "sid": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "uri": {
+                    "type": "string"
                 }
             }
         },
-        "api.Error": {
+        "api.typingIndicator": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action is started or stopped.",
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.unreadCount": {
+            "type": "object",
+            "properties": {
+                "peer": {
+                    "type": "string"
+                },
+                "unread": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.unreadCounts": {
+            "type": "object",
+            "properties": {
+                "conversations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.unreadCount"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.uploadStatus": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.uploadToken": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.v3Error": {
             "type": "object",
             "properties": {
                 "error": {
+                    "type": "object",
+                    "$ref": "#/definitions/api.v3ErrorBody"
+                }
+            }
+        },
+        "api.v3ErrorBody": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "integer"
                 }
             }
         },
-        "api.GroupEntry": {
+        "api.v3Group": {
             "type": "object",
             "properties": {
                 "active": {
                     "type": "boolean"
                 },
-                "blocked": {
-                    "type": "boolean"
+                "expiration_timer": {
+                    "type": "integer"
                 },
                 "id": {
                     "type": "string"
                 },
-                "internal_id": {
-                    "type": "string"
+                "member_details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
                 },
                 "members": {
                     "type": "array",
@@ -451,41 +6000,233 @@ var doc = `{
                 },
                 "name": {
                     "type": "string"
+                },
+                "pending_members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
+                },
+                "requesting_members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.groupMemberEntry"
+                    }
                 }
             }
         },
-        "api.SendMessageV1": {
+        "api.v3Registration": {
             "type": "object",
             "properties": {
-                "base64_attachment": {
+                "captcha": {
                     "type": "string"
                 },
-                "is_group": {
+                "force": {
+                    "description": "Force registers a number again that is already active.",
                     "type": "boolean"
                 },
-                "message": {
+                "use_voice": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.v3SendRequest": {
+            "type": "object",
+            "properties": {
+                "attachment_tokens": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "attachments": {
+                    "description": "Attachments are base64 encoded.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "group_id": {
                     "type": "string"
                 },
-                "number": {
+                "message": {
                     "type": "string"
                 },
                 "recipients": {
+                    "description": "Recipients are phone numbers, \"self\" sends a note to self.",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "timestamp": {
+                    "type": "integer"
                 }
             }
         },
-        "api.SendMessageV2": {
+        "api.v3Verification": {
             "type": "object",
             "properties": {
-                "base64_attachments": {
+                "code": {
+                    "type": "string"
+                },
+                "pin": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.verificationAttempt": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "type": "string"
+                },
+                "requested_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.verificationState": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.verificationAttempt"
+                    }
+                },
+                "attempts_remaining": {
+                    "type": "integer"
+                },
+                "call_placed": {
+                    "description": "CallPlaced is set if the last code was delivered by a voice call.",
+                    "type": "boolean"
+                },
+                "method": {
+                    "description": "Method is how the last code was delivered.",
+                    "type": "string"
+                },
+                "resend_after": {
+                    "description": "ResendAfter is when another code can be requested.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.verifyNumberSettings": {
+            "type": "object",
+            "properties": {
+                "pin": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookStatus": {
+            "type": "object",
+            "properties": {
+                "dead_lettered": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "last_delivered": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.windowStats": {
+            "type": "object",
+            "properties": {
+                "average_latency_ms": {
+                    "type": "number"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "received": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.GroupUpdate": {
+            "type": "object",
+            "properties": {
+                "expiration_timer": {
+                    "description": "ExpirationTimer is the time in seconds after which messages disappear,\n0 disables it. It is the timer of the group, the timers of contacts are\nseparate.",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.Send": {
+            "type": "object",
+            "properties": {
+                "attachmentTokens": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "base64Attachments": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "client": {
+                    "description": "Client sent the message, its quotas apply.",
+                    "type": "string"
+                },
+                "context": {
+                    "description": "Context is the context of the request that sends the message,\nprocessing the attachments stops when it is done.",
+                    "type": "string"
+                },
+                "isGroup": {
+                    "type": "boolean"
+                },
                 "message": {
                     "type": "string"
                 },
@@ -497,14 +6238,10 @@ var doc = `{
                     "items": {
                         "type": "string"
                     }
-                }
-            }
-        },
-        "api.VerifyNumberSettings": {
-            "type": "object",
-            "properties": {
-                "pin": {
-                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Timestamp of the message, now if it is 0.",
+                    "type": "integer"
                 }
             }
         }
@@ -522,9 +6259,37 @@ var doc = `{
             "description": "Create, List and Delete Signal Groups.",
             "name": "Groups"
         },
+        {
+            "description": "List Contacts.",
+            "name": "Contacts"
+        },
         {
             "description": "Send and Receive Signal Messages.",
             "name": "Messages"
+        },
+        {
+            "description": "Upload attachments.",
+            "name": "Attachments"
+        },
+        {
+            "description": "Register chat commands.",
+            "name": "Commands"
+        },
+        {
+            "description": "Ask groups questions and count the answers.",
+            "name": "Polls"
+        },
+        {
+            "description": "Manage the data stored about contacts.",
+            "name": "Data"
+        },
+        {
+            "description": "Show information about the registered numbers.",
+            "name": "Accounts"
+        },
+        {
+            "description": "Configure the registered numbers.",
+            "name": "Admin"
         }
     ]
 }`
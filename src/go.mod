@@ -8,9 +8,13 @@ require (
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-openapi/spec v0.19.8 // indirect
 	github.com/go-openapi/swag v0.19.9 // indirect
+	github.com/gomodule/redigo v1.8.9
 	github.com/h2non/filetype v1.1.0
 	github.com/json-iterator/go v1.1.9
+	github.com/lib/pq v1.10.7
 	github.com/mailru/easyjson v0.7.1 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/sirupsen/logrus v1.6.0
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/swaggo/files v0.0.0-20190704085106-630677cd5c14
@@ -2,6 +2,11 @@ package main
 
 import (
 	"flag"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/abaskin/signald-rest-api/api"
 	_ "github.com/abaskin/signald-rest-api/docs"
@@ -11,6 +16,34 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// The build of the API, set with -ldflags "-X main.gitCommit=... -X
+// main.buildDate=...".
+var (
+	gitCommit = "unknown"
+	buildDate = "unknown"
+)
+
+// stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitList splits a comma separated flag value, an empty value is an empty
+// list.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 // @title Signal Cli REST API
 // @version 1.0
 // @description This is the Signal Cli REST API documentation.
@@ -24,22 +57,295 @@ import (
 // @tag.name Groups
 // @tag.description Create, List and Delete Signal Groups.
 
+// @tag.name Contacts
+// @tag.description List Contacts.
+
 // @tag.name Messages
 // @tag.description Send and Receive Signal Messages.
 
+// @tag.name Attachments
+// @tag.description Upload attachments.
+
+// @tag.name Commands
+// @tag.description Register chat commands.
+
+// @tag.name Polls
+// @tag.description Ask groups questions and count the answers.
+
+// @tag.name Data
+// @tag.description Manage the data stored about contacts.
+
+// @tag.name Accounts
+// @tag.description Show information about the registered numbers.
+
+// @tag.name Admin
+// @tag.description Configure the registered numbers.
+
 // @host 127.0.0.1:8080
 // @BasePath /
 func main() {
 	signaldSocketPath := flag.String("signald-socket-path", "/var/run/signald/signald.sock", "signald socket path")
 	attachmentTmpDir := flag.String("attachment-tmp-dir", "/tmp/", "Attachment tmp directory")
+	attachmentFsync := flag.String("attachment-fsync", api.FsyncAlways, "When attachment files are flushed to disk: always before sending, never (left to the operating system) or batch (every second)")
+	attachmentTimeout := flag.Duration("attachment-timeout", 2*time.Minute, "How long the attachments of a message may take to process (decoding, writing, video checks) before the request fails (0 means no limit)")
+	attachmentTmpReserve := flag.Int64("attachment-tmp-reserve", 100*1024*1024, "Bytes kept free in the attachment tmp directory, attachments that don't fit are rejected with 507 (0 disables the check)")
+	attachmentCacheTTL := flag.Duration("attachment-cache-ttl", time.Minute, "How long an attachment is kept in the tmp directory after it was sent, so retries can reuse it (0 removes it right away)")
+	sendHookURLs := stringList{}
+	flag.Var(&sendHookURLs, "send-hook-url", "URL of a hook that can modify or veto outgoing messages (can be given multiple times, hooks are called in order)")
+	sendHookTimeout := flag.Duration("send-hook-timeout", 5*time.Second, "Timeout for calling a send hook")
+	receiveProcessorsConfig := flag.String("receive-processors-config", "", "JSON file with the HTTP processors incoming messages are passed through")
+	dataDir := flag.String("data-dir", "", "Directory the server side state (e.g. chat commands) is persisted in, if empty the state is only kept in memory")
+	commandPrefix := flag.String("command-prefix", "!", "Prefix of chat commands in incoming messages")
+	commandTimeout := flag.Duration("command-timeout", 10*time.Second, "Timeout for calling the webhook of a chat command")
+	webhookURLs := stringList{}
+	flag.Var(&webhookURLs, "webhook-url", "URL events (e.g. group membership changes) are posted to (can be given multiple times)")
+	webhooksConfig := flag.String("webhooks-config", "", "JSON file with webhooks and the format (normalized, raw, template) events are posted to them in")
+	webhookTimeout := flag.Duration("webhook-timeout", 5*time.Second, "Timeout for delivering an event to a webhook")
+	webhookRetries := flag.Int("webhook-retries", 3, "How often the delivery of an event to a webhook is retried before it is dead-lettered")
+	defaultDeviceName := flag.String("default-device-name", "", "Name of linked devices if the link request has no device_name, a template that can use {{.Hostname}} and {{env \"NAME\"}}")
+	userAgent := flag.String("user-agent", "", "User agent of the outgoing HTTP requests, a template that can use {{.Hostname}} and {{env \"NAME\"}} (default the one of Go)")
+	captchaSolverURL := flag.String("captcha-solver-url", "", "URL the captchas Signal requires for registering are posted to, the solver responds with the token of the solved captcha")
+	captchaSolverTimeout := flag.Duration("captcha-solver-timeout", 2*time.Minute, "Timeout for solving a captcha")
+	receiveTimeout := flag.Duration("receive-timeout", time.Second, "How long the receive endpoint waits for messages by default (rounded up to full seconds)")
+	drainInterval := flag.Duration("drain-interval", 0, "How often the messages of numbers that only send are received in the background, so that signald processes receipts and key updates (0 disables)")
+	trustPolicy := flag.String("trust-policy", api.TrustNever, "How new identities (changed safety numbers) of contacts are trusted automatically (never, tofu, always)")
+	decryptionFailurePolicy := flag.String("decryption-failure-policy", api.DecryptionFailureIgnore, "How messages that can't be decrypted are handled: ignore only reports them with a decryption_failed event, reset also resets the session with the sender (at most once an hour per sender)")
+	accountSettingsConfig := flag.String("account-settings-config", "", "JSON file with the settings of the registered numbers")
+	matrixConfig := flag.String("matrix-config", "", "JSON file with the Matrix homeserver and the rooms Signal conversations are bridged to, the API then serves as its application service")
+	smtpConfig := flag.String("smtp-config", "", "JSON file with the listen address, users and routes of an SMTP server that sends the emails it receives as Signal messages")
+	syslogConfig := flag.String("syslog-config", "", "JSON file with the UDP and TCP addresses of a syslog listener and the rules which messages it forwards to which Signal recipients")
+	xmppConfig := flag.String("xmpp-config", "", "JSON file with the XMPP server and the users and MUCs Signal contacts and groups are bridged to, the API connects to the server as component")
+	quotasConfig := flag.String("quotas-config", "", "JSON file with the hourly and daily quotas of messages and attachment bytes of the clients (the user the reverse proxy passes on, * for all others)")
+	subscribeNumbers := stringList{}
+	flag.Var(&subscribeNumbers, "subscribe-number", "Number that is subscribed to incoming messages on startup, incoming messages are buffered until they are received (can be given multiple times)")
+	listingCacheTTL := flag.Duration("listing-cache-ttl", 5*time.Second, "How long the group, contact and account listings of signald are cached (0 disables the cache)")
+	inlineAttachmentMaxSize := flag.Int64("inline-attachment-max-size", 256*1024, "Maximum size in bytes of the attachments embedded in received messages with attachments=inline")
+	signaldAttachmentDir := flag.String("signald-attachment-dir", "", "signald attachment directory, thumbnails of attachments that weren't received through the API are looked up there")
+	ffmpegPath := flag.String("ffmpeg-path", "ffmpeg", "Path of ffmpeg, used for thumbnails of videos (empty disables them)")
+	videoCheck := flag.Bool("video-check", false, "Check outgoing video attachments with ffprobe and reject the ones Signal wouldn't accept")
+	ffprobePath := flag.String("ffprobe-path", "ffprobe", "Path of ffprobe, used to check video attachments")
+	videoMaxSize := flag.Int64("video-max-size", 100*1024*1024, "Maximum size in bytes of video attachments (0 means unlimited)")
+	videoMaxBitrate := flag.Int64("video-max-bitrate", 0, "Maximum bitrate in bits per second of video attachments (0 means unlimited)")
+	videoContainers := flag.String("video-containers", "mp4,3gp", "Comma separated list of the accepted video containers")
+	videoCodecs := flag.String("video-codecs", "h264,aac", "Comma separated list of the accepted video and audio codecs")
+	videoTranscode := flag.Bool("video-transcode", false, "Transcode video attachments that fail the checks to H.264/AAC in MP4 with ffmpeg instead of rejecting them")
+	messageStoreSize := flag.Int("message-store-size", 0, "Number of messages kept per conversation in the message store (0 disables the message store)")
+	messageStoreKeyFile := flag.String("message-store-key-file", "", "File with the base64 encoded 256 bit key the persisted message store is encrypted with")
+	messagePurgeAfter := flag.Duration("message-purge-after", 24*time.Hour, "How long deleted messages are kept in the message store before they are purged")
+	proxyURL := flag.String("proxy-url", "", "Proxy (http://, https:// or socks5:// URL) for the outgoing HTTP requests to hooks, processors, chat commands and webhooks, if empty the proxy environment variables apply")
+	egressAllowlist := flag.String("egress-allowlist", "", "Comma separated list of the hosts outgoing HTTP requests may go to (*.example.com matches all subdomains), if empty all hosts are allowed")
+	unixSocket := flag.String("unix-socket", "", "Serve the REST API on this Unix domain socket instead of a TCP port")
+	unixSocketMode := flag.String("unix-socket-mode", "0660", "File mode of the Unix domain socket")
+	storageDSN := flag.String("storage-dsn", "", "Database the server side state is kept in (postgres://... or sqlite:/path/to/state.db), defaults to state.db in the data dir")
+	redisURL := flag.String("redis-url", "", "Redis (e.g. redis://localhost:6379/0) the replicas of the API share the send queue, receive buffers, idempotency keys and rate limits in, if empty they are kept in memory")
+	signaldBackendsConfig := flag.String("signald-backends-config", "", "JSON file with further signald backends and the numbers routed to them, reloaded on SIGHUP")
+	signaldDataDir := flag.String("signald-data-dir", "", "Data directory of signald, account archives are extracted there")
+	accountArchive := flag.String("signald-account-archive", os.Getenv("SIGNALD_ACCOUNT_ARCHIVE"), "tar.gz archive of a signald data dir that is extracted into -signald-data-dir on startup (existing files are kept), e.g. to start with accounts in ephemeral environments (default $SIGNALD_ACCOUNT_ARCHIVE)")
+	autoLinkURL := flag.String("auto-link-url", os.Getenv("AUTO_LINK_URL"), "If signald has no account on startup, link it as device named -default-device-name by posting the linking URI to this URL, e.g. /v1/devices/<number> of the API running the primary device (default $AUTO_LINK_URL)")
+	faultInjection := flag.Bool("fault-injection", false, "Allow injecting faults (delays, errors, dropped received messages) via /admin/faults to test clients, never enable it in production")
+	signaldCheck := flag.String("signald-check", api.SignaldCheckDegraded, "Check on startup that the signald sockets exist, accept connections and signald is recent enough: strict refuses to start if not, degraded starts and reports not ready until signald is fixed, off skips the check")
+	signaldCommand := flag.String("signald-command", "", "Command line of signald (e.g. \"signald -s /var/run/signald/signald.sock\"), if set the API runs signald and restarts it when it exits")
+	statsWindows := flag.String("stats-windows", "1h,24h", "Comma separated list of the time windows the account statistics are reported for")
+	resendAfterTrust := flag.Bool("resend-after-trust", false, "Queue messages that can't be sent because the identity of the recipient isn't trusted and send them once it is")
+	uploadTTL := flag.Duration("upload-ttl", time.Hour, "How long unfinished resumable uploads and attachment tokens are kept")
+	uploadMaxSize := flag.Int64("upload-max-size", 100*1024*1024, "Maximum size in bytes of a resumable upload")
+	metricsBuckets := flag.String("metrics-buckets", "0.1,0.25,0.5,1,2.5,5,10,30", "Comma separated list of the buckets of the send duration histogram in seconds")
+	metricsLabels := flag.String("metrics-labels", "", "Comma separated list of the optional labels of the metrics (number, recipient), each multiplies the number of time series")
+	securityHeaders := flag.Bool("security-headers", true, "Add security headers (X-Content-Type-Options, X-Frame-Options, Referrer-Policy and HSTS on HTTPS) to the responses")
+	hstsMaxAge := flag.Duration("hsts-max-age", 365*24*time.Hour, "max-age of the Strict-Transport-Security header sent on requests over HTTPS (0 disables it)")
+	httpsRedirect := flag.Bool("https-redirect", false, "Redirect requests over plain HTTP to HTTPS (behind a reverse proxy that terminates TLS and sets X-Forwarded-Proto), except the health checks")
+	auditActorHeader := flag.String("audit-actor-header", "X-Forwarded-User", "Header the authenticating reverse proxy puts the user in, who is recorded as actor in the audit log (basic auth is used if it is missing)")
+	logRedaction := flag.String("log-redaction", api.RedactionMask, "Redaction of phone numbers (E.164, with a leading +) in the logs (off, mask, hash)")
+	logRedactionSalt := flag.String("log-redaction-salt", "", "Salt used when hashing phone numbers in the logs, required with -log-redaction hash")
 	flag.Parse()
 
-	router := gin.Default()
+	redactor, err := api.NewRedactor(*logRedaction, *logRedactionSalt)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	api.RedactLogs(redactor)
+
+	var messageStoreKey []byte
+	if *messageStoreKeyFile != "" {
+		messageStoreKey, err = api.LoadStateKey(*messageStoreKeyFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	receiveProcessors := []api.ReceiveProcessor{}
+	if *receiveProcessorsConfig != "" {
+		receiveProcessors, err = api.LoadReceiveProcessors(*receiveProcessorsConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	webhooks := []api.Webhook{}
+	if *webhooksConfig != "" {
+		webhooks, err = api.LoadWebhooks(*webhooksConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	windows, err := api.ParseStatsWindows(*statsWindows)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	buckets, err := api.ParseMetricsBuckets(*metricsBuckets)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	accountSettings := map[string]api.AccountSettings{}
+	if *accountSettingsConfig != "" {
+		accountSettings, err = api.LoadAccountSettings(*accountSettingsConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var matrixBridge *api.MatrixBridge
+	if *matrixConfig != "" {
+		matrixBridge, err = api.LoadMatrixBridge(*matrixConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var xmppBridge *api.XMPPBridge
+	if *xmppConfig != "" {
+		xmppBridge, err = api.LoadXMPPBridge(*xmppConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var smtpServer *api.SMTPServer
+	if *smtpConfig != "" {
+		smtpServer, err = api.LoadSMTPServer(*smtpConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var syslogListener *api.SyslogListener
+	if *syslogConfig != "" {
+		syslogListener, err = api.LoadSyslogListener(*syslogConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	quotas := map[string]api.QuotaLimits{}
+	if *quotasConfig != "" {
+		quotas, err = api.LoadQuotas(*quotasConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	var videoLimits *api.VideoLimits
+	if *videoCheck {
+		videoLimits = &api.VideoLimits{
+			FFprobePath: *ffprobePath,
+			FFmpegPath:  *ffmpegPath,
+			MaxSize:     *videoMaxSize,
+			MaxBitrate:  *videoMaxBitrate,
+			Containers:  splitList(*videoContainers),
+			Codecs:      splitList(*videoCodecs),
+			Transcode:   *videoTranscode,
+		}
+	}
+
+	mode, err := strconv.ParseUint(*unixSocketMode, 8, 32)
+	if err != nil {
+		log.Fatal("Invalid unix socket mode " + *unixSocketMode)
+	}
+	listener, err := api.Listener(*unixSocket, os.FileMode(mode))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery(), api.RequestLogger(redactor), api.SecurityHeaders(api.SecuritySettings{
+		Headers:       *securityHeaders,
+		HSTSMaxAge:    *hstsMaxAge,
+		HTTPSRedirect: *httpsRedirect,
+	}))
 	// gin.SetMode(gin.ReleaseMode)
 
 	log.Info("Started signald REST API")
 
-	api := api.NewApi(*signaldSocketPath, *attachmentTmpDir)
+	api, err := api.NewApi(api.Config{
+		SignaldSocketPath:       *signaldSocketPath,
+		AttachmentTmpDir:        *attachmentTmpDir,
+		AttachmentCacheTTL:      *attachmentCacheTTL,
+		UploadTTL:               *uploadTTL,
+		SendHookURLs:            sendHookURLs,
+		SendHookTimeout:         *sendHookTimeout,
+		ReceiveProcessors:       receiveProcessors,
+		DataDir:                 *dataDir,
+		CommandPrefix:           *commandPrefix,
+		CommandTimeout:          *commandTimeout,
+		WebhookURLs:             webhookURLs,
+		Webhooks:                webhooks,
+		WebhookRetries:          *webhookRetries,
+		DrainInterval:           *drainInterval,
+		ReceiveTimeout:          *receiveTimeout,
+		DefaultDeviceName:       *defaultDeviceName,
+		UserAgent:               *userAgent,
+		CaptchaSolverURL:        *captchaSolverURL,
+		CaptchaSolverTimeout:    *captchaSolverTimeout,
+		WebhookTimeout:          *webhookTimeout,
+		TrustPolicy:             *trustPolicy,
+		ResendAfterTrust:        *resendAfterTrust,
+		AccountSettings:         accountSettings,
+		StatsWindows:            windows,
+		SubscribeNumbers:        subscribeNumbers,
+		ListingCacheTTL:         *listingCacheTTL,
+		InlineAttachmentMaxSize: *inlineAttachmentMaxSize,
+		SignaldAttachmentDir:    *signaldAttachmentDir,
+		FFmpegPath:              *ffmpegPath,
+		VideoLimits:             videoLimits,
+		MessageStoreSize:        *messageStoreSize,
+		MessagePurgeAfter:       *messagePurgeAfter,
+		MessageStoreKey:         messageStoreKey,
+		ProxyURL:                *proxyURL,
+		EgressAllowlist:         splitList(*egressAllowlist),
+		MetricsBuckets:          buckets,
+		MetricsLabels:           splitList(*metricsLabels),
+		SignaldCommand:          strings.Fields(*signaldCommand),
+		SignaldBackendsConfig:   *signaldBackendsConfig,
+		RedisURL:                *redisURL,
+		StorageDSN:              *storageDSN,
+		AttachmentFsync:         *attachmentFsync,
+		AttachmentTimeout:       *attachmentTimeout,
+		AttachmentTmpReserve:    *attachmentTmpReserve,
+		UploadMaxSize:           *uploadMaxSize,
+		AuditActorHeader:        *auditActorHeader,
+		SignaldCheck:            *signaldCheck,
+		Build:                   api.BuildInfo{GitCommit: gitCommit, BuildDate: buildDate},
+		FaultInjection:          *faultInjection,
+		SignaldDataDir:          *signaldDataDir,
+		AccountArchive:          *accountArchive,
+		AutoLinkURL:             *autoLinkURL,
+		DecryptionFailurePolicy: *decryptionFailurePolicy,
+		Quotas:                  quotas,
+		Matrix:                  matrixBridge,
+		XMPP:                    xmppBridge,
+		SMTP:                    smtpServer,
+		Syslog:                  syslogListener,
+	})
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	router.Use(api.BackendGate, api.InjectFaults)
+
+	router.GET("/metrics", api.Metrics)
+
 	v1 := router.Group("/v1")
 	{
 		about := v1.Group("/about")
@@ -47,45 +353,267 @@ func main() {
 			about.GET("", api.About)
 		}
 
+		health := v1.Group("/health")
+		{
+			health.GET("ready", api.Ready)
+		}
+
+		selftest := v1.Group("/selftest", api.AccountGate)
+		{
+			selftest.POST(":number", api.SelfTest)
+		}
+
 		register := v1.Group("/register")
 		{
 			register.POST(":number", api.RegisterNumber)
+			register.GET(":number/captcha", api.GetCaptchaChallenge)
+			register.GET(":number/status", api.GetRegistrationStatus)
+			register.POST(":number/resend", api.ResendVerificationCode)
 			register.POST(":number/verify/:token", api.VerifyRegisteredNumber)
 		}
 
 		sendV1 := v1.Group("/send")
 		{
-			sendV1.POST("", api.Send)
+			sendV1.POST("", api.Idempotent, api.Send)
 		}
 
-		receive := v1.Group("/receive")
+		receive := v1.Group("/receive", api.AccountGate, api.CompressResponse)
 		{
 			receive.GET(":number", api.Receive)
 		}
 
-		groups := v1.Group("/groups")
+		receipts := v1.Group("/receipts", api.AccountGate)
+		{
+			receipts.POST(":number/bulk", api.SendBulkReceipts)
+		}
+
+		groups := v1.Group("/groups", api.AccountGate)
 		{
 			groups.POST(":number", api.CreateGroup)
 			groups.GET(":number", api.GetGroups)
+			groups.PATCH(":number/:groupid", api.UpdateGroup)
 			groups.DELETE(":number/:groupid", api.DeleteGroup)
+			groups.POST(":number/sync", api.SyncGroups)
+		}
+
+		devices := v1.Group("/devices", api.AccountGate)
+		{
+			devices.POST(":number", api.AddDevice)
+			devices.PUT(":number/:device_id/name", api.RequireCapability("device_names"), api.SetDeviceName)
+		}
+
+		contacts := v1.Group("/contacts", api.AccountGate)
+		{
+			contacts.GET(":number", api.GetContacts)
+		}
+
+		profiles := v1.Group("/profiles", api.AccountGate)
+		{
+			profiles.GET(":number/:recipient/avatar", api.RequireCapability("profiles"), api.GetAvatar)
+		}
+
+		sessions := v1.Group("/sessions", api.AccountGate)
+		{
+			sessions.POST(":number/:recipient/reset", api.ResetSession)
 		}
 
 		link := v1.Group("link")
 		{
 			link.GET("", api.Link)
+			link.GET("sessions", api.GetLinkSessions)
+		}
+
+		attachments := v1.Group("/attachments")
+		{
+			attachments.POST("uploads", api.CreateUpload)
+			attachments.GET(":number/:id", api.GetUploadOrNotFound)
+			attachments.PUT("uploads/:id", api.UploadChunk)
+			attachments.DELETE("uploads/:id", api.DeleteUpload)
+			attachments.POST("uploads/:id/finalize", api.FinalizeUpload)
+			attachments.GET(":number/:id/thumbnail", api.AccountGate, api.GetThumbnail)
+		}
+
+		commands := v1.Group("/commands")
+		{
+			commands.GET("", api.GetCommands)
+			commands.POST("", api.CreateCommand)
+			commands.DELETE(":name", api.DeleteCommand)
+		}
+
+		conversations := v1.Group("/conversations", api.AccountGate)
+		{
+			conversations.GET(":number", api.GetConversations)
+			conversations.GET(":number/unread", api.GetUnreadCounts)
+			conversations.POST(":number/:peer/read", api.MarkConversationRead)
+		}
+
+		messages := v1.Group("/messages", api.AccountGate)
+		{
+			messages.GET(":number/:peer", api.GetMessages)
+			messages.DELETE(":number/:message_id", api.DeleteStoredMessage)
+		}
+
+		search := v1.Group("/search", api.AccountGate)
+		{
+			search.GET("messages/:number", api.SearchMessages)
+		}
+
+		messageRequests := v1.Group("/message-requests", api.AccountGate)
+		{
+			messageRequests.GET(":number", api.GetMessageRequests)
+			messageRequests.POST(":number/:sender/accept", api.AcceptMessageRequest)
+			messageRequests.POST(":number/:sender/block", api.BlockMessageRequest)
+			messageRequests.POST(":number/:sender/report-spam", api.ReportSpam)
+		}
+
+		polls := v1.Group("/polls")
+		{
+			polls.POST("", api.CreatePoll)
+			polls.GET(":id", api.GetPoll)
+			polls.DELETE(":id", api.DeletePoll)
+		}
+
+		challenges := v1.Group("/challenges")
+		{
+			challenges.GET("", api.GetChallenges)
+			challenges.GET(":id", api.GetChallenge)
+			challenges.POST(":id", api.SolveChallenge)
+			challenges.DELETE(":id", api.DeleteChallenge)
+		}
+
+		simple := v1.Group("/simple")
+		{
+			simple.GET("send", api.SimpleSendQuery)
+			simple.POST("send", api.SimpleSend)
+			simple.GET("receive", api.SimpleReceive)
+			simple.GET("groups", api.SimpleGroups)
+		}
+
+		queue := v1.Group("/queue", api.AccountGate)
+		{
+			queue.GET(":number", api.GetQueue)
+			queue.DELETE(":number/:id", api.DeleteQueuedMessage)
+		}
+
+		accounts := v1.Group("/accounts")
+		{
+			accounts.GET("", api.GetAccounts)
+			accounts.GET(":number/stats", api.AccountGate, api.GetAccountStats)
+			accounts.GET(":number/settings", api.AccountGate, api.GetPrivacySettings)
+			accounts.PUT(":number/settings", api.AccountGate, api.UpdatePrivacySettings)
+		}
+
+		data := v1.Group("/data", api.AccountGate)
+		{
+			data.DELETE(":number/:contact", api.ConfirmDestructive, api.DeleteContactData)
+		}
+	}
+
+	admin := router.Group("/admin")
+	{
+		admin.GET("export", api.ExportConfig)
+		admin.POST("import", api.ImportConfig)
+		admin.GET("audit", api.GetAuditLog)
+
+		backends := admin.Group("/backends")
+		{
+			backends.GET("", api.GetBackends)
+			backends.POST(":name/drain", api.DrainBackend)
+			backends.POST(":name/resume", api.ResumeBackend)
+		}
+
+		webhooks := admin.Group("/webhooks")
+		{
+			webhooks.GET("", api.GetWebhooks)
+			webhooks.GET("deliveries", api.GetWebhookDeliveries)
+			webhooks.GET("dead-letters", api.GetDeadLetters)
+			webhooks.POST("dead-letters/:id/replay", api.ReplayDeadLetter)
+			webhooks.DELETE("dead-letters/:id", api.DeleteDeadLetter)
+		}
+
+		faults := admin.Group("/faults")
+		{
+			faults.GET("", api.GetFaults)
+			faults.PUT("", api.SetFaults)
+			faults.DELETE("", api.DeleteFaults)
+		}
+
+		maintenance := admin.Group("/maintenance")
+		{
+			maintenance.GET("", api.GetMaintenance)
+			maintenance.PUT("", api.SetMaintenance)
+			maintenance.DELETE("held/:id", api.DiscardHeldMessage)
+		}
+
+		quotas := admin.Group("/quotas")
+		{
+			quotas.GET("", api.GetQuotas)
+			quotas.GET(":client", api.GetQuota)
+			quotas.DELETE(":client", api.ResetQuota)
+		}
+
+		accounts := admin.Group("/accounts")
+		{
+			accounts.GET(":number/settings", api.GetAccountSettings)
+			accounts.PUT(":number/settings", api.UpdateAccountSettings)
+			accounts.DELETE(":number/settings", api.ResetAccountSettings)
+		}
+	}
+
+	if matrixBridge != nil {
+		matrix := router.Group("/_matrix/app/v1")
+		{
+			matrix.PUT("transactions/:txnId", api.MatrixTransaction)
+			matrix.GET("users/:id", api.MatrixQuery)
+			matrix.GET("rooms/:id", api.MatrixQuery)
 		}
 	}
 
+	router.POST("/2010-04-01/Accounts/:sid/Messages.json", api.TwilioSend)
+
 	v2 := router.Group("/v2")
 	{
 		sendV2 := v2.Group("/send")
 		{
-			sendV2.POST("", api.SendV2)
+			sendV2.POST("", api.Idempotent, api.SendV2)
+		}
+	}
+
+	v3 := router.Group("/v3", api.CompressResponse, api.V3Errors)
+	{
+		accounts := v3.Group("/accounts")
+		{
+			accounts.GET("", api.GetAccounts)
+			accounts.POST(":number/registration", api.V3Register)
+			accounts.GET(":number/registration", api.V3GetRegistration)
+			accounts.POST(":number/registration/verification", api.V3Verify)
+		}
+
+		account := v3.Group("/accounts/:number", api.AccountGate)
+		{
+			account.POST("messages", api.Idempotent, api.V3SendMessage)
+			account.GET("messages", api.Receive)
+			account.GET("groups", api.V3GetGroups)
+			account.POST("groups", api.CreateGroup)
+			account.GET("groups/:group_id", api.V3GetGroup)
+			account.PATCH("groups/:group_id", api.V3UpdateGroup)
+			account.DELETE("groups/:group_id", api.V3LeaveGroup)
+			account.GET("contacts", api.GetContacts)
+			account.GET("conversations", api.GetConversations)
+			account.GET("stats", api.GetAccountStats)
 		}
 	}
 
 	swaggerUrl := ginSwagger.URL("http://127.0.0.1:8080/swagger/doc.json")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, swaggerUrl))
 
-	router.Run()
+	if listener == nil {
+		router.Run()
+		return
+	}
+
+	log.Info("Listening on ", listener.Addr().String())
+	if err := http.Serve(listener, router); err != nil {
+		log.Fatal(err.Error())
+	}
 }
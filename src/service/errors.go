@@ -0,0 +1,57 @@
+package service
+
+// Kind classifies the errors of the service, a transport reports them in its
+// own terms (e.g. as HTTP status).
+type Kind int
+
+const (
+	// Invalid is an invalid request.
+	Invalid Kind = iota
+	// Forbidden is a request that isn't allowed, e.g. vetoed by a send hook.
+	Forbidden
+	// NotFound is a request for something that doesn't exist.
+	NotFound
+	// Conflict is a request that conflicts with the current state.
+	Conflict
+	// RateLimited is a request that exceeds a rate limit or quota.
+	RateLimited
+	// Unsupported is a request signald doesn't support.
+	Unsupported
+	// Failed is a request signald failed to carry out.
+	Failed
+	// Unavailable is a request that can't be served right now.
+	Unavailable
+	// Timeout is a request that took too long.
+	Timeout
+	// StorageFull is a request there is no space for.
+	StorageFull
+)
+
+// Error is an error of the service with its kind.
+type Error struct {
+	Kind    Kind
+	Message string
+	// RetryAfter is the number of seconds after which the operation can be
+	// retried, if it is set.
+	RetryAfter int
+	// Metadata is passed on to the client, e.g. what is left of its quotas.
+	Metadata map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an error of the given kind.
+func NewError(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// KindOf returns the kind of an error, errors that aren't Errors are invalid
+// requests.
+func KindOf(err error) Kind {
+	if e, ok := err.(*Error); ok {
+		return e.Kind
+	}
+	return Invalid
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// GroupUpdate changes the settings of a group that are given.
+type GroupUpdate struct {
+	Name *string `json:"name"`
+	// ExpirationTimer is the time in seconds after which messages disappear,
+	// 0 disables it. It is the timer of the group, the timers of contacts are
+	// separate.
+	ExpirationTimer *int `json:"expiration_timer"`
+}
+
+// Verify verifies a registered number with the code it received.
+func (s *Service) Verify(number string, code string, pin string) error {
+	return s.backend.Verify(number, code, pin)
+}
+
+// CreateGroup creates a group of number and returns its group id.
+func (s *Service) CreateGroup(number string, name string, members []string) (string, error) {
+	b := s.backend
+	if err := b.CreateGroup(number, name, members); err != nil {
+		return "", err
+	}
+	b.GroupsChanged(number)
+
+	groups, err := b.ListGroups(number)
+	if err != nil {
+		return "", err
+	}
+
+	internalID := ""
+	for _, group := range groups {
+		if group.Name == name {
+			internalID = group.InternalID
+			break
+		}
+	}
+	return b.GroupID(internalID), nil
+}
+
+// LeaveGroup leaves a group of number, given in any form ResolveGroup
+// accepts. If newAdmin (a number or UUID of a member) is given, the member is
+// made admin before, so that a group the account is the last admin of isn't
+// left without one.
+func (s *Service) LeaveGroup(number string, group string, newAdmin string, force bool) error {
+	b := s.backend
+	groupID, err := b.ResolveGroup(number, group)
+	if err != nil {
+		return err
+	}
+
+	if newAdmin != "" {
+		if err := b.PromoteGroupMember(number, groupID, newAdmin); err != nil {
+			return err
+		}
+	} else if b.LastGroupAdmin(number, groupID) {
+		if !force {
+			return NewError(Conflict, "The account is the last admin of the group, please provide new_admin or "+
+				"force=true to leave it without admin")
+		}
+		log.Warn(number, " leaves group ", b.GroupID(groupID), " as its last admin, the group is left without admin")
+	}
+
+	if err := b.LeaveGroup(number, groupID); err != nil {
+		return err
+	}
+	b.GroupsChanged(number)
+	return nil
+}
+
+// UpdateGroup changes the name and disappearing message timer of a group of
+// number, given in any form ResolveGroup accepts.
+func (s *Service) UpdateGroup(number string, group string, update GroupUpdate) error {
+	if update.ExpirationTimer != nil && *update.ExpirationTimer < 0 {
+		return NewError(Invalid, "Invalid expiration timer, it needs to be 0 (disabled) or more seconds")
+	}
+	if update.Name != nil && *update.Name == "" {
+		return NewError(Invalid, "Please provide a group name")
+	}
+
+	b := s.backend
+	groupID, err := b.ResolveGroup(number, group)
+	if err != nil {
+		return err
+	}
+	defer b.GroupsChanged(number)
+
+	if update.Name != nil {
+		if err := b.RenameGroup(number, groupID, *update.Name); err != nil {
+			return err
+		}
+	}
+	if update.ExpirationTimer != nil {
+		if err := b.SetGroupExpiration(number, groupID, *update.ExpirationTimer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLeaveGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  fakeBackend
+		group    string
+		newAdmin string
+		force    bool
+		wantErr  bool
+		kind     Kind
+		left     []string
+		promoted []string
+	}{
+		{
+			name:  "member",
+			group: "group.a",
+			left:  []string{"a"},
+		},
+		{
+			name:    "unknown group",
+			group:   "group.b",
+			wantErr: true,
+			kind:    NotFound,
+		},
+		{
+			name:    "last admin",
+			backend: fakeBackend{lastAdmin: true},
+			group:   "group.a",
+			wantErr: true,
+			kind:    Conflict,
+		},
+		{
+			name:    "last admin forced",
+			backend: fakeBackend{lastAdmin: true},
+			group:   "group.a",
+			force:   true,
+			left:    []string{"a"},
+		},
+		{
+			name:     "last admin with new admin",
+			backend:  fakeBackend{lastAdmin: true},
+			group:    "group.a",
+			newAdmin: "+3",
+			left:     []string{"a"},
+			promoted: []string{"+3"},
+		},
+		{
+			name:    "signald fails",
+			backend: fakeBackend{groupErr: NewError(Forbidden, "not a member")},
+			group:   "group.a",
+			wantErr: true,
+			kind:    Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backend
+			b.groups = []Group{{InternalID: "a", Name: "A"}}
+			err := New(&b, 0).LeaveGroup("+1", tt.group, tt.newAdmin, tt.force)
+			if tt.wantErr {
+				if err == nil || KindOf(err) != tt.kind {
+					t.Errorf("LeaveGroup() error = %v, want kind %v", err, tt.kind)
+				}
+				if len(b.left) > 0 {
+					t.Errorf("left %v", b.left)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LeaveGroup() error = %v", err)
+			}
+			if !reflect.DeepEqual(b.left, tt.left) || !reflect.DeepEqual(b.promoted, tt.promoted) {
+				t.Errorf("left %v and promoted %v, want %v and %v", b.left, b.promoted, tt.left, tt.promoted)
+			}
+			if b.changed != 1 {
+				t.Errorf("the groups changed %d times, want 1", b.changed)
+			}
+		})
+	}
+}
+
+func TestUpdateGroup(t *testing.T) {
+	name := func(s string) *string { return &s }
+	timer := func(i int) *int { return &i }
+
+	tests := []struct {
+		name    string
+		backend fakeBackend
+		update  GroupUpdate
+		wantErr bool
+		kind    Kind
+		renamed []string
+		timers  []int
+	}{
+		{
+			name:    "negative timer",
+			update:  GroupUpdate{ExpirationTimer: timer(-1)},
+			wantErr: true,
+			kind:    Invalid,
+		},
+		{
+			name:    "empty name",
+			update:  GroupUpdate{Name: name("")},
+			wantErr: true,
+			kind:    Invalid,
+		},
+		{
+			name:    "rename",
+			update:  GroupUpdate{Name: name("B")},
+			renamed: []string{"B"},
+		},
+		{
+			name:    "rename and disable the timer",
+			update:  GroupUpdate{Name: name("B"), ExpirationTimer: timer(0)},
+			renamed: []string{"B"},
+			timers:  []int{0},
+		},
+		{
+			name:    "signald fails",
+			backend: fakeBackend{groupErr: NewError(NotFound, "unknown group")},
+			update:  GroupUpdate{ExpirationTimer: timer(60)},
+			wantErr: true,
+			kind:    NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backend
+			b.groups = []Group{{InternalID: "a", Name: "A"}}
+			err := New(&b, 0).UpdateGroup("+1", "group.a", tt.update)
+			if tt.wantErr {
+				if err == nil || KindOf(err) != tt.kind {
+					t.Errorf("UpdateGroup() error = %v, want kind %v", err, tt.kind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateGroup() error = %v", err)
+			}
+			if !reflect.DeepEqual(b.renamed, tt.renamed) || !reflect.DeepEqual(b.timers, tt.timers) {
+				t.Errorf("renamed %v and set timers %v, want %v and %v", b.renamed, b.timers, tt.renamed, tt.timers)
+			}
+		})
+	}
+}
+
+func TestCreateGroup(t *testing.T) {
+	b := &fakeBackend{groups: []Group{{InternalID: "a", Name: "A"}}}
+	groupID, err := New(b, 0).CreateGroup("+1", "B", []string{"+2"})
+	if err != nil {
+		t.Fatalf("CreateGroup() error = %v", err)
+	}
+	if groupID != "group.id-B" {
+		t.Errorf("CreateGroup() = %q, want the id of the new group", groupID)
+	}
+	if b.changed != 1 {
+		t.Errorf("the groups changed %d times, want 1", b.changed)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{"valid code", "123-456", nil},
+		{"invalid code", "000-000", errors.New("Invalid code")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New(&fakeBackend{}, 0).Verify("+1", tt.code, "")
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
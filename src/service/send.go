@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+)
+
+// Send is a message to send to recipients or to a group.
+type Send struct {
+	Number            string
+	Message           string
+	Recipients        []string
+	Base64Attachments []string
+	AttachmentTokens  []string
+	IsGroup           bool
+	// Timestamp of the message, now if it is 0.
+	Timestamp int64
+	// Client sent the message, its quotas apply.
+	Client string
+	// Context is the context of the request that sends the message,
+	// processing the attachments stops when it is done.
+	Context context.Context
+}
+
+// RequestContext returns the context of the request, or the background
+// context if the message isn't sent on behalf of a request.
+func (s Send) RequestContext() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
+}
+
+// Result is the outcome of sending a message. Queued contains the ids of the
+// messages that are held, e.g. until the identity of a recipient is trusted.
+type Result struct {
+	Timestamp int64
+	Queued    []string
+	// Quota tells the client what is left of its quotas.
+	Quota map[string]string
+	// Results are the send results signald reported for the recipients.
+	Results []signald.SendResult
+}
+
+// attachmentContext returns the context the attachments of a message are
+// processed in, which is done after the attachment timeout or when the
+// request is done.
+func (s *Service) attachmentContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if s.attachmentTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, s.attachmentTimeout)
+}
+
+// attachmentsExpired fails with Timeout once the attachment timeout passed,
+// and with Unavailable if the request was canceled.
+func (s *Service) attachmentsExpired(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return NewError(Timeout, "Processing the attachments took longer than "+s.attachmentTimeout.String())
+	}
+	return NewError(Unavailable, "The request was canceled while processing the attachments")
+}
+
+// Submit sends a message after passing it through the send hooks. During
+// maintenance the message is held and sent when the maintenance ends.
+func (s *Service) Submit(send Send) (Result, error) {
+	b := s.backend
+	number, recipients := send.Number, append([]string(nil), send.Recipients...)
+	if len(recipients) == 0 {
+		return Result{}, NewError(Invalid, "Please specify at least one recipient")
+	}
+	if b.MaintenanceActive() {
+		if err := b.KnownAccount(number); err != nil {
+			return Result{}, err
+		}
+		id, err := b.Hold(send)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Queued: []string{id}}, nil
+	}
+
+	release, err := b.Acquire(number)
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	if !b.AllowSend(number, len(recipients)) {
+		return Result{}, NewError(RateLimited, "Send rate limit of "+number+" exceeded")
+	}
+
+	hookRecipients := recipients
+	groupID := ""
+	if send.IsGroup {
+		if len(recipients) > 1 {
+			return Result{}, NewError(Invalid, "More than one group is currently not allowed")
+		}
+
+		groupID, err = b.ResolveGroup(number, recipients[0])
+		if err != nil {
+			return Result{}, err
+		}
+		hookRecipients = []string{b.GroupID(groupID)}
+		recipients = []string{""}
+	}
+
+	message, err := b.ApplyHooks(number, hookRecipients, send.IsGroup, send.Message,
+		len(send.Base64Attachments)+len(send.AttachmentTokens))
+	if err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := s.attachmentContext(send.RequestContext())
+	defer cancel()
+
+	attachments := []Attachment{}
+	for _, encoded := range send.Base64Attachments {
+		if err := s.attachmentsExpired(ctx); err != nil {
+			return Result{}, err
+		}
+		attachment, err := b.StageAttachment(ctx, encoded)
+		if err != nil {
+			if expired := s.attachmentsExpired(ctx); expired != nil {
+				return Result{}, expired
+			}
+			return Result{}, err
+		}
+		defer b.ReleaseAttachment(attachment.Hash)
+		attachments = append(attachments, attachment)
+	}
+
+	for _, token := range send.AttachmentTokens {
+		attachment, ok := b.LookupAttachment(token)
+		if !ok {
+			return Result{}, NewError(Invalid, "Unknown or expired attachment token "+token)
+		}
+		defer b.ReleaseAttachment(attachment.Hash)
+		attachments = append(attachments, attachment)
+	}
+
+	for i := range attachments {
+		transcoded, ok, err := b.TranscodeVideo(ctx, attachments[i])
+		if err != nil {
+			if expired := s.attachmentsExpired(ctx); expired != nil {
+				return Result{}, expired
+			}
+			return Result{}, err
+		}
+		if ok {
+			defer b.ReleaseAttachment(transcoded.Hash)
+			attachments[i] = transcoded
+		}
+	}
+
+	// the files are on disk before they are handed to signald
+	if err := b.SyncAttachments(ctx); err != nil {
+		if expired := s.attachmentsExpired(ctx); expired != nil {
+			return Result{}, expired
+		}
+		return Result{}, err
+	}
+	if err := s.attachmentsExpired(ctx); err != nil {
+		return Result{}, err
+	}
+
+	quota, err := b.AllowQuota(send.Client, len(recipients), attachments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	timestamp := send.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	queued := []string{}
+	results := []signald.SendResult{}
+	for i, to := range recipients {
+		delivery := Delivery{
+			To:          to,
+			GroupID:     groupID,
+			Message:     message,
+			Attachments: attachments,
+			Timestamp:   timestamp,
+		}
+		outcome, err := b.Deliver(number, delivery)
+		results = append(results, outcome.Results...)
+
+		// Signal won't take further messages until the challenge is solved,
+		// so the remaining recipients are held as well
+		if err != nil && outcome.ProofRequired {
+			for _, pending := range recipients[i:] {
+				delivery.To = pending
+				id, perr := b.ParkChallenged(number, err, delivery)
+				if perr != nil {
+					return Result{}, perr
+				}
+				queued = append(queued, id)
+			}
+			break
+		}
+
+		if err != nil && outcome.Untrusted && to != "" {
+			if id, ok := b.QueueUntrusted(number, delivery); ok {
+				queued = append(queued, id)
+				continue
+			}
+		}
+
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{Timestamp: timestamp, Queued: queued, Quota: quota, Results: results}, nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubmit(t *testing.T) {
+	proofRequired := func(d Delivery) (Outcome, error) {
+		return Outcome{ProofRequired: true}, errors.New("proof required")
+	}
+	untrusted := func(d Delivery) (Outcome, error) {
+		if d.To == "+2" {
+			return Outcome{Untrusted: true}, errors.New("untrusted identity")
+		}
+		return Outcome{}, nil
+	}
+
+	tests := []struct {
+		name    string
+		backend fakeBackend
+		send    Send
+		wantErr bool
+		kind    Kind
+		// recipients the message was handed to signald for
+		delivered []string
+		queued    []string
+	}{
+		{
+			name:    "no recipients",
+			send:    Send{Number: "+1", Message: "hi"},
+			wantErr: true,
+			kind:    Invalid,
+		},
+		{
+			name:    "held during maintenance",
+			backend: fakeBackend{maintenance: true},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			queued:  []string{"held"},
+		},
+		{
+			name:    "unknown account during maintenance",
+			backend: fakeBackend{maintenance: true, unknown: true},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			wantErr: true,
+			kind:    NotFound,
+		},
+		{
+			name:    "backend draining",
+			backend: fakeBackend{draining: true},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			wantErr: true,
+			kind:    Unavailable,
+		},
+		{
+			name:    "rate limited",
+			backend: fakeBackend{rateLimited: true},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			wantErr: true,
+			kind:    RateLimited,
+		},
+		{
+			name:    "more than one group",
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"group.a", "group.b"}, IsGroup: true},
+			wantErr: true,
+			kind:    Invalid,
+		},
+		{
+			name:    "unknown group",
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"group.a"}, IsGroup: true},
+			wantErr: true,
+			kind:    NotFound,
+		},
+		{
+			name:    "vetoed by a send hook",
+			backend: fakeBackend{veto: true},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			wantErr: true,
+			kind:    Forbidden,
+		},
+		{
+			name:    "unknown attachment token",
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}, AttachmentTokens: []string{"gone"}},
+			wantErr: true,
+			kind:    Invalid,
+		},
+		{
+			name:    "quota exceeded",
+			backend: fakeBackend{quotaErr: &Error{Kind: RateLimited, Message: "Quota of test exceeded", RetryAfter: 60}},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}},
+			wantErr: true,
+			kind:    RateLimited,
+		},
+		{
+			name:      "sent to numbers",
+			send:      Send{Number: "+1", Message: "hi", Recipients: []string{"+2", "+3"}},
+			delivered: []string{"+2", "+3"},
+			queued:    []string{},
+		},
+		{
+			name:      "sent to a group",
+			backend:   fakeBackend{groups: []Group{{InternalID: "a", Name: "A"}}},
+			send:      Send{Number: "+1", Message: "hi", Recipients: []string{"group.a"}, IsGroup: true},
+			delivered: []string{""},
+			queued:    []string{},
+		},
+		{
+			name:      "proof required holds the remaining recipients",
+			backend:   fakeBackend{deliver: proofRequired},
+			send:      Send{Number: "+1", Message: "hi", Recipients: []string{"+2", "+3"}},
+			delivered: []string{"+2"},
+			queued:    []string{"challenge-+2", "challenge-+3"},
+		},
+		{
+			name:      "untrusted recipient queued",
+			backend:   fakeBackend{deliver: untrusted, queueUntrusted: true},
+			send:      Send{Number: "+1", Message: "hi", Recipients: []string{"+2", "+3"}},
+			delivered: []string{"+2", "+3"},
+			queued:    []string{"queued-+2"},
+		},
+		{
+			name:    "untrusted recipient without queue",
+			backend: fakeBackend{deliver: untrusted},
+			send:    Send{Number: "+1", Message: "hi", Recipients: []string{"+2", "+3"}},
+			wantErr: true,
+			kind:    Invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backend
+			result, err := New(&b, 0).Submit(tt.send)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Submit() succeeded, want error of kind %v", tt.kind)
+				}
+				if KindOf(err) != tt.kind {
+					t.Errorf("Submit() error kind = %v, want %v (%v)", KindOf(err), tt.kind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			delivered := []string{}
+			for _, d := range b.delivered {
+				delivered = append(delivered, d.To)
+			}
+			if len(tt.delivered) > 0 && !reflect.DeepEqual(delivered, tt.delivered) {
+				t.Errorf("delivered to %v, want %v", delivered, tt.delivered)
+			}
+			if !reflect.DeepEqual(result.Queued, tt.queued) {
+				t.Errorf("Queued = %v, want %v", result.Queued, tt.queued)
+			}
+			if b.acquired != 0 {
+				t.Errorf("%d operations weren't released", b.acquired)
+			}
+		})
+	}
+}
+
+func TestSubmitToGroup(t *testing.T) {
+	b := &fakeBackend{groups: []Group{{InternalID: "a", Name: "A"}}}
+	recipients := []string{"group.a"}
+	result, err := New(b, 0).Submit(Send{Number: "+1", Message: "hi", Recipients: recipients, IsGroup: true,
+		Timestamp: 42})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	want := Delivery{GroupID: "a", Message: "hi (hooked)", Attachments: []Attachment{}, Timestamp: 42}
+	if len(b.delivered) != 1 || !reflect.DeepEqual(b.delivered[0], want) {
+		t.Errorf("delivered %+v, want %+v", b.delivered, want)
+	}
+	if !reflect.DeepEqual(b.hooked, []string{"group.a"}) {
+		t.Errorf("hooks saw recipients %v, want the group id", b.hooked)
+	}
+	if result.Timestamp != 42 || result.Quota["X-Quota-Remaining"] != "1" {
+		t.Errorf("result = %+v", result)
+	}
+	if recipients[0] != "group.a" {
+		t.Errorf("the recipients of the caller were changed to %v", recipients)
+	}
+}
+
+func TestSubmitAttachments(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  fakeBackend
+		ctx      func() (context.Context, context.CancelFunc)
+		timeout  time.Duration
+		wantErr  bool
+		kind     Kind
+		releases []string
+	}{
+		{
+			name:     "staged and released",
+			backend:  fakeBackend{staged: map[string]Attachment{"token": {Hash: "token", Filename: "/tmp/token"}}},
+			releases: []string{"token", "hash-data"},
+		},
+		{
+			name:    "request canceled",
+			backend: fakeBackend{staged: map[string]Attachment{"token": {Hash: "token", Filename: "/tmp/token"}}},
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: true,
+			kind:    Unavailable,
+		},
+		{
+			name:    "timeout",
+			backend: fakeBackend{staged: map[string]Attachment{"token": {Hash: "token", Filename: "/tmp/token"}}},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			},
+			timeout: time.Second,
+			wantErr: true,
+			kind:    Timeout,
+		},
+		{
+			name: "sync fails",
+			backend: fakeBackend{
+				staged:  map[string]Attachment{"token": {Hash: "token", Filename: "/tmp/token"}},
+				syncErr: errors.New("sync failed"),
+			},
+			wantErr:  true,
+			kind:     Invalid,
+			releases: []string{"token", "hash-data"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backend
+			send := Send{Number: "+1", Message: "hi", Recipients: []string{"+2"}, Base64Attachments: []string{"data"},
+				AttachmentTokens: []string{"token"}}
+			if tt.ctx != nil {
+				ctx, cancel := tt.ctx()
+				defer cancel()
+				send.Context = ctx
+			}
+
+			_, err := New(&b, tt.timeout).Submit(send)
+			if tt.wantErr {
+				if KindOf(err) != tt.kind || err == nil {
+					t.Errorf("Submit() error = %v, want kind %v", err, tt.kind)
+				}
+			} else if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if tt.releases != nil && !reflect.DeepEqual(b.releases, tt.releases) {
+				t.Errorf("released %v, want %v", b.releases, tt.releases)
+			}
+			if !tt.wantErr {
+				want := []Attachment{{Hash: "hash-data", Filename: "/tmp/data"}, {Hash: "token", Filename: "/tmp/token"}}
+				if !reflect.DeepEqual(b.delivered[0].Attachments, want) {
+					t.Errorf("delivered attachments %v, want %v", b.delivered[0].Attachments, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package service implements sending messages and managing groups and accounts
+// independently of the transport, so that every API version (and the other
+// transports like SMTP or XMPP) share it. Its errors are Errors whose kind the
+// transport reports in its own terms, other errors are invalid requests.
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/abaskin/signald-go/signald"
+)
+
+// Attachment is an attachment staged as a file, content addressed by its
+// hash.
+type Attachment struct {
+	Hash     string
+	Filename string
+}
+
+// Delivery is a message to a single recipient (or a group) as it is handed to
+// signald.
+type Delivery struct {
+	To string
+	// GroupID is the internal id of the group the message is sent to.
+	GroupID     string
+	Message     string
+	Attachments []Attachment
+	Timestamp   int64
+}
+
+// Outcome is what signald reported for a delivery.
+type Outcome struct {
+	Results []signald.SendResult
+	// ProofRequired is set if Signal requires the number to solve a
+	// challenge before it takes further messages.
+	ProofRequired bool
+	// Untrusted is set if the identity of the recipient isn't trusted.
+	Untrusted bool
+}
+
+// Group is a group of an account.
+type Group struct {
+	InternalID string
+	Name       string
+}
+
+// Messaging is what sending a message needs: the subsystems a message passes
+// on its way to signald.
+type Messaging interface {
+	// MaintenanceActive returns whether messages are held.
+	MaintenanceActive() bool
+	// Hold keeps a message until the maintenance ends and returns its id.
+	Hold(send Send) (string, error)
+	// KnownAccount fails if number isn't an account of this instance.
+	KnownAccount(number string) error
+	// Acquire tracks an operation of number on its backend until release is
+	// called.
+	Acquire(number string) (release func(), err error)
+	// AllowSend takes sends to recipients from the rate limit of number.
+	AllowSend(number string, recipients int) bool
+	// ResolveGroup returns the internal id of a group given in any form.
+	ResolveGroup(number string, group string) (string, error)
+	// GroupID returns the group id of an internal group id.
+	GroupID(internalID string) string
+	// ApplyHooks passes the message through the send hooks and returns the
+	// message to send.
+	ApplyHooks(number string, recipients []string, isGroup bool, message string, attachments int) (string, error)
+	// StageAttachment stages a base64 encoded attachment.
+	StageAttachment(ctx context.Context, encoded string) (Attachment, error)
+	// LookupAttachment takes a reference on a staged attachment.
+	LookupAttachment(token string) (Attachment, bool)
+	// ReleaseAttachment releases a reference on a staged attachment.
+	ReleaseAttachment(hash string)
+	// TranscodeVideo returns the attachment to send in place of a video
+	// Signal won't take, ok is false if it can be sent as it is.
+	TranscodeVideo(ctx context.Context, attachment Attachment) (transcoded Attachment, ok bool, err error)
+	// SyncAttachments waits until the staged attachments are on disk.
+	SyncAttachments(ctx context.Context) error
+	// AllowQuota takes a send from the quotas of client and returns what is
+	// left of them.
+	AllowQuota(client string, recipients int, attachments []Attachment) (map[string]string, error)
+	// Deliver hands a message to signald.
+	Deliver(number string, delivery Delivery) (Outcome, error)
+	// ParkChallenged holds a delivery until the challenge Signal requires
+	// (cause) is solved and returns its id.
+	ParkChallenged(number string, cause error, delivery Delivery) (string, error)
+	// QueueUntrusted holds a delivery until the identity of its recipient is
+	// trusted and returns its id, ok is false if it wasn't queued.
+	QueueUntrusted(number string, delivery Delivery) (id string, ok bool)
+}
+
+// Groups is what managing accounts and groups needs from signald.
+type Groups interface {
+	// Verify verifies a registered number with the code it received.
+	Verify(number string, code string, pin string) error
+	CreateGroup(number string, name string, members []string) error
+	ListGroups(number string) ([]Group, error)
+	LeaveGroup(number string, internalID string) error
+	RenameGroup(number string, internalID string, name string) error
+	SetGroupExpiration(number string, internalID string, seconds int) error
+	// PromoteGroupMember makes a member, given as number or UUID, admin.
+	PromoteGroupMember(number string, internalID string, member string) error
+	// LastGroupAdmin returns whether the account is the only admin.
+	LastGroupAdmin(number string, internalID string) bool
+	// GroupsChanged is called after the groups of number changed.
+	GroupsChanged(number string)
+}
+
+// Backend is what the service needs from the rest of the API.
+type Backend interface {
+	Messaging
+	Groups
+}
+
+// Service sends messages and manages groups and accounts through a Backend.
+type Service struct {
+	backend Backend
+	// attachmentTimeout limits processing the attachments of a message, 0
+	// disables it.
+	attachmentTimeout time.Duration
+}
+
+func New(backend Backend, attachmentTimeout time.Duration) *Service {
+	return &Service{backend: backend, attachmentTimeout: attachmentTimeout}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/abaskin/signald-go/signald"
+)
+
+// fakeBackend records what the service asks of it, its fields set up the
+// answers.
+type fakeBackend struct {
+	maintenance    bool
+	unknown        bool
+	draining       bool
+	rateLimited    bool
+	groups         []Group
+	veto           bool
+	staged         map[string]Attachment
+	syncErr        error
+	quotaErr       error
+	deliver        func(d Delivery) (Outcome, error)
+	queueUntrusted bool
+	lastAdmin      bool
+	groupErr       error
+
+	held      []Send
+	hooked    []string
+	delivered []Delivery
+	parked    []Delivery
+	queued    []Delivery
+	acquired  int
+	releases  []string
+	promoted  []string
+	left      []string
+	renamed   []string
+	timers    []int
+	changed   int
+}
+
+func (b *fakeBackend) MaintenanceActive() bool {
+	return b.maintenance
+}
+
+func (b *fakeBackend) Hold(send Send) (string, error) {
+	b.held = append(b.held, send)
+	return "held", nil
+}
+
+func (b *fakeBackend) KnownAccount(number string) error {
+	if b.unknown {
+		return NewError(NotFound, "Account "+number+" not found on this instance")
+	}
+	return nil
+}
+
+func (b *fakeBackend) Acquire(number string) (func(), error) {
+	if b.draining {
+		return nil, &Error{Kind: Unavailable, Message: "draining", RetryAfter: 5}
+	}
+	if err := b.KnownAccount(number); err != nil {
+		return nil, err
+	}
+	b.acquired++
+	return func() { b.acquired-- }, nil
+}
+
+func (b *fakeBackend) AllowSend(number string, recipients int) bool {
+	return !b.rateLimited
+}
+
+func (b *fakeBackend) ResolveGroup(number string, group string) (string, error) {
+	for _, g := range b.groups {
+		if b.GroupID(g.InternalID) == group {
+			return g.InternalID, nil
+		}
+	}
+	return "", NewError(NotFound, "No such group")
+}
+
+func (b *fakeBackend) GroupID(internalID string) string {
+	return "group." + internalID
+}
+
+func (b *fakeBackend) ApplyHooks(number string, recipients []string, isGroup bool, message string,
+	attachments int) (string, error) {
+	if b.veto {
+		return "", NewError(Forbidden, "vetoed")
+	}
+	b.hooked = append(b.hooked, recipients...)
+	return message + " (hooked)", nil
+}
+
+func (b *fakeBackend) StageAttachment(ctx context.Context, encoded string) (Attachment, error) {
+	if ctx.Err() != nil {
+		return Attachment{}, ctx.Err()
+	}
+	return Attachment{Hash: "hash-" + encoded, Filename: "/tmp/" + encoded}, nil
+}
+
+func (b *fakeBackend) LookupAttachment(token string) (Attachment, bool) {
+	attachment, ok := b.staged[token]
+	return attachment, ok
+}
+
+func (b *fakeBackend) ReleaseAttachment(hash string) {
+	b.releases = append(b.releases, hash)
+}
+
+func (b *fakeBackend) TranscodeVideo(ctx context.Context, attachment Attachment) (Attachment, bool, error) {
+	return Attachment{}, false, nil
+}
+
+func (b *fakeBackend) SyncAttachments(ctx context.Context) error {
+	return b.syncErr
+}
+
+func (b *fakeBackend) AllowQuota(client string, recipients int, attachments []Attachment) (map[string]string,
+	error) {
+	if b.quotaErr != nil {
+		return nil, b.quotaErr
+	}
+	return map[string]string{"X-Quota-Remaining": "1"}, nil
+}
+
+func (b *fakeBackend) Deliver(number string, d Delivery) (Outcome, error) {
+	b.delivered = append(b.delivered, d)
+	if b.deliver != nil {
+		return b.deliver(d)
+	}
+	return Outcome{Results: []signald.SendResult{{Address: signald.RequestAddress{Number: d.To}}}}, nil
+}
+
+func (b *fakeBackend) ParkChallenged(number string, cause error, d Delivery) (string, error) {
+	b.parked = append(b.parked, d)
+	return "challenge-" + d.To, nil
+}
+
+func (b *fakeBackend) QueueUntrusted(number string, d Delivery) (string, bool) {
+	if !b.queueUntrusted {
+		return "", false
+	}
+	b.queued = append(b.queued, d)
+	return "queued-" + d.To, true
+}
+
+func (b *fakeBackend) Verify(number string, code string, pin string) error {
+	if code != "123-456" {
+		return errors.New("Invalid code")
+	}
+	return nil
+}
+
+func (b *fakeBackend) CreateGroup(number string, name string, members []string) error {
+	b.groups = append(b.groups, Group{InternalID: "id-" + name, Name: name})
+	return nil
+}
+
+func (b *fakeBackend) ListGroups(number string) ([]Group, error) {
+	return b.groups, nil
+}
+
+func (b *fakeBackend) LeaveGroup(number string, internalID string) error {
+	if b.groupErr != nil {
+		return b.groupErr
+	}
+	b.left = append(b.left, internalID)
+	return nil
+}
+
+func (b *fakeBackend) RenameGroup(number string, internalID string, name string) error {
+	if b.groupErr != nil {
+		return b.groupErr
+	}
+	b.renamed = append(b.renamed, name)
+	return nil
+}
+
+func (b *fakeBackend) SetGroupExpiration(number string, internalID string, seconds int) error {
+	if b.groupErr != nil {
+		return b.groupErr
+	}
+	b.timers = append(b.timers, seconds)
+	return nil
+}
+
+func (b *fakeBackend) PromoteGroupMember(number string, internalID string, member string) error {
+	b.promoted = append(b.promoted, member)
+	return nil
+}
+
+func (b *fakeBackend) LastGroupAdmin(number string, internalID string) bool {
+	return b.lastAdmin
+}
+
+func (b *fakeBackend) GroupsChanged(number string) {
+	b.changed++
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"service error", NewError(Timeout, "too slow"), Timeout},
+		{"error with retry", &Error{Kind: RateLimited, Message: "later", RetryAfter: 3}, RateLimited},
+		{"other error", errors.New("broken"), Invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}